@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefPickAddress is the preference key for asking which address to monitor when a name has several
+const prefPickAddress = "pickAddress"
+
+// addressLookupTimeout bounds resolving a name before the Start button opens the address picker
+const addressLookupTimeout = 5 * time.Second
+
+// onStartPressed starts a session from the Start button
+// With the picker enabled, a name with several addresses first asks which one to monitor,
+// since which backend of a CDN or load balancer you land on matters when debugging it
+func (vm *VisualMTR) onStartPressed() {
+	hostname := vm.hostnameEntry.Text
+	if !vm.app.Preferences().Bool(prefPickAddress) || hostname == "" || isAddress(hostname) {
+		vm.onStart()
+		return
+	}
+	protocol := network.Protocol(vm.protocolSelect.Selected)
+
+	vm.startButton.Disable()
+	vm.statusLabel.SetText(fmt.Sprintf("Resolving %s...", hostname))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), addressLookupTimeout)
+		defer cancel()
+		addrs, err := network.ResolveAddresses(ctx, hostname, protocol)
+
+		fyne.Do(func() {
+			vm.startButton.Enable()
+			// Lookup failures are reported by the session itself
+			if err != nil || len(addrs) < 2 {
+				vm.onStart()
+				return
+			}
+			vm.pickAddress(hostname, addrs)
+		})
+	}()
+}
+
+// pickAddress asks which of the hostname's addresses to monitor and starts the session on it
+func (vm *VisualMTR) pickAddress(hostname string, addrs []net.IPAddr) {
+	options := make([]string, len(addrs))
+	for i, addr := range addrs {
+		options[i] = addr.String()
+	}
+	picker := widget.NewRadioGroup(options, nil)
+	picker.SetSelected(options[0])
+	picker.Required = true
+
+	message := widget.NewLabel(fmt.Sprintf("%s resolves to %d addresses. Which one should be monitored?", hostname, len(addrs)))
+	message.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(message, picker)
+
+	d := dialog.NewCustomConfirm("Pick Address", "Monitor", "Cancel", content, func(ok bool) {
+		if !ok {
+			vm.statusLabel.SetText("Ready - Enter a hostname and click Start")
+			return
+		}
+		vm.hopsMutex.Lock()
+		vm.address = picker.Selected
+		vm.hopsMutex.Unlock()
+		vm.onStart()
+	}, vm.window)
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// isAddress reports whether a target is an IP address rather than a name, including scoped
+// link-local addresses such as fe80::1%eth0
+func isAddress(target string) bool {
+	_, err := netip.ParseAddr(target)
+	return err == nil
+}
@@ -0,0 +1,331 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afroash/visual-mtr/network"
+)
+
+// Severity is how urgent an alert is
+type Severity string
+
+const (
+	SeverityWarning  Severity = "Warning"
+	SeverityCritical Severity = "Critical"
+)
+
+// Metric is the hop measurement a rule checks
+type Metric string
+
+const (
+	MetricLoss    Metric = "loss"    // Packet loss percentage over the latency history window
+	MetricLatency Metric = "latency" // Average latency in milliseconds
+	// MetricExpression is the value of the rule's Expression over the hop's metrics, such as
+	// "loss*2 + jitter > 30"; a condition is 1 while it holds, so it breaches a threshold of 1
+	MetricExpression Metric = "expression"
+	// MetricSharedFate is the number of degraded targets whose paths cross one lossy hop, see
+	// network.CorrelateSharedFate; only SharedFateRule uses it
+	MetricSharedFate Metric = "shared_fate"
+)
+
+// Rule fires when a metric stays at or above Threshold for FireAfter consecutive samples,
+// and recovers once it stays below for ClearAfter consecutive samples
+type Rule struct {
+	Name       string   `json:"name"`
+	Metric     Metric   `json:"metric"`
+	Threshold  float64  `json:"threshold"`
+	Severity   Severity `json:"severity"`
+	AllHops    bool     `json:"all_hops"`    // Check every hop instead of only the destination
+	FireAfter  int      `json:"fire_after"`  // Consecutive breaching samples required before firing
+	ClearAfter int      `json:"clear_after"` // Consecutive healthy samples required before recovering
+	// Expression is computed for MetricExpression, see network.ParseExpr
+	Expression string `json:"expression,omitempty"`
+}
+
+// DefaultRules are the rules used until the user configures their own
+var DefaultRules = []Rule{
+	{Name: "Destination loss", Metric: MetricLoss, Threshold: 5, Severity: SeverityWarning, FireAfter: 5, ClearAfter: 10},
+	{Name: "Destination heavy loss", Metric: MetricLoss, Threshold: 20, Severity: SeverityCritical, FireAfter: 3, ClearAfter: 10},
+	{Name: "Destination latency", Metric: MetricLatency, Threshold: 150, Severity: SeverityWarning, FireAfter: 5, ClearAfter: 10},
+}
+
+// SharedFateRule is the built-in rule for loss on a hop shared by several targets' paths
+// While it fires, the per-target alerts it explains are held back, so one upstream fault
+// raises one alert rather than one per target. Sinks without a route of their own get it by
+// its severity
+var SharedFateRule = Rule{Name: "Shared upstream loss", Metric: MetricSharedFate, Threshold: 2, Severity: SeverityCritical, FireAfter: 2, ClearAfter: 10}
+
+// Validate reports whether the rule can be evaluated, e.g. after loading it from a file
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule has no name")
+	}
+	switch r.Metric {
+	case MetricLoss, MetricLatency:
+	case MetricExpression:
+		if _, err := network.ParseExpr(r.Expression); err != nil {
+			return fmt.Errorf("rule %q has an invalid expression: %v", r.Name, err)
+		}
+	default:
+		return fmt.Errorf("rule %q has unknown metric %q", r.Name, r.Metric)
+	}
+	if r.Severity != SeverityWarning && r.Severity != SeverityCritical {
+		return fmt.Errorf("rule %q has unknown severity %q", r.Name, r.Severity)
+	}
+	if r.FireAfter < 1 || r.ClearAfter < 1 {
+		return fmt.Errorf("rule %q needs at least one sample to fire and clear", r.Name)
+	}
+	return nil
+}
+
+// Event is a state change of a rule for one hop: either firing or recovering
+type Event struct {
+	Rule      Rule
+	Target    string
+	Hop       int    // 0-based hop index
+	IP        string // Hop address
+	Value     float64
+	Recovered bool // True for recovery notifications
+	Time      time.Time
+}
+
+// Title returns a short one-line description of the event
+func (e Event) Title() string {
+	if e.Recovered {
+		return fmt.Sprintf("Recovered: %s", e.Rule.Name)
+	}
+	return fmt.Sprintf("%s: %s", e.Rule.Severity, e.Rule.Name)
+}
+
+// Message returns the event details
+func (e Event) Message() string {
+	if e.Rule.Metric == MetricSharedFate {
+		return fmt.Sprintf("Hop %d (%s) is lossy on the paths to %.0f targets: %s", e.Hop+1, e.IP, e.Value, e.Target)
+	}
+	if e.Rule.Metric == MetricExpression {
+		return fmt.Sprintf("%s hop %d (%s): %s = %s (threshold %s)", e.Target, e.Hop+1, e.IP,
+			e.Rule.Expression, network.FormatMetricValue(e.Value), network.FormatMetricValue(e.Rule.Threshold))
+	}
+	unit := "%"
+	if e.Rule.Metric == MetricLatency {
+		unit = " ms"
+	}
+	return fmt.Sprintf("%s hop %d (%s): %s %.1f%s (threshold %.1f%s)",
+		e.Target, e.Hop+1, e.IP, e.Rule.Metric, e.Value, unit, e.Rule.Threshold, unit)
+}
+
+// ruleState tracks hysteresis for one rule on one hop
+type ruleState struct {
+	firing     bool
+	breach     int  // Consecutive breaching samples
+	healthy    int  // Consecutive healthy samples
+	suppressed bool // Fired while a shared fate alert covered it, so its recovery is held back too
+}
+
+// stateKey identifies a rule applied to a hop of a target
+type stateKey struct {
+	rule   string
+	target string
+	hop    int
+}
+
+// Engine evaluates rules against hop updates with hysteresis and duplicate suppression
+// It is safe for concurrent use
+type Engine struct {
+	mu     sync.Mutex
+	rules  []Rule
+	exprs  map[string]*network.Expr // Parsed expressions of MetricExpression rules, by rule name
+	states map[stateKey]*ruleState
+
+	fate      ruleState          // Hysteresis of SharedFateRule
+	fateFound network.SharedFate // Shared hop of the firing SharedFateRule alert
+}
+
+// NewEngine creates an engine for the given rules
+func NewEngine(rules []Rule) *Engine {
+	return &Engine{
+		rules:  rules,
+		exprs:  parseExpressions(rules),
+		states: make(map[stateKey]*ruleState),
+	}
+}
+
+// parseExpressions parses the expressions of MetricExpression rules; rules whose expression
+// doesn't parse are left out and never breach
+func parseExpressions(rules []Rule) map[string]*network.Expr {
+	exprs := make(map[string]*network.Expr)
+	for _, rule := range rules {
+		if rule.Metric != MetricExpression {
+			continue
+		}
+		if expr, err := network.ParseExpr(rule.Expression); err == nil {
+			exprs[rule.Name] = expr
+		}
+	}
+	return exprs
+}
+
+// Evaluate checks one hop sample and returns any fire or recovery events
+// An alert fires once per breach and is not repeated until it has recovered
+func (e *Engine) Evaluate(target string, index int, hop network.NetworkHop, isDestination bool) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := make([]Event, 0)
+	for _, rule := range e.rules {
+		if !rule.AllHops && !isDestination {
+			continue
+		}
+
+		key := stateKey{rule: rule.Name, target: target, hop: index}
+		state, ok := e.states[key]
+		if !ok {
+			state = &ruleState{}
+			e.states[key] = state
+		}
+
+		value := e.metricValue(rule, index, hop, isDestination)
+		if value >= rule.Threshold {
+			state.breach++
+			state.healthy = 0
+		} else {
+			state.healthy++
+			state.breach = 0
+		}
+
+		switch {
+		case !state.firing && state.breach >= max(rule.FireAfter, 1):
+			state.firing = true
+			state.suppressed = e.coveredBySharedFate(target, index)
+		case state.firing && state.healthy >= max(rule.ClearAfter, 1):
+			state.firing = false
+		default:
+			continue // No state change, nothing to report
+		}
+		if state.suppressed {
+			state.suppressed = state.firing
+			continue
+		}
+
+		events = append(events, Event{
+			Rule:      rule,
+			Target:    target,
+			Hop:       index,
+			IP:        hop.IP,
+			Value:     value,
+			Recovered: !state.firing,
+			Time:      time.Now(),
+		})
+	}
+	return events
+}
+
+// EvaluateSharedFate checks one sample of the correlation across targets, found telling whether
+// network.CorrelateSharedFate found a shared lossy hop, and returns any SharedFateRule event
+func (e *Engine) EvaluateSharedFate(fate network.SharedFate, found bool) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rule := SharedFateRule
+	if found && float64(len(fate.Targets)) >= rule.Threshold {
+		e.fate.breach++
+		e.fate.healthy = 0
+	} else {
+		e.fate.healthy++
+		e.fate.breach = 0
+	}
+
+	switch {
+	case !e.fate.firing && e.fate.breach >= rule.FireAfter:
+		e.fate.firing = true
+		e.fateFound = fate
+		return []Event{sharedFateEvent(fate, false)}
+	case e.fate.firing && e.fate.healthy >= rule.ClearAfter:
+		e.fate.firing = false
+		last := e.fateFound
+		e.fateFound = network.SharedFate{}
+		return []Event{sharedFateEvent(last, true)}
+	}
+	// The firing alert follows its hop as targets join or leave it
+	if e.fate.firing && found && fate.IP == e.fateFound.IP {
+		e.fateFound = fate
+	}
+	return nil
+}
+
+// sharedFateEvent reports SharedFateRule firing or recovering for a shared hop
+func sharedFateEvent(fate network.SharedFate, recovered bool) Event {
+	return Event{
+		Rule:      SharedFateRule,
+		Target:    strings.Join(fate.Targets, ", "),
+		Hop:       fate.Index,
+		IP:        fate.IP,
+		Value:     float64(len(fate.Targets)),
+		Recovered: recovered,
+		Time:      time.Now(),
+	}
+}
+
+// SharedFate returns the shared lossy hop of the firing SharedFateRule alert, if any
+func (e *Engine) SharedFate() (network.SharedFate, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fateFound, e.fate.firing
+}
+
+// coveredBySharedFate reports whether the firing SharedFateRule alert explains alerts on a hop:
+// the target's path crosses the shared hop at or before it; callers hold e.mu
+func (e *Engine) coveredBySharedFate(target string, index int) bool {
+	if !e.fate.firing {
+		return false
+	}
+	position, ok := e.fateFound.Hops[target]
+	return ok && index >= position
+}
+
+// Rules returns the rules the engine evaluates
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Rule(nil), e.rules...)
+}
+
+// SetRules replaces the rules the engine evaluates and clears all hysteresis state
+func (e *Engine) SetRules(rules []Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = append([]Rule(nil), rules...)
+	e.exprs = parseExpressions(rules)
+	e.states = make(map[stateKey]*ruleState)
+}
+
+// Reset clears all hysteresis state, e.g. when a new session starts
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.states = make(map[stateKey]*ruleState)
+	e.fate = ruleState{}
+	e.fateFound = network.SharedFate{}
+}
+
+// metricValue extracts the rule's metric from a hop; callers hold e.mu
+func (e *Engine) metricValue(rule Rule, index int, hop network.NetworkHop, isDestination bool) float64 {
+	switch rule.Metric {
+	case MetricLoss:
+		return network.HistoryLossPercent(hop)
+	case MetricLatency:
+		return hop.AvgLatency
+	case MetricExpression:
+		expr, ok := e.exprs[rule.Name]
+		if !ok {
+			return math.Inf(-1)
+		}
+		return expr.Eval(network.HopMetrics(hop, index, isDestination))
+	default:
+		return 0
+	}
+}
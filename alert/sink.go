@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Built-in sink names
+const (
+	SinkInApp   = "In-app"
+	SinkSound   = "Sound"
+	SinkWebhook = "Webhook"
+)
+
+// Sink delivers alert events to a channel such as a notification, sound or webhook
+type Sink interface {
+	Name() string
+	Send(event Event) error
+}
+
+// defaultRoutes maps severities to sinks when a rule has no explicit route
+var defaultRoutes = map[Severity][]string{
+	SeverityCritical: {SinkInApp, SinkSound, SinkWebhook},
+	SeverityWarning:  {SinkInApp},
+}
+
+// sinkQueueSize is how many events may wait for a sink before newer ones are dropped
+const sinkQueueSize = 64
+
+// Router sends each rule's events to the sinks configured for it
+// Each sink delivers from its own queue, so a slow or unreachable webhook delays neither the
+// other sinks nor the caller. It is safe for concurrent use
+type Router struct {
+	mu     sync.RWMutex
+	sinks  map[string]Sink
+	queues map[string]chan Event // Events waiting for each sink, drained by one goroutine per sink
+	order  []string              // Sink names in registration order
+	routes map[string][]string   // Rule name -> sink names
+	closed bool
+
+	// OnError is called from a sink's goroutine when it fails to deliver an event
+	// Set it before dispatching events
+	OnError func(sink string, event Event, err error)
+}
+
+// NewRouter creates a router with no sinks
+func NewRouter() *Router {
+	return &Router{
+		sinks:  make(map[string]Sink),
+		queues: make(map[string]chan Event),
+		order:  make([]string, 0),
+		routes: make(map[string][]string),
+	}
+}
+
+// AddSink registers a sink, replacing any sink with the same name
+// Events already queued for the name are delivered by the new sink
+func (r *Router) AddSink(sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := sink.Name()
+	if _, ok := r.sinks[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.sinks[name] = sink
+	if _, ok := r.queues[name]; !ok && !r.closed {
+		queue := make(chan Event, sinkQueueSize)
+		r.queues[name] = queue
+		go r.deliver(name, queue)
+	}
+}
+
+// deliver sends the events queued for a sink until the router is closed
+func (r *Router) deliver(name string, queue chan Event) {
+	for event := range queue {
+		r.mu.RLock()
+		sink := r.sinks[name]
+		r.mu.RUnlock()
+		if err := sink.Send(event); err != nil && r.OnError != nil {
+			r.OnError(name, event, err)
+		}
+	}
+}
+
+// Sinks returns the registered sink names
+func (r *Router) Sinks() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.order...)
+}
+
+// SetRoute sets which sinks receive the rule's events
+func (r *Router) SetRoute(rule string, sinks []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[rule] = append([]string(nil), sinks...)
+}
+
+// Route returns the sinks for a rule, falling back to the severity default
+func (r *Router) Route(rule Rule) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if sinks, ok := r.routes[rule.Name]; ok {
+		return append([]string(nil), sinks...)
+	}
+	return append([]string(nil), defaultRoutes[rule.Severity]...)
+}
+
+// Dispatch queues the event for every sink routed for its rule and returns without waiting
+// for delivery, whose failures go to OnError. Unknown sink names are ignored; sinks whose
+// queue is full drop the event, and those are joined in the error
+func (r *Router) Dispatch(event Event) error {
+	var errs []error
+	for _, name := range r.Route(event.Rule) {
+		r.mu.RLock()
+		queue, ok := r.queues[name]
+		if ok && !r.closed {
+			select {
+			case queue <- event:
+			default:
+				errs = append(errs, fmt.Errorf("%s: %d events already waiting, dropped", name, sinkQueueSize))
+			}
+		}
+		r.mu.RUnlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the sinks' goroutines once they have delivered the events already queued
+// Events dispatched afterwards are dropped
+func (r *Router) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	for _, queue := range r.queues {
+		close(queue)
+	}
+}
+
+// Test sends a synthetic event to a single sink to verify delivery and payload formatting
+func (r *Router) Test(name string) error {
+	r.mu.RLock()
+	sink, ok := r.sinks[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown alert sink %q", name)
+	}
+	return sink.Send(TestEvent())
+}
+
+// TestEvent returns a synthetic event used to test-fire sinks
+func TestEvent() Event {
+	return Event{
+		Rule:      Rule{Name: "Test alert", Metric: MetricLoss, Threshold: 5, Severity: SeverityWarning},
+		Target:    "visual-mtr test",
+		IP:        "192.0.2.1", // TEST-NET-1, never a real hop
+		Value:     0,
+		Recovered: false,
+		Time:      time.Now(),
+	}
+}
+
+// SoundSink rings the terminal bell, which most desktops turn into a system beep
+type SoundSink struct{}
+
+// Name returns the sink name
+func (SoundSink) Name() string { return SinkSound }
+
+// Send rings the bell
+func (SoundSink) Send(event Event) error {
+	_, err := os.Stderr.WriteString("\a")
+	return err
+}
+
+// WebhookSink POSTs events as JSON to a URL
+type WebhookSink struct {
+	URL    string
+	Client *http.Client // Defaults to a client with a 10 second timeout
+}
+
+// webhookPayload is the JSON body sent to webhooks
+type webhookPayload struct {
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Rule      string    `json:"rule"`
+	Severity  Severity  `json:"severity"`
+	Target    string    `json:"target"`
+	Hop       int       `json:"hop"`
+	IP        string    `json:"ip"`
+	Value     float64   `json:"value"`
+	Recovered bool      `json:"recovered"`
+	Time      time.Time `json:"time"`
+}
+
+// Name returns the sink name
+func (w *WebhookSink) Name() string { return SinkWebhook }
+
+// Send posts the event; a webhook without a URL is treated as disabled
+func (w *WebhookSink) Send(event Event) error {
+	if w.URL == "" {
+		return nil
+	}
+	return w.post(event)
+}
+
+// Test posts a synthetic event, failing if no URL is configured
+func (w *WebhookSink) Test() error {
+	if w.URL == "" {
+		return fmt.Errorf("no webhook URL configured")
+	}
+	return w.post(TestEvent())
+}
+
+// post sends the event as JSON
+func (w *WebhookSink) post(event Event) error {
+	body, err := json.Marshal(webhookPayload{
+		Title:     event.Title(),
+		Message:   event.Message(),
+		Rule:      event.Rule.Name,
+		Severity:  event.Rule.Severity,
+		Target:    event.Target,
+		Hop:       event.Hop + 1,
+		IP:        event.IP,
+		Value:     event.Value,
+		Recovered: event.Recovered,
+		Time:      event.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %v", err)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
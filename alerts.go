@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/alert"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// Alert preference keys
+const (
+	prefAlertWebhookURL  = "alertWebhookURL"
+	prefAlertRoutePrefix = "alertRoute." // Followed by the rule name
+)
+
+// sharedFateWindow is how long after a target's session ended its last path still counts when
+// correlating loss across targets; older paths say little about the network now
+const sharedFateWindow = 15 * time.Minute
+
+// notificationSink shows alerts as desktop notifications and in the status bar
+type notificationSink struct {
+	vm *VisualMTR
+}
+
+// Name returns the sink name
+func (n notificationSink) Name() string { return alert.SinkInApp }
+
+// Send shows the notification
+func (n notificationSink) Send(event alert.Event) error {
+	n.vm.app.SendNotification(fyne.NewNotification(event.Title(), event.Message()))
+	fyne.Do(func() {
+		n.vm.statusLabel.SetText(event.Title() + " - " + event.Message())
+	})
+	return nil
+}
+
+// newWebhookSink creates a webhook sink posting to url through the proxy set in Network Access
+func newWebhookSink(url string) *alert.WebhookSink {
+	client := &http.Client{Transport: network.HTTPClient().Transport, Timeout: 10 * time.Second}
+	return &alert.WebhookSink{URL: url, Client: client}
+}
+
+// setupAlertRouting registers the alert sinks and loads per-rule routes from preferences
+func (vm *VisualMTR) setupAlertRouting() {
+	prefs := vm.app.Preferences()
+
+	// A router replaced after importing a configuration finishes its queued events, then stops
+	if vm.alertRouter != nil {
+		vm.alertRouter.Close()
+	}
+	vm.alertRouter = alert.NewRouter()
+	vm.alertRouter.OnError = func(sink string, event alert.Event, err error) {
+		log.Printf("[ALERT] Delivery of %q to %s failed: %v\n", event.Title(), sink, err)
+	}
+	vm.alertRouter.AddSink(notificationSink{vm: vm})
+	vm.alertRouter.AddSink(alert.SoundSink{})
+	vm.alertRouter.AddSink(newWebhookSink(prefs.String(prefAlertWebhookURL)))
+
+	for _, rule := range vm.alertRules() {
+		routes := prefs.StringListWithFallback(prefAlertRoutePrefix+rule.Name, vm.alertRouter.Route(rule))
+		vm.alertRouter.SetRoute(rule.Name, routes)
+	}
+}
+
+// alertRules returns the configured rules with the built-in ones, which are routed like any other
+func (vm *VisualMTR) alertRules() []alert.Rule {
+	return append(vm.alerts.Rules(), alert.SharedFateRule)
+}
+
+// sharedFatePaths returns the current target's live path with the paths of targets whose
+// sessions ended within sharedFateWindow
+func (vm *VisualMTR) sharedFatePaths() map[string][]network.NetworkHop {
+	paths := vm.pathCache.Since(time.Now().Add(-sharedFateWindow))
+	vm.hopsMutex.RLock()
+	if vm.target != "" && len(vm.hops) > 0 {
+		paths[vm.target] = append([]network.NetworkHop(nil), vm.hops...)
+	}
+	vm.hopsMutex.RUnlock()
+	return paths
+}
+
+// correlateSharedFate checks whether one lossy hop explains loss on several targets and alerts
+// once for all of them; the per-target alerts it explains are held back meanwhile
+func (vm *VisualMTR) correlateSharedFate() {
+	fate, found := network.CorrelateSharedFate(vm.sharedFatePaths())
+	for _, event := range vm.alerts.EvaluateSharedFate(fate, found) {
+		vm.deliverAlert(event)
+	}
+}
+
+// deliverAlert queues an alert event for the sinks routed for its rule, without waiting for them,
+// and lists it in the daily digest
+func (vm *VisualMTR) deliverAlert(event alert.Event) {
+	log.Printf("[ALERT] %s - %s\n", event.Title(), event.Message())
+	vm.recordDigestAlert(event)
+	if err := vm.alertRouter.Dispatch(event); err != nil {
+		log.Printf("[ALERT] Delivery dropped: %v\n", err)
+	}
+}
+
+// onAlertSettings lets the user choose which sinks each rule is routed to
+func (vm *VisualMTR) onAlertSettings() {
+	prefs := vm.app.Preferences()
+
+	webhookEntry := widget.NewEntry()
+	webhookEntry.SetPlaceHolder("https://hooks.example.com/visual-mtr")
+	webhookEntry.SetText(prefs.String(prefAlertWebhookURL))
+
+	items := []*widget.FormItem{widget.NewFormItem("Webhook URL", webhookEntry)}
+
+	rules := vm.alertRules()
+	groups := make([]*widget.CheckGroup, len(rules))
+	for i, rule := range rules {
+		groups[i] = widget.NewCheckGroup(vm.alertRouter.Sinks(), nil)
+		groups[i].Horizontal = true
+		groups[i].SetSelected(vm.alertRouter.Route(rule))
+		items = append(items, widget.NewFormItem(string(rule.Severity)+": "+rule.Name, groups[i]))
+	}
+
+	// Test-fire buttons verify each sink before relying on it in a real incident
+	testButtons := container.NewHBox()
+	for _, name := range vm.alertRouter.Sinks() {
+		testButtons.Add(widget.NewButton("Test "+name, func() {
+			vm.testAlertSink(name, webhookEntry.Text)
+		}))
+	}
+	items = append(items, widget.NewFormItem("Send test alert", testButtons))
+
+	d := dialog.NewForm("Alert Settings", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		prefs.SetString(prefAlertWebhookURL, webhookEntry.Text)
+		vm.alertRouter.AddSink(newWebhookSink(webhookEntry.Text))
+
+		for i, rule := range rules {
+			prefs.SetStringList(prefAlertRoutePrefix+rule.Name, groups[i].Selected)
+			vm.alertRouter.SetRoute(rule.Name, groups[i].Selected)
+		}
+	}, vm.window)
+	d.Resize(fyne.NewSize(600, 0))
+	d.Show()
+}
+
+// testAlertSink sends a synthetic alert to one sink in the background and reports the result
+// The webhook is tested with the URL currently entered, before it is saved
+func (vm *VisualMTR) testAlertSink(name, webhookURL string) {
+	go func() {
+		var err error
+		if name == alert.SinkWebhook {
+			err = newWebhookSink(webhookURL).Test()
+		} else {
+			err = vm.alertRouter.Test(name)
+		}
+
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s test failed: %v", name, err), vm.window)
+				return
+			}
+			dialog.ShowInformation("Test Alert", name+" test alert sent successfully.", vm.window)
+		})
+	}()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// brandingFile is the branding config for white-label builds, set at build time with
+// -ldflags "-X main.brandingFile=/path/to/branding.json"
+var brandingFile = ""
+
+// defaultBrandingFile is looked for next to the executable when no path is built in
+const defaultBrandingFile = "branding.json"
+
+// Settings a branded build can lock
+const (
+	lockAlertSettings  = "alerts"         // Alert Settings dialog
+	lockProviderStatus = "providerStatus" // Provider status toggle
+	lockThroughputURL  = "throughputURL"  // Throughput test download URL
+	lockIXPRefresh     = "ixpRefresh"     // Refreshing IXP data from PeeringDB
+)
+
+// Branding customizes the app for an MSP shipping it to their customers
+type Branding struct {
+	AppName        string   `json:"app_name"`        // Product name in the title and reports
+	WindowTitle    string   `json:"window_title"`    // Main window title
+	Logo           string   `json:"logo"`            // Image used as the app icon, relative to the config file
+	DefaultTargets []string `json:"default_targets"` // Quick picks for the target; the first is prefilled
+	LockedSettings []string `json:"locked_settings"` // Settings users can't change (see lock* constants)
+
+	// Preset values for settings; locked settings are reset to these on every start
+	WebhookURL          string `json:"webhook_url"`
+	ThroughputURL       string `json:"throughput_url"`
+	CheckProviderStatus bool   `json:"check_provider_status"`
+
+	dir string // Directory of the config file, for resolving the logo
+}
+
+// defaultBranding is used when no branding config is present
+var defaultBranding = Branding{
+	AppName:     "Visual MTR",
+	WindowTitle: "Visual MTR - Network Path Health Monitor",
+}
+
+// loadBranding reads the branding config, falling back to the stock branding
+func loadBranding() Branding {
+	path := brandingFile
+	if path == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return defaultBranding
+		}
+		path = filepath.Join(filepath.Dir(exe), defaultBrandingFile)
+	}
+
+	branding, err := readBranding(path)
+	if err != nil {
+		if !os.IsNotExist(err) || brandingFile != "" {
+			log.Printf("[DEBUG] Using default branding: %v\n", err)
+		}
+		return defaultBranding
+	}
+	return branding
+}
+
+// readBranding parses a branding config, filling unset names from the stock branding
+func readBranding(path string) (Branding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Branding{}, err
+	}
+
+	var branding Branding
+	if err := json.Unmarshal(data, &branding); err != nil {
+		return Branding{}, fmt.Errorf("failed to parse branding config: %v", err)
+	}
+	if branding.AppName == "" {
+		branding.AppName = defaultBranding.AppName
+	}
+	if branding.WindowTitle == "" {
+		branding.WindowTitle = branding.AppName
+	}
+	branding.dir = filepath.Dir(path)
+	return branding, nil
+}
+
+// Locked reports whether a setting can't be changed by the user
+func (b Branding) Locked(setting string) bool {
+	return slices.Contains(b.LockedSettings, setting)
+}
+
+// apply sets the app icon, report names and preset settings
+func (b Branding) apply(a fyne.App) {
+	network.ProductName = b.AppName
+
+	if b.Logo != "" {
+		logo := b.Logo
+		if !filepath.IsAbs(logo) {
+			logo = filepath.Join(b.dir, logo)
+		}
+		icon, err := fyne.LoadResourceFromPath(logo)
+		if err != nil {
+			log.Printf("[DEBUG] Failed to load branding logo: %v\n", err)
+		} else {
+			a.SetIcon(icon)
+		}
+	}
+
+	prefs := a.Preferences()
+	if b.WebhookURL != "" && (b.Locked(lockAlertSettings) || prefs.String(prefAlertWebhookURL) == "") {
+		prefs.SetString(prefAlertWebhookURL, b.WebhookURL)
+	}
+	if b.ThroughputURL != "" && (b.Locked(lockThroughputURL) || prefs.String(prefThroughputURL) == "") {
+		prefs.SetString(prefThroughputURL, b.ThroughputURL)
+	}
+	if b.Locked(lockProviderStatus) {
+		prefs.SetBool(prefCheckProviderStatus, b.CheckProviderStatus)
+	}
+}
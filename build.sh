@@ -1,9 +1,14 @@
 #!/bin/bash
 # Build script for Visual MTR
 # This builds the application so it can be run with sudo
+# Set BRANDING=/path/to/branding.json to build a white-label version
 
 echo "Building visual-mtr..."
-go build -o visual-mtr .
+if [ -n "$BRANDING" ]; then
+    go build -ldflags "-X main.brandingFile=$BRANDING" -o visual-mtr .
+else
+    go build -o visual-mtr .
+fi
 if [ $? -eq 0 ]; then
     echo "Build successful!"
     echo ""
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// uplinkSession monitors the destination from one local source address, or over one IP version
+type uplinkSession struct {
+	label   string // Names the session in its column: the source address or the IP version
+	scanner *network.Scanner
+	mu      sync.Mutex
+	hops    []network.NetworkHop
+	err     error
+}
+
+// run starts the scanner and collects its hop updates until it stops
+func (u *uplinkSession) run() {
+	go func() {
+		if err := u.scanner.Start(context.Background()); err != nil {
+			u.mu.Lock()
+			u.err = err
+			u.mu.Unlock()
+		}
+	}()
+
+	go func() {
+		for update := range u.scanner.Updates() {
+			if update.Pending {
+				continue
+			}
+			u.mu.Lock()
+			for len(u.hops) <= update.Index {
+				u.hops = append(u.hops, network.NetworkHop{})
+			}
+			u.hops[update.Index] = update.Hop
+			u.mu.Unlock()
+		}
+	}()
+}
+
+// comparisonRows are the metrics shown per session, after the row naming the sessions
+var comparisonRows = []string{"Hops", "Destination latency", "Destination loss", "Path"}
+
+// column returns the session's label, then its metric values in comparisonRows order
+func (u *uplinkSession) column() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.err != nil {
+		return []string{u.label, "-", "-", "-", fmt.Sprintf("Error: %v", u.err)}
+	}
+	if len(u.hops) == 0 {
+		return []string{u.label, "0", "N/A", "N/A", "Tracing..."}
+	}
+
+	dest := u.hops[len(u.hops)-1]
+	latency := "N/A"
+	if dest.AvgLatency > 0 {
+		latency = ui.FormatLatency(dest.AvgLatency)
+	}
+	path := make([]string, len(u.hops))
+	for i, hop := range u.hops {
+		path[i] = hop.IP
+	}
+	return []string{
+		u.label,
+		fmt.Sprintf("%d", len(u.hops)),
+		latency,
+		fmt.Sprintf("%.1f%%", network.HistoryLossPercent(dest)),
+		strings.Join(path, "\n"),
+	}
+}
+
+// onCompareUplinks asks for two local source addresses to compare paths to the same destination
+func (vm *VisualMTR) onCompareUplinks() {
+	targetEntry := widget.NewEntry()
+	targetEntry.SetText(vm.hostnameEntry.Text)
+	sourceA := widget.NewEntry()
+	sourceA.SetPlaceHolder("Local IP or interface on uplink A, e.g. 192.168.1.10 or eth0")
+	sourceB := widget.NewEntry()
+	sourceB.SetPlaceHolder("Local IP or interface on uplink B, e.g. 10.0.0.10 or wg0")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Target", targetEntry),
+		widget.NewFormItem("Uplink A source", sourceA),
+		widget.NewFormItem("Uplink B source", sourceB),
+	}
+	d := dialog.NewForm("Compare Uplinks", "Start", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if targetEntry.Text == "" || sourceA.Text == "" || sourceB.Text == "" {
+			dialog.ShowInformation("Compare Uplinks", "Enter a target and a source address for each uplink.", vm.window)
+			return
+		}
+		vm.showUplinkComparison(targetEntry.Text, []string{sourceA.Text, sourceB.Text})
+	}, vm.window)
+	d.Resize(fyne.NewSize(450, 0))
+	d.Show()
+}
+
+// showUplinkComparison runs one session per source address and shows them side by side until closed
+func (vm *VisualMTR) showUplinkComparison(target string, sources []string) {
+	sessions := make([]*uplinkSession, len(sources))
+	for i, source := range sources {
+		sessions[i] = &uplinkSession{
+			label:   source,
+			scanner: network.NewScannerFrom(target, source, vm.scannerOptions()...),
+			hops:    make([]network.NetworkHop, 0),
+		}
+	}
+	vm.showComparison("Uplink Comparison - "+target, "Source", sessions)
+}
+
+// onCompareDualStack monitors the target over IPv4 and IPv6 at once, comparing both paths side by side
+func (vm *VisualMTR) onCompareDualStack() {
+	target := vm.hostnameEntry.Text
+	if target == "" {
+		dialog.ShowInformation("Compare IPv4 and IPv6", "Enter a hostname with both A and AAAA records first.", vm.window)
+		return
+	}
+
+	protocols := []network.Protocol{network.ProtocolIPv4, network.ProtocolIPv6}
+	sessions := make([]*uplinkSession, len(protocols))
+	for i, protocol := range protocols {
+		scanner := network.NewScanner(target, vm.scannerOptions()...)
+		scanner.SetProtocol(protocol)
+		scanner.SetProbesPerRound(vm.probesPerRound())
+		scanner.SetASNResolver(vm.asnResolver)
+		sessions[i] = &uplinkSession{
+			label:   string(protocol),
+			scanner: scanner,
+			hops:    make([]network.NetworkHop, 0),
+		}
+	}
+	vm.showComparison("IPv4 / IPv6 Comparison - "+target, "IP version", sessions)
+}
+
+// showComparison runs the sessions and shows them side by side until the window is closed
+// The first row names each session under header
+func (vm *VisualMTR) showComparison(title, header string, sessions []*uplinkSession) {
+	w := vm.app.NewWindow(title)
+	for _, session := range sessions {
+		session.run()
+	}
+
+	// Grid: one header column of metric names, then one column per session
+	grid := container.NewGridWithColumns(len(sessions) + 1)
+	rows := append([]string{header}, comparisonRows...)
+	values := make([][]*widget.Label, len(rows))
+	for row, name := range rows {
+		header := widget.NewLabel(name)
+		header.TextStyle = fyne.TextStyle{Bold: true}
+		grid.Add(header)
+
+		values[row] = make([]*widget.Label, len(sessions))
+		for col := range sessions {
+			values[row][col] = widget.NewLabel("")
+			grid.Add(values[row][col])
+		}
+	}
+
+	done := make(chan struct{})
+	w.SetOnClosed(func() {
+		close(done)
+		for _, session := range sessions {
+			session.scanner.Stop()
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				columns := make([][]string, len(sessions))
+				for i, session := range sessions {
+					columns[i] = session.column()
+				}
+				fyne.Do(func() {
+					for col, column := range columns {
+						for row, value := range column {
+							values[row][col].SetText(value)
+						}
+					}
+				})
+			}
+		}
+	}()
+
+	w.SetContent(container.NewScroll(grid))
+	w.Resize(fyne.NewSize(700, 500))
+	w.Show()
+}
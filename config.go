@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/afroash/visual-mtr/alert"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// configVersion is the format version written to exported configurations
+const configVersion = 1
+
+// prefTargets is the preference key for the quick-pick targets of unbranded builds
+const prefTargets = "targets"
+
+// AppConfig is the application configuration exported to a single JSON file
+// so it can be backed up or cloned onto other NOC workstations
+type AppConfig struct {
+	Version             int                 `json:"version"`
+	Exported            time.Time           `json:"exported"`
+	Targets             []string            `json:"targets"` // Quick-pick targets in display order
+	Protocol            string              `json:"protocol"`
+	ProbeMethod         string              `json:"probe_method"`
+	TCPPort             int                 `json:"tcp_port"`
+	ProbesPerRound      int                 `json:"probes_per_round,omitempty"`
+	AlertRules          []alert.Rule        `json:"alert_rules"`  // Thresholds and hysteresis
+	AlertRoutes         map[string][]string `json:"alert_routes"` // Sinks for each rule, by rule name
+	WebhookURL          string              `json:"webhook_url"`
+	ThroughputURL       string              `json:"throughput_url"`
+	CheckProviderStatus bool                `json:"check_provider_status"`
+	PickAddress         bool                `json:"pick_address"` // Ask which address to monitor when a name has several
+	ReportLanguage      string              `json:"report_language,omitempty"`
+	DerivedMetrics      []string            `json:"derived_metrics,omitempty"` // Derived metric columns, each "name = expression"
+	Layouts             []Layout            `json:"layouts"`
+}
+
+// alertRulesPath returns where alert rules imported from a configuration are stored
+func alertRulesPath(a fyne.App) string {
+	return filepath.Join(a.Storage().RootURI().Path(), "alerts.json")
+}
+
+// loadAlertRules reads the stored alert rules, falling back to the defaults
+func loadAlertRules(path string) []alert.Rule {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[DEBUG] Using default alert rules: %v\n", err)
+		}
+		return alert.DefaultRules
+	}
+
+	var rules []alert.Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("[DEBUG] Using default alert rules: failed to parse %s: %v\n", path, err)
+		return alert.DefaultRules
+	}
+	return rules
+}
+
+// saveAlertRules writes the alert rules to path
+func saveAlertRules(path string, rules []alert.Rule) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode alert rules: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create alert rules directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alert rules: %v", err)
+	}
+	return nil
+}
+
+// readConfig parses and validates an exported configuration
+func readConfig(r io.Reader) (AppConfig, error) {
+	var config AppConfig
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return AppConfig{}, fmt.Errorf("failed to parse configuration: %v", err)
+	}
+	if config.Version < 1 || config.Version > configVersion {
+		return AppConfig{}, fmt.Errorf("unsupported configuration version %d", config.Version)
+	}
+	for _, rule := range config.AlertRules {
+		if err := rule.Validate(); err != nil {
+			return AppConfig{}, fmt.Errorf("invalid alert rule: %v", err)
+		}
+	}
+	for _, definition := range config.DerivedMetrics {
+		if _, err := network.ParseDerivedMetric(definition); err != nil {
+			return AppConfig{}, fmt.Errorf("invalid derived metric: %v", err)
+		}
+	}
+	if config.TCPPort < 0 || config.TCPPort > 65535 {
+		return AppConfig{}, fmt.Errorf("invalid TCP port: %d", config.TCPPort)
+	}
+	if config.ProbesPerRound < 0 || config.ProbesPerRound > network.MaxProbesPerRound {
+		return AppConfig{}, fmt.Errorf("invalid probes per round: %d", config.ProbesPerRound)
+	}
+	return config, nil
+}
+
+// currentConfig collects the configuration in effect
+func (vm *VisualMTR) currentConfig() (AppConfig, error) {
+	prefs := vm.app.Preferences()
+
+	layouts, err := loadLayouts(vm.layoutsPath())
+	if err != nil {
+		return AppConfig{}, err
+	}
+	port, _ := strconv.Atoi(vm.portEntry.Text)
+
+	config := AppConfig{
+		Version:             configVersion,
+		Exported:            time.Now(),
+		Targets:             vm.quickTargets,
+		Protocol:            vm.protocolSelect.Selected,
+		ProbeMethod:         vm.methodSelect.Selected,
+		TCPPort:             port,
+		ProbesPerRound:      vm.probesPerRound(),
+		AlertRules:          vm.alerts.Rules(),
+		AlertRoutes:         make(map[string][]string),
+		WebhookURL:          prefs.String(prefAlertWebhookURL),
+		ThroughputURL:       prefs.String(prefThroughputURL),
+		CheckProviderStatus: prefs.Bool(prefCheckProviderStatus),
+		PickAddress:         prefs.Bool(prefPickAddress),
+		ReportLanguage:      string(vm.reportLanguage()),
+		DerivedMetrics:      prefs.StringList(prefDerivedMetrics),
+		Layouts:             layouts,
+	}
+	for _, rule := range config.AlertRules {
+		config.AlertRoutes[rule.Name] = vm.alertRouter.Route(rule)
+	}
+	return config, nil
+}
+
+// onExportConfig asks for a file and writes the current configuration to it
+func (vm *VisualMTR) onExportConfig() {
+	config, err := vm.currentConfig()
+	if err != nil {
+		dialog.ShowError(err, vm.window)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to write configuration: %v", err), vm.window)
+			return
+		}
+		vm.statusLabel.SetText("Configuration exported")
+	}, vm.window)
+	save.SetFileName("visual-mtr-config.json")
+	save.Show()
+}
+
+// onImportConfig asks for an exported configuration and applies it after confirmation
+func (vm *VisualMTR) onImportConfig() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if reader == nil {
+			return // Cancelled
+		}
+		defer reader.Close()
+
+		config, err := readConfig(reader)
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+
+		message := "Replace your targets, probe settings, alerts and layouts with the imported configuration?"
+		if len(vm.branding.LockedSettings) > 0 {
+			message += "\n\nSettings locked by this build are kept."
+		}
+		dialog.ShowConfirm("Import Configuration", message, func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := vm.applyConfig(config); err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			vm.statusLabel.SetText("Configuration imported")
+		}, vm.window)
+	}, vm.window)
+}
+
+// applyConfig replaces the current configuration, keeping settings locked by branding
+func (vm *VisualMTR) applyConfig(config AppConfig) error {
+	prefs := vm.app.Preferences()
+
+	if err := saveLayouts(vm.layoutsPath(), config.Layouts); err != nil {
+		return err
+	}
+
+	if len(vm.branding.DefaultTargets) == 0 {
+		prefs.SetStringList(prefTargets, config.Targets)
+	}
+	vm.quickTargets = config.Targets
+	vm.targetSelect.SetOptions(config.Targets)
+	if len(config.Targets) > 0 && !vm.startButton.Disabled() {
+		vm.hostnameEntry.SetText(config.Targets[0])
+	}
+
+	// The selects persist their own preferences; the choices apply to the next session
+	if config.Protocol != "" {
+		vm.protocolSelect.SetSelected(config.Protocol)
+	}
+	if config.ProbeMethod != "" {
+		vm.methodSelect.SetSelected(config.ProbeMethod)
+	}
+	if config.TCPPort != 0 {
+		vm.portEntry.SetText(strconv.Itoa(config.TCPPort))
+	}
+	if config.ProbesPerRound != 0 {
+		vm.probesSelect.SetSelected(formatProbesPerRound(config.ProbesPerRound))
+	}
+
+	if !vm.branding.Locked(lockAlertSettings) {
+		if len(config.AlertRules) > 0 {
+			if err := saveAlertRules(alertRulesPath(vm.app), config.AlertRules); err != nil {
+				return err
+			}
+			vm.alerts.SetRules(config.AlertRules)
+		}
+		prefs.SetString(prefAlertWebhookURL, config.WebhookURL)
+		for rule, sinks := range config.AlertRoutes {
+			prefs.SetStringList(prefAlertRoutePrefix+rule, sinks)
+		}
+		vm.setupAlertRouting()
+	}
+	if !vm.branding.Locked(lockThroughputURL) {
+		prefs.SetString(prefThroughputURL, config.ThroughputURL)
+	}
+	if !vm.branding.Locked(lockProviderStatus) {
+		prefs.SetBool(prefCheckProviderStatus, config.CheckProviderStatus)
+	}
+	prefs.SetBool(prefPickAddress, config.PickAddress)
+	if config.ReportLanguage != "" {
+		prefs.SetString(prefReportLanguage, string(network.ParseReportLanguage(config.ReportLanguage)))
+	}
+	if config.DerivedMetrics != nil {
+		prefs.SetStringList(prefDerivedMetrics, config.DerivedMetrics)
+		vm.loadDerivedMetrics()
+		vm.hopList.Refresh()
+	}
+
+	// Rebuild the menu so toggles show the imported state
+	vm.setupMenu()
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// contactLookupTimeout bounds the registry queries behind a contact lookup
+const contactLookupTimeout = 20 * time.Second
+
+// onWorstProviderContacts finds the provider segment most likely behind the trouble and shows
+// its operators' NOC and abuse contacts with copy buttons, ready for an escalation
+func (vm *VisualMTR) onWorstProviderContacts() {
+	vm.hopsMutex.RLock()
+	hops := append([]network.NetworkHop(nil), vm.hops...)
+	vm.hopsMutex.RUnlock()
+
+	group, reason, ok := network.WorstProvider(hops)
+	if !ok {
+		dialog.ShowInformation("Provider Contacts", "No provider on the path is known yet. Run a session and wait for the AS lookups of its hops.", vm.window)
+		return
+	}
+	ip := hops[group.Exit].IP
+	provider := formatProvider(group)
+	vm.statusLabel.SetText(fmt.Sprintf("Looking up contacts for %s...", provider))
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), contactLookupTimeout)
+		defer cancel()
+		contacts, err := network.LookupContacts(ctx, group.ASN, ip)
+
+		fyne.Do(func() {
+			vm.statusLabel.SetText("Contact lookup complete")
+			if err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			which := fmt.Sprintf("Hops %s belong", formatHopRange(group))
+			if group.Hops() == 1 {
+				which = fmt.Sprintf("Hop %s belongs", formatHopRange(group))
+			}
+			vm.showContacts(provider, fmt.Sprintf("%s to %s, picked because %s.", which, provider, reason), contacts)
+		})
+	}()
+}
+
+// showContacts lists contacts with buttons copying their email address and phone number
+func (vm *VisualMTR) showContacts(provider, why string, contacts []network.Contact) {
+	message := widget.NewLabel(why)
+	message.Wrapping = fyne.TextWrapWord
+	rows := container.NewVBox(message)
+
+	copyButton := func(label, text string) fyne.CanvasObject {
+		button := widget.NewButton(label, func() {
+			vm.app.Clipboard().SetContent(text)
+			vm.statusLabel.SetText(fmt.Sprintf("Copied %s", text))
+		})
+		if text == "" {
+			button.Disable()
+		}
+		return button
+	}
+	for _, contact := range contacts {
+		text := fmt.Sprintf("%s (%s): %s", contact.Role, contact.Source, contact.Name)
+		if contact.Email != "" {
+			text += " <" + contact.Email + ">"
+		}
+		if contact.Phone != "" {
+			text += " " + contact.Phone
+		}
+		label := widget.NewLabel(text)
+		label.Wrapping = fyne.TextWrapWord
+		buttons := container.NewHBox(copyButton("Copy Email", contact.Email), copyButton("Copy Phone", contact.Phone))
+		rows.Add(container.NewBorder(nil, nil, nil, buttons, label))
+	}
+
+	d := dialog.NewCustom(fmt.Sprintf("Contacts for %s", provider), "Close", container.NewVScroll(rows), vm.window)
+	d.Resize(fyne.NewSize(640, 420))
+	d.Show()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefDatasetAutoUpdate is the preference key for refreshing datasets on their schedule
+const prefDatasetAutoUpdate = "datasetAutoUpdate"
+
+// ixpDatasetName names the PeeringDB IXP prefixes among the datasets
+const ixpDatasetName = "PeeringDB IXPs"
+
+// datasetUpdateTimeout bounds one dataset update started from the menu
+const datasetUpdateTimeout = 10 * time.Minute
+
+// setupDatasets creates the dataset manager, loads the datasets already downloaded and starts
+// the scheduled updates unless they are turned off
+func (vm *VisualMTR) setupDatasets() {
+	datasets := []network.Dataset{network.IP2ASNDataset()}
+	if !vm.branding.Locked(lockIXPRefresh) {
+		datasets = append(datasets, network.Dataset{
+			Name:    ixpDatasetName,
+			File:    "ixp.json",
+			Feature: network.LookupPeeringDB,
+			MaxAge:  7 * 24 * time.Hour,
+			Build: func(ctx context.Context, path string) error {
+				if err := vm.ixpDB.Refresh(ctx); err != nil {
+					return err
+				}
+				return vm.ixpDB.Save(path)
+			},
+		})
+	}
+
+	vm.datasets = network.NewDatasetManager(vm.app.Storage().RootURI().Path(), datasets...)
+	vm.datasets.SetOnUpdate(func(d network.Dataset, path string) {
+		vm.loadDataset(d, path)
+		fyne.Do(func() {
+			vm.hopList.Refresh()
+		})
+	})
+
+	vm.loadIXPData()
+	asnDataset := network.IP2ASNDataset()
+	if err := vm.datasets.Verify(asnDataset); err != nil {
+		log.Printf("[DEBUG] Using DNS for ASN lookups: %v\n", err)
+	} else {
+		go vm.loadDataset(asnDataset, vm.datasets.Path(asnDataset))
+	}
+
+	if vm.app.Preferences().BoolWithFallback(prefDatasetAutoUpdate, true) {
+		vm.startDatasetUpdates()
+	}
+}
+
+// loadDataset puts a downloaded dataset to use
+// Building the IXP dataset already refreshed the IXP database, so only the ASN table is loaded
+func (vm *VisualMTR) loadDataset(d network.Dataset, path string) {
+	if d.Name != network.IP2ASNDataset().Name {
+		return
+	}
+	table, err := network.LoadASNTable(path)
+	if err != nil {
+		log.Printf("[DEBUG] Using DNS for ASN lookups: %v\n", err)
+		return
+	}
+	vm.asnResolver.SetTable(table)
+}
+
+// startDatasetUpdates refreshes the datasets in the background as they come due
+func (vm *VisualMTR) startDatasetUpdates() {
+	if vm.stopDatasetUpdates != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	vm.stopDatasetUpdates = cancel
+	go vm.datasets.Run(ctx)
+}
+
+// updateDataset updates one dataset now in the background, reporting the outcome
+func (vm *VisualMTR) updateDataset(d network.Dataset, done func()) {
+	vm.statusLabel.SetText(fmt.Sprintf("Updating %s...", d.Name))
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), datasetUpdateTimeout)
+		defer cancel()
+		err := vm.datasets.Update(ctx, d)
+
+		fyne.Do(func() {
+			if err != nil {
+				vm.statusLabel.SetText(fmt.Sprintf("%s update failed", d.Name))
+				dialog.ShowError(err, vm.window)
+			} else {
+				vm.statusLabel.SetText(fmt.Sprintf("%s updated", d.Name))
+			}
+			if done != nil {
+				done()
+			}
+		})
+	}()
+}
+
+// datasetStatus describes a dataset's age, size and latest failure for the dataset list
+func (vm *VisualMTR) datasetStatus(d network.Dataset) string {
+	if vm.datasets.Updating(d) {
+		return "Updating..."
+	}
+	state := vm.datasets.State(d)
+	status := "Never downloaded"
+	if !state.Updated.IsZero() {
+		status = fmt.Sprintf("Updated %s, %.1f MB", state.Updated.Format("2006-01-02 15:04"), float64(state.Size)/1e6)
+	}
+	if state.Error != "" {
+		status += "\nLast update failed: " + state.Error
+	}
+	return status
+}
+
+// onDatasets lists the enrichment datasets with their age and lets the user update them now
+// or turn scheduled updates off
+func (vm *VisualMTR) onDatasets() {
+	prefs := vm.app.Preferences()
+
+	autoCheck := widget.NewCheck("Update automatically when due", nil)
+	autoCheck.SetChecked(prefs.BoolWithFallback(prefDatasetAutoUpdate, true))
+	autoCheck.OnChanged = func(checked bool) {
+		prefs.SetBool(prefDatasetAutoUpdate, checked)
+		if checked {
+			vm.startDatasetUpdates()
+		} else if vm.stopDatasetUpdates != nil {
+			vm.stopDatasetUpdates()
+			vm.stopDatasetUpdates = nil
+		}
+	}
+
+	rows := container.NewVBox()
+	for _, d := range vm.datasets.Datasets() {
+		status := widget.NewLabel(vm.datasetStatus(d))
+		status.Wrapping = fyne.TextWrapWord
+		var button *widget.Button
+		button = widget.NewButton("Update Now", func() {
+			button.Disable()
+			status.SetText("Updating...")
+			vm.updateDataset(d, func() {
+				button.Enable()
+				status.SetText(vm.datasetStatus(d))
+			})
+		})
+		rows.Add(widget.NewCard(d.Name, "", container.NewBorder(nil, nil, nil, button, status)))
+	}
+
+	content := container.NewBorder(nil, autoCheck, nil, nil, container.NewVScroll(rows))
+	d := dialog.NewCustom("Datasets", "Close", content, vm.window)
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
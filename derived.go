@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/alert"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefDerivedMetrics is the preference key for the derived metric columns, each "name = expression"
+const prefDerivedMetrics = "derivedMetrics"
+
+// Settings of the alert rules created from expressions; the severity, samples and threshold of a
+// rule kept under the same name are left as they were
+const (
+	expressionFireAfter  = 3
+	expressionClearAfter = 5
+)
+
+// loadDerivedMetrics parses the derived metric columns from preferences, leaving out any that
+// no longer parse
+func (vm *VisualMTR) loadDerivedMetrics() {
+	vm.derived = nil
+	for _, definition := range vm.app.Preferences().StringList(prefDerivedMetrics) {
+		metric, err := network.ParseDerivedMetric(definition)
+		if err != nil {
+			log.Printf("[DEBUG] Skipping derived metric: %v\n", err)
+			continue
+		}
+		vm.derived = append(vm.derived, metric)
+	}
+	if vm.derivedHeader != nil {
+		vm.derivedHeader.SetText(derivedHeaderText(vm.derived))
+	}
+}
+
+// derivedHeaderText names the derived metric columns, in the order derivedValues lists them
+func derivedHeaderText(metrics []network.DerivedMetric) string {
+	names := make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = metric.Name
+	}
+	return strings.Join(names, " / ")
+}
+
+// derivedValues computes the derived metrics of a hop for its row
+func derivedValues(metrics []network.DerivedMetric, hop network.NetworkHop, index int, destination bool) string {
+	if len(metrics) == 0 {
+		return ""
+	}
+	vars := network.HopMetrics(hop, index, destination)
+	values := make([]string, len(metrics))
+	for i, metric := range metrics {
+		values[i] = network.FormatMetricValue(metric.Expr.Eval(vars))
+	}
+	return strings.Join(values, " / ")
+}
+
+// parseDefinitions splits text into its non-empty lines and parses each as "name = expression"
+func parseDefinitions(text string) ([]network.DerivedMetric, error) {
+	var metrics []network.DerivedMetric
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		metric, err := network.ParseDerivedMetric(line)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// formatDefinition writes a derived metric back as "name = expression"
+func formatDefinition(name, expression string) string {
+	return name + " = " + expression
+}
+
+// expressionVariablesHelp lists the metrics expressions can use, for the dialog
+func expressionVariablesHelp() string {
+	lines := make([]string, len(network.ExprVariables))
+	for i, v := range network.ExprVariables {
+		lines[i] = fmt.Sprintf("%s: %s", v.Name, v.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// onDerivedMetrics edits the user-defined metrics: columns computed for every hop each cycle,
+// and alerts firing while an expression holds
+func (vm *VisualMTR) onDerivedMetrics() {
+	columnsEntry := widget.NewMultiLineEntry()
+	columnsEntry.SetPlaceHolder("score = loss*2 + jitter")
+	columnsEntry.SetText(strings.Join(vm.app.Preferences().StringList(prefDerivedMetrics), "\n"))
+	columnsEntry.Validator = func(text string) error {
+		_, err := parseDefinitions(text)
+		return err
+	}
+
+	rules := vm.alerts.Rules()
+	var expressions []string
+	for _, rule := range rules {
+		if rule.Metric == alert.MetricExpression {
+			expressions = append(expressions, formatDefinition(rule.Name, rule.Expression))
+		}
+	}
+	alertsEntry := widget.NewMultiLineEntry()
+	alertsEntry.SetPlaceHolder("choppy_call = dest && loss*2 + jitter > 30")
+	alertsEntry.SetText(strings.Join(expressions, "\n"))
+	alertsEntry.Validator = columnsEntry.Validator
+	alertsLocked := vm.branding.Locked(lockAlertSettings)
+	if alertsLocked {
+		alertsEntry.Disable()
+	}
+
+	help := widget.NewLabel(expressionVariablesHelp())
+	help.Importance = widget.LowImportance
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Columns", columnsEntry),
+		widget.NewFormItem("Alerts", alertsEntry),
+		widget.NewFormItem("Metrics", help),
+	}
+	items[0].HintText = "One name = expression per line, shown for every hop"
+	items[1].HintText = "Fire while the expression holds on any hop; use dest for the destination only"
+
+	d := dialog.NewForm("Derived Metrics", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		columns, _ := parseDefinitions(columnsEntry.Text)
+		definitions := make([]string, len(columns))
+		for i, metric := range columns {
+			definitions[i] = formatDefinition(metric.Name, metric.Expr.String())
+		}
+		vm.app.Preferences().SetStringList(prefDerivedMetrics, definitions)
+		vm.loadDerivedMetrics()
+		vm.hopList.Refresh()
+
+		if alertsLocked {
+			return
+		}
+		alerts, _ := parseDefinitions(alertsEntry.Text)
+		if err := vm.setExpressionRules(rules, alerts); err != nil {
+			dialog.ShowError(err, vm.window)
+		}
+	}, vm.window)
+	d.Resize(fyne.NewSize(600, 0))
+	d.Show()
+}
+
+// setExpressionRules replaces the expression rules among rules with ones for alerts, then
+// saves and applies them; new rules are routed to the default sinks
+func (vm *VisualMTR) setExpressionRules(rules []alert.Rule, alerts []network.DerivedMetric) error {
+	previous := make(map[string]alert.Rule)
+	names := make(map[string]bool)
+	kept := make([]alert.Rule, 0, len(rules)+len(alerts))
+	for _, rule := range rules {
+		if rule.Metric == alert.MetricExpression {
+			previous[rule.Name] = rule
+		} else {
+			kept = append(kept, rule)
+			names[rule.Name] = true
+		}
+	}
+	for _, metric := range alerts {
+		// Hysteresis and routes are kept by rule name, so names must be unique
+		if names[metric.Name] {
+			return fmt.Errorf("an alert rule called %q already exists", metric.Name)
+		}
+		names[metric.Name] = true
+		rule, ok := previous[metric.Name]
+		if !ok {
+			rule = alert.Rule{
+				Name:       metric.Name,
+				Metric:     alert.MetricExpression,
+				Threshold:  1,
+				Severity:   alert.SeverityWarning,
+				AllHops:    true,
+				FireAfter:  expressionFireAfter,
+				ClearAfter: expressionClearAfter,
+			}
+		}
+		rule.Expression = metric.Expr.String()
+		kept = append(kept, rule)
+	}
+
+	if err := saveAlertRules(alertRulesPath(vm.app), kept); err != nil {
+		return err
+	}
+	vm.alerts.SetRules(kept)
+	vm.setupAlertRouting()
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// prefSyncZoom is the preference key for zooming every hop graph along with the detail chart
+const prefSyncZoom = "syncZoom"
+
+// liveHops is a HopSource over the hops of the current session
+type liveHops struct {
+	vm *VisualMTR
+}
+
+// Target returns the host being monitored
+func (l liveHops) Target() string {
+	l.vm.hopsMutex.RLock()
+	defer l.vm.hopsMutex.RUnlock()
+	return l.vm.target
+}
+
+// Hops returns a copy of the current path
+func (l liveHops) Hops() []network.NetworkHop {
+	l.vm.hopsMutex.RLock()
+	defer l.vm.hopsMutex.RUnlock()
+	return append([]network.NetworkHop(nil), l.vm.hops...)
+}
+
+// showHopDetail opens the detail view for the hop in the given row
+func (vm *VisualMTR) showHopDetail(id widget.ListItemID) {
+	vm.hopsMutex.RLock()
+	var hop network.NetworkHop
+	destination := false
+	switch {
+	case id < len(vm.hops):
+		hop = vm.hops[id]
+		destination = id == len(vm.hops)-1
+	case id < len(vm.cachedHops):
+		hop = vm.cachedHops[id]
+		destination = id == len(vm.cachedHops)-1
+	default:
+		vm.hopsMutex.RUnlock()
+		return
+	}
+	scanner := vm.scanner
+	vm.hopsMutex.RUnlock()
+
+	text := formatHopDetail(hop, destination) + vm.formatKnowledge(hop)
+	if scanner != nil {
+		text += fmt.Sprintf("\n\nTiming: %s\nSockets: %s", scanner.ClockSource(), scanner.SocketAccess())
+	}
+	details := widget.NewLabel(text)
+	details.Wrapping = fyne.TextWrapWord
+
+	// A label names the address in every later session
+	facts, _ := vm.knowledge.Facts(hop.IP)
+	labelEntry := widget.NewEntry()
+	labelEntry.SetPlaceHolder("e.g. office firewall")
+	labelEntry.SetText(facts.Label)
+	labelButton := widget.NewButton("Save Label", func() {
+		vm.knowledge.SetLabel(hop.IP, labelEntry.Text)
+		go vm.saveKnowledge()
+		vm.hopList.Refresh()
+	})
+	labelRow := container.NewBorder(nil, nil, widget.NewLabel("Label"), labelButton, labelEntry)
+	if hop.IP == "" {
+		labelRow.Hide()
+	}
+
+	// Zoomable history; optionally every row graph follows it to line up spikes across hops
+	chart := ui.NewHopChart(ui.NewHopLatencyGraph(liveHops{vm: vm}, id))
+	windowLabel := widget.NewLabel(describeWindow(ui.GraphWindow{}))
+	chart.OnWindowChanged = func(window ui.GraphWindow) {
+		windowLabel.SetText(describeWindow(window))
+	}
+	if window := vm.zoomGroup.Window(); window != (ui.GraphWindow{}) {
+		chart.Graph.SetWindow(window)
+		windowLabel.SetText(describeWindow(window))
+	}
+	syncCheck := widget.NewCheck("Zoom all hops together", func(on bool) {
+		vm.app.Preferences().SetBool(prefSyncZoom, on)
+		if on {
+			chart.SetSync(vm.zoomGroup)
+		} else {
+			chart.SetSync(nil)
+		}
+	})
+	syncCheck.SetChecked(vm.app.Preferences().Bool(prefSyncZoom))
+	resetButton := widget.NewButton("Reset Zoom", chart.ResetZoom)
+	controls := container.NewBorder(nil, nil, nil, resetButton, container.NewHBox(syncCheck, windowLabel))
+
+	stopRefresh := ui.AutoRefresh(time.Second, chart.Graph)
+	content := container.NewVBox(details, labelRow, chart, widget.NewLabel("Scroll to zoom, drag to pan"), controls)
+	d := dialog.NewCustom(fmt.Sprintf("Hop %d", id+1), "Close", content, vm.window)
+	d.SetOnClosed(stopRefresh)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
+
+// describeWindow explains which part of the history a zoomed graph shows
+func describeWindow(window ui.GraphWindow) string {
+	switch {
+	case window.Span == 0:
+		return "Whole history"
+	case window.Offset == 0:
+		return fmt.Sprintf("Last %d samples", window.Span)
+	default:
+		return fmt.Sprintf("%d samples, ending %d samples ago", window.Span, window.Offset)
+	}
+}
+
+// formatHopDetail renders everything known about a hop as text; destination tells whether it
+// is the last hop of the path
+func formatHopDetail(hop network.NetworkHop, destination bool) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "IP address: %s\n", hop.IP)
+	fmt.Fprintf(&b, "TTL: %d\n", hop.TTL)
+	// A Destination Unreachable explains why the path ends at this hop
+	if hop.Status.Dead() {
+		fmt.Fprintf(&b, "Status: %s %s\n%s\n", hop.Status, hop.Status.Annotation(), hop.Status.Explain())
+	} else if hop.Status != "" {
+		fmt.Fprintf(&b, "Status: %s\n", hop.Status)
+	}
+	if hop.ASN != 0 {
+		fmt.Fprintf(&b, "Origin AS: AS%d %s\n", hop.ASN, hop.ASName)
+	}
+	if hop.AvgLatency > 0 {
+		fmt.Fprintf(&b, "Average latency: %s\n", ui.FormatLatency(hop.AvgLatency))
+	} else {
+		fmt.Fprintf(&b, "Average latency: N/A\n")
+	}
+	if hop.Received > 0 {
+		fmt.Fprintf(&b, "Last / best / worst: %s / %s / %s\n", ui.FormatLatency(hop.LastLatency), ui.FormatLatency(hop.BestLatency), ui.FormatLatency(hop.WorstLatency))
+	}
+	if len(hop.RTTSamples) > 0 {
+		fmt.Fprintf(&b, "p50 / p95 / p99: %s / %s / %s (last %d probes)\n", ui.FormatLatency(hop.P50), ui.FormatLatency(hop.P95), ui.FormatLatency(hop.P99), len(hop.RTTSamples))
+	}
+	if hop.Received > 1 {
+		fmt.Fprintf(&b, "Std dev: %s, jitter: %s\n", ui.FormatLatency(hop.StdDev), ui.FormatLatency(hop.Jitter))
+	}
+	fmt.Fprintf(&b, "Loss: %.1f%% (last %d rounds)\n", network.HistoryLossPercent(hop), len(hop.LatencyHistory))
+	if hop.Sent > 0 {
+		fmt.Fprintf(&b, "Probes: %d sent, %d received (%.1f%% loss)\n", hop.Sent, hop.Received, hop.LifetimeLossPercent)
+	}
+	if hop.Duplicates > 0 {
+		fmt.Fprintf(&b, "Duplicate answers: %d, not counted as received\n", hop.Duplicates)
+	}
+	if len(hop.RecentProbes) > 0 {
+		fmt.Fprintf(&b, "Recent loss: %.1f%% (last %d probes)\n", hop.LossPercent, len(hop.RecentProbes))
+	}
+	if hop.RateLimited {
+		fmt.Fprintf(&b, "Loss is well above the destination's, so this router likely rate-limits its ICMP answers rather than dropping traffic\n")
+	}
+	fmt.Fprintf(&b, "Responder stability: %.0f%%\n", hop.Stability)
+
+	// Size and TTL of the answer expose middleboxes rewriting packets and asymmetric return paths
+	if hop.ReplySize > 0 {
+		fmt.Fprintf(&b, "Reply size: %d bytes\n", hop.ReplySize)
+	}
+	if back, ok := network.ReturnHops(hop.ReplyTTL); ok {
+		fmt.Fprintf(&b, "Reply TTL: %d (about %d hops back, probed at TTL %d)\n", hop.ReplyTTL, back, hop.TTL)
+	} else {
+		fmt.Fprintf(&b, "Reply TTL: N/A\n")
+	}
+	// Carrier cores label-switch traffic; the stack identifies the LSP when escalating to the ISP
+	if labels := network.MPLSLabels(hop.Extensions); len(labels) > 0 {
+		fmt.Fprintf(&b, "MPLS labels: %s\n", network.FormatMPLS(labels))
+	}
+	for _, iface := range network.Interfaces(hop.Extensions) {
+		fmt.Fprintf(&b, "Interface: %s\n", iface)
+	}
+	// Knowing the probable vendor helps pick whose support to escalate to
+	if hint, ok := network.FingerprintHop(hop, destination && hop.Status == network.HopOK); ok {
+		fmt.Fprintf(&b, "Device (heuristic guess): %s\n", hint.Guess)
+		fmt.Fprintf(&b, "  Based on: %s\n", strings.Join(hint.Clues, "; "))
+	}
+
+	// Every router that answered this TTL, e.g. each ECMP next-hop, with its share of the answers
+	if len(hop.Responders) > 1 {
+		total := 0
+		for _, r := range hop.Responders {
+			total += r.Count
+		}
+		fmt.Fprintf(&b, "\nResponders this session:\n")
+		for _, r := range hop.Responders {
+			fmt.Fprintf(&b, "  %s: %d answers (%.0f%%)\n", r.IP, r.Count, float64(r.Count)/float64(total)*100)
+		}
+	}
+
+	// Alternate responders, e.g. other ECMP next-hops answering for the same TTL
+	if len(hop.Alternates) == 0 {
+		fmt.Fprintf(&b, "\nNo alternate responders seen")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\nAlternate responders:\n")
+	for _, alt := range hop.Alternates {
+		fmt.Fprintf(&b, "  %s (%.0f%% of responses)\n", alt.IP, alt.Share)
+	}
+	return b.String()
+}
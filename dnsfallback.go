@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefKnownAddresses is the preference key for the addresses hostnames last resolved to,
+// stored as "hostname address" entries with the most recent first
+const prefKnownAddresses = "knownAddresses"
+
+// maxKnownAddresses bounds how many hostnames keep a last-known address
+const maxKnownAddresses = 50
+
+// rememberAddress records the address a hostname resolved to, so a later session can still
+// be started when DNS is down
+func (vm *VisualMTR) rememberAddress(hostname, address string) {
+	if hostname == "" || address == "" || isAddress(hostname) {
+		return
+	}
+	prefs := vm.app.Preferences()
+	known := []string{hostname + " " + address}
+	for _, entry := range prefs.StringList(prefKnownAddresses) {
+		if name, _, ok := strings.Cut(entry, " "); ok && name != hostname && len(known) < maxKnownAddresses {
+			known = append(known, entry)
+		}
+	}
+	prefs.SetStringList(prefKnownAddresses, known)
+}
+
+// knownAddress returns the address a hostname last resolved to, empty when it never has
+func (vm *VisualMTR) knownAddress(hostname string) string {
+	for _, entry := range vm.app.Preferences().StringList(prefKnownAddresses) {
+		if name, address, ok := strings.Cut(entry, " "); ok && name == hostname {
+			return address
+		}
+	}
+	return ""
+}
+
+// offerLastKnownAddress handles a session whose target didn't resolve
+// A failing resolver is often part of the outage being debugged, so when the hostname has
+// resolved before this offers to monitor that address instead of giving up
+func (vm *VisualMTR) offerLastKnownAddress(hostname string, err error) {
+	vm.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+	address := vm.knownAddress(hostname)
+	if address == "" {
+		dialog.ShowInformation("DNS Lookup Failed", fmt.Sprintf("%s could not be resolved:\n%v\n\n%s",
+			hostname, err, network.CategoryDNS.Hint()), vm.window)
+		return
+	}
+	message := fmt.Sprintf("%s could not be resolved:\n%v\n\nIt last resolved to %s. Monitor that address instead?\n"+
+		"The session will be marked as using a last-known address.", hostname, err, address)
+	dialog.ShowConfirm("DNS Lookup Failed", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		vm.hopsMutex.Lock()
+		vm.address = address
+		vm.fallback = address
+		vm.hopsMutex.Unlock()
+		vm.onStart()
+	}, vm.window)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// onDNSLookups shows the session's DNS lookups: which name server answered each and how fast
+// Lookups failing while probes get through point at name service rather than the path
+func (vm *VisualMTR) onDNSLookups() {
+	verdict := widget.NewLabel("")
+	verdict.Wrapping = fyne.TextWrapWord
+	servers := widget.NewLabel("")
+	servers.Wrapping = fyne.TextWrapWord
+
+	var queries []network.DNSQuery
+	list := widget.NewList(
+		func() int { return len(queries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(formatDNSQuery(queries[id]))
+		},
+	)
+	refresh := func() {
+		queries = network.DNSQueries()
+		verdict.SetText(dnsVerdict(queries))
+		servers.SetText(formatDNSServers(network.SummarizeDNSServers(queries)))
+		slices.Reverse(queries)
+		list.Refresh()
+	}
+	refresh()
+
+	refreshButton := widget.NewButton("Refresh", refresh)
+	content := container.NewBorder(container.NewVBox(verdict, servers), refreshButton, nil, nil, list)
+	d := dialog.NewCustom("DNS Lookups", "Close", content, vm.window)
+	d.Resize(fyne.NewSize(640, 440))
+	d.Show()
+}
+
+// dnsVerdict tells in a sentence whether name service is working
+func dnsVerdict(queries []network.DNSQuery) string {
+	if len(queries) == 0 {
+		return "No DNS lookups this session."
+	}
+	failed := 0
+	for _, query := range queries {
+		if query.Failed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return fmt.Sprintf("All %d DNS lookups were answered - name service is working.", len(queries))
+	}
+	return fmt.Sprintf("%d of %d DNS lookups got no answer. This is a name service problem, "+
+		"separate from the loss and latency the hops show.", failed, len(queries))
+}
+
+// formatDNSServers describes each name server on one line
+func formatDNSServers(servers []network.DNSServerStats) string {
+	if len(servers) == 0 {
+		return "No name server was queried."
+	}
+	lines := make([]string, 0, len(servers))
+	for _, server := range servers {
+		line := fmt.Sprintf("%s: %d lookups, %d unanswered", server.Server, server.Queries, server.Failures)
+		if server.Failures < server.Queries {
+			line += fmt.Sprintf(", average %s, slowest %s", formatDNSDuration(server.Average), formatDNSDuration(server.Slowest))
+		}
+		if isLoopbackServer(server.Server) {
+			line += " (local caching resolver)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDNSQuery describes a DNS lookup on one line
+func formatDNSQuery(query network.DNSQuery) string {
+	outcome := "answered"
+	switch {
+	case query.Failed:
+		outcome = "no answer"
+	case query.Err != "":
+		outcome = "not found"
+	}
+	server := query.Server
+	if server == "" {
+		server = "local"
+	}
+	return fmt.Sprintf("%s  %s  %s %s  via %s  %s  %s",
+		query.Time.Format("15:04:05"), query.Purpose, query.Type, query.Name, server, formatDNSDuration(query.Duration), outcome)
+}
+
+// formatDNSDuration formats a lookup time like the latency columns
+func formatDNSDuration(d time.Duration) string {
+	return ui.FormatLatency(float64(d) / float64(time.Millisecond))
+}
+
+// isLoopbackServer reports whether a name server runs on this host, such as systemd-resolved's stub
+func isLoopbackServer(server string) bool {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		host = server
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
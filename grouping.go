@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// prefGroupByProvider is the preference key for listing one row per provider instead of per hop
+const prefGroupByProvider = "groupByProvider"
+
+// setGroupByProvider switches the hop list between one row per hop and one row per provider
+func (vm *VisualMTR) setGroupByProvider(grouped bool) {
+	vm.hopsMutex.Lock()
+	vm.byProvider = grouped
+	vm.hopsMutex.Unlock()
+	vm.app.Preferences().SetBool(prefGroupByProvider, grouped)
+	vm.hopList.UnselectAll()
+	vm.hopList.Refresh()
+}
+
+// groupedHops returns the hops provider rows are built from, and whether they are cached ones
+// Callers hold hopsMutex
+func (vm *VisualMTR) groupedHops() ([]network.NetworkHop, bool) {
+	if len(vm.hops) == 0 {
+		return vm.cachedHops, true
+	}
+	return vm.hops, false
+}
+
+// hopOfRow returns the hop a list row shows, which for a provider row is its exit hop
+func (vm *VisualMTR) hopOfRow(row widget.ListItemID) int {
+	vm.hopsMutex.RLock()
+	defer vm.hopsMutex.RUnlock()
+	if !vm.byProvider {
+		return row
+	}
+	hops, _ := vm.groupedHops()
+	groups := network.GroupByProvider(hops)
+	if row >= len(groups) {
+		return row
+	}
+	return groups[row].Exit
+}
+
+// rowOfHop returns the list row showing a hop
+func (vm *VisualMTR) rowOfHop(index int) widget.ListItemID {
+	vm.hopsMutex.RLock()
+	defer vm.hopsMutex.RUnlock()
+	if !vm.byProvider {
+		return index
+	}
+	hops, _ := vm.groupedHops()
+	for row, g := range network.GroupByProvider(hops) {
+		if index <= g.Last {
+			return row
+		}
+	}
+	return index
+}
+
+// updateProviderRow fills a hop list row with a provider group: its hop range, the AS, the
+// latency at its exit and how much of it the provider added
+// Callers hold hopsMutex
+func (vm *VisualMTR) updateProviderRow(id widget.ListItemID, objects []fyne.CanvasObject) {
+	hops, stale := vm.groupedHops()
+	groups := network.GroupByProvider(hops)
+	if id >= len(groups) {
+		return
+	}
+	g := groups[id]
+	exit := hops[g.Exit]
+	segments := network.ClassifySegments(hops)
+
+	segmentMarker := objects[0].(*canvas.Rectangle)
+	hopNumLabel := objects[1].(*widget.Label)
+	ipLabel := objects[3].(*widget.Label)
+	latencyLabel := objects[5].(*widget.Label)
+	rangeLabel := objects[7].(*widget.Label)
+	lossLabel := objects[9].(*widget.Label)
+	statusLabel := objects[11].(*widget.Label)
+	graph := objects[13].(*ui.LatencyGraph)
+	segmentLabel := objects[15].(*widget.Label)
+	spinner := objects[16].(*widget.Activity)
+	derivedLabel := objects[17].(*widget.Label)
+
+	importance := widget.MediumImportance
+	if stale {
+		importance = widget.LowImportance
+	}
+	for _, label := range []*widget.Label{hopNumLabel, ipLabel, latencyLabel, rangeLabel, lossLabel, statusLabel, segmentLabel, derivedLabel} {
+		label.Importance = importance
+	}
+	spinner.Stop()
+	spinner.Hide()
+
+	hopNumLabel.SetText(formatHopRange(g))
+	ipLabel.SetText(fmt.Sprintf("%s (%d %s)", formatProvider(g), g.Hops(), pluralHops(g.Hops())))
+
+	if g.ExitLatency > 0 {
+		latencyLabel.SetText(ui.FormatLatency(g.ExitLatency))
+		rangeLabel.SetText(fmt.Sprintf("+%s within provider", ui.FormatLatency(g.AddedLatency)))
+	} else {
+		latencyLabel.SetText("N/A")
+		rangeLabel.SetText("- / - / -")
+	}
+	lossLabel.SetText(fmt.Sprintf("%.1f%%", g.LossPercent))
+
+	status := vm.computeStatus(exit)
+	if stale {
+		status = "Cached"
+	}
+	statusLabel.SetText(status)
+	graph.SetData(exit.LatencyHistory)
+
+	segment := segments[g.First]
+	segmentMarker.FillColor = ui.SegmentColor(string(segment))
+	segmentMarker.Refresh()
+	segmentLabel.SetText(string(segment))
+	derivedLabel.SetText(derivedValues(vm.derived, exit, g.Exit, g.Exit == len(hops)-1))
+}
+
+// formatHopRange numbers the hops of a provider group, e.g. "3-7"
+func formatHopRange(g network.ProviderGroup) string {
+	if g.Hops() == 1 {
+		return fmt.Sprintf("%d", g.First+1)
+	}
+	return fmt.Sprintf("%d-%d", g.First+1, g.Last+1)
+}
+
+// formatProvider names a provider group by its AS
+func formatProvider(g network.ProviderGroup) string {
+	switch {
+	case g.ASN == 0:
+		return "Local / unannounced"
+	case g.ASName == "":
+		return fmt.Sprintf("AS%d", g.ASN)
+	}
+	return fmt.Sprintf("AS%d %s", g.ASN, g.ASName)
+}
+
+// pluralHops returns "hop" or "hops" for a count
+func pluralHops(n int) string {
+	if n == 1 {
+		return "hop"
+	}
+	return "hops"
+}
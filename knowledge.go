@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// knowledgeFile is the knowledge base's file in the app's data directory
+const knowledgeFile = "knowledge.json"
+
+// setupKnowledge loads what earlier sessions learned about hop addresses and hands their origin
+// ASes to the resolver, so new sessions show enriched rows at once
+func (vm *VisualMTR) setupKnowledge() {
+	kb, err := network.OpenKnowledgeBase(filepath.Join(vm.app.Storage().RootURI().Path(), knowledgeFile))
+	if err != nil {
+		log.Printf("[DEBUG] Starting with an empty knowledge base: %v\n", err)
+	}
+	vm.knowledge = kb
+	vm.knowledge.SeedASNs(vm.asnResolver)
+}
+
+// learnSession records a finished session's hops in the knowledge base and saves it in the background
+func (vm *VisualMTR) learnSession(hops []network.NetworkHop) {
+	if len(hops) == 0 {
+		return
+	}
+	vm.knowledge.Learn(hops)
+	go vm.saveKnowledge()
+}
+
+// saveKnowledge writes the knowledge base, logging a failure
+func (vm *VisualMTR) saveKnowledge() {
+	if err := vm.knowledge.Save(); err != nil {
+		log.Printf("[DEBUG] %v\n", err)
+	}
+}
+
+// knownName returns the hop address prefixed with the user's label for it, or else its reverse
+// DNS name, starting a lookup of the name if it isn't known yet
+func (vm *VisualMTR) knownName(ip string) string {
+	vm.knowledge.PrefetchName(ip)
+	facts, _ := vm.knowledge.Facts(ip)
+	switch {
+	case facts.Label != "":
+		return fmt.Sprintf("%s %s", facts.Label, ip)
+	case facts.RDNS != "":
+		return fmt.Sprintf("%s %s", facts.RDNS, ip)
+	default:
+		return ip
+	}
+}
+
+// typicalLatency describes a hop's latency against its typical latency in earlier sessions,
+// empty if the address has no history
+func (vm *VisualMTR) typicalLatency(hop network.NetworkHop) string {
+	facts, ok := vm.knowledge.Facts(hop.IP)
+	if !ok || facts.Sessions == 0 {
+		return ""
+	}
+	return fmt.Sprintf("typ %s", ui.FormatLatency(facts.TypicalLatency))
+}
+
+// formatKnowledge renders what earlier sessions learned about the hop, empty if nothing
+func (vm *VisualMTR) formatKnowledge(hop network.NetworkHop) string {
+	facts, ok := vm.knowledge.Facts(hop.IP)
+	if !ok {
+		return ""
+	}
+	text := "\n\nFrom earlier sessions:"
+	if facts.Label != "" {
+		text += fmt.Sprintf("\nLabel: %s", facts.Label)
+	}
+	if facts.RDNS != "" {
+		text += fmt.Sprintf("\nReverse DNS: %s", facts.RDNS)
+	}
+	if facts.Sessions > 0 {
+		text += fmt.Sprintf("\nTypical latency: %s over %d sessions", ui.FormatLatency(facts.TypicalLatency), facts.Sessions)
+		if hop.AvgLatency > 0 {
+			text += fmt.Sprintf(", now %+.2f ms", hop.AvgLatency-facts.TypicalLatency)
+		}
+	}
+	if !facts.LastSeen.IsZero() {
+		text += fmt.Sprintf("\nLast seen: %s", facts.LastSeen.Format("2006-01-02 15:04"))
+	}
+	return text
+}
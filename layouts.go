@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// Views a layout can open
+const (
+	viewHopTable  = "Hop Table"
+	viewPathGraph = "ECMP Path Graph"
+	viewTopology  = "Multi-Target Topology"
+)
+
+// layoutViews lists the views in display order
+var layoutViews = []string{viewHopTable, viewPathGraph, viewTopology}
+
+// Layout is a named dashboard arrangement for a duty context, e.g. "NOC" or "On-call"
+type Layout struct {
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"` // Quick-pick targets in display order; the first is prefilled
+	View    string   `json:"view"`    // View opened alongside the hop table
+	Width   float32  `json:"width"`   // Main window size
+	Height  float32  `json:"height"`
+}
+
+// layoutsPath returns where saved layouts are stored
+func (vm *VisualMTR) layoutsPath() string {
+	return filepath.Join(vm.app.Storage().RootURI().Path(), "layouts.json")
+}
+
+// loadLayouts reads the saved layouts, returning none if the file doesn't exist yet
+func loadLayouts(path string) ([]Layout, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layouts: %v", err)
+	}
+
+	var layouts []Layout
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return nil, fmt.Errorf("failed to parse layouts: %v", err)
+	}
+	return layouts, nil
+}
+
+// saveLayouts writes the layouts to path
+func saveLayouts(path string, layouts []Layout) error {
+	data, err := json.MarshalIndent(layouts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode layouts: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create layouts directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write layouts: %v", err)
+	}
+	return nil
+}
+
+// currentTargets returns the quick-pick targets with the entered target included
+func (vm *VisualMTR) currentTargets() []string {
+	targets := append([]string(nil), vm.quickTargets...)
+	if target := vm.hostnameEntry.Text; target != "" && !slices.Contains(targets, target) {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// onLayouts manages saved layouts: apply, delete, reorder their targets by dragging, or save the current one
+func (vm *VisualMTR) onLayouts() {
+	path := vm.layoutsPath()
+	layouts, err := loadLayouts(path)
+	if err != nil {
+		dialog.ShowError(err, vm.window)
+		return
+	}
+
+	selected := -1
+	targets := ui.NewReorderList(nil)
+	targets.OnReordered = func(items []string) {
+		if selected < 0 {
+			return
+		}
+		layouts[selected].Targets = items
+		if err := saveLayouts(path, layouts); err != nil {
+			dialog.ShowError(err, vm.window)
+		}
+	}
+	details := widget.NewLabel("")
+
+	names := make([]string, len(layouts))
+	for i, layout := range layouts {
+		names[i] = layout.Name
+	}
+	picker := widget.NewSelect(names, func(name string) {
+		selected = slices.IndexFunc(layouts, func(l Layout) bool { return l.Name == name })
+		if selected < 0 {
+			return
+		}
+		layout := layouts[selected]
+		details.SetText(fmt.Sprintf("View: %s\nWindow: %.0f x %.0f\nTargets (drag to reorder):",
+			layout.View, layout.Width, layout.Height))
+		targets.Items = layout.Targets
+		targets.Refresh()
+	})
+	picker.PlaceHolder = "Choose a layout"
+
+	var d dialog.Dialog
+	apply := widget.NewButton("Apply", func() {
+		if selected < 0 {
+			return
+		}
+		d.Hide()
+		vm.applyLayout(layouts[selected])
+	})
+	remove := widget.NewButton("Delete", func() {
+		if selected < 0 {
+			return
+		}
+		layouts = slices.Delete(layouts, selected, selected+1)
+		if err := saveLayouts(path, layouts); err != nil {
+			dialog.ShowError(err, vm.window)
+		}
+		d.Hide()
+	})
+	saveCurrent := widget.NewButton("Save Current As...", func() {
+		d.Hide()
+		vm.onSaveLayout(layouts)
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(picker, details),
+		container.NewHBox(apply, remove, saveCurrent),
+		nil, nil,
+		container.NewVScroll(targets),
+	)
+	d = dialog.NewCustom("Layouts", "Close", content, vm.window)
+	d.Resize(fyne.NewSize(420, 420))
+	d.Show()
+}
+
+// onSaveLayout saves the current targets and window size, with a chosen view, as a named layout
+func (vm *VisualMTR) onSaveLayout(layouts []Layout) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. NOC wall")
+	viewSelect := widget.NewSelect(layoutViews, nil)
+	viewSelect.SetSelected(viewHopTable)
+	targets := ui.NewReorderList(vm.currentTargets())
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("View", viewSelect),
+		widget.NewFormItem("Targets", targets),
+	}
+	items[2].HintText = "Drag to reorder"
+
+	d := dialog.NewForm("Save Layout", "Save", "Cancel", items, func(ok bool) {
+		if !ok || nameEntry.Text == "" {
+			return
+		}
+		size := vm.window.Canvas().Size()
+		layout := Layout{
+			Name:    nameEntry.Text,
+			Targets: targets.Items,
+			View:    viewSelect.Selected,
+			Width:   size.Width,
+			Height:  size.Height,
+		}
+
+		// Saving under an existing name replaces that layout
+		if i := slices.IndexFunc(layouts, func(l Layout) bool { return l.Name == layout.Name }); i >= 0 {
+			layouts[i] = layout
+		} else {
+			layouts = append(layouts, layout)
+		}
+		if err := saveLayouts(vm.layoutsPath(), layouts); err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		vm.statusLabel.SetText(fmt.Sprintf("Saved layout %q", layout.Name))
+	}, vm.window)
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// applyLayout switches to a layout's targets, window size and view
+func (vm *VisualMTR) applyLayout(layout Layout) {
+	vm.quickTargets = layout.Targets
+	vm.targetSelect.SetOptions(layout.Targets)
+	if len(layout.Targets) > 0 && !vm.startButton.Disabled() {
+		vm.hostnameEntry.SetText(layout.Targets[0])
+	}
+	if layout.Width > 0 && layout.Height > 0 {
+		vm.window.Resize(fyne.NewSize(layout.Width, layout.Height))
+	}
+
+	switch layout.View {
+	case viewPathGraph:
+		vm.onShowPathGraph()
+	case viewTopology:
+		vm.onShowTopology()
+	}
+	vm.statusLabel.SetText(fmt.Sprintf("Applied layout %q", layout.Name))
+}
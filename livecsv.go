@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// Live CSV preference keys
+const (
+	prefLiveCSV    = "liveCSV"    // Append each cycle to a daily CSV file while running
+	prefLiveCSVDir = "liveCSVDir" // Folder the live CSV files are written to
+)
+
+// liveCSVPrefix starts the name of every live CSV file, followed by the date
+const liveCSVPrefix = "visual-mtr-live"
+
+// liveCSVDir returns the folder live CSV files go to, by default next to the app's other data
+func (vm *VisualMTR) liveCSVDir() string {
+	return vm.app.Preferences().StringWithFallback(prefLiveCSVDir, filepath.Join(vm.app.Storage().RootURI().Path(), "live"))
+}
+
+// startLiveCSV opens a live CSV tail for a new session when the option is on
+func (vm *VisualMTR) startLiveCSV() {
+	var tail *network.CSVTail
+	if vm.app.Preferences().Bool(prefLiveCSV) {
+		tail = network.NewCSVTail(vm.liveCSVDir(), liveCSVPrefix)
+	}
+	vm.hopsMutex.Lock()
+	previous := vm.liveCSV
+	vm.liveCSV = tail
+	vm.hopsMutex.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+}
+
+// stopLiveCSV closes the session's live CSV tail, if any
+func (vm *VisualMTR) stopLiveCSV() {
+	vm.hopsMutex.Lock()
+	tail := vm.liveCSV
+	vm.liveCSV = nil
+	vm.hopsMutex.Unlock()
+	if tail != nil {
+		tail.Close()
+	}
+}
+
+// appendLiveCSV adds a monitoring update to the live CSV file
+// Failures are logged rather than shown, as the option runs unattended
+func (vm *VisualMTR) appendLiveCSV(tail *network.CSVTail, target string, update network.HopUpdate) {
+	if tail == nil {
+		return
+	}
+	if err := tail.Append(target, update); err != nil {
+		log.Printf("[DEBUG] Failed to append to live CSV: %v\n", err)
+	}
+}
+
+// onLiveCSVSettings edits whether sessions append each cycle to a daily CSV file, and where
+func (vm *VisualMTR) onLiveCSVSettings() {
+	prefs := vm.app.Preferences()
+
+	enabled := widget.NewCheck("Append each cycle to a CSV file while running", nil)
+	enabled.SetChecked(prefs.Bool(prefLiveCSV))
+	dirEntry := widget.NewEntry()
+	dirEntry.SetText(vm.liveCSVDir())
+	browse := widget.NewButton("Browse...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				return
+			}
+			dirEntry.SetText(dir.Path())
+		}, vm.window)
+	})
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("", enabled),
+		widget.NewFormItem("Folder", container.NewBorder(nil, nil, nil, browse, dirEntry)),
+	}
+	items[1].HintText = "A new file is started each day: " + liveCSVPrefix + "-YYYY-MM-DD.csv"
+
+	d := dialog.NewForm("Live CSV Output", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		prefs.SetBool(prefLiveCSV, enabled.Checked)
+		prefs.SetString(prefLiveCSVDir, dirEntry.Text)
+		vm.statusLabel.SetText("Live CSV settings saved - they apply to the next session")
+	}, vm.window)
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
+
+// onExportHistoryParquet converts the live CSV history into a single Parquet file
+// Conversion runs in the background, as multi-day histories can hold millions of rows
+func (vm *VisualMTR) onExportHistoryParquet() {
+	paths, err := network.TailFiles(vm.liveCSVDir(), liveCSVPrefix)
+	if err != nil || len(paths) == 0 {
+		dialog.ShowInformation("Export History", "There is no live CSV history yet. Turn on Settings > Live CSV Output to record it.", vm.window)
+		return
+	}
+
+	dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		vm.statusLabel.SetText(fmt.Sprintf("Exporting %d days of history to Parquet...", len(paths)))
+		go func() {
+			rows, err := network.WriteHistoryParquet(writer, paths)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+			fyne.Do(func() {
+				if err != nil {
+					vm.statusLabel.SetText("History export failed")
+					dialog.ShowError(err, vm.window)
+					return
+				}
+				vm.statusLabel.SetText(fmt.Sprintf("Exported %d rows from %d days of history", rows, len(paths)))
+			})
+		}()
+	}, vm.window)
+}
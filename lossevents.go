@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// lossJumpSpan is how many samples the row graphs show around a loss event jumped to
+const lossJumpSpan = 20
+
+// onLossEvents lists the loss events of the current session; selecting one jumps the graphs to it
+func (vm *VisualMTR) onLossEvents() {
+	vm.hopsMutex.RLock()
+	var events []network.LossEvent
+	if vm.lossEvents != nil {
+		events = vm.lossEvents.Events()
+	}
+	vm.hopsMutex.RUnlock()
+
+	summary := widget.NewLabel(fmt.Sprintf("%d loss events this session. Select one to show it on the hop graphs.", len(events)))
+	summary.Wrapping = fyne.TextWrapWord
+
+	list := widget.NewList(
+		func() int { return len(events) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(formatLossEvent(events[id]))
+		},
+	)
+
+	var d dialog.Dialog
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+		if !vm.jumpToLossEvent(events[id]) {
+			summary.SetText("That event is older than the graph history, which keeps the last " +
+				fmt.Sprintf("%d samples of each hop.", network.MaxLatencyHistory))
+			return
+		}
+		d.Hide()
+	}
+	reset := widget.NewButton("Show Whole History", func() {
+		vm.zoomGroup.SetWindow(ui.GraphWindow{})
+		vm.zoomGroup.SetCursor(-1)
+	})
+
+	content := container.NewBorder(summary, reset, nil, nil, list)
+	d = dialog.NewCustom("Loss Events", "Close", content, vm.window)
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
+
+// jumpToLossEvent zooms the row graphs onto the start of a loss event, marks it and scrolls to its hop
+// Returns false if the event has scrolled out of the graph history
+func (vm *VisualMTR) jumpToLossEvent(event network.LossEvent) bool {
+	vm.hopsMutex.RLock()
+	samplesBack := vm.lossEvents.SamplesSince(event)
+	vm.hopsMutex.RUnlock()
+	if samplesBack >= network.MaxLatencyHistory {
+		return false
+	}
+
+	// Centre the event's start in the window where the history allows
+	offset := min(max(samplesBack-lossJumpSpan/2, 0), network.MaxLatencyHistory-lossJumpSpan)
+	vm.zoomGroup.SetWindow(ui.GraphWindow{Span: lossJumpSpan, Offset: offset})
+	vm.zoomGroup.SetCursor(samplesBack)
+	vm.hopList.ScrollTo(vm.rowOfHop(event.Hop))
+	return true
+}
+
+// incidentSummary describes the loss events, path changes and throughput tests of the current or
+// last session in lang
+func (vm *VisualMTR) incidentSummary(hops []network.NetworkHop, lang network.ReportLanguage) string {
+	vm.hopsMutex.RLock()
+	defer vm.hopsMutex.RUnlock()
+	if vm.lossEvents == nil {
+		return "No session has been run yet."
+	}
+	return network.SummarizeIncidents(lang, vm.lossEvents.Events(), vm.lossEvents.PathChanges(), vm.lossEvents.Throughput(), hops)
+}
+
+// onIncidentSummary shows the incident summary of the session so far, with a copy button
+func (vm *VisualMTR) onIncidentSummary() {
+	vm.hopsMutex.RLock()
+	hops := append([]network.NetworkHop(nil), vm.hops...)
+	vm.hopsMutex.RUnlock()
+
+	// Written in the report language, as it is copied into messages to the ISP
+	text := vm.incidentSummary(hops, vm.reportLanguage())
+	summary := widget.NewLabel(text)
+	summary.Wrapping = fyne.TextWrapWord
+	copyButton := widget.NewButton("Copy", func() {
+		vm.app.Clipboard().SetContent(text)
+	})
+
+	d := dialog.NewCustom("Incident Summary", "Close", container.NewVBox(summary, copyButton), vm.window)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
+
+// formatLossEvent describes a loss event on one line
+func formatLossEvent(event network.LossEvent) string {
+	duration := "ongoing"
+	if !event.Ongoing() {
+		duration = event.Duration().Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s  Hop %d (%s)  %s  %d of %d samples lost (%.0f%%)",
+		event.Start.Format("Jan 2 15:04:05"), event.Hop+1, event.IP, duration, event.Lost, event.Samples, event.Depth())
+}
+
+// formatPathChange describes a path change on one line
+func formatPathChange(change network.PathChange) string {
+	return change.At.Format("Jan 2 15:04:05") + "  " + change.Describe()
+}
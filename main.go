@@ -1,65 +1,246 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/alert"
 	"github.com/afroash/visual-mtr/network"
 	"github.com/afroash/visual-mtr/ui"
 )
 
+// AppID uniquely identifies the application for preferences and storage
+const AppID = "io.github.afroash.visualmtr"
+
 type VisualMTR struct {
-	app           fyne.App
-	window        fyne.Window
-	hostnameEntry *widget.Entry
-	startButton   *widget.Button
-	stopButton    *widget.Button
-	statusLabel   *widget.Label
-	hopList       *widget.List
-	scanner       *network.Scanner
-	hops          []network.NetworkHop
-	hopsMutex     sync.RWMutex
-	updateChan    chan network.HopUpdate
+	app                fyne.App
+	window             fyne.Window
+	hostnameEntry      *widget.Entry
+	targetSelect       *widget.SelectEntry // Wraps hostnameEntry with quick-pick targets
+	quickTargets       []string            // Options currently offered by targetSelect
+	protocolSelect     *widget.Select      // IPv4/IPv6 choice for dual-stacked targets
+	methodSelect       *widget.Select      // ICMP, UDP or TCP SYN probes
+	portEntry          *widget.Entry       // Destination port of TCP probes
+	probesSelect       *widget.Select      // Probes sent to each hop per round
+	profileSelect      *widget.Select      // Probing profile, e.g. VoIP, for the next session
+	sourceSelect       *widget.Select      // Local interface or address probes are sent from
+	sources            map[string]string   // Source behind each sourceSelect option, empty for any
+	startButton        *widget.Button
+	stopButton         *widget.Button
+	pauseButton        *widget.Button   // Freezes the statistics of a running session, or resumes it
+	presetButtons      []*widget.Button // Bounded-session shortcuts next to Start
+	statusLabel        *widget.Label
+	hopList            *widget.List
+	scanner            *network.Scanner
+	hops               []network.NetworkHop
+	hopsMutex          sync.RWMutex
+	updateChan         chan network.HopUpdate
+	pathCache          *network.PathCache      // Last-known paths for recently monitored targets
+	cachedHops         []network.NetworkHop    // Stale hops shown while fresh discovery runs
+	pending            *network.HopUpdate      // Row discovery is still probing, nil when none
+	target             string                  // Hostname of the current session
+	address            string                  // Address of the hostname picked for the next session, empty for the resolver's choice
+	ixpDB              *network.IXPDatabase    // Known IXP peering LANs for badging hops
+	asnResolver        *network.ASNResolver    // Origin AS of hops, cached across sessions
+	knowledge          *network.KnowledgeBase  // Names, origin ASes and typical latency of hop addresses from earlier sessions
+	datasets           *network.DatasetManager // Downloaded enrichment data, refreshed on a schedule
+	stopDatasetUpdates context.CancelFunc      // Stops the scheduled dataset updates, nil when off
+	digest             *network.DailyDigest    // Today's summary for the current target, guarded by digestMu
+	digestMu           sync.Mutex              // Serializes changes and saves of the digest
+	sessionTimer       *time.Timer             // Ends a bounded session started from a preset
+	evidence           *network.EvidencePack   // Evidence pack being collected, if any
+	providerLabel      *widget.Label           // Shows incidents reported by the destination's provider
+	providerCheck      time.Time               // When the provider status feed was last requested
+	stopThroughput     context.CancelFunc      // Cancels the throughput test in progress, nil when none runs
+	alerts             *alert.Engine           // Threshold alerts with hysteresis
+	alertRouter        *alert.Router           // Routes each alert rule to its sinks
+	branding           Branding                // White-label names, defaults and locked settings
+	restored           []network.NetworkHop    // Hops of a resumed session, handed to the next scanner
+	nightActive        bool                    // Night display palette is applied
+	zoomGroup          *ui.ZoomGroup           // Time window shared by the row graphs
+	lossEvents         *network.LossTracker    // Loss events and throughput tests of the current session
+	pathLinks          *network.PathLinks      // Links between consecutive hops' responders seen by the current session's probes
+	liveCSV            *network.CSVTail        // Live CSV output of the current session, nil when off
+	fallback           string                  // Last-known address monitored because the target didn't resolve, empty when it did
+	byProvider         bool                    // List one row per provider (AS) instead of one per hop
+	profile            network.Profile         // Probing profile of the current or last session
+	derived            []network.DerivedMetric // User-defined metric columns, computed for every hop
+	derivedHeader      *widget.Label           // Names the derived metric columns
+}
+
+// Provider status cross-checking
+const (
+	prefCheckProviderStatus = "checkProviderStatus" // Preference key for the opt-in toggle
+	providerCheckLoss       = 5.0                   // Destination loss (%) that triggers a check
+	providerCheckInterval   = 5 * time.Minute       // Minimum time between status feed requests
+)
+
+// prefThroughputURL is the preference key for the last throughput test URL
+const prefThroughputURL = "throughputURL"
+
+// digestSaveInterval is how often the daily digest is saved while it accumulates
+const digestSaveInterval = 15 * time.Minute
+
+// pathMTUTimeout bounds a path MTU probe, whose sizes that go unanswered are each retried
+const pathMTUTimeout = time.Minute
+
+// Probe preference keys
+const (
+	prefProtocol    = "protocol"       // Last selected IP protocol
+	prefProbeMethod = "probeMethod"    // Last selected probe method
+	prefTCPPort     = "tcpPort"        // Last entered TCP probe port
+	prefProbes      = "probesPerRound" // Last selected probes per round
+)
+
+// testPreset is a bounded session length offered next to the Start button
+type testPreset struct {
+	label    string
+	duration time.Duration
+}
+
+// testPresets are the quick-duration tests, sized for guiding end users through support calls
+var testPresets = []testPreset{
+	{label: "30s Quick Test", duration: 30 * time.Second},
+	{label: "5m Test", duration: 5 * time.Minute},
+	{label: "1h Soak", duration: time.Hour},
 }
 
 func NewVisualMTR() *VisualMTR {
-	myApp := app.New()
+	myApp := app.NewWithID(AppID)
+	myApp.Settings().SetTheme(ui.NewTheme(theme.DefaultTheme()))
 
-	window := myApp.NewWindow("Visual MTR - Network Path Health Monitor")
+	branding := loadBranding()
+	branding.apply(myApp)
+
+	window := myApp.NewWindow(branding.WindowTitle)
 	window.Resize(fyne.NewSize(800, 600))
 
 	vm := &VisualMTR{
-		app:        myApp,
-		window:     window,
-		hops:       make([]network.NetworkHop, 0),
-		updateChan: make(chan network.HopUpdate, 100),
+		app:         myApp,
+		window:      window,
+		hops:        make([]network.NetworkHop, 0),
+		updateChan:  make(chan network.HopUpdate, 100),
+		pathCache:   network.NewPathCache(network.DefaultPathCacheSize),
+		ixpDB:       network.NewIXPDatabase(),
+		asnResolver: network.NewASNResolver(),
+		alerts:      alert.NewEngine(loadAlertRules(alertRulesPath(myApp))),
+		branding:    branding,
+		zoomGroup:   ui.NewZoomGroup(),
+		byProvider:  myApp.Preferences().Bool(prefGroupByProvider),
 	}
 
+	vm.applyNetworkAccess()
+	vm.applyGovernorLimits()
+	vm.setupKnowledge()
+	vm.loadDerivedMetrics()
 	vm.setupUI()
 	vm.setupMenu()
 	vm.setupCloseHandler()
+	vm.setupAlertRouting()
+	vm.setupDatasets()
+	vm.startNightSchedule()
+	vm.startDigestSchedule()
 	return vm
 }
 
 func (vm *VisualMTR) setupUI() {
-	// Top section: Hostname entry and buttons
-	vm.hostnameEntry = widget.NewEntry()
+	// Top section: Hostname entry with quick-pick targets (from branding, a layout or an imported configuration) and buttons
+	vm.quickTargets = vm.branding.DefaultTargets
+	if len(vm.quickTargets) == 0 {
+		vm.quickTargets = vm.app.Preferences().StringList(prefTargets)
+	}
+	vm.targetSelect = widget.NewSelectEntry(vm.quickTargets)
+	vm.hostnameEntry = &vm.targetSelect.Entry
+	if targets := vm.quickTargets; len(targets) > 0 {
+		vm.hostnameEntry.SetText(targets[0])
+	}
 	vm.hostnameEntry.SetPlaceHolder("Enter hostname or IP address (e.g., google.com)")
 	// Ensure entry is enabled and focusable
 	vm.hostnameEntry.Enable()
+	// Pressing Enter during a session switches it to the entered target
+	vm.hostnameEntry.OnSubmitted = vm.onRetarget
+
+	protocols := make([]string, len(network.Protocols))
+	for i, protocol := range network.Protocols {
+		protocols[i] = string(protocol)
+	}
+	vm.protocolSelect = widget.NewSelect(protocols, func(selected string) {
+		vm.app.Preferences().SetString(prefProtocol, selected)
+	})
+	vm.protocolSelect.SetSelected(vm.app.Preferences().StringWithFallback(prefProtocol, string(network.ProtocolAuto)))
+
+	// TCP probes reach through firewalls that drop ICMP; the port only applies to them
+	vm.portEntry = widget.NewEntry()
+	vm.portEntry.SetText(vm.app.Preferences().StringWithFallback(prefTCPPort, strconv.Itoa(network.DefaultTCPPort)))
+	vm.portEntry.OnChanged = func(port string) {
+		vm.app.Preferences().SetString(prefTCPPort, port)
+	}
+	methods := make([]string, len(network.ProbeMethods))
+	for i, method := range network.ProbeMethods {
+		methods[i] = string(method)
+	}
+	vm.methodSelect = widget.NewSelect(methods, func(selected string) {
+		vm.app.Preferences().SetString(prefProbeMethod, selected)
+		if network.ProbeMethod(selected) == network.ProbeTCP {
+			vm.portEntry.Show()
+		} else {
+			vm.portEntry.Hide()
+		}
+	})
+	vm.methodSelect.SetSelected(vm.app.Preferences().StringWithFallback(prefProbeMethod, string(network.ProbeICMP)))
+
+	// More probes per round give finer loss figures at the cost of slower rounds
+	probeCounts := make([]string, network.MaxProbesPerRound)
+	for i := range probeCounts {
+		probeCounts[i] = formatProbesPerRound(i + 1)
+	}
+	vm.probesSelect = widget.NewSelect(probeCounts, func(selected string) {
+		vm.app.Preferences().SetString(prefProbes, selected)
+	})
+	vm.probesSelect.SetSelected(vm.app.Preferences().StringWithFallback(prefProbes, formatProbesPerRound(network.DefaultProbesPerRound)))
+
+	// Multi-homed hosts pick the uplink probes leave on, e.g. the VPN or the LAN
+	vm.sourceSelect = widget.NewSelect(nil, func(selected string) {
+		vm.app.Preferences().SetString(prefSource, vm.sources[selected])
+	})
+	vm.refreshSources()
+
+	// Gaming, VoIP and bulk transfers care about different things; each target keeps its own
+	vm.setupProfileSelect()
 
-	vm.startButton = widget.NewButton("Start", vm.onStart)
+	vm.startButton = widget.NewButton("Start", vm.onStartPressed)
 	vm.stopButton = widget.NewButton("Stop", vm.onStop)
 	vm.stopButton.Disable()
+	vm.pauseButton = widget.NewButton("Pause", vm.onPause)
+	vm.pauseButton.Disable()
 
-	topBar := container.NewBorder(nil, nil, nil,
-		container.NewHBox(vm.startButton, vm.stopButton),
-		vm.hostnameEntry)
+	buttons := container.NewHBox(vm.startButton, vm.stopButton, vm.pauseButton, widget.NewSeparator())
+	for _, preset := range testPresets {
+		button := widget.NewButton(preset.label, func() {
+			vm.onStartBounded(preset.duration)
+		})
+		vm.presetButtons = append(vm.presetButtons, button)
+		buttons.Add(button)
+	}
+
+	vm.hostnameEntry.OnChanged = vm.onTargetEdited
+
+	probeOptions := container.NewHBox(vm.protocolSelect, vm.methodSelect, vm.portEntry, vm.probesSelect, vm.profileSelect, vm.sourceSelect)
+	topBar := container.NewBorder(nil, nil, probeOptions, buttons, vm.targetSelect)
 
 	// Status label - shows current operation state
 	vm.statusLabel = widget.NewLabel("Ready - Enter a hostname and click Start")
@@ -71,8 +252,13 @@ func (vm *VisualMTR) setupUI() {
 		vm.statusLabel,
 	)
 
+	// Provider incident notice - only shown when the destination's provider reports a problem
+	vm.providerLabel = widget.NewLabel("")
+	vm.providerLabel.Importance = widget.WarningImportance
+	vm.providerLabel.Hide()
+
 	// Combine top bar and status into header section
-	topSection := container.NewVBox(topBar, statusBar)
+	topSection := container.NewVBox(topBar, statusBar, vm.providerLabel)
 
 	// Hop list with custom data binding
 	vm.hopList = widget.NewList(
@@ -80,20 +266,32 @@ func (vm *VisualMTR) setupUI() {
 		vm.hopListCreateItem,
 		vm.hopListUpdateItem,
 	)
+	vm.hopList.OnSelected = func(id widget.ListItemID) {
+		vm.showHopDetail(vm.hopOfRow(id))
+		vm.hopList.Unselect(id)
+	}
 
-	// Create header row for table
+	// Create header row for table; the derived metric columns follow the built-in ones
+	vm.derivedHeader = widget.NewLabel(derivedHeaderText(vm.derived))
 	header := container.NewHBox(
+		ui.NewSegmentMarker(), // Aligns with the segment bar on each row
 		widget.NewLabel("Hop#"),
 		widget.NewLabel("  "),
 		widget.NewLabel("IP Address"),
 		widget.NewLabel("  "),
 		widget.NewLabel("Latency"),
 		widget.NewLabel("  "),
+		widget.NewLabel("Last / Best / Worst"),
+		widget.NewLabel("  "),
 		widget.NewLabel("Loss"),
 		widget.NewLabel("  "),
 		widget.NewLabel("Status"),
 		widget.NewLabel("  "),
 		widget.NewLabel("Latency Graph"),
+		widget.NewLabel("  "),
+		widget.NewLabel("Segment"),
+		widget.NewLabel("  "),
+		vm.derivedHeader,
 	)
 	headerTextStyle := fyne.TextStyle{Bold: true}
 	for _, obj := range header.Objects {
@@ -120,11 +318,319 @@ func (vm *VisualMTR) setupMenu() {
 		vm.onQuit()
 	})
 
-	fileMenu := fyne.NewMenu("File", quitItem)
-	mainMenu := fyne.NewMainMenu(fileMenu)
+	exportHistoryItem := fyne.NewMenuItem("Export History as Parquet...", func() {
+		vm.onExportHistoryParquet()
+	})
+
+	fileMenu := fyne.NewMenu("File", exportHistoryItem, fyne.NewMenuItemSeparator(), quitItem)
+
+	refreshIXPItem := fyne.NewMenuItem("Refresh IXP Data", func() {
+		vm.onRefreshIXPData()
+	})
+	datasetsItem := fyne.NewMenuItem("Datasets...", func() {
+		vm.onDatasets()
+	})
+	alertSettingsItem := fyne.NewMenuItem("Alert Settings...", func() {
+		vm.onAlertSettings()
+	})
+	evidencePackItem := fyne.NewMenuItem("ISP Evidence Pack...", func() {
+		vm.onEvidencePack()
+	})
+	atlasItem := fyne.NewMenuItem("Compare with RIPE Atlas", func() {
+		vm.onCompareAtlas()
+	})
+	throughputItem := fyne.NewMenuItem("Throughput Test...", func() {
+		vm.onThroughputTest()
+	})
+	incidentItem := fyne.NewMenuItem("Incident Summary", func() {
+		vm.onIncidentSummary()
+	})
+	contactsItem := fyne.NewMenuItem("Contact Worst Provider...", func() {
+		vm.onWorstProviderContacts()
+	})
+	ticketItem := fyne.NewMenuItem("Create Ticket...", func() {
+		vm.onCreateTicket()
+	})
+	selfCheckItem := fyne.NewMenuItem("Firewall Self-Check", func() {
+		vm.onFirewallSelfCheck()
+	})
+	pathMTUItem := fyne.NewMenuItem("Path MTU Test", func() {
+		vm.onPathMTUTest()
+	})
+	uplinksItem := fyne.NewMenuItem("Compare Uplinks...", func() {
+		vm.onCompareUplinks()
+	})
+	dualStackItem := fyne.NewMenuItem("Compare IPv4 and IPv6", func() {
+		vm.onCompareDualStack()
+	})
+	providerItem := fyne.NewMenuItem("Check Provider Status on Loss", nil)
+	providerItem.Checked = vm.app.Preferences().Bool(prefCheckProviderStatus)
+	providerItem.Action = func() {
+		providerItem.Checked = !providerItem.Checked
+		vm.app.Preferences().SetBool(prefCheckProviderStatus, providerItem.Checked)
+		vm.window.MainMenu().Refresh()
+	}
+
+	// Branded builds can lock settings their customers shouldn't change
+	alertSettingsItem.Disabled = vm.branding.Locked(lockAlertSettings)
+	providerItem.Disabled = vm.branding.Locked(lockProviderStatus)
+	refreshIXPItem.Disabled = vm.branding.Locked(lockIXPRefresh)
+	toolsMenu := fyne.NewMenu("Tools",
+		incidentItem, ticketItem, contactsItem, evidencePackItem, atlasItem, throughputItem, uplinksItem, dualStackItem, selfCheckItem, pathMTUItem, providerItem,
+		fyne.NewMenuItemSeparator(),
+		alertSettingsItem, datasetsItem, refreshIXPItem)
+
+	pathGraphItem := fyne.NewMenuItem("ECMP Path Graph", func() {
+		vm.onShowPathGraph()
+	})
+	topologyItem := fyne.NewMenuItem("Multi-Target Topology", func() {
+		vm.onShowTopology()
+	})
+	layoutsItem := fyne.NewMenuItem("Layouts...", func() {
+		vm.onLayouts()
+	})
+	lossEventsItem := fyne.NewMenuItem("Loss Events...", func() {
+		vm.onLossEvents()
+	})
+	dnsLookupsItem := fyne.NewMenuItem("DNS Lookups...", func() {
+		vm.onDNSLookups()
+	})
+	groupItem := fyne.NewMenuItem("Group Hops by Provider", nil)
+	groupItem.Checked = vm.byProvider
+	groupItem.Action = func() {
+		groupItem.Checked = !groupItem.Checked
+		vm.setGroupByProvider(groupItem.Checked)
+		vm.window.MainMenu().Refresh()
+	}
+	nightDisplayItem := fyne.NewMenuItem("Night Display...", func() {
+		vm.onNightDisplay()
+	})
+	viewMenu := fyne.NewMenu("View", pathGraphItem, topologyItem, lossEventsItem, dnsLookupsItem, groupItem, fyne.NewMenuItemSeparator(), layoutsItem, nightDisplayItem)
+
+	exportConfigItem := fyne.NewMenuItem("Export Configuration...", func() {
+		vm.onExportConfig()
+	})
+	importConfigItem := fyne.NewMenuItem("Import Configuration...", func() {
+		vm.onImportConfig()
+	})
+	probeSettingsItem := fyne.NewMenuItem("Probe Settings...", func() {
+		vm.onProbeSettings()
+	})
+	networkAccessItem := fyne.NewMenuItem("Network Access...", func() {
+		vm.onNetworkAccess()
+	})
+	liveCSVItem := fyne.NewMenuItem("Live CSV Output...", func() {
+		vm.onLiveCSVSettings()
+	})
+	derivedItem := fyne.NewMenuItem("Derived Metrics...", func() {
+		vm.onDerivedMetrics()
+	})
+	pickAddressItem := fyne.NewMenuItem("Ask Which Address to Monitor", nil)
+	pickAddressItem.Checked = vm.app.Preferences().Bool(prefPickAddress)
+	pickAddressItem.Action = func() {
+		pickAddressItem.Checked = !pickAddressItem.Checked
+		vm.app.Preferences().SetBool(prefPickAddress, pickAddressItem.Checked)
+		vm.window.MainMenu().Refresh()
+	}
+	reportLanguageItem := fyne.NewMenuItem("Report Language", nil)
+	reportLanguageItem.ChildMenu = vm.reportLanguageMenu()
+	settingsMenu := fyne.NewMenu("Settings", probeSettingsItem, networkAccessItem, liveCSVItem, derivedItem, pickAddressItem, reportLanguageItem, fyne.NewMenuItemSeparator(), exportConfigItem, importConfigItem)
+
+	mainMenu := fyne.NewMainMenu(fileMenu, viewMenu, toolsMenu, settingsMenu)
 	vm.window.SetMainMenu(mainMenu)
 }
 
+// onCompareAtlas queries public RIPE Atlas pings to the target and shows them next to our destination stats
+func (vm *VisualMTR) onCompareAtlas() {
+	target := vm.hostnameEntry.Text
+	if target == "" {
+		dialog.ShowInformation("RIPE Atlas", "Enter a hostname to compare against public measurements.", vm.window)
+		return
+	}
+	vm.statusLabel.SetText("Querying RIPE Atlas...")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		summary, err := network.QueryAtlas(ctx, target)
+
+		vm.hopsMutex.RLock()
+		var local string
+		if len(vm.hops) > 0 && vm.target == target {
+			dest := vm.hops[len(vm.hops)-1]
+			local = fmt.Sprintf("%s average, %.1f%% loss", ui.FormatLatency(dest.AvgLatency), network.HistoryLossPercent(dest))
+		} else {
+			local = "no local measurement running"
+		}
+		vm.hopsMutex.RUnlock()
+
+		fyne.Do(func() {
+			vm.statusLabel.SetText("RIPE Atlas query complete")
+			if err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			message := fmt.Sprintf("Target: %s\n\n"+
+				"Your result: %s\n"+
+				"Public probes: %d across %d measurements, median %.2f ms, %.1f%% loss",
+				target, local, summary.Probes, len(summary.Measurements), summary.MedianRTT, summary.LossPercent)
+			dialog.ShowInformation("RIPE Atlas Comparison", message, vm.window)
+		})
+	}()
+}
+
+// onFirewallSelfCheck checks whether the local host is blocking probes or replies to the target
+// "100% loss everywhere" is usually local policy, so this reports it explicitly
+func (vm *VisualMTR) onFirewallSelfCheck() {
+	target := vm.hostnameEntry.Text
+	if target == "" {
+		dialog.ShowInformation("Firewall Self-Check", "Enter a hostname to test against.", vm.window)
+		return
+	}
+	vm.statusLabel.SetText("Running firewall self-check...")
+
+	go func() {
+		result, err := network.FirewallSelfCheck(target)
+
+		fyne.Do(func() {
+			vm.statusLabel.SetText("Firewall self-check complete")
+			if err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			dialog.ShowInformation("Firewall Self-Check", result.String(), vm.window)
+		})
+	}()
+}
+
+// onPathMTUTest probes the path MTU to the target and names the tunnel it points to, if any
+func (vm *VisualMTR) onPathMTUTest() {
+	target := vm.hostnameEntry.Text
+	if target == "" {
+		dialog.ShowInformation("Path MTU Test", "Enter a hostname to test against.", vm.window)
+		return
+	}
+	vm.statusLabel.SetText("Probing path MTU...")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pathMTUTimeout)
+		defer cancel()
+		result, err := network.ProbePathMTU(ctx, target)
+
+		fyne.Do(func() {
+			vm.statusLabel.SetText("Path MTU test complete")
+			if err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			dialog.ShowInformation("Path MTU Test", result.String(), vm.window)
+		})
+	}()
+}
+
+// onThroughputTest asks for a download URL and runs an HTTP throughput test over the current path
+func (vm *VisualMTR) onThroughputTest() {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://example.com/100MB.bin")
+	urlEntry.SetText(vm.app.Preferences().String(prefThroughputURL))
+	if vm.branding.Locked(lockThroughputURL) {
+		urlEntry.Disable()
+	}
+
+	items := []*widget.FormItem{widget.NewFormItem("Download URL", urlEntry)}
+	d := dialog.NewForm("Throughput Test", "Run", "Cancel", items, func(ok bool) {
+		if !ok || urlEntry.Text == "" {
+			return
+		}
+		vm.app.Preferences().SetString(prefThroughputURL, urlEntry.Text)
+		vm.runThroughputTest(urlEntry.Text)
+	}, vm.window)
+	d.Resize(fyne.NewSize(500, 0))
+	d.Show()
+}
+
+// runThroughputTest downloads url in the background and reports throughput alongside the hops that
+// lost probes meanwhile, recording the result for the session's incident summary
+// Stopping the session or starting another test cancels it
+func (vm *VisualMTR) runThroughputTest(url string) {
+	vm.statusLabel.SetText("Running throughput test...")
+	vm.cancelThroughputTest()
+	ctx, cancel := context.WithCancel(context.Background())
+	vm.stopThroughput = cancel
+
+	vm.hopsMutex.RLock()
+	tracker := vm.lossEvents
+	vm.hopsMutex.RUnlock()
+	path := func() []network.NetworkHop {
+		vm.hopsMutex.RLock()
+		defer vm.hopsMutex.RUnlock()
+		return append([]network.NetworkHop(nil), vm.hops...)
+	}
+
+	go func() {
+		defer cancel()
+		result, err := network.RunDownloadTest(ctx, url, path)
+
+		fyne.Do(func() {
+			if errors.Is(err, network.ErrCanceled) {
+				// Whoever canceled it has set the status since
+				return
+			}
+			vm.statusLabel.SetText("Throughput test complete")
+			if err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			if tracker != nil {
+				vm.hopsMutex.Lock()
+				tracker.RecordThroughput(result)
+				vm.hopsMutex.Unlock()
+			}
+
+			degraded := "none"
+			if hops := result.DegradedHops(); len(hops) > 0 {
+				degraded = fmt.Sprint(hops)
+			}
+			message := fmt.Sprintf("URL: %s\nDownloaded: %.1f MB in %s\nThroughput: %.1f Mbps\n\n"+
+				"Path: %d hops\nHops with loss during test: %s",
+				result.URL, float64(result.Bytes)/1e6, result.Duration.Round(time.Millisecond), result.Mbps(),
+				len(result.Path), degraded)
+			dialog.ShowInformation("Throughput Test", message, vm.window)
+		})
+	}()
+}
+
+// cancelThroughputTest cancels the throughput test in progress, if any
+func (vm *VisualMTR) cancelThroughputTest() {
+	if vm.stopThroughput != nil {
+		vm.stopThroughput()
+		vm.stopThroughput = nil
+	}
+}
+
+// ixpDataPath returns where downloaded IXP prefixes are stored
+func (vm *VisualMTR) ixpDataPath() string {
+	return filepath.Join(vm.app.Storage().RootURI().Path(), "ixp.json")
+}
+
+// loadIXPData loads previously downloaded IXP prefixes, keeping the built-in list if none exist
+func (vm *VisualMTR) loadIXPData() {
+	if err := vm.ixpDB.Load(vm.ixpDataPath()); err != nil {
+		log.Printf("[DEBUG] Using built-in IXP list: %v\n", err)
+	}
+}
+
+// onRefreshIXPData downloads the latest IXP prefixes from PeeringDB and saves them
+func (vm *VisualMTR) onRefreshIXPData() {
+	for _, d := range vm.datasets.Datasets() {
+		if d.Name == ixpDatasetName {
+			vm.updateDataset(d, func() {
+				vm.statusLabel.SetText(fmt.Sprintf("Loaded %d IXP prefixes", vm.ixpDB.Len()))
+			})
+		}
+	}
+}
+
 // setupCloseHandler handles window close events
 func (vm *VisualMTR) setupCloseHandler() {
 	vm.window.SetCloseIntercept(func() {
@@ -132,19 +638,38 @@ func (vm *VisualMTR) setupCloseHandler() {
 	})
 }
 
-// onQuit handles application exit with proper cleanup
+// onQuit offers to save a running session, then exits
 func (vm *VisualMTR) onQuit() {
+	vm.hopsMutex.RLock()
+	running := vm.scanner != nil
+	vm.hopsMutex.RUnlock()
+
+	if running {
+		vm.confirmSaveSession(vm.quit)
+		return
+	}
+	vm.quit()
+}
+
+// quit handles application exit with proper cleanup
+func (vm *VisualMTR) quit() {
 	// Stop any running scanner
 	if vm.scanner != nil {
 		vm.hopsMutex.Lock()
 		scanner := vm.scanner
 		vm.scanner = nil
+		vm.knowledge.Learn(vm.hops)
 		vm.hopsMutex.Unlock()
 
 		if scanner != nil {
 			scanner.Stop()
 		}
 	}
+	vm.cancelThroughputTest()
+	vm.saveDigest()
+	vm.stopLiveCSV()
+	// Names looked up since the last session ended are kept too
+	vm.saveKnowledge()
 	// Close the application
 	vm.app.Quit()
 }
@@ -153,11 +678,24 @@ func (vm *VisualMTR) onQuit() {
 func (vm *VisualMTR) hopListLength() int {
 	vm.hopsMutex.RLock()
 	defer vm.hopsMutex.RUnlock()
-	return len(vm.hops)
+	if vm.byProvider {
+		hops, _ := vm.groupedHops()
+		return len(network.GroupByProvider(hops))
+	}
+	// Cached hops fill the rows that fresh discovery hasn't reached yet
+	length := max(len(vm.hops), len(vm.cachedHops))
+	if vm.pending != nil {
+		length = max(length, vm.pending.Index+1)
+	}
+	return length
 }
 
 func (vm *VisualMTR) hopListCreateItem() fyne.CanvasObject {
-	// Create table-like layout with 6 columns: Hop#, IP, Latency, Loss, Status, Graph
+	// Create table-like layout with 9 columns: Hop#, IP, Latency, Last/Best/Worst, Loss, Status, Graph, Segment, Derived
+	// A colored bar at the start of the row marks which path segment the hop belongs to, and a
+	// spinner after the segment marks the row discovery is still probing
+	segmentMarker := ui.NewSegmentMarker()
+
 	hopNumLabel := widget.NewLabel("")
 	hopNumLabel.TextStyle = fyne.TextStyle{Bold: true}
 
@@ -165,26 +703,40 @@ func (vm *VisualMTR) hopListCreateItem() fyne.CanvasObject {
 	ipLabel.TextStyle = fyne.TextStyle{Bold: true}
 
 	latencyLabel := widget.NewLabel("")
+	rangeLabel := widget.NewLabel("")
 	lossLabel := widget.NewLabel("")
 	statusLabel := widget.NewLabel("")
+	segmentLabel := widget.NewLabel("")
+	derivedLabel := widget.NewLabel("")
 
-	// Create the latency graph widget
+	// Create the latency graph widget; hop details can zoom all of them together
 	graph := ui.NewLatencyGraph()
+	vm.zoomGroup.Add(graph)
+
+	spinner := widget.NewActivity()
+	spinner.Hide()
 
 	// Use HBox with proper spacing for table-like appearance
-	// Structure: [Hop#, IP, Latency, Loss, Status, Graph]
+	// Structure: [Marker, Hop#, IP, Latency, Last/Best/Worst, Loss, Status, Graph, Segment, Spinner, Derived]
 	return container.NewHBox(
+		segmentMarker,
 		hopNumLabel,
 		widget.NewLabel("  "), // Spacer
 		ipLabel,
 		widget.NewLabel("  "), // Spacer
 		latencyLabel,
 		widget.NewLabel("  "), // Spacer
+		rangeLabel,
+		widget.NewLabel("  "), // Spacer
 		lossLabel,
 		widget.NewLabel("  "), // Spacer
 		statusLabel,
 		widget.NewLabel("  "), // Spacer
 		graph,
+		widget.NewLabel("  "), // Spacer
+		segmentLabel,
+		spinner,
+		derivedLabel,
 	)
 }
 
@@ -192,12 +744,25 @@ func (vm *VisualMTR) hopListUpdateItem(id widget.ListItemID, obj fyne.CanvasObje
 	vm.hopsMutex.RLock()
 	defer vm.hopsMutex.RUnlock()
 
-	if id >= len(vm.hops) {
+	var hop network.NetworkHop
+	var segments []network.Segment
+	stale := false
+	pending := false
+	switch {
+	case id < len(vm.hops):
+		hop = vm.hops[id]
+		segments = network.ClassifySegments(vm.hops)
+	case vm.pending != nil && id == vm.pending.Index:
+		hop = vm.pending.Hop
+		pending = true
+	case id < len(vm.cachedHops):
+		hop = vm.cachedHops[id]
+		segments = network.ClassifySegments(vm.cachedHops)
+		stale = true
+	default:
 		return
 	}
 
-	hop := vm.hops[id]
-
 	// Access the HBox container's objects using reflection
 	// container.NewHBox returns a container with an unexported Objects field
 	// We use reflection to access it
@@ -210,48 +775,146 @@ func (vm *VisualMTR) hopListUpdateItem(id widget.ListItemID, obj fyne.CanvasObje
 	// Safely convert to []fyne.CanvasObject with type assertion check
 	objectsInterface := objectsField.Interface()
 	objects, ok := objectsInterface.([]fyne.CanvasObject)
-	if !ok || len(objects) < 11 {
+	if !ok || len(objects) < 18 {
+		return
+	}
+	if vm.byProvider {
+		vm.updateProviderRow(id, objects)
 		return
 	}
 
-	// Objects structure: [hopNumLabel, spacer, ipLabel, spacer, latencyLabel, spacer, lossLabel, spacer, statusLabel, spacer, graph]
-	hopNumLabel := objects[0].(*widget.Label)
-	ipLabel := objects[2].(*widget.Label)
-	latencyLabel := objects[4].(*widget.Label)
-	lossLabel := objects[6].(*widget.Label)
-	statusLabel := objects[8].(*widget.Label)
-	graph := objects[10].(*ui.LatencyGraph)
+	// Objects structure: [segmentMarker, hopNumLabel, spacer, ipLabel, spacer, latencyLabel, spacer, rangeLabel, spacer, lossLabel, spacer, statusLabel, spacer, graph, spacer, segmentLabel, spinner, derivedLabel]
+	segmentMarker := objects[0].(*canvas.Rectangle)
+	hopNumLabel := objects[1].(*widget.Label)
+	ipLabel := objects[3].(*widget.Label)
+	latencyLabel := objects[5].(*widget.Label)
+	rangeLabel := objects[7].(*widget.Label)
+	lossLabel := objects[9].(*widget.Label)
+	statusLabel := objects[11].(*widget.Label)
+	graph := objects[13].(*ui.LatencyGraph)
+	segmentLabel := objects[15].(*widget.Label)
+	spinner := objects[16].(*widget.Activity)
+	derivedLabel := objects[17].(*widget.Label)
+
+	// Grey out rows restored from the path cache or still being probed
+	importance := widget.MediumImportance
+	if stale || pending {
+		importance = widget.LowImportance
+	}
+	for _, label := range []*widget.Label{hopNumLabel, ipLabel, latencyLabel, rangeLabel, lossLabel, statusLabel, segmentLabel, derivedLabel} {
+		label.Importance = importance
+	}
 
 	// Column 1: Hop Number
 	hopNumLabel.SetText(fmt.Sprintf("%d", id+1))
 
-	// Column 2: IP Address
-	ipLabel.SetText(hop.IP)
+	// A row discovery is still probing shows only the TTL being tried, with its spinner running
+	if pending {
+		status := fmt.Sprintf("Probing TTL %d...", hop.TTL)
+		if hop.TTL == 0 {
+			status = "Locating destination..."
+		}
+		ipLabel.SetText("*")
+		for _, label := range []*widget.Label{latencyLabel, rangeLabel, lossLabel, segmentLabel, derivedLabel} {
+			label.SetText("")
+		}
+		statusLabel.SetText(status)
+		graph.SetData(nil)
+		segmentMarker.FillColor = ui.SegmentColor("")
+		segmentMarker.Refresh()
+		spinner.Show()
+		spinner.Start()
+		return
+	}
+	spinner.Stop()
+	spinner.Hide()
+
+	// Column 2: IP Address with its label or name and origin AS, badged when the hop sits on an exchange peering LAN
+	ip := vm.knownName(hop.IP)
+	if vm.scanner != nil && !stale {
+		// Addresses synthesized by NAT64 stand for the IPv4 host behind the translator
+		if nat, ok := vm.scanner.NAT64(); ok {
+			if ip4, ok := nat.Embedded(hop.IP); ok {
+				ip = fmt.Sprintf("%s = %s", ip, ip4)
+			}
+		}
+	}
+	if hop.ASN != 0 {
+		ip = fmt.Sprintf("%s (AS%d)", ip, hop.ASN)
+	}
+	// Multipath hops list the other routers in the details, like mtr's multipath view
+	if len(hop.Responders) > 1 {
+		ip = fmt.Sprintf("%s [+%d more]", ip, len(hop.Responders)-1)
+	}
+	if labels := network.MPLSLabels(hop.Extensions); len(labels) > 0 {
+		ip = fmt.Sprintf("%s [MPLS %d]", ip, labels[0].Label)
+	}
+	if ixp, ok := vm.ixpDB.Lookup(hop.IP); ok {
+		ipLabel.SetText(fmt.Sprintf("%s [IX: %s]", ip, ixp.Name))
+	} else {
+		ipLabel.SetText(ip)
+	}
 
 	// Column 3: Latency
 	if hop.AvgLatency > 0 {
-		latencyLabel.SetText(fmt.Sprintf("%.2f ms", hop.AvgLatency))
+		latency := ui.FormatLatency(hop.AvgLatency)
+		if typical := vm.typicalLatency(hop); typical != "" {
+			latency = fmt.Sprintf("%s (%s)", latency, typical)
+		}
+		latencyLabel.SetText(latency)
 	} else {
 		latencyLabel.SetText("N/A")
 	}
 
-	// Column 4: Packet Loss
+	// Column 4: Last / Best / Worst RTT, as MTR shows them
+	if hop.Received > 0 {
+		rangeLabel.SetText(fmt.Sprintf("%s / %s / %s", ui.FormatLatency(hop.LastLatency), ui.FormatLatency(hop.BestLatency), ui.FormatLatency(hop.WorstLatency)))
+	} else {
+		rangeLabel.SetText("- / - / -")
+	}
+
+	// Column 5: Packet Loss
 	if hop.LossPercent > 0 {
 		lossLabel.SetText(fmt.Sprintf("%.1f%%", hop.LossPercent))
 	} else {
 		lossLabel.SetText("0%")
 	}
 
-	// Column 5: Status (computed dynamically)
+	// Column 6: Status (computed dynamically)
 	status := vm.computeStatus(hop)
+	if stale {
+		status = "Cached"
+	}
 	statusLabel.SetText(status)
 
-	// Column 6: Latency Graph - update with history data
+	// Column 7: Latency Graph - update with history data
 	graph.SetData(hop.LatencyHistory)
+
+	// Column 8: Segment - named only on the first hop of each segment so it reads as a separator
+	segment := segments[id]
+	segmentMarker.FillColor = ui.SegmentColor(string(segment))
+	segmentMarker.Refresh()
+	if id == 0 || segments[id-1] != segment {
+		segmentLabel.SetText(string(segment))
+	} else {
+		segmentLabel.SetText("")
+	}
+
+	// Column 9: Derived metrics, recomputed from the hop's metrics on every update
+	derivedLabel.SetText(derivedValues(vm.derived, hop, id, id == len(segments)-1))
 }
 
 // computeStatus determines the status of a hop based on its metrics
 func (vm *VisualMTR) computeStatus(hop network.NetworkHop) string {
+	if hop.Status.Dead() {
+		return fmt.Sprintf("%s %s", hop.Status, hop.Status.Annotation())
+	}
+	if hop.Flapping {
+		return fmt.Sprintf("Flapping (%.0f%% stable)", hop.Stability)
+	}
+	if hop.RateLimited {
+		return "Likely rate-limited"
+	}
 	if hop.AvgLatency > 0 {
 		return "Active"
 	}
@@ -261,48 +924,141 @@ func (vm *VisualMTR) computeStatus(hop network.NetworkHop) string {
 	return "Timeout"
 }
 
+// sessionSetup is how a session probes, taken from the UI unless a measurement protocol pins it
+type sessionSetup struct {
+	options        []network.ScannerOption
+	method         network.ProbeMethod
+	probesPerRound int
+	profile        network.Profile
+}
+
+// uiSessionSetup returns the probe settings, method, probes per round and profile chosen in the UI
+func (vm *VisualMTR) uiSessionSetup() sessionSetup {
+	return sessionSetup{
+		options:        vm.scannerOptions(),
+		method:         network.ProbeMethod(vm.methodSelect.Selected),
+		probesPerRound: vm.probesPerRound(),
+		profile:        vm.selectedProfile(),
+	}
+}
+
+// evidenceSessionSetup returns the fixed setup of the evidence protocol, whatever the UI says
+func evidenceSessionSetup() sessionSetup {
+	return sessionSetup{
+		options:        network.EvidenceOptions(),
+		method:         network.ProbeICMP,
+		probesPerRound: 1,
+		profile:        network.ProfileGeneral,
+	}
+}
+
 func (vm *VisualMTR) onStart() {
+	vm.startSession(vm.uiSessionSetup())
+}
+
+// startSession starts monitoring the entered hostname as setup says
+func (vm *VisualMTR) startSession(setup sessionSetup) {
 	hostname := vm.hostnameEntry.Text
 	if hostname == "" {
 		vm.statusLabel.SetText("Error: Please enter a hostname")
 		return
 	}
+	tcpPort := 0
+	if setup.method == network.ProbeTCP {
+		port, err := strconv.Atoi(vm.portEntry.Text)
+		if err != nil || port < 1 || port > 65535 {
+			vm.statusLabel.SetText("Error: TCP port must be between 1 and 65535")
+			return
+		}
+		tcpPort = port
+	}
 
 	// Update UI state only after validation passes
-	vm.startButton.Disable()
-	vm.hostnameEntry.Disable()
-	vm.stopButton.Enable()
+	vm.setControlsRunning(true)
 	vm.statusLabel.SetText("Starting...")
 
+	vm.providerLabel.Hide()
+	vm.alerts.Reset()
+	vm.startLiveCSV()
+
+	// Show the last-known path for this target while fresh discovery runs
+	vm.rememberProfile(hostname)
+	vm.hopsMutex.Lock()
+	vm.target = hostname
+	vm.profile = setup.profile
+	vm.providerCheck = time.Time{}
+	vm.lossEvents = network.NewLossTracker()
+	vm.pathLinks = network.NewPathLinks()
+	vm.hops = make([]network.NetworkHop, 0)
+	vm.pending = nil
+	vm.cachedHops, _ = vm.pathCache.Get(hostname)
+	restored := vm.restored
+	vm.restored = nil
+	address := vm.address
+	vm.address = ""
+	if vm.fallback == "" {
+		// A session on a last-known address keeps the failed lookups that led to it
+		network.ResetDNSQueries()
+	}
+	if len(restored) > 0 {
+		vm.cachedHops = restored
+	}
+	hasCached := len(vm.cachedHops) > 0
+	vm.hopsMutex.Unlock()
+	if hasCached {
+		vm.statusLabel.SetText("Showing cached path - refreshing...")
+	}
+	vm.hopList.Refresh()
+
 	// Create new scanner with mutex protection
 	vm.hopsMutex.Lock()
-	vm.scanner = network.NewScanner(hostname)
+	vm.scanner = network.NewScannerFrom(hostname, vm.selectedSource(), setup.options...)
+	vm.scanner.SetProtocol(network.Protocol(vm.protocolSelect.Selected))
+	vm.scanner.SetProbeMethod(setup.method)
+	if tcpPort != 0 {
+		vm.scanner.SetTCPProbe(tcpPort)
+	}
+	vm.scanner.SetProbesPerRound(setup.probesPerRound)
+	vm.scanner.SetASNResolver(vm.asnResolver)
+	vm.scanner.SetAddress(address)
+	vm.scanner.RestoreHistory(restored)
 	scanner := vm.scanner
 	vm.hopsMutex.Unlock()
+	vm.updateTitle()
 
 	// Start scanning in background
 	go func() {
-		err := scanner.Start()
+		err := scanner.Start(context.Background())
+		if errors.Is(err, network.ErrCanceled) {
+			// Stopped during discovery; onStop has already reset the UI
+			return
+		}
 		if err != nil {
-			fmt.Printf("Error starting scanner: %v\n", err)
 			// Reset UI state on error - must use fyne.Do() from goroutine
 			fyne.Do(func() {
-				vm.startButton.Enable()
-				vm.hostnameEntry.Enable()
-				vm.stopButton.Disable()
-				vm.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				if vm.sessionTimer != nil {
+					vm.sessionTimer.Stop()
+					vm.sessionTimer = nil
+				}
+				vm.setControlsRunning(false)
+				vm.showScannerError(hostname, err)
 			})
 			// Clear scanner reference
 			vm.hopsMutex.Lock()
 			vm.scanner = nil
+			vm.fallback = ""
 			vm.hopsMutex.Unlock()
+			vm.updateTitle()
 			return
 		}
 	}()
 
 	// Start update handler goroutines
 	go vm.handleUpdates()
+	go vm.handleProbes()
 	go vm.handleStatus()
+	go vm.handleEvents()
+	go vm.handleErrors()
 }
 
 func (vm *VisualMTR) onStop() {
@@ -316,18 +1072,336 @@ func (vm *VisualMTR) onStop() {
 		scanner.Stop()
 	}
 
-	vm.startButton.Enable()
-	vm.hostnameEntry.Enable()
-	vm.stopButton.Disable()
+	// A manual stop cancels any pending bounded-session end
+	if vm.sessionTimer != nil {
+		vm.sessionTimer.Stop()
+		vm.sessionTimer = nil
+	}
+
+	vm.cancelThroughputTest()
+	vm.setControlsRunning(false)
 	vm.statusLabel.SetText("Stopped - Enter a hostname and click Start")
+	vm.saveDigest()
+	vm.stopLiveCSV()
 
-	// Clear hops
+	// Remember the path for this target, then clear hops
 	vm.hopsMutex.Lock()
+	vm.pathCache.Put(vm.target, vm.hops)
+	vm.learnSession(vm.hops)
 	vm.hops = make([]network.NetworkHop, 0)
+	vm.cachedHops = nil
+	vm.pending = nil
+	vm.evidence = nil
+	vm.fallback = ""
 	vm.hopsMutex.Unlock()
 
 	// Refresh UI
 	vm.hopList.Refresh()
+	vm.updateTitle()
+}
+
+// onRetarget switches the running session to hostname: the scanner re-runs discovery for it
+// while the hop list, alerts and loss events start over. Without a session it does nothing
+func (vm *VisualMTR) onRetarget(hostname string) {
+	vm.hopsMutex.Lock()
+	scanner := vm.scanner
+	previous := vm.target
+	if scanner == nil || hostname == "" || hostname == previous {
+		vm.hopsMutex.Unlock()
+		return
+	}
+	if vm.evidence != nil {
+		vm.hopsMutex.Unlock()
+		vm.statusLabel.SetText("The evidence pack measures one target - stop it to switch")
+		return
+	}
+	vm.pathCache.Put(previous, vm.hops)
+	vm.learnSession(vm.hops)
+	vm.hopsMutex.Unlock()
+	vm.saveDigest()
+
+	vm.providerLabel.Hide()
+	vm.alerts.Reset()
+	network.ResetDNSQueries()
+
+	vm.rememberProfile(hostname)
+	vm.hopsMutex.Lock()
+	vm.target = hostname
+	vm.profile = vm.selectedProfile()
+	vm.providerCheck = time.Time{}
+	vm.lossEvents = network.NewLossTracker()
+	vm.pathLinks = network.NewPathLinks()
+	vm.hops = make([]network.NetworkHop, 0)
+	vm.pending = nil
+	vm.cachedHops, _ = vm.pathCache.Get(hostname)
+	vm.fallback = ""
+	vm.hopsMutex.Unlock()
+
+	vm.pauseButton.SetText("Pause")
+	vm.statusLabel.SetText(fmt.Sprintf("Switching to %s...", hostname))
+	vm.hopList.Refresh()
+	vm.updateTitle()
+
+	go func() {
+		err := scanner.SetTarget(context.Background(), hostname)
+		if err == nil || errors.Is(err, network.ErrCanceled) {
+			// Switched again or stopped meanwhile
+			return
+		}
+		fyne.Do(func() {
+			vm.showScannerError(hostname, err)
+		})
+	}()
+}
+
+// setControlsRunning enables the controls that apply while a session is (or isn't) running
+func (vm *VisualMTR) setControlsRunning(running bool) {
+	if running {
+		vm.startButton.Disable()
+		vm.protocolSelect.Disable()
+		vm.methodSelect.Disable()
+		vm.portEntry.Disable()
+		vm.probesSelect.Disable()
+		vm.profileSelect.Disable()
+		vm.sourceSelect.Disable()
+		vm.stopButton.Enable()
+		vm.pauseButton.Enable()
+		for _, button := range vm.presetButtons {
+			button.Disable()
+		}
+		return
+	}
+	vm.startButton.Enable()
+	vm.protocolSelect.Enable()
+	vm.methodSelect.Enable()
+	vm.portEntry.Enable()
+	vm.probesSelect.Enable()
+	vm.profileSelect.Enable()
+	vm.sourceSelect.Enable()
+	// Interfaces come and go, e.g. when a VPN connects
+	vm.refreshSources()
+	vm.stopButton.Disable()
+	vm.pauseButton.SetText("Pause")
+	vm.pauseButton.Disable()
+	for _, button := range vm.presetButtons {
+		button.Enable()
+	}
+}
+
+// onPause stops or restarts the probes of the running session; while paused the numbers stay
+// frozen, e.g. for writing up an incident
+func (vm *VisualMTR) onPause() {
+	vm.hopsMutex.RLock()
+	scanner := vm.scanner
+	vm.hopsMutex.RUnlock()
+	if scanner == nil {
+		return
+	}
+
+	if scanner.Paused() {
+		scanner.Resume()
+		vm.pauseButton.SetText("Pause")
+	} else {
+		scanner.Pause()
+		vm.pauseButton.SetText("Resume")
+	}
+	vm.updateTitle()
+}
+
+// formatProbesPerRound labels a probes-per-round choice
+func formatProbesPerRound(probes int) string {
+	if probes == 1 {
+		return "1 probe/round"
+	}
+	return fmt.Sprintf("%d probes/round", probes)
+}
+
+// probesPerRound returns the selected number of probes per round
+func (vm *VisualMTR) probesPerRound() int {
+	for probes := 1; probes <= network.MaxProbesPerRound; probes++ {
+		if formatProbesPerRound(probes) == vm.probesSelect.Selected {
+			return probes
+		}
+	}
+	return network.DefaultProbesPerRound
+}
+
+// onStartBounded starts a preset session that ends with a summary dialog
+func (vm *VisualMTR) onStartBounded(duration time.Duration) {
+	vm.startBoundedSession(vm.uiSessionSetup(), duration, func(target string, hops []network.NetworkHop) {
+		vm.showSessionSummary(target, duration, hops)
+	})
+}
+
+// startBoundedSession starts a session set up as setup says that stops itself after duration
+// The final hop table is handed to finish on the UI thread; returns false if the session didn't start
+func (vm *VisualMTR) startBoundedSession(setup sessionSetup, duration time.Duration, finish func(target string, hops []network.NetworkHop)) bool {
+	vm.startSession(setup)
+
+	vm.hopsMutex.RLock()
+	started := vm.scanner != nil
+	vm.hopsMutex.RUnlock()
+	if !started {
+		return false
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(duration, func() {
+		fyne.Do(func() {
+			// Ignore timers from sessions that were already stopped
+			if vm.sessionTimer != timer {
+				return
+			}
+
+			vm.hopsMutex.RLock()
+			target := vm.target
+			hops := append([]network.NetworkHop(nil), vm.hops...)
+			vm.hopsMutex.RUnlock()
+
+			vm.onStop()
+			finish(target, hops)
+		})
+	})
+	vm.sessionTimer = timer
+	return true
+}
+
+// showSessionSummary pops a summary with a verdict and export option
+func (vm *VisualMTR) showSessionSummary(target string, duration time.Duration, hops []network.NetworkHop) {
+	verdict, detail := sessionVerdict(hops, vm.sessionProfile(), network.ReportEnglish)
+	summary := widget.NewLabel(fmt.Sprintf("%s\n\nTarget: %s\nDuration: %s\nHops: %d\n\nVerdict: %s\n%s",
+		vm.incidentSummary(hops, network.ReportEnglish), target, duration, len(hops), verdict, detail))
+	summary.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewCustomConfirm("Test Complete", "Export CSV...", "Close", summary, func(export bool) {
+		if export {
+			vm.exportHopsCSV(hops)
+		}
+	}, vm.window)
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// onEvidencePack explains the evidence pack protocol and starts it once confirmed
+func (vm *VisualMTR) onEvidencePack() {
+	hostname := vm.hostnameEntry.Text
+	if hostname == "" {
+		dialog.ShowInformation("ISP Evidence Pack", "Enter the hostname your ISP should investigate, then try again.", vm.window)
+		return
+	}
+
+	message := fmt.Sprintf("This runs a standardized %s measurement to %s at 1 second intervals, "+
+		"takes a hop table snapshot every %s and collects system information and the path MTU.\n\n"+
+		"When it finishes you'll be asked where to save a zip you can send to your ISP's support team. "+
+		"Keep the window open until then.", network.EvidenceDuration, hostname, network.EvidenceSnapshotInterval)
+	dialog.ShowConfirm("ISP Evidence Pack", message, func(ok bool) {
+		if ok {
+			vm.startEvidencePack(hostname)
+		}
+	}, vm.window)
+}
+
+// startEvidencePack runs the evidence protocol, snapshotting the hop table until the session ends
+func (vm *VisualMTR) startEvidencePack(hostname string) {
+	pack := network.NewEvidencePack(hostname, time.Now())
+	// Every pack measures the same way, whatever the probe settings and profile say
+	started := vm.startBoundedSession(evidenceSessionSetup(), network.EvidenceDuration, func(target string, hops []network.NetworkHop) {
+		vm.finishEvidencePack(pack, hops)
+	})
+	if !started {
+		return
+	}
+
+	vm.hopsMutex.Lock()
+	vm.evidence = pack
+	vm.hopsMutex.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pathMTUTimeout)
+		defer cancel()
+		pack.SetPathMTU(network.ProbePathMTU(ctx, hostname))
+	}()
+
+	go func() {
+		ticker := time.NewTicker(network.EvidenceSnapshotInterval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			vm.hopsMutex.RLock()
+			active := vm.evidence == pack
+			if active {
+				pack.AddSnapshot(now, vm.hops)
+			}
+			vm.hopsMutex.RUnlock()
+
+			if !active {
+				return
+			}
+		}
+	}()
+}
+
+// finishEvidencePack records the final hop table and asks where to save the zip
+func (vm *VisualMTR) finishEvidencePack(pack *network.EvidencePack, hops []network.NetworkHop) {
+	pack.AddSnapshot(time.Now(), hops)
+	pack.Language = vm.reportLanguage()
+	verdict, detail := sessionVerdict(hops, vm.sessionProfile(), pack.Language)
+	summary := fmt.Sprintf("%s\n\n%s\n%s", vm.incidentSummary(hops, pack.Language), pack.Language.Sprintf("Verdict: %s", verdict), detail)
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+
+		if err := pack.WriteZip(writer, summary); err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		vm.statusLabel.SetText("Evidence pack saved")
+	}, vm.window)
+	save.SetFileName(pack.FileName())
+	save.Show()
+}
+
+// sessionVerdict grades the path from the destination's loss, latency and jitter by the
+// session's profile, worded in lang
+func sessionVerdict(hops []network.NetworkHop, profile network.Profile, lang network.ReportLanguage) (string, string) {
+	if len(hops) == 0 {
+		return lang.Phrase("Inconclusive"), lang.Phrase("No hops were discovered.")
+	}
+
+	dest := hops[len(hops)-1]
+	if dest.AvgLatency <= 0 {
+		return lang.Phrase("Unreachable"), lang.Phrase("The destination never replied.")
+	}
+	loss := network.HistoryLossPercent(dest)
+	detail := lang.Sprintf("Destination %s: %s average, %.1f%% loss.", dest.IP, ui.FormatLatency(dest.AvgLatency), loss)
+	score := profile.Score(dest.AvgLatency, dest.Jitter, loss)
+	detail += " " + lang.Sprintf("%s score: %d/100.", lang.Phrase(profile.Name), score)
+	return lang.Phrase(profile.Grade(dest.AvgLatency, dest.Jitter, loss)), detail
+}
+
+// exportHopsCSV asks for a file and writes the hop table to it
+func (vm *VisualMTR) exportHopsCSV(hops []network.NetworkHop) {
+	dialog.ShowFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+
+		if err := network.WriteHopsCSV(writer, hops); err != nil {
+			dialog.ShowError(err, vm.window)
+		}
+	}, vm.window)
 }
 
 // handleUpdates processes hop updates from the scanner and updates the UI
@@ -348,6 +1422,25 @@ func (vm *VisualMTR) handleUpdates() {
 	for update := range updates {
 		vm.hopsMutex.Lock()
 
+		// Updates about the target before a switch may still be queued
+		if update.Target != vm.target {
+			vm.hopsMutex.Unlock()
+			continue
+		}
+
+		// Rows still being probed are only shown, never counted as hops
+		if update.Pending {
+			vm.pending = &update
+			vm.hopsMutex.Unlock()
+			fyne.Do(func() {
+				vm.hopList.Refresh()
+			})
+			continue
+		}
+		if vm.pending != nil && vm.pending.Index == update.Index {
+			vm.pending = nil
+		}
+
 		// Ensure we have enough slots in the hops slice
 		for len(vm.hops) <= update.Index {
 			vm.hops = append(vm.hops, network.NetworkHop{})
@@ -355,8 +1448,34 @@ func (vm *VisualMTR) handleUpdates() {
 
 		// Update the hop data
 		vm.hops[update.Index] = update.Hop
+		change, changed := vm.lossEvents.Observe(update.Index, update.Hop, update.Time)
+		isDestination := update.Index == len(vm.hops)-1
+		target := vm.target
+		liveCSV := vm.liveCSV
 		vm.hopsMutex.Unlock()
 
+		vm.appendLiveCSV(liveCSV, target, update)
+		if changed {
+			vm.recordDigestPathChanges([]network.PathChange{change})
+		}
+
+		if isDestination {
+			vm.recordDigestSample(update.Hop)
+			vm.crossCheckProvider(update.Hop)
+			vm.updateTitle()
+		}
+
+		// Only monitoring samples feed alerts; discovery updates carry no history
+		if len(update.Hop.LatencyHistory) > 0 {
+			// Once per cycle, before the destination's own rules so a shared fault can hold them back
+			if isDestination {
+				vm.correlateSharedFate()
+			}
+			for _, event := range vm.alerts.Evaluate(target, update.Index, update.Hop, isDestination) {
+				vm.deliverAlert(event)
+			}
+		}
+
 		// Update UI on main thread using fyne.Do()
 		// Since Fyne v2.6.0, all UI updates from goroutines must use fyne.Do()
 		fyne.Do(func() {
@@ -365,6 +1484,175 @@ func (vm *VisualMTR) handleUpdates() {
 	}
 }
 
+// recordDigestSample feeds the newest destination RTT into the daily digest
+// When the day rolls over, the finished digest is saved and a new one started
+func (vm *VisualMTR) recordDigestSample(hop network.NetworkHop) {
+	// Discovery updates carry no history; only monitoring samples count
+	if len(hop.LatencyHistory) == 0 {
+		return
+	}
+	vm.updateDigest(func(digest *network.DailyDigest) {
+		digest.Add(hop.LatencyHistory[len(hop.LatencyHistory)-1])
+	})
+}
+
+// recordDigestAlert lists an alert that fired or recovered in the daily digest
+func (vm *VisualMTR) recordDigestAlert(event alert.Event) {
+	vm.updateDigest(func(digest *network.DailyDigest) {
+		digest.AddAlert(event.Time, event.Title(), event.Message(), event.Recovered)
+	})
+}
+
+// recordDigestPathChanges lists changes of the path in the daily digest
+func (vm *VisualMTR) recordDigestPathChanges(changes []network.PathChange) {
+	if len(changes) == 0 {
+		return
+	}
+	vm.updateDigest(func(digest *network.DailyDigest) {
+		digest.AddPathChanges(changes)
+	})
+}
+
+// updateDigest applies update to today's digest for the current target. When the target or the
+// day changed, the digest it replaces is saved and the one for the new target and day carries
+// on from its saved copy, if any
+func (vm *VisualMTR) updateDigest(update func(digest *network.DailyDigest)) {
+	now := time.Now()
+	vm.hopsMutex.RLock()
+	target := vm.target
+	vm.hopsMutex.RUnlock()
+
+	vm.digestMu.Lock()
+	defer vm.digestMu.Unlock()
+	if vm.digest == nil || vm.digest.Target != target || !vm.digest.Covers(now) {
+		if vm.digest != nil {
+			writeDigest(vm.digestDir(), vm.digest)
+		}
+		digest, err := network.LoadDailyDigest(vm.digestDir(), target, now)
+		if err != nil {
+			log.Printf("[DEBUG] Starting a new daily digest: %v\n", err)
+		}
+		vm.digest = digest
+	}
+	update(vm.digest)
+}
+
+// crossCheckProvider asks a well-known destination's status feed whether it reports an incident
+// Only runs when enabled, when the destination is losing packets, and at most every providerCheckInterval
+func (vm *VisualMTR) crossCheckProvider(hop network.NetworkHop) {
+	if !vm.app.Preferences().Bool(prefCheckProviderStatus) {
+		return
+	}
+	if network.HistoryLossPercent(hop) < providerCheckLoss {
+		return
+	}
+
+	vm.hopsMutex.Lock()
+	target := vm.target
+	provider, known := network.LookupProvider(target)
+	due := time.Since(vm.providerCheck) >= providerCheckInterval
+	if known && due {
+		vm.providerCheck = time.Now()
+	}
+	vm.hopsMutex.Unlock()
+
+	if !known || !due {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		status, err := network.CheckProviderStatus(ctx, provider)
+		if err != nil {
+			log.Printf("[DEBUG] %v\n", err)
+			return
+		}
+
+		fyne.Do(func() {
+			if !status.Incident() {
+				vm.providerLabel.Hide()
+				return
+			}
+			vm.providerLabel.SetText(fmt.Sprintf("⚠ %s reports an incident: %s", provider.Name, status.Description))
+			vm.providerLabel.Show()
+		})
+	}()
+}
+
+// saveDigest writes the current digest so far to the digests directory
+func (vm *VisualMTR) saveDigest() {
+	vm.digestMu.Lock()
+	defer vm.digestMu.Unlock()
+	if vm.digest != nil {
+		writeDigest(vm.digestDir(), vm.digest)
+	}
+}
+
+// startDigestSchedule saves the digest every digestSaveInterval, so a crash loses little of the
+// day, and at midnight, when the finished day is saved even if no sample arrives after it
+func (vm *VisualMTR) startDigestSchedule() {
+	go func() {
+		for {
+			now := time.Now()
+			year, month, day := now.Date()
+			midnight := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+			time.Sleep(min(midnight.Sub(now), digestSaveInterval))
+			vm.rollOverDigest()
+		}
+	}()
+}
+
+// rollOverDigest saves the digest, dropping it once its day is over; the next sample starts
+// the new day's
+func (vm *VisualMTR) rollOverDigest() {
+	vm.digestMu.Lock()
+	defer vm.digestMu.Unlock()
+	if vm.digest == nil {
+		return
+	}
+	writeDigest(vm.digestDir(), vm.digest)
+	if !vm.digest.Covers(time.Now()) {
+		vm.digest = nil
+	}
+}
+
+// digestDir returns where daily summaries are saved
+func (vm *VisualMTR) digestDir() string {
+	return filepath.Join(vm.app.Storage().RootURI().Path(), "digests")
+}
+
+// writeDigest saves a digest, replacing any earlier copy for the same target and day
+func writeDigest(dir string, digest *network.DailyDigest) {
+	if digest.Samples == 0 {
+		return
+	}
+	if err := digest.Save(dir); err != nil {
+		log.Printf("[DEBUG] Failed to save daily digest: %v\n", err)
+		return
+	}
+	log.Printf("[DEBUG] Saved daily digest to %s\n", filepath.Join(dir, digest.FileName()))
+}
+
+// handleProbes pairs the responders of consecutive hops from every monitoring probe, for the
+// links the path graph draws
+func (vm *VisualMTR) handleProbes() {
+	vm.hopsMutex.RLock()
+	scanner := vm.scanner
+	vm.hopsMutex.RUnlock()
+
+	if scanner == nil {
+		return
+	}
+
+	for result := range scanner.Probes() {
+		vm.hopsMutex.Lock()
+		vm.pathLinks.Observe(result)
+		vm.hopsMutex.Unlock()
+	}
+}
+
 // handleStatus processes status updates from the scanner and updates the status label
 func (vm *VisualMTR) handleStatus() {
 	vm.hopsMutex.RLock()
@@ -385,17 +1673,152 @@ func (vm *VisualMTR) handleStatus() {
 	}
 }
 
+// handleEvents follows the scanner's lifecycle to tell when discovery is over
+func (vm *VisualMTR) handleEvents() {
+	vm.hopsMutex.RLock()
+	scanner := vm.scanner
+	vm.hopsMutex.RUnlock()
+
+	if scanner == nil {
+		return
+	}
+
+	// Events are about this target until the scanner reports a switch
+	vm.hopsMutex.RLock()
+	eventTarget := vm.target
+	vm.hopsMutex.RUnlock()
+	var monitoringStarted time.Time
+
+	for event := range scanner.Events() {
+		switch event.Kind {
+		case network.EventTargetChanged:
+			eventTarget = event.Target
+		case network.EventMonitoringStarted:
+			monitoringStarted = event.Time
+		case network.EventCompleted:
+			// A cycle-limited session ends like a bounded one, with a summary of its final hops
+			target, hops, elapsed := eventTarget, event.Path, event.Time.Sub(monitoringStarted).Round(time.Second)
+			fyne.Do(func() {
+				vm.hopsMutex.RLock()
+				current := vm.scanner == scanner
+				vm.hopsMutex.RUnlock()
+				if !current {
+					return
+				}
+				vm.onStop()
+				vm.showSessionSummary(target, elapsed, hops)
+			})
+		case network.EventDiscoveryStarted:
+			// Remember what the target resolved to in case DNS fails next time
+			vm.hopsMutex.RLock()
+			fallback := vm.fallback
+			vm.hopsMutex.RUnlock()
+			if fallback == "" {
+				vm.rememberAddress(eventTarget, event.Address)
+			}
+		case network.EventPathChanged:
+			// Show the re-traced path and keep its changes for the incident summary and digest
+			vm.hopsMutex.Lock()
+			if eventTarget != vm.target {
+				vm.hopsMutex.Unlock()
+				continue
+			}
+			vm.hops = event.Path
+			vm.lossEvents.RecordPathChanges(event.Changes)
+			// Hops may have moved to other rows, so earlier rounds would pair the wrong ones
+			vm.pathLinks = network.NewPathLinks()
+			vm.hopsMutex.Unlock()
+			vm.recordDigestPathChanges(event.Changes)
+			for _, change := range event.Changes {
+				log.Printf("[DEBUG] Path changed: %s\n", formatPathChange(change))
+			}
+			status := fmt.Sprintf("🔀 Path changed at %s - monitoring %d hops...", event.Time.Format("15:04:05"), event.Hops)
+			fyne.Do(func() {
+				vm.statusLabel.SetText(status)
+				vm.hopList.Refresh()
+			})
+		case network.EventDiscoveryComplete, network.EventError, network.EventStopped:
+			// No row is being probed any more, and once the fresh path is complete
+			// the cached one is no longer needed
+			vm.hopsMutex.Lock()
+			if event.Kind == network.EventDiscoveryComplete {
+				vm.cachedHops = nil
+			}
+			vm.pending = nil
+			vm.hopsMutex.Unlock()
+			fyne.Do(func() {
+				vm.hopList.Refresh()
+			})
+		}
+	}
+}
+
+// handleErrors surfaces probe failures from the scanner
+// The first failure of a session opens a dialog; later ones only update the status line
+func (vm *VisualMTR) handleErrors() {
+	vm.hopsMutex.RLock()
+	scanner := vm.scanner
+	vm.hopsMutex.RUnlock()
+
+	if scanner == nil {
+		return
+	}
+
+	shown := false
+	for err := range scanner.Errors() {
+		first := !shown
+		shown = true
+		fyne.Do(func() {
+			vm.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			if first {
+				message := fmt.Sprintf("%v\n\nMonitoring continues; affected probes count as lost.", err)
+				if hint := network.CategoryOf(err).Hint(); hint != "" {
+					message += "\n" + hint
+				}
+				dialog.ShowInformation("Probe Failed", message, vm.window)
+			}
+		})
+	}
+}
+
 // formatStatus converts a ScannerStatus to a user-friendly message
 func (vm *VisualMTR) formatStatus(status network.ScannerStatus) string {
 	vm.hopsMutex.RLock()
 	hopCount := len(vm.hops)
+	scanner := vm.scanner
+	fallback := vm.fallback
 	vm.hopsMutex.RUnlock()
 
+	// Sessions without raw socket access can only send ICMP and time it less precisely
+	notes := ""
+	if scanner != nil && scanner.SocketAccess() == network.AccessUnprivileged {
+		notes = " (unprivileged ICMP - see hop details)"
+	}
+	// On IPv6-only networks an IPv4 destination is reached through a translator that hides the rest of the path
+	if scanner != nil {
+		if nat, ok := scanner.NAT64(); ok {
+			notes += fmt.Sprintf(" (via NAT64 %s to %s)", nat.Prefix, nat.IPv4)
+		}
+	}
+	if fallback != "" {
+		notes += fmt.Sprintf(" (DNS lookup failed - monitoring last-known address %s)", fallback)
+	}
+	// Many sessions at once run their rounds less often rather than flood the host
+	if scanner != nil {
+		if slowdown := scanner.Slowdown(); slowdown > 1.05 {
+			notes += fmt.Sprintf(" (rounds slowed %.1fx to limit the load of all sessions)", slowdown)
+		}
+	}
+
 	switch status {
 	case network.StatusTracing:
-		return "🔍 Tracing route to destination..."
+		return "🔍 Tracing route to destination..." + notes
 	case network.StatusPinging:
-		return fmt.Sprintf("📡 Monitoring %d hops...", hopCount)
+		return fmt.Sprintf("📡 Monitoring %d hops...", hopCount) + notes
+	case network.StatusPaused:
+		return fmt.Sprintf("⏸ Paused - statistics of %d hops frozen until Resume", hopCount)
+	case network.StatusCompleted:
+		return "🏁 Completed"
 	case network.StatusStopped:
 		return "⏹ Stopped"
 	case network.StatusError:
@@ -406,6 +1829,7 @@ func (vm *VisualMTR) formatStatus(status network.ScannerStatus) string {
 }
 
 func (vm *VisualMTR) Run() {
+	vm.offerResumeSession()
 	vm.window.ShowAndRun()
 }
 
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// Network access preference keys
+const (
+	prefOffline              = "offline"         // Turn every external lookup off
+	prefProxyURL             = "proxyURL"        // Proxy for HTTP requests, empty for the system proxy
+	prefLookupDisabledPrefix = "lookupDisabled." // Followed by the lookup feature
+)
+
+// applyNetworkAccess sets the offline mode, proxy and lookup toggles saved in Network Access
+func (vm *VisualMTR) applyNetworkAccess() {
+	prefs := vm.app.Preferences()
+
+	network.SetOffline(prefs.Bool(prefOffline))
+	for _, feature := range network.LookupFeatures {
+		network.SetLookupEnabled(feature, !prefs.Bool(prefLookupDisabledPrefix+string(feature)))
+	}
+	if err := network.SetProxy(prefs.String(prefProxyURL)); err != nil {
+		log.Printf("[DEBUG] Using the system proxy: %v\n", err)
+	}
+
+	// The webhook sink copies the client, so it is recreated to pick up a new proxy
+	if vm.alertRouter != nil {
+		vm.alertRouter.AddSink(newWebhookSink(prefs.String(prefAlertWebhookURL)))
+	}
+}
+
+// onNetworkAccess edits the proxy auxiliary lookups go through and which of them may run,
+// for networks where only the probes themselves may leave the host
+func (vm *VisualMTR) onNetworkAccess() {
+	prefs := vm.app.Preferences()
+
+	offlineCheck := widget.NewCheck("No lookups outside the measured path", nil)
+	offlineCheck.SetChecked(prefs.Bool(prefOffline))
+	proxyEntry := widget.NewEntry()
+	proxyEntry.SetPlaceHolder("http://proxy.example:3128")
+	proxyEntry.SetText(prefs.String(prefProxyURL))
+	proxyEntry.Validator = network.ValidateProxyURL
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Offline", offlineCheck),
+		widget.NewFormItem("Proxy", proxyEntry),
+	}
+	items[0].HintText = "Air-gapped networks; overrides the lookups below"
+	items[1].HintText = "Empty uses the system proxy; DNS lookups use the system resolver"
+
+	featureChecks := make([]*widget.Check, len(network.LookupFeatures))
+	for i, feature := range network.LookupFeatures {
+		featureChecks[i] = widget.NewCheck(string(feature), nil)
+		featureChecks[i].SetChecked(!prefs.Bool(prefLookupDisabledPrefix + string(feature)))
+		label := ""
+		if i == 0 {
+			label = "Lookups"
+		}
+		items = append(items, widget.NewFormItem(label, featureChecks[i]))
+	}
+
+	d := dialog.NewForm("Network Access", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		prefs.SetBool(prefOffline, offlineCheck.Checked)
+		prefs.SetString(prefProxyURL, proxyEntry.Text)
+		for i, feature := range network.LookupFeatures {
+			prefs.SetBool(prefLookupDisabledPrefix+string(feature), !featureChecks[i].Checked)
+		}
+		vm.applyNetworkAccess()
+		vm.statusLabel.SetText("Network access saved")
+	}, vm.window)
+	d.Resize(fyne.NewSize(480, 0))
+	d.Show()
+}
@@ -0,0 +1,55 @@
+package network
+
+// ProviderGroup is a run of consecutive hops announced by the same AS, shown as one
+// provider row so paths crossing several networks read as a handful of providers
+type ProviderGroup struct {
+	ASN          int     // Origin AS of the hops, 0 for hops no AS announces (e.g. the local network)
+	ASName       string  // Registered name of the AS
+	First        int     // Index of the group's first hop (0-based)
+	Last         int     // Index of the group's last hop
+	ExitLatency  float64 // Average latency at the group's last answering hop, in milliseconds; 0 if none answered
+	AddedLatency float64 // Latency added within the provider: exit latency minus the latency before entering it
+	LossPercent  float64 // Loss at the group's last answering hop, which carries on down the path
+	Exit         int     // Index of the group's last answering hop, Last if none answered
+}
+
+// Hops returns the number of hops in the group
+func (g ProviderGroup) Hops() int {
+	return g.Last - g.First + 1
+}
+
+// GroupByProvider collapses consecutive hops of the same origin AS into provider groups
+// Hops whose AS is unknown, such as timeouts and unannounced transfer networks, belong to
+// the group before them; leading ones form a group of their own with ASN 0
+func GroupByProvider(hops []NetworkHop) []ProviderGroup {
+	var groups []ProviderGroup
+	for i, hop := range hops {
+		if n := len(groups); n > 0 && (hop.ASN == 0 || hop.ASN == groups[n-1].ASN) {
+			groups[n-1].Last = i
+			continue
+		}
+		groups = append(groups, ProviderGroup{ASN: hop.ASN, ASName: hop.ASName, First: i, Last: i})
+	}
+
+	entry := 0.0
+	for i := range groups {
+		g := &groups[i]
+		g.Exit = g.Last
+		for j := g.Last; j >= g.First; j-- {
+			if hops[j].AvgLatency > 0 {
+				g.Exit = j
+				break
+			}
+		}
+		exit := hops[g.Exit]
+		g.LossPercent = exit.LossPercent
+		if exit.AvgLatency <= 0 {
+			continue
+		}
+		g.ExitLatency = exit.AvgLatency
+		// Routers answering slowly can make a later provider look faster than an earlier one
+		g.AddedLatency = max(exit.AvgLatency-entry, 0)
+		entry = exit.AvgLatency
+	}
+	return groups
+}
@@ -0,0 +1,231 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Team Cymru IP-to-ASN DNS zones
+const (
+	cymruOriginZone  = "origin.asn.cymru.com"
+	cymruOrigin6Zone = "origin6.asn.cymru.com"
+	cymruASNZone     = "asn.cymru.com"
+)
+
+// asnLookupTimeout bounds the DNS queries behind one hop's ASN lookup
+const asnLookupTimeout = 5 * time.Second
+
+// ASNInfo is the autonomous system announcing an address
+type ASNInfo struct {
+	ASN     int    // Origin AS number, 0 if unknown
+	Name    string // AS name, e.g. "CLOUDFLARENET, US"
+	Prefix  string // Announced prefix containing the address
+	Country string // Registry country code of the prefix
+}
+
+// ASNResolver maps hop addresses to their origin AS using a downloaded table if it has one,
+// else Team Cymru's DNS service
+// Results, including failures, are cached for the resolver's lifetime. It is safe for concurrent use
+type ASNResolver struct {
+	mu       sync.Mutex
+	cache    map[string]ASNInfo
+	inFlight map[string]bool
+	table    *ASNTable
+}
+
+// NewASNResolver creates a resolver with an empty cache
+func NewASNResolver() *ASNResolver {
+	return &ASNResolver{
+		cache:    make(map[string]ASNInfo),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// SetTable makes the resolver answer from an offline table, falling back to DNS for addresses
+// it lacks; nil goes back to DNS only. Failures cached before are forgotten
+func (r *ASNResolver) SetTable(table *ASNTable) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.table = table
+	for ip, info := range r.cache {
+		if info.ASN == 0 {
+			delete(r.cache, ip)
+		}
+	}
+}
+
+// Remember caches the AS of ip learned earlier, e.g. in a previous session, unless ip has been
+// looked up already
+func (r *ASNResolver) Remember(ip string, info ASNInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cache[ip]; !ok {
+		r.cache[ip] = info
+	}
+}
+
+// fromTable looks ip up in the offline table, if there is one
+func (r *ASNResolver) fromTable(ip string) (ASNInfo, bool) {
+	r.mu.Lock()
+	table := r.table
+	r.mu.Unlock()
+	if table == nil {
+		return ASNInfo{}, false
+	}
+	return table.Lookup(ip)
+}
+
+// Cached returns the AS of ip if it has been looked up
+func (r *ASNResolver) Cached(ip string) (ASNInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.cache[ip]
+	return info, ok
+}
+
+// Prefetch looks up the AS of ip in the background unless it is cached or already being looked up
+// Private and local addresses are never looked up, nor is anything while ASN lookups are off
+// and no offline table is loaded
+func (r *ASNResolver) Prefetch(ip string) {
+	if isPrivateIP(ip) || net.ParseIP(ip) == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.table == nil && !LookupAllowed(LookupASN) {
+		r.mu.Unlock()
+		return
+	}
+	_, cached := r.cache[ip]
+	if cached || r.inFlight[ip] {
+		r.mu.Unlock()
+		return
+	}
+	r.inFlight[ip] = true
+	r.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), asnLookupTimeout)
+		defer cancel()
+		if _, err := r.Lookup(ctx, ip); err != nil {
+			log.Printf("[DEBUG] ASN lookup for %s failed: %v\n", ip, err)
+		}
+		r.mu.Lock()
+		delete(r.inFlight, ip)
+		r.mu.Unlock()
+	}()
+}
+
+// Lookup queries the origin AS of ip and its name, caching the result
+// The offline table answers without any query; otherwise this fails with ErrLookupDisabled,
+// caching nothing, while ASN lookups are off
+func (r *ASNResolver) Lookup(ctx context.Context, ip string) (ASNInfo, error) {
+	if info, ok := r.Cached(ip); ok {
+		return info, nil
+	}
+	if info, ok := r.fromTable(ip); ok {
+		r.mu.Lock()
+		r.cache[ip] = info
+		r.mu.Unlock()
+		return info, nil
+	}
+	if err := checkLookup(LookupASN); err != nil {
+		return ASNInfo{}, err
+	}
+
+	info, err := lookupOrigin(ctx, ip)
+	if err == nil && info.ASN != 0 {
+		// The name is a nicety; an origin without it is still worth keeping
+		if name, err := lookupASName(ctx, info.ASN); err == nil {
+			info.Name = name
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[ip] = info
+	r.mu.Unlock()
+	return info, err
+}
+
+// lookupOrigin queries the origin AS, prefix and country of ip
+// Answers look like "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11"; with several origins
+// announcing the prefix the first one is used
+func lookupOrigin(ctx context.Context, ip string) (ASNInfo, error) {
+	name, err := cymruOriginName(ip)
+	if err != nil {
+		return ASNInfo{}, err
+	}
+	records, err := lookupTXT(ctx, DNSASN, name)
+	if err != nil {
+		return ASNInfo{}, fmt.Errorf("failed to query %s: %v", name, err)
+	}
+	for _, record := range records {
+		fields := splitCymru(record)
+		if len(fields) < 3 {
+			continue
+		}
+		origins := strings.Fields(fields[0])
+		if len(origins) == 0 {
+			continue
+		}
+		asn, err := strconv.Atoi(origins[0])
+		if err != nil {
+			continue
+		}
+		return ASNInfo{ASN: asn, Prefix: fields[1], Country: fields[2]}, nil
+	}
+	return ASNInfo{}, fmt.Errorf("no origin AS announced for %s", ip)
+}
+
+// lookupASName queries the registered name of an AS
+// Answers look like "13335 | US | arin | 2010-07-14 | CLOUDFLARENET, US"
+func lookupASName(ctx context.Context, asn int) (string, error) {
+	name := fmt.Sprintf("AS%d.%s", asn, cymruASNZone)
+	records, err := lookupTXT(ctx, DNSASN, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to query %s: %v", name, err)
+	}
+	for _, record := range records {
+		if fields := splitCymru(record); len(fields) >= 5 {
+			return fields[4], nil
+		}
+	}
+	return "", fmt.Errorf("no name registered for AS%d", asn)
+}
+
+// cymruOriginName returns the DNS name to query for the origin of ip:
+// reversed octets for IPv4 and reversed nibbles for IPv6
+func cymruOriginName(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address: %q", ip)
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.%s", v4[3], v4[2], v4[1], v4[0], cymruOriginZone), nil
+	}
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	v6 := parsed.To16()
+	for i := len(v6) - 1; i >= 0; i-- {
+		b.WriteByte(hex[v6[i]&0xf])
+		b.WriteByte('.')
+		b.WriteByte(hex[v6[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString(cymruOrigin6Zone)
+	return b.String(), nil
+}
+
+// splitCymru splits a Team Cymru TXT answer into its trimmed "|"-separated fields
+func splitCymru(record string) []string {
+	fields := strings.Split(record, "|")
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	return fields
+}
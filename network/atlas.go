@@ -0,0 +1,77 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// RIPEAtlasURL is the base URL of the RIPE Atlas REST API
+const RIPEAtlasURL = "https://atlas.ripe.net/api/v2"
+
+// AtlasMaxMeasurements limits how many public measurements are sampled per query
+const AtlasMaxMeasurements = 5
+
+// AtlasSummary aggregates the latest public ping results toward a target
+type AtlasSummary struct {
+	Target       string  // Target the measurements were found for
+	Measurements []int   // IDs of the measurements sampled
+	Probes       int     // Number of probe results included
+	MedianRTT    float64 // Median of the per-probe average RTTs (ms)
+	LossPercent  float64 // Packet loss across all probe results
+}
+
+// QueryAtlas fetches ongoing public RIPE Atlas ping measurements toward target
+// and summarizes their latest results, so local problems can be told apart from widespread ones
+func QueryAtlas(ctx context.Context, target string) (AtlasSummary, error) {
+	summary := AtlasSummary{Target: target}
+
+	var measurements struct {
+		Results []struct {
+			ID int `json:"id"`
+		} `json:"results"`
+	}
+	query := url.Values{
+		"target":    {target},
+		"type":      {"ping"},
+		"status":    {"2"}, // Ongoing
+		"page_size": {fmt.Sprintf("%d", AtlasMaxMeasurements)},
+	}
+	if err := fetchJSON(ctx, LookupAtlas, RIPEAtlasURL+"/measurements/?"+query.Encode(), &measurements); err != nil {
+		return summary, err
+	}
+	if len(measurements.Results) == 0 {
+		return summary, fmt.Errorf("no public RIPE Atlas ping measurements found for %s", target)
+	}
+
+	rtts := make([]float64, 0)
+	var sent, received int
+	for _, m := range measurements.Results {
+		var latest []struct {
+			Avg  float64 `json:"avg"`
+			Sent int     `json:"sent"`
+			Rcvd int     `json:"rcvd"`
+		}
+		if err := fetchJSON(ctx, LookupAtlas, fmt.Sprintf("%s/measurements/%d/latest/", RIPEAtlasURL, m.ID), &latest); err != nil {
+			return summary, err
+		}
+
+		summary.Measurements = append(summary.Measurements, m.ID)
+		for _, result := range latest {
+			summary.Probes++
+			sent += result.Sent
+			received += result.Rcvd
+			if result.Avg > 0 {
+				rtts = append(rtts, result.Avg)
+			}
+		}
+	}
+
+	sort.Float64s(rtts)
+	summary.MedianRTT = percentile(rtts, 50)
+	if sent > 0 {
+		summary.LossPercent = float64(sent-received) / float64(sent) * 100
+	}
+	return summary, nil
+}
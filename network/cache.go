@@ -0,0 +1,121 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPathCacheSize is the number of recent targets kept by a PathCache
+const DefaultPathCacheSize = 10
+
+// PathCache remembers the last-known path and stats for recently monitored targets
+// It is safe for concurrent use
+type PathCache struct {
+	mu      sync.Mutex
+	size    int
+	order   []string // Targets ordered from least to most recently stored
+	entries map[string][]NetworkHop
+	stored  map[string]time.Time // When each target's path was stored
+}
+
+// NewPathCache creates a cache holding at most size targets
+func NewPathCache(size int) *PathCache {
+	if size <= 0 {
+		size = DefaultPathCacheSize
+	}
+	return &PathCache{
+		size:    size,
+		order:   make([]string, 0, size),
+		entries: make(map[string][]NetworkHop),
+		stored:  make(map[string]time.Time),
+	}
+}
+
+// Put stores a copy of the hops for the target, evicting the oldest target if full
+func (c *PathCache) Put(target string, hops []NetworkHop) {
+	if target == "" || len(hops) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeFromOrder(target)
+	c.order = append(c.order, target)
+	c.entries[target] = copyHops(hops)
+	c.stored[target] = time.Now()
+
+	// Evict least recently stored targets
+	for len(c.order) > c.size {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+		delete(c.stored, oldest)
+	}
+}
+
+// Get returns a copy of the cached hops for the target
+func (c *PathCache) Get(target string) ([]NetworkHop, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hops, ok := c.entries[target]
+	if !ok {
+		return nil, false
+	}
+	return copyHops(hops), true
+}
+
+// All returns copies of every cached path keyed by target
+func (c *PathCache) All() map[string][]NetworkHop {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all := make(map[string][]NetworkHop, len(c.entries))
+	for target, hops := range c.entries {
+		all[target] = copyHops(hops)
+	}
+	return all
+}
+
+// Since returns copies of the paths stored at or after t keyed by target, leaving out those too
+// old to say anything about the network now
+func (c *PathCache) Since(t time.Time) map[string][]NetworkHop {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	recent := make(map[string][]NetworkHop)
+	for target, hops := range c.entries {
+		if !c.stored[target].Before(t) {
+			recent[target] = copyHops(hops)
+		}
+	}
+	return recent
+}
+
+// removeFromOrder drops the target from the recency list; caller must hold the lock
+func (c *PathCache) removeFromOrder(target string) {
+	for i, t := range c.order {
+		if t == target {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// copyHops deep-copies a hop slice so cached history can't be mutated by callers
+func copyHops(hops []NetworkHop) []NetworkHop {
+	out := make([]NetworkHop, len(hops))
+	for i, hop := range hops {
+		out[i] = hop
+		out[i].LatencyHistory = append([]float64(nil), hop.LatencyHistory...)
+		out[i].ProbeHistory = append([]float64(nil), hop.ProbeHistory...)
+		out[i].ResponderHistory = append([]string(nil), hop.ResponderHistory...)
+		out[i].Alternates = append([]Responder(nil), hop.Alternates...)
+		out[i].Responders = append([]ResponderCount(nil), hop.Responders...)
+		out[i].RTTSamples = append([]float64(nil), hop.RTTSamples...)
+		out[i].RecentProbes = append([]bool(nil), hop.RecentProbes...)
+		out[i].Extensions = append([]ICMPExtension(nil), hop.Extensions...)
+	}
+	return out
+}
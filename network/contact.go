@@ -0,0 +1,221 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RDAPURL is the base URL of the RDAP bootstrap service, which redirects queries to the registry holding the address
+const RDAPURL = "https://rdap.org"
+
+// worstLossThreshold is the destination loss (%) above which loss, rather than latency, picks the worst provider
+const worstLossThreshold = 1.0
+
+// Contact is a way to reach a network's operators
+type Contact struct {
+	Source string // Registry it came from: "RDAP" or "PeeringDB"
+	Role   string // What the contact handles, e.g. "NOC", "Abuse" or "Technical"
+	Name   string
+	Email  string
+	Phone  string
+}
+
+// WorstProvider picks the provider segment most likely behind the path's trouble
+// Loss that starts at some hop and carries on to the destination blames the provider of that hop;
+// without such loss the provider adding the most latency is picked. Hops no AS announces are
+// never picked since nobody can be contacted about them. Returns false if no provider qualifies,
+// and otherwise also why the provider was picked
+func WorstProvider(hops []NetworkHop) (ProviderGroup, string, bool) {
+	groups := GroupByProvider(hops)
+	if len(hops) == 0 {
+		return ProviderGroup{}, "", false
+	}
+
+	dest := hops[len(hops)-1]
+	if loss := dest.LossPercent; loss >= worstLossThreshold {
+		// The first hop from which every answering hop loses at least half the destination's loss
+		start := len(hops) - 1
+		for i := len(hops) - 2; i >= 0; i-- {
+			if hops[i].AvgLatency <= 0 {
+				continue
+			}
+			if hops[i].LossPercent < loss/2 {
+				break
+			}
+			start = i
+		}
+		for _, g := range groups {
+			if g.ASN != 0 && start >= g.First && start <= g.Last {
+				return g, fmt.Sprintf("%.1f%% loss starting at hop %d carries on to the destination", loss, start+1), true
+			}
+		}
+	}
+
+	worst, found := ProviderGroup{}, false
+	for _, g := range groups {
+		if g.ASN != 0 && (!found || g.AddedLatency > worst.AddedLatency) {
+			worst, found = g, true
+		}
+	}
+	if !found {
+		return ProviderGroup{}, "", false
+	}
+	return worst, fmt.Sprintf("it adds %.1f ms, the most latency of any provider on the path", worst.AddedLatency), true
+}
+
+// LookupContacts finds the operator contacts of the network holding ip, announced by asn,
+// from the address's registry (RDAP) and PeeringDB. NOC contacts come first, then abuse ones
+// Fails only if neither source has any
+func LookupContacts(ctx context.Context, asn int, ip string) ([]Contact, error) {
+	contacts, rdapErr := lookupRDAPContacts(ctx, ip)
+	peering, peeringErr := lookupPeeringDBContacts(ctx, asn)
+	contacts = append(contacts, peering...)
+	if len(contacts) == 0 {
+		switch {
+		case rdapErr != nil:
+			return nil, rdapErr
+		case peeringErr != nil:
+			return nil, peeringErr
+		}
+		return nil, fmt.Errorf("no contacts published for AS%d or %s", asn, ip)
+	}
+
+	rank := func(role string) int {
+		switch strings.ToLower(role) {
+		case "noc":
+			return 0
+		case "abuse":
+			return 1
+		case "technical":
+			return 2
+		}
+		return 3
+	}
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return rank(contacts[i].Role) < rank(contacts[j].Role)
+	})
+	return contacts, nil
+}
+
+// rdapEntity is a contact of an RDAP object, possibly with contacts of its own
+type rdapEntity struct {
+	Roles      []string        `json:"roles"`
+	VCardArray json.RawMessage `json:"vcardArray"`
+	Entities   []rdapEntity    `json:"entities"`
+}
+
+// lookupRDAPContacts queries the registry holding ip for its abuse, NOC and technical contacts
+func lookupRDAPContacts(ctx context.Context, ip string) ([]Contact, error) {
+	var object struct {
+		Entities []rdapEntity `json:"entities"`
+	}
+	if err := fetchJSON(ctx, LookupRDAP, RDAPURL+"/ip/"+url.PathEscape(ip), &object); err != nil {
+		return nil, err
+	}
+
+	var contacts []Contact
+	seen := make(map[Contact]bool)
+	var walk func(entities []rdapEntity)
+	walk = func(entities []rdapEntity) {
+		for _, entity := range entities {
+			for _, role := range entity.Roles {
+				if role != "abuse" && role != "noc" && role != "technical" {
+					continue
+				}
+				contact := parseVCard(entity.VCardArray)
+				if contact.Email == "" && contact.Phone == "" {
+					continue
+				}
+				contact.Source = "RDAP"
+				contact.Role = rdapRoleName(role)
+				if !seen[contact] {
+					seen[contact] = true
+					contacts = append(contacts, contact)
+				}
+			}
+			walk(entity.Entities)
+		}
+	}
+	walk(object.Entities)
+	return contacts, nil
+}
+
+// rdapRoleName spells an RDAP role the way PeeringDB does
+func rdapRoleName(role string) string {
+	if role == "noc" {
+		return "NOC"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// parseVCard reads the name, email and phone of an RDAP jCard (RFC 7095), e.g.
+// ["vcard", [["fn", {}, "text", "Example NOC"], ["email", {}, "text", "noc@example.net"]]]
+func parseVCard(raw json.RawMessage) Contact {
+	var card []json.RawMessage
+	if json.Unmarshal(raw, &card) != nil || len(card) < 2 {
+		return Contact{}
+	}
+	var properties [][]any
+	if json.Unmarshal(card[1], &properties) != nil {
+		return Contact{}
+	}
+
+	var contact Contact
+	for _, property := range properties {
+		if len(property) < 4 {
+			continue
+		}
+		name, _ := property[0].(string)
+		value, _ := property[3].(string)
+		switch name {
+		case "fn":
+			contact.Name = value
+		case "email":
+			if contact.Email == "" {
+				contact.Email = value
+			}
+		case "tel":
+			if contact.Phone == "" {
+				contact.Phone = strings.TrimPrefix(value, "tel:")
+			}
+		}
+	}
+	return contact
+}
+
+// lookupPeeringDBContacts fetches the public contacts a network lists on PeeringDB
+func lookupPeeringDBContacts(ctx context.Context, asn int) ([]Contact, error) {
+	var response struct {
+		Data []struct {
+			Name string `json:"name"`
+			Pocs []struct {
+				Role  string `json:"role"`
+				Name  string `json:"name"`
+				Email string `json:"email"`
+				Phone string `json:"phone"`
+			} `json:"poc_set"`
+		} `json:"data"`
+	}
+	if err := fetchJSON(ctx, LookupPeeringDB, fmt.Sprintf("%s/net?asn=%d&depth=2", PeeringDBURL, asn), &response); err != nil {
+		return nil, err
+	}
+
+	var contacts []Contact
+	for _, network := range response.Data {
+		for _, poc := range network.Pocs {
+			if poc.Email == "" && poc.Phone == "" {
+				continue
+			}
+			name := poc.Name
+			if name == "" {
+				name = network.Name
+			}
+			contacts = append(contacts, Contact{Source: "PeeringDB", Role: poc.Role, Name: name, Email: poc.Email, Phone: poc.Phone})
+		}
+	}
+	return contacts, nil
+}
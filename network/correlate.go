@@ -0,0 +1,57 @@
+package network
+
+import "sort"
+
+// SharedFate is a lossy hop common to several degraded targets' paths
+type SharedFate struct {
+	IP      string         // Address of the common hop
+	Index   int            // Earliest position of the hop in any affected path (0-based)
+	Targets []string       // Degraded targets whose path crosses the hop
+	Hops    map[string]int // Position of the hop in each affected target's path (0-based)
+}
+
+// CorrelateSharedFate looks for the earliest lossy hop shared by at least two degraded targets
+// A target is degraded when its destination shows loss. Candidates affecting more targets win,
+// then the one closest to the source, since upstream loss propagates to everything behind it.
+func CorrelateSharedFate(paths map[string][]NetworkHop) (SharedFate, bool) {
+	candidates := make(map[string]*SharedFate)
+
+	for target, hops := range paths {
+		if len(hops) == 0 || HistoryLossPercent(hops[len(hops)-1]) == 0 {
+			continue
+		}
+		for i, hop := range hops {
+			if hop.IP == "" || HistoryLossPercent(hop) == 0 {
+				continue
+			}
+			c, ok := candidates[hop.IP]
+			if !ok {
+				c = &SharedFate{IP: hop.IP, Index: i, Hops: make(map[string]int)}
+				candidates[hop.IP] = c
+			}
+			if _, seen := c.Hops[target]; seen {
+				continue
+			}
+			c.Index = min(c.Index, i)
+			c.Targets = append(c.Targets, target)
+			c.Hops[target] = i
+		}
+	}
+
+	var best *SharedFate
+	for _, c := range candidates {
+		if len(c.Targets) < 2 {
+			continue
+		}
+		if best == nil || len(c.Targets) > len(best.Targets) ||
+			(len(c.Targets) == len(best.Targets) && c.Index < best.Index) {
+			best = c
+		}
+	}
+	if best == nil {
+		return SharedFate{}, false
+	}
+
+	sort.Strings(best.Targets)
+	return *best, true
+}
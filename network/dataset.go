@@ -0,0 +1,363 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// datasetManifestFile records, next to the datasets, when each was last updated and its checksum
+const datasetManifestFile = "datasets.json"
+
+// datasetCheckInterval is how often the scheduler looks for datasets due for an update
+const datasetCheckInterval = time.Hour
+
+// IP2ASNURL is iptoasn.com's combined IPv4 and IPv6 address-to-AS table, including country codes
+const IP2ASNURL = "https://iptoasn.com/data/ip2asn-combined.tsv.gz"
+
+// Dataset is a file of enrichment data kept up to date in the app's data directory
+type Dataset struct {
+	Name        string        // Shown in the dataset list, e.g. "ASN and country"
+	File        string        // File name in the data directory
+	URL         string        // Where the file is downloaded from
+	ChecksumURL string        // Optional file of SHA-256 sums the download is verified against
+	Feature     LookupFeature // Lookup feature whose toggle gates updates
+	MaxAge      time.Duration // Age after which the scheduler updates it
+
+	// Build, if set, writes the file to path in place of downloading URL, for datasets
+	// assembled from an API such as PeeringDB
+	Build func(ctx context.Context, path string) error
+}
+
+// IP2ASNDataset returns the dataset of address ranges with their origin AS and country
+func IP2ASNDataset() Dataset {
+	return Dataset{
+		Name:    "ASN and country",
+		File:    "ip2asn-combined.tsv.gz",
+		URL:     IP2ASNURL,
+		Feature: LookupASN,
+		MaxAge:  24 * time.Hour,
+	}
+}
+
+// DatasetState is the recorded state of a dataset's file
+type DatasetState struct {
+	Updated time.Time `json:"updated"`           // Zero if never updated
+	Size    int64     `json:"size"`              // Bytes of the current file
+	SHA256  string    `json:"sha256"`            // Hex checksum of the current file
+	Error   string    `json:"error,omitempty"`   // Why the latest update failed, empty if it didn't
+	PartTag string    `json:"partTag,omitempty"` // ETag of an interrupted download, so it resumes only if unchanged
+}
+
+// DatasetManager downloads datasets into a directory, verifies them and refreshes them on a schedule
+// It is safe for concurrent use
+type DatasetManager struct {
+	dir      string
+	datasets []Dataset
+	onUpdate func(Dataset, string)
+
+	mu       sync.Mutex
+	state    map[string]DatasetState
+	updating map[string]bool
+}
+
+// NewDatasetManager creates a manager keeping datasets in dir, loading their recorded state
+func NewDatasetManager(dir string, datasets ...Dataset) *DatasetManager {
+	m := &DatasetManager{
+		dir:      dir,
+		datasets: datasets,
+		state:    make(map[string]DatasetState),
+		updating: make(map[string]bool),
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, datasetManifestFile)); err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			log.Printf("[DEBUG] Ignoring unreadable dataset manifest: %v\n", err)
+		}
+	}
+	return m
+}
+
+// SetOnUpdate registers a function called with a dataset and its path after each successful update
+// Must be called before Run or Update
+func (m *DatasetManager) SetOnUpdate(fn func(Dataset, string)) {
+	m.onUpdate = fn
+}
+
+// Datasets returns the managed datasets
+func (m *DatasetManager) Datasets() []Dataset {
+	return m.datasets
+}
+
+// Path returns where a dataset's file is stored
+func (m *DatasetManager) Path(d Dataset) string {
+	return filepath.Join(m.dir, d.File)
+}
+
+// State returns the recorded state of a dataset
+func (m *DatasetManager) State(d Dataset) DatasetState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state[d.Name]
+}
+
+// Updating reports whether a dataset is being updated
+func (m *DatasetManager) Updating(d Dataset) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updating[d.Name]
+}
+
+// Verify checks a dataset's file against the checksum recorded when it was updated,
+// so a truncated or altered file is not loaded
+func (m *DatasetManager) Verify(d Dataset) error {
+	state := m.State(d)
+	if state.SHA256 == "" {
+		return fmt.Errorf("%s has not been downloaded", d.Name)
+	}
+	sum, _, err := fileSHA256(m.Path(d))
+	if err != nil {
+		return err
+	}
+	if sum != state.SHA256 {
+		return fmt.Errorf("%s is corrupt: checksum %s, expected %s", d.Name, sum, state.SHA256)
+	}
+	return nil
+}
+
+// Run updates datasets as they come due until ctx is done, checking at start and then hourly
+func (m *DatasetManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(datasetCheckInterval)
+	defer ticker.Stop()
+	for {
+		for _, d := range m.datasets {
+			if !m.due(d) || !LookupAllowed(d.Feature) {
+				continue
+			}
+			if err := m.Update(ctx, d); err != nil {
+				log.Printf("[DEBUG] Dataset update of %s failed: %v\n", d.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// due reports whether a dataset is older than its maximum age
+func (m *DatasetManager) due(d Dataset) bool {
+	state := m.State(d)
+	return d.MaxAge > 0 && time.Since(state.Updated) >= d.MaxAge
+}
+
+// Update downloads or builds a dataset now, verifies it and replaces the current file
+// An interrupted download resumes where it stopped on the next update
+func (m *DatasetManager) Update(ctx context.Context, d Dataset) error {
+	m.mu.Lock()
+	if m.updating[d.Name] {
+		m.mu.Unlock()
+		return fmt.Errorf("%s is already being updated", d.Name)
+	}
+	m.updating[d.Name] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.updating, d.Name)
+		m.mu.Unlock()
+	}()
+
+	err := m.update(ctx, d)
+
+	m.mu.Lock()
+	state := m.state[d.Name]
+	if err != nil {
+		state.Error = err.Error()
+	}
+	m.state[d.Name] = state
+	m.mu.Unlock()
+	if saveErr := m.saveManifest(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if err == nil && m.onUpdate != nil {
+		m.onUpdate(d, m.Path(d))
+	}
+	return err
+}
+
+// update fetches a dataset into its file and records the new state
+func (m *DatasetManager) update(ctx context.Context, d Dataset) error {
+	if err := checkLookup(d.Feature); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	path := m.Path(d)
+	part := path + ".part"
+	if d.Build != nil {
+		if err := d.Build(ctx, part); err != nil {
+			os.Remove(part)
+			return err
+		}
+	} else if err := m.download(ctx, d, part); err != nil {
+		return err
+	}
+
+	sum, size, err := fileSHA256(part)
+	if err != nil {
+		return err
+	}
+	if d.ChecksumURL != "" {
+		expected, err := fetchChecksum(ctx, d)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			// A bad download must not be resumed
+			os.Remove(part)
+			m.setPartTag(d, "")
+			return fmt.Errorf("%s failed verification: checksum %s, expected %s", d.Name, sum, expected)
+		}
+	}
+	if err := os.Rename(part, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", d.File, err)
+	}
+
+	m.mu.Lock()
+	m.state[d.Name] = DatasetState{Updated: time.Now(), Size: size, SHA256: sum}
+	m.mu.Unlock()
+	return nil
+}
+
+// download fetches a dataset's URL into part, resuming a previous partial download of the same
+// version with a range request
+func (m *DatasetManager) download(ctx context.Context, d Dataset, part string) error {
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", part, err)
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", part, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", ProductName)
+	tag := m.State(d).PartTag
+	if offset > 0 && tag != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", tag)
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", d.URL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Appending to what was already downloaded
+	case http.StatusOK:
+		// No resume, or the file changed since the interrupted download
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("failed to reset %s: %v", part, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to reset %s: %v", part, err)
+		}
+		m.setPartTag(d, resp.Header.Get("ETag"))
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete
+		return nil
+	default:
+		return fmt.Errorf("%s returned %s", d.URL, resp.Status)
+	}
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return fmt.Errorf("download of %s interrupted: %v", d.Name, err)
+	}
+	return nil
+}
+
+// setPartTag records the ETag an interrupted download may resume against
+func (m *DatasetManager) setPartTag(d Dataset, tag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := m.state[d.Name]
+	state.PartTag = tag
+	m.state[d.Name] = state
+}
+
+// saveManifest writes the recorded dataset states to the data directory
+func (m *DatasetManager) saveManifest() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode dataset manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.dir, datasetManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write dataset manifest: %v", err)
+	}
+	return nil
+}
+
+// fetchChecksum downloads a dataset's checksum file and returns the sum listed for it
+// Accepts sha256sum output ("<sum>  <file>" per line) or a file holding just the sum
+func fetchChecksum(ctx context.Context, d Dataset) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.ChecksumURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %v", d.ChecksumURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", d.ChecksumURL, resp.Status)
+	}
+
+	base := filepath.Base(d.URL)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 || (len(fields) >= 2 && strings.TrimPrefix(fields[1], "*") == base) {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", base, d.ChecksumURL)
+}
+
+// fileSHA256 returns the hex SHA-256 checksum and size of a file
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,46 @@
+package network
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// unprivilegedICMPSupported reports whether ICMP datagram sockets can be used without raw socket access
+// macOS delivers every ICMP message for the socket to it, errors included
+const unprivilegedICMPSupported = true
+
+// dgramQuotesIPHeader reports whether ICMP errors read from a datagram socket quote the probe
+// with its IP header; macOS hands them over whole, as a raw socket would read them
+const dgramQuotesIPHeader = true
+
+// dgramDeniedHint explains why neither a raw nor a datagram ICMP socket could be opened
+const dgramDeniedHint = "raw ICMP sockets need root on macOS, and the unprivileged ICMP socket could not be opened either"
+
+// rawICMPDenied reports that raw ICMP sockets can't be opened without trying: macOS only opens
+// them for root, so other users go straight to an ICMP datagram socket and never need sudo
+func rawICMPDenied() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("raw ICMP sockets need root on macOS: %w", os.ErrPermission)
+	}
+	return nil
+}
+
+// enableDgramErrors is a no-op where ICMP errors are read like any other packet
+func enableDgramErrors(conn *icmp.PacketConn) error {
+	return nil
+}
+
+// dgramEchoID returns the echo ID replies carry; macOS sends the one requested unchanged
+func dgramEchoID(conn *icmp.PacketConn, requested int) int {
+	return requested
+}
+
+// recvDgram reads the next ICMP message from an ICMP datagram socket
+// The IPv4 header of the message itself is stripped (IP_STRIPHDR), the one it quotes is not
+func recvDgram(conn *icmp.PacketConn, family *ipFamily, buf []byte) (receivedPacket, error) {
+	n, peer, err := conn.ReadFrom(buf)
+	return receivedPacket{n: n, peer: peer, at: time.Now()}, err
+}
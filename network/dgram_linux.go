@@ -0,0 +1,176 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// unprivilegedICMPSupported reports whether ICMP datagram sockets can be used without raw socket access
+const unprivilegedICMPSupported = true
+
+// dgramQuotesIPHeader reports whether ICMP errors read from a datagram socket quote the probe
+// with its IP header; the error queue hands back only the echo request
+const dgramQuotesIPHeader = false
+
+// dgramDeniedHint explains why neither a raw nor a datagram ICMP socket could be opened
+const dgramDeniedHint = "raw ICMP sockets need root or CAP_NET_RAW, and unprivileged ICMP sockets are not permitted for this user (see net.ipv4.ping_group_range)"
+
+// rawICMPDenied reports nil, to try raw ICMP sockets and see: CAP_NET_RAW opens them for any user
+func rawICMPDenied() error {
+	return nil
+}
+
+// enableDgramErrors asks the kernel to queue ICMP errors for an ICMP datagram socket
+// Linux never delivers TimeExceeded to these sockets as ordinary packets
+func enableDgramErrors(conn *icmp.PacketConn) error {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return err
+	}
+	level, opt := unix.IPPROTO_IP, unix.IP_RECVERR
+	if conn.IPv6PacketConn() != nil {
+		level, opt = unix.IPPROTO_IPV6, unix.IPV6_RECVERR
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, opt, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// dgramEchoID returns the echo ID replies to an ICMP datagram socket carry
+// Linux replaces the ID of outgoing echoes with the socket's local port
+func dgramEchoID(conn *icmp.PacketConn, requested int) int {
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.Port
+	}
+	return requested
+}
+
+// recvDgram reads the next echo reply or queued ICMP error from an ICMP datagram socket
+// Queued errors are returned as the ICMP message a raw socket would have read, quoting
+// the echo request that caused them
+func recvDgram(conn *icmp.PacketConn, family *ipFamily, buf []byte) (receivedPacket, error) {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return receivedPacket{at: time.Now()}, err
+	}
+
+	var pkt receivedPacket
+	var readErr error
+	err = rc.Read(func(fd uintptr) bool {
+		pkt.n, pkt.peer, readErr = readDgramError(int(fd), family, buf)
+		if readErr == nil || readErr != unix.EAGAIN {
+			return true
+		}
+
+		oob := make([]byte, 64)
+		var oobn int
+		var from unix.Sockaddr
+		pkt.n, oobn, _, from, readErr = unix.Recvmsg(int(fd), buf, oob, unix.MSG_DONTWAIT)
+		if readErr == unix.EAGAIN {
+			return false // Wait until readable or the deadline passes
+		}
+		pkt.peer = sockaddrIP(from)
+		parseRecvControl(oob[:oobn], &pkt)
+		return true
+	})
+	pkt.at = time.Now()
+	if err != nil {
+		return receivedPacket{at: pkt.at}, err
+	}
+	return pkt, readErr
+}
+
+// readDgramError reads one ICMP error from the socket's error queue and marshals it into buf
+// Returns EAGAIN when no ICMP error is queued
+func readDgramError(fd int, family *ipFamily, buf []byte) (int, net.Addr, error) {
+	for {
+		payload := make([]byte, len(buf))
+		oob := make([]byte, 512)
+		n, oobn, _, _, err := unix.Recvmsg(fd, payload, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			isIPv4 := msg.Header.Level == unix.IPPROTO_IP && msg.Header.Type == unix.IP_RECVERR
+			isIPv6 := msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_RECVERR
+			if !isIPv4 && !isIPv6 {
+				continue
+			}
+			eeLen := int(unsafe.Sizeof(unix.SockExtendedErr{}))
+			if len(msg.Data) < eeLen {
+				continue
+			}
+			ee := (*unix.SockExtendedErr)(unsafe.Pointer(&msg.Data[0]))
+			if ee.Origin != unix.SO_EE_ORIGIN_ICMP && ee.Origin != unix.SO_EE_ORIGIN_ICMP6 {
+				continue
+			}
+
+			// The offending router's address follows the error (SO_EE_OFFENDER)
+			peer := offenderAddr(msg.Data[eeLen:])
+			var typ icmp.Type = ipv4.ICMPType(ee.Type)
+			if family == familyIPv6 {
+				typ = ipv6.ICMPType(ee.Type)
+			}
+			var body icmp.MessageBody
+			switch typ {
+			case family.timeExceeded:
+				body = &icmp.TimeExceeded{Data: payload[:n]}
+			case family.destUnreachable:
+				body = &icmp.DstUnreach{Data: payload[:n]}
+			default:
+				continue
+			}
+			b, err := (&icmp.Message{Type: typ, Code: int(ee.Code), Body: body}).Marshal(nil)
+			if err != nil {
+				continue
+			}
+			return copy(buf, b), peer, nil
+		}
+	}
+}
+
+// offenderAddr decodes the sockaddr_in or sockaddr_in6 of the router that sent an ICMP error
+func offenderAddr(b []byte) net.Addr {
+	if len(b) < 2 {
+		return &net.IPAddr{}
+	}
+	switch binary.NativeEndian.Uint16(b) {
+	case unix.AF_INET:
+		if len(b) >= 8 {
+			return &net.IPAddr{IP: net.IP(append([]byte(nil), b[4:8]...))}
+		}
+	case unix.AF_INET6:
+		if len(b) >= 24 {
+			return &net.IPAddr{IP: net.IP(append([]byte(nil), b[8:24]...))}
+		}
+	}
+	return &net.IPAddr{}
+}
+
+// sockaddrIP converts a socket address to the address of the host it names
+func sockaddrIP(sa unix.Sockaddr) net.Addr {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return &net.IPAddr{IP: net.IP(append([]byte(nil), sa.Addr[:]...))}
+	case *unix.SockaddrInet6:
+		return &net.IPAddr{IP: net.IP(append([]byte(nil), sa.Addr[:]...))}
+	}
+	return &net.IPAddr{}
+}
@@ -0,0 +1,40 @@
+//go:build !linux && !darwin
+
+package network
+
+import (
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// unprivilegedICMPSupported reports whether ICMP datagram sockets can be used without raw socket access
+const unprivilegedICMPSupported = false
+
+// dgramQuotesIPHeader reports whether ICMP errors read from a datagram socket quote the probe
+// with its IP header
+const dgramQuotesIPHeader = true
+
+// dgramDeniedHint explains why neither a raw nor a datagram ICMP socket could be opened
+const dgramDeniedHint = "raw ICMP sockets need administrator rights"
+
+// rawICMPDenied reports nil, to try raw ICMP sockets and see
+func rawICMPDenied() error {
+	return nil
+}
+
+// enableDgramErrors is a no-op where ICMP errors are read like any other packet
+func enableDgramErrors(conn *icmp.PacketConn) error {
+	return nil
+}
+
+// dgramEchoID returns the echo ID replies carry, which is the one sent outside Linux
+func dgramEchoID(conn *icmp.PacketConn, requested int) int {
+	return requested
+}
+
+// recvDgram reads the next ICMP message from an ICMP datagram socket
+func recvDgram(conn *icmp.PacketConn, family *ipFamily, buf []byte) (receivedPacket, error) {
+	n, peer, err := conn.ReadFrom(buf)
+	return receivedPacket{n: n, peer: peer, at: time.Now()}, err
+}
@@ -0,0 +1,221 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDigestEntries caps how many alerts and path changes a digest lists; the oldest are dropped first
+const maxDigestEntries = 500
+
+// DigestAlert is an alert that fired or recovered during the day
+type DigestAlert struct {
+	Time      time.Time
+	Title     string // e.g. "Critical: Destination heavy loss"
+	Message   string // Details, e.g. the hop and value that breached the threshold
+	Recovered bool
+}
+
+// DailyDigest accumulates a day of destination measurements for one target, with the alerts
+// and path changes seen meanwhile
+type DailyDigest struct {
+	Target      string        // Hostname being monitored
+	Day         time.Time     // Midnight (local time) of the day being summarized
+	Samples     int           // Probes sent to the destination
+	Replies     int           // Probes that got a reply
+	Alerts      []DigestAlert // Alerts that fired or recovered, oldest first
+	PathChanges []PathChange  // Changes of the path, oldest first
+	latencies   []float64     // Successful RTTs in milliseconds
+}
+
+// NewDailyDigest creates an empty digest for the day containing t
+func NewDailyDigest(target string, t time.Time) *DailyDigest {
+	return &DailyDigest{
+		Target:    target,
+		Day:       startOfDay(t),
+		latencies: make([]float64, 0),
+	}
+}
+
+// Add records one destination sample; latency <= 0 counts as a timeout
+func (d *DailyDigest) Add(latency float64) {
+	d.Samples++
+	if latency > 0 {
+		d.Replies++
+		d.latencies = append(d.latencies, latency)
+	}
+}
+
+// AddAlert records an alert that fired, or recovered, at t
+func (d *DailyDigest) AddAlert(t time.Time, title, message string, recovered bool) {
+	d.Alerts = append(d.Alerts, DigestAlert{Time: t, Title: title, Message: message, Recovered: recovered})
+	if len(d.Alerts) > maxDigestEntries {
+		d.Alerts = d.Alerts[1:]
+	}
+}
+
+// AddPathChanges records changes of the path
+func (d *DailyDigest) AddPathChanges(changes []PathChange) {
+	d.PathChanges = append(d.PathChanges, changes...)
+	if len(d.PathChanges) > maxDigestEntries {
+		d.PathChanges = d.PathChanges[len(d.PathChanges)-maxDigestEntries:]
+	}
+}
+
+// Covers reports whether t falls on the digest's day
+func (d *DailyDigest) Covers(t time.Time) bool {
+	return startOfDay(t).Equal(d.Day)
+}
+
+// Availability returns the percentage of samples that got a reply
+func (d *DailyDigest) Availability() float64 {
+	if d.Samples == 0 {
+		return 0
+	}
+	return float64(d.Replies) / float64(d.Samples) * 100
+}
+
+// P95 returns the 95th percentile latency of successful samples
+func (d *DailyDigest) P95() float64 {
+	sorted := append([]float64(nil), d.latencies...)
+	sort.Float64s(sorted)
+	return percentile(sorted, 95)
+}
+
+// FileName returns a filesystem-safe name for the digest
+func (d *DailyDigest) FileName() string {
+	return digestBaseName(d.Target, d.Day) + ".txt"
+}
+
+// digestBaseName names the files of the digest for target on day, without extension
+func digestBaseName(target string, day time.Time) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == '%' {
+			return '_'
+		}
+		return r
+	}, target)
+	return fmt.Sprintf("%s-%s", safe, day.Format("2006-01-02"))
+}
+
+// savedDigest is the state of a digest saved next to its report, so later sessions on the
+// same day carry on from it
+type savedDigest struct {
+	Target      string        `json:"target"`
+	Day         time.Time     `json:"day"`
+	Samples     int           `json:"samples"`
+	Replies     int           `json:"replies"`
+	Alerts      []DigestAlert `json:"alerts,omitempty"`
+	PathChanges []PathChange  `json:"path_changes,omitempty"`
+	Latencies   []float64     `json:"latencies,omitempty"`
+}
+
+// Save writes the digest to dir as a text report (see FileName) and the state LoadDailyDigest
+// reads back, replacing any earlier copy for the same target and day
+func (d *DailyDigest) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create digest directory: %v", err)
+	}
+	data, err := json.Marshal(savedDigest{
+		Target:      d.Target,
+		Day:         d.Day,
+		Samples:     d.Samples,
+		Replies:     d.Replies,
+		Alerts:      d.Alerts,
+		PathChanges: d.PathChanges,
+		Latencies:   d.latencies,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode digest: %v", err)
+	}
+	base := filepath.Join(dir, digestBaseName(d.Target, d.Day))
+	if err := os.WriteFile(base+".json", data, 0644); err != nil {
+		return fmt.Errorf("failed to write digest: %v", err)
+	}
+	if err := os.WriteFile(base+".txt", []byte(d.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write digest: %v", err)
+	}
+	return nil
+}
+
+// LoadDailyDigest returns the digest saved in dir for target on the day containing t, or an
+// empty one if none was saved. Sessions switching back to a target, or starting after a
+// restart, continue the day's digest instead of replacing it
+func LoadDailyDigest(dir, target string, t time.Time) (*DailyDigest, error) {
+	d := NewDailyDigest(target, t)
+	data, err := os.ReadFile(filepath.Join(dir, digestBaseName(target, d.Day)+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return d, fmt.Errorf("failed to read digest: %v", err)
+	}
+	var saved savedDigest
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return d, fmt.Errorf("failed to parse digest: %v", err)
+	}
+	if saved.Target != target || !saved.Day.Equal(d.Day) {
+		return d, nil
+	}
+	d.Samples = saved.Samples
+	d.Replies = saved.Replies
+	d.Alerts = saved.Alerts
+	d.PathChanges = saved.PathChanges
+	d.latencies = append(d.latencies, saved.Latencies...)
+	return d, nil
+}
+
+// String renders the digest as a plain-text report
+func (d *DailyDigest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s daily summary\n", ProductName)
+	fmt.Fprintf(&b, "Target:       %s\n", d.Target)
+	fmt.Fprintf(&b, "Date:         %s\n", d.Day.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Samples:      %d\n", d.Samples)
+	fmt.Fprintf(&b, "Availability: %.2f%%\n", d.Availability())
+	if len(d.latencies) > 0 {
+		fmt.Fprintf(&b, "p95 latency:  %.2f ms\n", d.P95())
+	} else {
+		fmt.Fprintf(&b, "p95 latency:  N/A\n")
+	}
+
+	fired := 0
+	for _, alert := range d.Alerts {
+		if !alert.Recovered {
+			fired++
+		}
+	}
+	fmt.Fprintf(&b, "Alerts:       %d fired, %d recovered\n", fired, len(d.Alerts)-fired)
+	for _, alert := range d.Alerts {
+		fmt.Fprintf(&b, "  %s  %s - %s\n", alert.Time.Format("15:04:05"), alert.Title, alert.Message)
+	}
+	fmt.Fprintf(&b, "Path changes: %d\n", len(d.PathChanges))
+	for _, change := range d.PathChanges {
+		fmt.Fprintf(&b, "  %s  %s\n", change.At.Format("15:04:05"), change.Describe())
+	}
+	return b.String()
+}
+
+// percentile returns the p-th percentile (0-100) of an ascending slice using nearest rank
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// startOfDay truncates t to local midnight
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
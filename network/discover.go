@@ -0,0 +1,41 @@
+package network
+
+import "log"
+
+// probeOutcome is the result of one probe sent during discovery
+type probeOutcome struct {
+	reply ProbeReply
+	ok    bool // Something answered the probe
+}
+
+// searchTargetTTL binary-searches for the lowest TTL at which the destination answers,
+// so discovery of a distant target doesn't wait out the silent TTLs past it
+// Returns 0 if the destination doesn't answer at the max TTL. The outcome of every probe
+// sent is returned as well so the traceroute need not repeat it
+func (s *Scanner) searchTargetTTL() (int, map[int]probeOutcome) {
+	probed := make(map[int]probeOutcome)
+	reaches := func(ttl int) bool {
+		reply, ok := s.probe(ttl)
+		probed[ttl] = probeOutcome{reply: reply, ok: ok}
+		return ok && reply.Reached
+	}
+
+	if !reaches(s.maxTTL) {
+		log.Printf("[DEBUG] Destination silent at TTL %d, tracing every TTL\n", s.maxTTL)
+		return 0, probed
+	}
+
+	// A lost probe at or past the destination only moves the bound further out; the
+	// traceroute still stops at the first TTL the destination answers
+	lo, hi := 1, s.maxTTL
+	for lo < hi && s.ctx.Err() == nil {
+		mid := (lo + hi) / 2
+		if reaches(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	log.Printf("[DEBUG] Destination answers at TTL %d after %d search probes\n", hi, len(probed))
+	return hi, probed
+}
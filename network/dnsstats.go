@@ -0,0 +1,168 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxDNSQueries caps how many DNS queries are kept; the oldest are dropped first
+const maxDNSQueries = 500
+
+// DNSPurpose says why a DNS query was made
+type DNSPurpose string
+
+const (
+	DNSTarget DNSPurpose = "Target" // Resolving the session's target
+	DNSASN    DNSPurpose = "ASN"    // Looking up the origin AS of a hop
+	DNSNAT64  DNSPurpose = "NAT64"  // Discovering the network's NAT64 prefix
+)
+
+// DNSQuery is one name lookup made while probing
+type DNSQuery struct {
+	Time     time.Time     // When the lookup started
+	Name     string        // Name looked up
+	Type     string        // Records asked for: "A/AAAA" or "TXT"
+	Purpose  DNSPurpose    // Why it was looked up
+	Server   string        // Name server the answer came from, the last one tried if none answered
+	Duration time.Duration // How long the lookup took
+	Err      string        // Why it failed, empty if answered
+	Failed   bool          // No name server answered; a name that doesn't exist is still an answer
+}
+
+// DNSServerStats sums up the queries sent to one name server
+type DNSServerStats struct {
+	Server   string        // Address of the name server, e.g. "127.0.0.53:53"
+	Queries  int           // Lookups it was the last server tried for
+	Failures int           // Lookups it didn't answer
+	Average  time.Duration // Mean duration of the lookups it answered
+	Slowest  time.Duration // Longest duration of the lookups it answered
+}
+
+// dnsLog keeps the recent DNS queries of the process
+var dnsLog struct {
+	mu      sync.Mutex
+	queries []DNSQuery
+}
+
+// dnsServerKey is the context key under which a lookup collects the name server it dialed
+type dnsServerKey struct{}
+
+// dnsServer is the name server a lookup last dialed
+// A and AAAA queries are sent in parallel, so it is guarded
+type dnsServer struct {
+	mu      sync.Mutex
+	address string
+}
+
+// dnsResolver is Go's own resolver, which lets the name server each query goes to be seen
+var dnsResolver = &net.Resolver{
+	PreferGo: true,
+	Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		if server, ok := ctx.Value(dnsServerKey{}).(*dnsServer); ok {
+			server.mu.Lock()
+			server.address = address
+			server.mu.Unlock()
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	},
+}
+
+// lookupIPAddr resolves host like net.DefaultResolver.LookupIPAddr, recording the query
+// Addresses aren't queries, so they aren't recorded
+func lookupIPAddr(ctx context.Context, purpose DNSPurpose, host string) ([]net.IPAddr, error) {
+	if net.ParseIP(host) != nil {
+		return net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+	server := &dnsServer{}
+	started := time.Now()
+	addrs, err := dnsResolver.LookupIPAddr(context.WithValue(ctx, dnsServerKey{}, server), host)
+	recordDNSQuery(DNSQuery{Time: started, Name: host, Type: "A/AAAA", Purpose: purpose}, server, err)
+	return addrs, err
+}
+
+// lookupTXT queries the TXT records of name like net.DefaultResolver.LookupTXT, recording the query
+func lookupTXT(ctx context.Context, purpose DNSPurpose, name string) ([]string, error) {
+	server := &dnsServer{}
+	started := time.Now()
+	records, err := dnsResolver.LookupTXT(context.WithValue(ctx, dnsServerKey{}, server), name)
+	recordDNSQuery(DNSQuery{Time: started, Name: name, Type: "TXT", Purpose: purpose}, server, err)
+	return records, err
+}
+
+// recordDNSQuery completes a query with its outcome and adds it to the log
+func recordDNSQuery(query DNSQuery, server *dnsServer, err error) {
+	query.Duration = time.Since(query.Time)
+	server.mu.Lock()
+	query.Server = server.address
+	server.mu.Unlock()
+	if err != nil {
+		query.Err = err.Error()
+		var dnsErr *net.DNSError
+		query.Failed = !errors.As(err, &dnsErr) || !dnsErr.IsNotFound
+	}
+
+	dnsLog.mu.Lock()
+	defer dnsLog.mu.Unlock()
+	dnsLog.queries = append(dnsLog.queries, query)
+	if len(dnsLog.queries) > maxDNSQueries {
+		dnsLog.queries = dnsLog.queries[1:]
+	}
+}
+
+// DNSQueries returns the recent DNS queries made for targets, hop ASNs and NAT64 discovery, oldest first
+func DNSQueries() []DNSQuery {
+	dnsLog.mu.Lock()
+	defer dnsLog.mu.Unlock()
+	return append([]DNSQuery(nil), dnsLog.queries...)
+}
+
+// ResetDNSQueries forgets the recorded DNS queries, e.g. when a new session starts
+func ResetDNSQueries() {
+	dnsLog.mu.Lock()
+	defer dnsLog.mu.Unlock()
+	dnsLog.queries = nil
+}
+
+// SummarizeDNSServers sums up queries per name server, busiest first
+// Queries that never reached a server (e.g. answered from the hosts file) are left out
+func SummarizeDNSServers(queries []DNSQuery) []DNSServerStats {
+	byServer := make(map[string]*DNSServerStats)
+	answeredTime := make(map[string]time.Duration)
+	for _, query := range queries {
+		if query.Server == "" {
+			continue
+		}
+		stats, ok := byServer[query.Server]
+		if !ok {
+			stats = &DNSServerStats{Server: query.Server}
+			byServer[query.Server] = stats
+		}
+		stats.Queries++
+		if query.Failed {
+			stats.Failures++
+			continue
+		}
+		answeredTime[query.Server] += query.Duration
+		stats.Slowest = max(stats.Slowest, query.Duration)
+	}
+
+	servers := make([]DNSServerStats, 0, len(byServer))
+	for server, stats := range byServer {
+		if answered := stats.Queries - stats.Failures; answered > 0 {
+			stats.Average = answeredTime[server] / time.Duration(answered)
+		}
+		servers = append(servers, *stats)
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Queries != servers[j].Queries {
+			return servers[i].Queries > servers[j].Queries
+		}
+		return servers[i].Server < servers[j].Server
+	})
+	return servers
+}
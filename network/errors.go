@@ -0,0 +1,99 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Errors the scanner wraps around their cause, so callers can tell the cases apart with
+// errors.Is or sort them with CategoryOf
+var (
+	// ErrResolve marks a session that could not start because its target didn't resolve
+	// DNS being down is often the incident itself, so callers may retry on a known address
+	ErrResolve = errors.New("failed to resolve hostname")
+	// ErrInvalidOption marks a session that could not start because of its probe settings
+	ErrInvalidOption = errors.New("invalid probe settings")
+	// ErrSocket marks a session whose probe sockets could not be opened
+	ErrSocket = errors.New("failed to open probe sockets")
+	// ErrPermission marks a session whose probe sockets need privileges the process lacks
+	ErrPermission = errors.New("not permitted to open probe sockets")
+	// ErrFirewall marks probes the operating system refused to send, as a local firewall does
+	ErrFirewall = errors.New("probes blocked by a local firewall")
+	// ErrCanceled marks a session that gave up because its context was done or Stop was called;
+	// the context's error, context.Canceled or context.DeadlineExceeded, is wrapped too
+	ErrCanceled = errors.New("canceled")
+)
+
+// ErrorCategory sorts the scanner's errors by what the user can do about them
+type ErrorCategory string
+
+const (
+	CategoryNone       ErrorCategory = ""                 // No error
+	CategoryCanceled   ErrorCategory = "Canceled"         // Stopped by the caller; nothing to report
+	CategorySettings   ErrorCategory = "Invalid settings" // ErrInvalidOption
+	CategoryDNS        ErrorCategory = "DNS"              // ErrResolve
+	CategoryPermission ErrorCategory = "Permission"       // ErrPermission
+	CategoryFirewall   ErrorCategory = "Firewall"         // ErrFirewall
+	CategorySocket     ErrorCategory = "Socket"           // ErrSocket
+	CategoryLimit      ErrorCategory = "Session limit"    // ErrGovernorLimit
+	CategoryLookup     ErrorCategory = "Lookups disabled" // ErrLookupDisabled
+	CategoryOther      ErrorCategory = "Other"            // Anything else
+)
+
+// CategoryOf returns the category of an error returned by the scanner or sent on its Errors channel
+func CategoryOf(err error) ErrorCategory {
+	switch {
+	case err == nil:
+		return CategoryNone
+	case errors.Is(err, ErrCanceled):
+		return CategoryCanceled
+	case errors.Is(err, ErrInvalidOption):
+		return CategorySettings
+	case errors.Is(err, ErrResolve):
+		return CategoryDNS
+	case errors.Is(err, ErrPermission):
+		return CategoryPermission
+	case errors.Is(err, ErrFirewall):
+		return CategoryFirewall
+	case errors.Is(err, ErrSocket):
+		return CategorySocket
+	case errors.Is(err, ErrGovernorLimit):
+		return CategoryLimit
+	case errors.Is(err, ErrLookupDisabled):
+		return CategoryLookup
+	default:
+		return CategoryOther
+	}
+}
+
+// Hint suggests what to do about errors of the category, empty if there is nothing to suggest
+func (c ErrorCategory) Hint() string {
+	switch c {
+	case CategorySettings:
+		return "Check the probe settings, e.g. that the spacing and jitter are shorter than the interval."
+	case CategoryDNS:
+		return "Check the hostname for typos and that a DNS server is reachable, or enter the target's IP address instead."
+	case CategoryPermission:
+		return "Raw sockets need administrator rights. Run the app with them, grant it the capability to send raw packets, or probe with ICMP."
+	case CategoryFirewall:
+		return "A firewall on this computer refused the probes. Allow outgoing ICMP, UDP or TCP probes and incoming ICMP errors for the app, or try another probe method."
+	case CategorySocket:
+		return "Check that the source address or interface still exists and that the network is up."
+	case CategoryLimit:
+		return "Stop another session, or raise the socket limit."
+	case CategoryLookup:
+		return "Turn the lookup back on in Network Access, or leave offline mode."
+	default:
+		return ""
+	}
+}
+
+// probeError describes a probe that couldn't be sent; a send the operating system refused is put
+// down to a local firewall, as sockets that opened fine are otherwise allowed to send
+func probeError(ttl int, err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%w: probe with TTL %d failed: %w", ErrFirewall, ttl, err)
+	}
+	return fmt.Errorf("probe with TTL %d failed: %w", ttl, err)
+}
@@ -0,0 +1,56 @@
+package network
+
+import "time"
+
+// EventKind names an entry of the scanner's event stream
+type EventKind string
+
+const (
+	EventDiscoveryStarted  EventKind = "DiscoveryStarted"  // Target resolved, tracing begins
+	EventHopDiscovered     EventKind = "HopDiscovered"     // Tracing found a hop; Update holds it
+	EventDiscoveryComplete EventKind = "DiscoveryComplete" // Tracing finished; Hops holds the path length
+	EventMonitoringStarted EventKind = "MonitoringStarted" // Monitoring rounds begin
+	EventHopUpdated        EventKind = "HopUpdated"        // A monitoring round updated a hop; Update holds it
+	EventPathChanged       EventKind = "PathChanged"       // A re-trace found a different path; Path and Changes hold it
+	EventPaused            EventKind = "Paused"            // Pause stopped the monitoring rounds
+	EventTargetChanged     EventKind = "TargetChanged"     // SetTarget dropped the previous target; Target holds the new one
+	EventResumed           EventKind = "Resumed"           // Resume restarted the monitoring rounds
+	EventCompleted         EventKind = "Completed"         // The rounds set with WithCycles are done; Path holds the final hops
+	EventStopped           EventKind = "Stopped"           // Stop was called; the stream closes after it
+	EventError             EventKind = "Error"             // Start failed or a probe failed; Err holds why
+)
+
+// Event is one entry of the scanner's unified event stream, which carries the session's
+// lifecycle alongside its hop updates so consumers need not infer state from statuses
+type Event struct {
+	Kind    EventKind
+	Time    time.Time    // When the event happened
+	Update  *HopUpdate   // Hop found or updated, for EventHopDiscovered and EventHopUpdated
+	Hops    int          // Hops in the path, for EventDiscoveryComplete, EventMonitoringStarted, EventPathChanged and EventCompleted
+	Address string       // Address the target resolved to, for EventDiscoveryStarted
+	Target  string       // Hostname now monitored, for EventTargetChanged
+	Path    []NetworkHop // Hops now monitored, for EventPathChanged; final hops, for EventCompleted
+	Changes []PathChange // What differs from the previous path, for EventPathChanged
+	Err     error        // What went wrong, for EventError
+}
+
+// sendEvent adds an event to the event stream (non-blocking)
+func (s *Scanner) sendEvent(event Event) {
+	if !s.enter() {
+		return
+	}
+	defer s.producers.Done()
+	event.Time = time.Now()
+	select {
+	case s.events <- event:
+	default:
+		// Nobody is reading the event stream
+	}
+}
+
+// fail reports an error that ends Start and returns it
+func (s *Scanner) fail(err error) error {
+	s.sendStatus(StatusError)
+	s.sendEvent(Event{Kind: EventError, Err: err})
+	return err
+}
@@ -0,0 +1,174 @@
+package network
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Standard measurement protocol used for ISP evidence packs
+const (
+	EvidenceDuration         = 15 * time.Minute // Total measurement time
+	EvidenceSnapshotInterval = 1 * time.Minute  // Time between hop table snapshots
+	EvidenceInterval         = 1 * time.Second  // Time between probes of each hop
+)
+
+// EvidenceOptions returns the scanner options of the evidence protocol: the defaults, probing
+// every EvidenceInterval until stopped. They replace the user's probe settings and profile so the
+// README describes every pack truthfully; the session probes with ICMP, one probe per round
+func EvidenceOptions() []ScannerOption {
+	return []ScannerOption{WithInterval(EvidenceInterval)}
+}
+
+// EvidenceSnapshot is the hop table at one point during the measurement
+type EvidenceSnapshot struct {
+	Time time.Time
+	Hops []NetworkHop
+}
+
+// EvidencePack collects the measurements for a zipped report aimed at ISP support teams
+// It is safe for concurrent use
+type EvidencePack struct {
+	Target    string
+	Started   time.Time
+	Language  ReportLanguage // Language of the README, English if empty
+	mu        sync.Mutex
+	snapshots []EvidenceSnapshot
+	pathMTU   string // Report of the path MTU probe, empty until it finishes
+}
+
+// NewEvidencePack creates an empty evidence pack for the target
+func NewEvidencePack(target string, started time.Time) *EvidencePack {
+	return &EvidencePack{
+		Target:    target,
+		Started:   started,
+		snapshots: make([]EvidenceSnapshot, 0),
+	}
+}
+
+// AddSnapshot records a copy of the hop table taken at t
+func (p *EvidencePack) AddSnapshot(t time.Time, hops []NetworkHop) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots = append(p.snapshots, EvidenceSnapshot{Time: t, Hops: copyHops(hops)})
+}
+
+// SetPathMTU records the outcome of probing the path MTU to the target (see ProbePathMTU)
+func (p *EvidencePack) SetPathMTU(result PathMTUResult, err error) {
+	report := result.String()
+	if err != nil {
+		report = fmt.Sprintf("Path MTU to %s: not measured (%v)\n", p.Target, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pathMTU = report
+}
+
+// FileName returns a suggested name for the zip file
+func (p *EvidencePack) FileName() string {
+	return fmt.Sprintf("visual-mtr-evidence-%s-%s.zip",
+		strings.NewReplacer("/", "_", ":", "_", "%", "_").Replace(p.Target),
+		p.Started.Format("20060102-150405"))
+}
+
+// WriteZip writes the evidence pack: summary (at the top of the README), system info, the path MTU,
+// the final hop table and every snapshot
+func (p *EvidencePack) WriteZip(w io.Writer, summary string) error {
+	p.mu.Lock()
+	snapshots := append([]EvidenceSnapshot(nil), p.snapshots...)
+	pathMTU := p.pathMTU
+	p.mu.Unlock()
+
+	zw := zip.NewWriter(w)
+
+	l := p.Language
+	label := func(english string) string { return l.Phrase(english) + ":" }
+	readme := fmt.Sprintf("%s\n\n"+
+		"%s\n\n"+
+		"%-10s%s\n"+
+		"%-10s%s\n"+
+		"%-10s%s\n",
+		l.Sprintf("%s evidence pack", ProductName), summary,
+		label("Target"), p.Target,
+		label("Started"), p.Started.Format(time.RFC1123Z),
+		label("Protocol"), l.Sprintf("ICMP echo to every hop at 1s intervals for %s, hop table snapshot every %s", EvidenceDuration, EvidenceSnapshotInterval))
+	if err := writeZipFile(zw, "README.txt", []byte(readme)); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "system.txt", []byte(SystemInfo())); err != nil {
+		return err
+	}
+	if pathMTU != "" {
+		if err := writeZipFile(zw, "path-mtu.txt", []byte(pathMTU)); err != nil {
+			return err
+		}
+	}
+
+	for i, snap := range snapshots {
+		var b strings.Builder
+		if err := WriteHopsCSV(&b, snap.Hops); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("snapshots/%02d-%s.csv", i+1, snap.Time.Format("150405"))
+		if i == len(snapshots)-1 {
+			name = "final.csv"
+		}
+		if err := writeZipFile(zw, name, []byte(b.String())); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish evidence pack: %v", err)
+	}
+	return nil
+}
+
+// SystemInfo describes the measuring host: OS, hostname, time zone and network interfaces
+// Interfaces with an MTU typical of a tunnel or VPN are annotated with the likely encapsulation
+func SystemInfo() string {
+	var b strings.Builder
+
+	hostname, _ := os.Hostname()
+	zone, offset := time.Now().Zone()
+	fmt.Fprintf(&b, "OS:        %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Hostname:  %s\n", hostname)
+	fmt.Fprintf(&b, "Time zone: %s (UTC%+d)\n", zone, offset/3600)
+	fmt.Fprintf(&b, "Go:        %s\n\n", runtime.Version())
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		fmt.Fprintf(&b, "Interfaces: unavailable (%v)\n", err)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Interfaces:\n")
+	for _, iface := range ifaces {
+		fmt.Fprintf(&b, "  %s (mtu %d, %s)\n", iface.Name, iface.MTU, iface.Flags)
+		if hint, ok := LikelyTunnel(iface.MTU); ok {
+			fmt.Fprintf(&b, "    MTU %d: %s\n", iface.MTU, hint.Advice())
+		}
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			fmt.Fprintf(&b, "    %s\n", addr)
+		}
+	}
+	return b.String()
+}
+
+// writeZipFile adds a single file to the zip archive
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to evidence pack: %v", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to evidence pack: %v", name, err)
+	}
+	return nil
+}
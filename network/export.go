@@ -0,0 +1,62 @@
+package network
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// HistoryLossPercent returns the share of timeouts in the hop's latency history
+func HistoryLossPercent(hop NetworkHop) float64 {
+	if len(hop.LatencyHistory) == 0 {
+		return hop.LossPercent
+	}
+	return calculateLossPercent(hop.LatencyHistory)
+}
+
+// hopCSVHeader names the columns of hopCSVRow
+var hopCSVHeader = []string{"hop", "ip", "asn", "as_name", "avg_ms", "loss_percent", "window_loss_percent", "lifetime_loss_percent", "sent", "received", "stddev_ms", "jitter_ms", "last_ms", "best_ms", "worst_ms", "p50_ms", "p95_ms", "p99_ms", "mpls_labels", "responders", "status"}
+
+// WriteHopsCSV writes a hop table as CSV with one row per hop
+func WriteHopsCSV(w io.Writer, hops []NetworkHop) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(hopCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+	for i, hop := range hops {
+		if err := cw.Write(hopCSVRow(i, hop)); err != nil {
+			return fmt.Errorf("failed to write CSV row: %v", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// hopCSVRow formats the hop at index as a CSV row
+func hopCSVRow(index int, hop NetworkHop) []string {
+	return []string{
+		fmt.Sprintf("%d", index+1),
+		hop.IP,
+		fmt.Sprintf("%d", hop.ASN),
+		hop.ASName,
+		fmt.Sprintf("%.2f", hop.AvgLatency),
+		fmt.Sprintf("%.1f", HistoryLossPercent(hop)),
+		fmt.Sprintf("%.1f", hop.LossPercent),
+		fmt.Sprintf("%.1f", hop.LifetimeLossPercent),
+		fmt.Sprintf("%d", hop.Sent),
+		fmt.Sprintf("%d", hop.Received),
+		fmt.Sprintf("%.2f", hop.StdDev),
+		fmt.Sprintf("%.2f", hop.Jitter),
+		fmt.Sprintf("%.2f", hop.LastLatency),
+		fmt.Sprintf("%.2f", hop.BestLatency),
+		fmt.Sprintf("%.2f", hop.WorstLatency),
+		fmt.Sprintf("%.2f", hop.P50),
+		fmt.Sprintf("%.2f", hop.P95),
+		fmt.Sprintf("%.2f", hop.P99),
+		FormatMPLS(MPLSLabels(hop.Extensions)),
+		FormatResponders(hop.Responders),
+		string(hop.Status),
+	}
+}
@@ -0,0 +1,423 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ExprVariable is a built-in hop metric expressions can use
+type ExprVariable struct {
+	Name        string
+	Description string
+}
+
+// ExprVariables lists the hop metrics expressions can use, in the order they are documented
+var ExprVariables = []ExprVariable{
+	{"loss", "loss over the latency history, %"},
+	{"recentloss", "loss over the loss window, %"},
+	{"latency", "average RTT, ms"},
+	{"last", "latest RTT, ms"},
+	{"best", "best RTT, ms"},
+	{"worst", "worst RTT, ms"},
+	{"jitter", "jitter, ms"},
+	{"stddev", "RTT standard deviation, ms"},
+	{"p50", "median RTT, ms"},
+	{"p95", "95th percentile RTT, ms"},
+	{"p99", "99th percentile RTT, ms"},
+	{"sent", "probes sent"},
+	{"received", "probes answered"},
+	{"duplicates", "duplicate answers"},
+	{"stability", "consecutive answers from the same IP, %"},
+	{"hop", "hop number, from 1"},
+	{"dest", "1 for the destination, else 0"},
+}
+
+// exprFunctions are the functions expressions can call, with how many arguments they take
+var exprFunctions = map[string]int{"min": 2, "max": 2, "abs": 1}
+
+// HopMetrics returns the values of ExprVariables for a hop; index is its 0-based position and
+// destination tells whether it is the last hop
+func HopMetrics(hop NetworkHop, index int, destination bool) map[string]float64 {
+	dest := 0.0
+	if destination {
+		dest = 1
+	}
+	return map[string]float64{
+		"loss":       HistoryLossPercent(hop),
+		"recentloss": hop.LossPercent,
+		"latency":    hop.AvgLatency,
+		"last":       hop.LastLatency,
+		"best":       hop.BestLatency,
+		"worst":      hop.WorstLatency,
+		"jitter":     hop.Jitter,
+		"stddev":     hop.StdDev,
+		"p50":        hop.P50,
+		"p95":        hop.P95,
+		"p99":        hop.P99,
+		"sent":       float64(hop.Sent),
+		"received":   float64(hop.Received),
+		"duplicates": float64(hop.Duplicates),
+		"stability":  hop.Stability,
+		"hop":        float64(index + 1),
+		"dest":       dest,
+	}
+}
+
+// Expr is a parsed expression over a hop's metrics, e.g. "loss*2 + jitter > 30"
+// It has numbers, the ExprVariables, + - * /, comparisons, && || !, parentheses and the
+// functions min, max and abs. Comparisons and logic yield 1 for true and 0 for false, and
+// division by zero yields 0
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// exprNode is a node of a parsed expression
+type exprNode interface {
+	eval(vars map[string]float64) float64
+}
+
+type (
+	exprNumber float64
+	exprVar    string
+	exprUnary  struct {
+		op string
+		x  exprNode
+	}
+	exprBinary struct {
+		op   string
+		l, r exprNode
+	}
+	exprCall struct {
+		fn   string
+		args []exprNode
+	}
+)
+
+func (n exprNumber) eval(map[string]float64) float64 { return float64(n) }
+
+func (v exprVar) eval(vars map[string]float64) float64 { return vars[string(v)] }
+
+func (u exprUnary) eval(vars map[string]float64) float64 {
+	x := u.x.eval(vars)
+	if u.op == "!" {
+		return truth(x == 0)
+	}
+	return -x
+}
+
+func (b exprBinary) eval(vars map[string]float64) float64 {
+	l := b.l.eval(vars)
+	// Logic short-circuits like Go's
+	switch b.op {
+	case "&&":
+		return truth(l != 0 && b.r.eval(vars) != 0)
+	case "||":
+		return truth(l != 0 || b.r.eval(vars) != 0)
+	}
+	r := b.r.eval(vars)
+	switch b.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	case "<":
+		return truth(l < r)
+	case "<=":
+		return truth(l <= r)
+	case ">":
+		return truth(l > r)
+	case ">=":
+		return truth(l >= r)
+	case "==":
+		return truth(l == r)
+	default: // "!="
+		return truth(l != r)
+	}
+}
+
+func (c exprCall) eval(vars map[string]float64) float64 {
+	switch c.fn {
+	case "min":
+		return math.Min(c.args[0].eval(vars), c.args[1].eval(vars))
+	case "max":
+		return math.Max(c.args[0].eval(vars), c.args[1].eval(vars))
+	default: // "abs"
+		return math.Abs(c.args[0].eval(vars))
+	}
+}
+
+// truth converts a condition to 1 or 0
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ParseExpr parses an expression, rejecting unknown variables and functions
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("unexpected %q in %q", tok, src)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression as written
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval computes the expression with the given variable values
+func (e *Expr) Eval(vars map[string]float64) float64 {
+	return e.root.eval(vars)
+}
+
+// tokenizeExpr splits an expression into numbers, names and operators
+func tokenizeExpr(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(src[i:j]))
+			i = j
+		default:
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "&&", "||", "<=", ">=", "==", "!=":
+					tokens = append(tokens, two)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/<>!(),", rune(c)) {
+				return nil, fmt.Errorf("unexpected %q in %q", c, src)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return tokens, nil
+}
+
+// exprParser parses tokens by recursive descent, loosest-binding operators first
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, empty at the end
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// binary parses operands joined by any of ops, left to right
+func (p *exprParser) binary(operand func() (exprNode, error), ops ...string) (exprNode, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		found := false
+		for _, candidate := range ops {
+			found = found || op == candidate
+		}
+		if !found {
+			return l, nil
+		}
+		p.pos++
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l = exprBinary{op: op, l: l, r: r}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) { return p.binary(p.parseAnd, "||") }
+
+func (p *exprParser) parseAnd() (exprNode, error) { return p.binary(p.parseComparison, "&&") }
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	return p.binary(p.parseSum, "<", "<=", ">", ">=", "==", "!=")
+}
+
+func (p *exprParser) parseSum() (exprNode, error) { return p.binary(p.parseProduct, "+", "-") }
+
+func (p *exprParser) parseProduct() (exprNode, error) { return p.binary(p.parseUnary, "*", "/") }
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op := p.peek(); op == "-" || op == "!" {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	p.pos++
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("expression ends early")
+	case tok == "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		value, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok)
+		}
+		return exprNumber(value), nil
+	case tok[0] == '_' || tok[0] >= 'a' && tok[0] <= 'z':
+		if arity, ok := exprFunctions[tok]; ok {
+			return p.parseCall(tok, arity)
+		}
+		for _, v := range ExprVariables {
+			if v.Name == tok {
+				return exprVar(tok), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown metric %q", tok)
+	default:
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+}
+
+// parseCall parses the parenthesized arguments of a function whose name was just read
+func (p *exprParser) parseCall(fn string, arity int) (exprNode, error) {
+	if p.peek() != "(" {
+		return nil, fmt.Errorf("%s needs arguments in parentheses", fn)
+	}
+	p.pos++
+	var args []exprNode
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ")" {
+		return nil, fmt.Errorf("missing ) after the arguments of %s", fn)
+	}
+	p.pos++
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", fn, arity, len(args))
+	}
+	return exprCall{fn: fn, args: args}, nil
+}
+
+// DerivedMetric is a user-defined metric computed from a hop's built-in ones every cycle
+type DerivedMetric struct {
+	Name string
+	Expr *Expr
+}
+
+// ParseDerivedMetric parses a definition written "name = expression"
+// The name must be an identifier other than a built-in metric or function, so that a mistyped
+// comparison such as "loss >= 5" isn't read as a metric named "loss >"
+func ParseDerivedMetric(definition string) (DerivedMetric, error) {
+	name, src, ok := strings.Cut(definition, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !isExprIdent(name) || isExprBuiltin(name) {
+		return DerivedMetric{}, fmt.Errorf("write %q as name = expression", definition)
+	}
+	expr, err := ParseExpr(strings.TrimSpace(src))
+	if err != nil {
+		return DerivedMetric{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return DerivedMetric{Name: name, Expr: expr}, nil
+}
+
+// isExprIdent reports whether name is made of letters, digits and _, not starting with a digit
+func isExprIdent(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isExprBuiltin reports whether name is one of the ExprVariables or functions, which expressions
+// read case-insensitively
+func isExprBuiltin(name string) bool {
+	name = strings.ToLower(name)
+	if _, ok := exprFunctions[name]; ok {
+		return true
+	}
+	for _, v := range ExprVariables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatMetricValue renders a derived metric's value: whole numbers, such as the 1 or 0 of a
+// condition, without decimals, others with one
+func FormatMetricValue(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64)
+}
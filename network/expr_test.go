@@ -0,0 +1,106 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	vars := map[string]float64{"loss": 10, "jitter": 4, "latency": 50, "dest": 1, "hop": 3}
+	tests := []struct {
+		src  string
+		want float64
+		err  string // Part of the error message; empty when the expression parses
+	}{
+		// Precedence and associativity
+		{src: "1 + 2 * 3", want: 7},
+		{src: "(1 + 2) * 3", want: 9},
+		{src: "10 - 4 - 3", want: 3},
+		{src: "12 / 3 / 2", want: 2},
+		{src: "-2 * 3", want: -6},
+		{src: "loss*2 + jitter", want: 24},
+		{src: "LOSS * 2", want: 20},
+		{src: "1 / 0", want: 0},
+
+		// Comparisons and logic yield 1 or 0
+		{src: "loss >= 10", want: 1},
+		{src: "loss > 10", want: 0},
+		{src: "latency <= 49.5", want: 0},
+		{src: "hop == 3", want: 1},
+		{src: "hop != 3", want: 0},
+		{src: "loss*2 + jitter > 30", want: 0},
+		{src: "dest && loss > 5", want: 1},
+		{src: "!dest || latency < 10", want: 0},
+		{src: "1 + 1 > 1 && 0 < 1", want: 1},
+		{src: "max(loss, jitter) - min(loss, abs(-jitter))", want: 6},
+
+		// Mistakes
+		{src: "", err: "empty expression"},
+		{src: "packetloss > 5", err: `unknown metric "packetloss"`},
+		{src: "max(loss)", err: "max takes 2 arguments, got 1"},
+		{src: "abs loss", err: "abs needs arguments in parentheses"},
+		{src: "(loss + 1", err: "missing )"},
+		{src: "loss >", err: "expression ends early"},
+		{src: "loss = 5", err: `unexpected '='`},
+		{src: "loss 5", err: `unexpected "5"`},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.src)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseExpr(%q) error = %v, want one containing %q", tt.src, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.src, err)
+			continue
+		}
+		if got := expr.Eval(vars); got != tt.want {
+			t.Errorf("ParseExpr(%q).Eval() = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseDerivedMetric(t *testing.T) {
+	tests := []struct {
+		definition string
+		name       string // Name of the metric; empty when the definition is rejected
+		expr       string
+		err        string
+	}{
+		{definition: "score = loss*2 + jitter", name: "score", expr: "loss*2 + jitter"},
+		{definition: "  choppy_call=dest && loss > 5 ", name: "choppy_call", expr: "dest && loss > 5"},
+		{definition: "Score2 = loss == 0", name: "Score2", expr: "loss == 0"},
+
+		// Malformed names
+		{definition: "loss >= 5", err: `write "loss >= 5" as name = expression`},
+		{definition: "latency <= 100", err: "as name = expression"},
+		{definition: "= loss", err: "as name = expression"},
+		{definition: "loss", err: "as name = expression"},
+		{definition: "choppy call = loss > 5", err: "as name = expression"},
+		{definition: "2fast = latency < 10", err: "as name = expression"},
+		{definition: "Loss = loss * 2", err: "as name = expression"},
+		{definition: "max = loss", err: "as name = expression"},
+
+		// Malformed expressions name the metric
+		{definition: "score = packetloss", err: `score: unknown metric "packetloss"`},
+		{definition: "score =", err: "score: empty expression"},
+	}
+	for _, tt := range tests {
+		metric, err := ParseDerivedMetric(tt.definition)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseDerivedMetric(%q) error = %v, want one containing %q", tt.definition, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDerivedMetric(%q): %v", tt.definition, err)
+			continue
+		}
+		if metric.Name != tt.name || metric.Expr.String() != tt.expr {
+			t.Errorf("ParseDerivedMetric(%q) = %q = %q, want %q = %q", tt.definition, metric.Name, metric.Expr, tt.name, tt.expr)
+		}
+	}
+}
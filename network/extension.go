@@ -0,0 +1,180 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ICMP extension object classes decoded into ICMPExtension fields
+const (
+	extClassMPLS      = 1 // MPLS label stack (RFC 4950)
+	extClassInterface = 2 // Interface information (RFC 5837)
+)
+
+// extCompatLength is where non-compliant routers, which leave the length field zero, start
+// the extension structure: after the first 128 bytes of the original datagram (RFC 4884)
+const extCompatLength = 128
+
+// ICMPExtension is one object of the extension structure routers append to TimeExceeded
+// and unreachable messages (RFC 4884). Known classes are decoded; others keep their payload
+type ICMPExtension struct {
+	Class     int            // Object class, e.g. 1 for MPLS label stacks, 2 for interface information
+	Type      int            // Object sub-type (C-Type)
+	MPLS      []MPLSLabel    // Label stack, top entry first, for class 1
+	Interface *InterfaceInfo // Interface the router describes, for class 2
+	Data      []byte         // Payload of objects of other classes
+}
+
+// InterfaceInfo identifies an interface of the answering router (RFC 5837)
+type InterfaceInfo struct {
+	Role  string // Which interface: incoming, its sub-IP component, outgoing, or the next hop
+	Index int    // ifIndex, 0 if not given
+	Addr  string // IP address, empty if not given
+	Name  string // Interface name, empty if not given
+	MTU   int    // MTU, 0 if not given
+}
+
+// String formats the interface, listing only what the router reported
+func (i InterfaceInfo) String() string {
+	parts := []string{i.Role}
+	if i.Name != "" {
+		parts = append(parts, i.Name)
+	}
+	if i.Addr != "" {
+		parts = append(parts, i.Addr)
+	}
+	if i.Index > 0 {
+		parts = append(parts, fmt.Sprintf("ifIndex %d", i.Index))
+	}
+	if i.MTU > 0 {
+		parts = append(parts, fmt.Sprintf("MTU %d", i.MTU))
+	}
+	return strings.Join(parts, " ")
+}
+
+// interfaceRoles names the interface roles of RFC 5837, by the top two bits of the C-Type
+var interfaceRoles = []string{"incoming", "incoming sub-IP", "outgoing", "next hop"}
+
+// MPLSLabels returns the label stack among a hop's extensions, or nil if it reported none
+func MPLSLabels(exts []ICMPExtension) []MPLSLabel {
+	var labels []MPLSLabel
+	for _, ext := range exts {
+		labels = append(labels, ext.MPLS...)
+	}
+	return labels
+}
+
+// Interfaces returns the interfaces the router described in its extensions
+func Interfaces(exts []ICMPExtension) []InterfaceInfo {
+	var ifaces []InterfaceInfo
+	for _, ext := range exts {
+		if ext.Interface != nil {
+			ifaces = append(ifaces, *ext.Interface)
+		}
+	}
+	return ifaces
+}
+
+// parseExtensions returns the extension objects of a raw ICMP or ICMPv6 error message
+// It is lenient: a malformed structure yields the objects read so far, never an error, as
+// the answer is still valid without them
+func parseExtensions(family *ipFamily, msg []byte) []ICMPExtension {
+	if len(msg) < 8 {
+		return nil
+	}
+	// The original datagram's length is in 32-bit words for ICMP, 64-bit words for ICMPv6
+	length := int(msg[5]) * 4
+	if family == familyIPv6 {
+		length = int(msg[4]) * 8
+	}
+	body := msg[8:]
+	if length < extCompatLength || length+4 > len(body) {
+		length = extCompatLength
+	}
+	if length+4 > len(body) {
+		return nil
+	}
+
+	// Extension header: version 2, reserved bits, then a checksum over the whole structure
+	structure := body[length:]
+	if structure[0]>>4 != 2 {
+		return nil
+	}
+	if binary.BigEndian.Uint16(structure[2:4]) != 0 && onesSum(structure) != 0xffff {
+		return nil
+	}
+
+	var exts []ICMPExtension
+	for objects := structure[4:]; len(objects) >= 4; {
+		objLen := int(binary.BigEndian.Uint16(objects[0:2]))
+		if objLen < 4 || objLen > len(objects) {
+			break
+		}
+		exts = append(exts, parseExtensionObject(int(objects[2]), int(objects[3]), objects[4:objLen]))
+		objects = objects[objLen:]
+	}
+	return exts
+}
+
+// parseExtensionObject decodes one extension object's payload by its class
+func parseExtensionObject(class, ctype int, payload []byte) ICMPExtension {
+	ext := ICMPExtension{Class: class, Type: ctype}
+	switch class {
+	case extClassMPLS:
+		for entries := payload; len(entries) >= 4; entries = entries[4:] {
+			word := binary.BigEndian.Uint32(entries)
+			ext.MPLS = append(ext.MPLS, MPLSLabel{
+				Label:  int(word >> 12),
+				Exp:    int(word>>9) & 0x7,
+				Bottom: word&0x100 != 0,
+				TTL:    int(word & 0xff),
+			})
+		}
+	case extClassInterface:
+		ext.Interface = parseInterfaceInfo(ctype, payload)
+	default:
+		ext.Data = append([]byte(nil), payload...)
+	}
+	return ext
+}
+
+// parseInterfaceInfo decodes an interface information object, whose C-Type holds the role
+// and flags for which of ifIndex, address, name and MTU follow, in that order (RFC 5837)
+func parseInterfaceInfo(ctype int, payload []byte) *InterfaceInfo {
+	info := &InterfaceInfo{Role: interfaceRoles[ctype>>6&0x3]}
+	if ctype&0x08 != 0 {
+		if len(payload) < 4 {
+			return info
+		}
+		info.Index = int(binary.BigEndian.Uint32(payload))
+		payload = payload[4:]
+	}
+	if ctype&0x04 != 0 {
+		if len(payload) < 4 {
+			return info
+		}
+		addrLen := net.IPv4len
+		if binary.BigEndian.Uint16(payload) == 2 { // AFI 2 is IPv6
+			addrLen = net.IPv6len
+		}
+		if len(payload) < 4+addrLen {
+			return info
+		}
+		info.Addr = net.IP(payload[4 : 4+addrLen]).String()
+		payload = payload[4+addrLen:]
+	}
+	if ctype&0x02 != 0 {
+		// The length byte counts itself and the padding to a 4-byte boundary
+		if len(payload) < 1 || int(payload[0]) < 1 || int(payload[0]) > len(payload) {
+			return info
+		}
+		info.Name = strings.TrimRight(string(payload[1:payload[0]]), "\x00")
+		payload = payload[payload[0]:]
+	}
+	if ctype&0x01 != 0 && len(payload) >= 4 {
+		info.MTU = int(binary.BigEndian.Uint32(payload))
+	}
+	return info
+}
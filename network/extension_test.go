@@ -0,0 +1,145 @@
+package network
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// internetChecksum computes the RFC 1071 checksum of b, independently of onesSum
+func internetChecksum(b []byte) uint16 {
+	var sum uint64
+	for i := 0; i < len(b); i += 2 {
+		word := uint64(b[i]) << 8
+		if i+1 < len(b) {
+			word |= uint64(b[i+1])
+		}
+		sum += word
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// extObject builds an extension object of class and C-Type around payload
+func extObject(class, ctype int, payload []byte) []byte {
+	obj := binary.BigEndian.AppendUint16(nil, uint16(4+len(payload)))
+	obj = append(obj, byte(class), byte(ctype))
+	return append(obj, payload...)
+}
+
+// extStructure builds a version 2 extension structure holding objects, with a valid checksum
+func extStructure(objects ...[]byte) []byte {
+	structure := []byte{0x20, 0, 0, 0}
+	for _, obj := range objects {
+		structure = append(structure, obj...)
+	}
+	binary.BigEndian.PutUint16(structure[2:4], internetChecksum(structure))
+	return structure
+}
+
+// mplsEntry encodes one label stack entry (RFC 3032)
+func mplsEntry(label, exp int, bottom bool, ttl int) []byte {
+	word := uint32(label)<<12 | uint32(exp)<<9 | uint32(ttl)
+	if bottom {
+		word |= 0x100
+	}
+	return binary.BigEndian.AppendUint32(nil, word)
+}
+
+// timeExceeded builds an ICMP TimeExceeded quoting quoteLen bytes of the original datagram,
+// with lengthWords in its length field, followed by structure
+func timeExceeded(lengthWords, quoteLen int, structure []byte) []byte {
+	msg := []byte{11, 0, 0, 0, 0, byte(lengthWords), 0, 0}
+	quote := make([]byte, quoteLen)
+	quote[0] = 0x45 // The IPv4 header of the probe
+	msg = append(msg, quote...)
+	return append(msg, structure...)
+}
+
+func TestParseExtensions(t *testing.T) {
+	stack := extObject(extClassMPLS, 1, append(mplsEntry(16003, 5, false, 1), mplsEntry(1048575, 0, true, 255)...))
+	labels := []MPLSLabel{
+		{Label: 16003, Exp: 5, Bottom: false, TTL: 1},
+		{Label: 1048575, Exp: 0, Bottom: true, TTL: 255},
+	}
+
+	// Incoming interface with ifIndex, IPv4 address, name and MTU
+	ifacePayload := binary.BigEndian.AppendUint32(nil, 7)
+	ifacePayload = append(ifacePayload, 0, 1, 0, 0, 192, 0, 2, 1) // AFI 1, reserved, address
+	ifacePayload = append(ifacePayload, 12)                       // Name length, padded to 4 bytes
+	ifacePayload = append(ifacePayload, "ge-0/0/1\x00\x00\x00"...)
+	ifacePayload = binary.BigEndian.AppendUint32(ifacePayload, 1500)
+	iface := extObject(extClassInterface, 0x0f, ifacePayload)
+
+	badChecksum := extStructure(stack)
+	badChecksum[3] ^= 0xff
+	uncheckedStructure := append([]byte{0x20, 0, 0, 0}, stack...)
+	truncated := extStructure(stack, extObject(99, 1, []byte{1, 2, 3, 4}))
+	truncated = truncated[:len(truncated)-2]
+	binary.BigEndian.PutUint16(truncated[2:4], 0)
+	binary.BigEndian.PutUint16(truncated[2:4], internetChecksum(truncated))
+
+	tests := []struct {
+		name string
+		msg  []byte
+		want []ICMPExtension
+	}{
+		{
+			name: "compliant length",
+			msg:  timeExceeded(36, 144, extStructure(stack)),
+			want: []ICMPExtension{{Class: extClassMPLS, Type: 1, MPLS: labels}},
+		},
+		{
+			name: "zero length in compat mode",
+			msg:  timeExceeded(0, extCompatLength, extStructure(stack)),
+			want: []ICMPExtension{{Class: extClassMPLS, Type: 1, MPLS: labels}},
+		},
+		{
+			name: "interface information",
+			msg:  timeExceeded(32, 128, extStructure(iface)),
+			want: []ICMPExtension{{Class: extClassInterface, Type: 0x0f, Interface: &InterfaceInfo{
+				Role: "incoming", Index: 7, Addr: "192.0.2.1", Name: "ge-0/0/1", MTU: 1500,
+			}}},
+		},
+		{
+			name: "unknown class keeps its payload",
+			msg:  timeExceeded(32, 128, extStructure(extObject(99, 3, []byte{1, 2, 3, 4}))),
+			want: []ICMPExtension{{Class: 99, Type: 3, Data: []byte{1, 2, 3, 4}}},
+		},
+		{
+			name: "bad checksum",
+			msg:  timeExceeded(32, 128, badChecksum),
+			want: nil,
+		},
+		{
+			name: "zero checksum is not checked",
+			msg:  timeExceeded(32, 128, uncheckedStructure),
+			want: []ICMPExtension{{Class: extClassMPLS, Type: 1, MPLS: labels}},
+		},
+		{
+			name: "truncated object keeps the objects before it",
+			msg:  timeExceeded(32, 128, truncated),
+			want: []ICMPExtension{{Class: extClassMPLS, Type: 1, MPLS: labels}},
+		},
+		{
+			name: "wrong version",
+			msg:  timeExceeded(32, 128, append([]byte{0x10, 0, 0, 0}, stack...)),
+			want: nil,
+		},
+		{
+			name: "no extensions",
+			msg:  timeExceeded(0, 28, nil),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseExtensions(familyIPv4, tt.msg)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseExtensions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Protocol selects the IP version used to reach the target
+type Protocol string
+
+const (
+	ProtocolAuto Protocol = "Auto" // Whichever address the resolver prefers
+	ProtocolIPv4 Protocol = "IPv4"
+	ProtocolIPv6 Protocol = "IPv6"
+)
+
+// Protocols lists the protocol choices in display order
+var Protocols = []Protocol{ProtocolAuto, ProtocolIPv4, ProtocolIPv6}
+
+// ipFamily holds what differs between probing over ICMPv4 and ICMPv6
+type ipFamily struct {
+	listenNet       string // Network name for icmp.ListenPacket
+	udpNet          string // Network name for UDP probes and unprivileged ICMP sockets
+	wildcard        string // Listen address when no source is set
+	proto           int    // Protocol number for icmp.ParseMessage
+	headerLen       int    // Size of the IP header without options
+	echoRequest     icmp.Type
+	echoReply       icmp.Type
+	timeExceeded    icmp.Type
+	destUnreachable icmp.Type
+}
+
+var (
+	familyIPv4 = &ipFamily{
+		listenNet:       "ip4:icmp",
+		udpNet:          "udp4",
+		wildcard:        "0.0.0.0",
+		proto:           1,
+		headerLen:       20,
+		echoRequest:     ipv4.ICMPTypeEcho,
+		echoReply:       ipv4.ICMPTypeEchoReply,
+		timeExceeded:    ipv4.ICMPTypeTimeExceeded,
+		destUnreachable: ipv4.ICMPTypeDestinationUnreachable,
+	}
+	familyIPv6 = &ipFamily{
+		listenNet:       "ip6:ipv6-icmp",
+		udpNet:          "udp6",
+		wildcard:        "::",
+		proto:           58,
+		headerLen:       40,
+		echoRequest:     ipv6.ICMPTypeEchoRequest,
+		echoReply:       ipv6.ICMPTypeEchoReply,
+		timeExceeded:    ipv6.ICMPTypeTimeExceeded,
+		destUnreachable: ipv6.ICMPTypeDestinationUnreachable,
+	}
+)
+
+// familyOf returns the ICMP family used to reach ip
+func familyOf(ip net.IP) *ipFamily {
+	if ip.To4() != nil {
+		return familyIPv4
+	}
+	return familyIPv6
+}
+
+// ResolveAddresses returns every address of hostname for the requested protocol, in the
+// resolver's order. A name behind a CDN or load balancer often has several, each reaching
+// a different backend
+// A zone suffix (fe80::1%eth0 or router.lan%eth0) scopes link-local addresses to that
+// interface; link-local addresses without one are skipped, as they are ambiguous
+func ResolveAddresses(ctx context.Context, hostname string, protocol Protocol) ([]net.IPAddr, error) {
+	host, zone := splitZone(hostname)
+	if zone != "" {
+		if _, err := zoneIndex(zone); err != nil {
+			return nil, err
+		}
+	}
+	addrs, err := lookupIPAddr(ctx, DNSTarget, host)
+	if err != nil {
+		return nil, err
+	}
+	matching := make([]net.IPAddr, 0, len(addrs))
+	unscoped := false
+	for _, addr := range addrs {
+		is4 := addr.IP.To4() != nil
+		if (protocol == ProtocolIPv4 && !is4) || (protocol == ProtocolIPv6 && is4) {
+			continue
+		}
+		if needsZone(addr.IP) {
+			if zone == "" {
+				unscoped = true
+				continue
+			}
+			addr.Zone = zone
+		}
+		matching = append(matching, addr)
+	}
+	if len(matching) == 0 && unscoped {
+		return nil, fmt.Errorf("%s is link-local; add the interface to reach it through, e.g. %s%%eth0", host, host)
+	}
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("no %s address found for %s", protocol, hostname)
+	}
+	return matching, nil
+}
+
+// splitZone separates the interface zone from a scoped address or name such as fe80::1%eth0
+// Brackets around the address are dropped
+func splitZone(host string) (string, string) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if i := strings.LastIndexByte(host, '%'); i >= 0 {
+		return host[:i], host[i+1:]
+	}
+	return host, ""
+}
+
+// parseScopedIP parses an IP address that may carry a zone, returning nil if it isn't one
+func parseScopedIP(s string) (net.IP, string) {
+	host, zone := splitZone(s)
+	return net.ParseIP(host), zone
+}
+
+// needsZone reports whether ip is only meaningful on a single link, so the interface
+// to send on must be named alongside it
+func needsZone(ip net.IP) bool {
+	return ip.To4() == nil && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast())
+}
+
+// zoneIndex returns the index of the interface a zone names, by name or by number
+func zoneIndex(zone string) (int, error) {
+	if index, err := strconv.Atoi(zone); err == nil {
+		if _, err := net.InterfaceByIndex(index); err != nil {
+			return 0, fmt.Errorf("no interface with index %d: %v", index, err)
+		}
+		return index, nil
+	}
+	iface, err := net.InterfaceByName(zone)
+	if err != nil {
+		return 0, fmt.Errorf("unknown interface %q: %v", zone, err)
+	}
+	return iface.Index, nil
+}
+
+// resolveTarget resolves hostname to an address of the requested protocol
+// Auto prefers IPv4 like net.ResolveIPAddr does. The lookup gives up when ctx is done
+func resolveTarget(ctx context.Context, hostname string, protocol Protocol) (*net.IPAddr, error) {
+	addrs, err := ResolveAddresses(ctx, hostname, protocol)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return &addr, nil
+		}
+	}
+	return &addrs[0], nil
+}
+
+// listen opens a raw ICMP socket of this family on addr, or on every address if addr is empty
+func (f *ipFamily) listen(addr string) (*icmp.PacketConn, error) {
+	if addr == "" {
+		addr = f.wildcard
+	}
+	return listenWithTTL(f.listenNet, addr)
+}
+
+// listenUnprivileged opens an ICMP datagram socket, which doesn't need root or CAP_NET_RAW
+// On Linux the user's group must be within net.ipv4.ping_group_range; macOS opens them for any user
+func (f *ipFamily) listenUnprivileged(addr string) (*icmp.PacketConn, error) {
+	if addr == "" {
+		addr = f.wildcard
+	}
+	return listenWithTTL(f.udpNet, addr)
+}
+
+// listenWithTTL opens an ICMP socket that reports the TTL answers arrive with where supported
+func listenWithTTL(network, addr string) (*icmp.PacketConn, error) {
+	conn, err := icmp.ListenPacket(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := enableRecvTTL(conn); err != nil {
+		log.Printf("[DEBUG] Received TTL unavailable: %v\n", err)
+	}
+	return conn, nil
+}
+
+// setTTL sets the TTL (IPv4) or hop limit (IPv6) of outgoing probes
+func (f *ipFamily) setTTL(conn *icmp.PacketConn, ttl int) error {
+	if p4 := conn.IPv4PacketConn(); p4 != nil {
+		return p4.SetTTL(ttl)
+	}
+	if p6 := conn.IPv6PacketConn(); p6 != nil {
+		return p6.SetHopLimit(ttl)
+	}
+	return fmt.Errorf("unsupported ICMP connection")
+}
+
+// setDSCP marks the packets of an ICMP socket with a DSCP value; 0 leaves them best effort
+// The value fills the upper six bits of the TOS (IPv4) or traffic class (IPv6) byte
+func (f *ipFamily) setDSCP(conn *icmp.PacketConn, dscp int) error {
+	if dscp == 0 {
+		return nil
+	}
+	if p4 := conn.IPv4PacketConn(); p4 != nil {
+		return p4.SetTOS(dscp << 2)
+	}
+	if p6 := conn.IPv6PacketConn(); p6 != nil {
+		return p6.SetTrafficClass(dscp << 2)
+	}
+	return fmt.Errorf("unsupported ICMP connection")
+}
+
+// setPacketDSCP marks a UDP socket's datagrams with a DSCP value; 0 leaves them best effort
+func (f *ipFamily) setPacketDSCP(conn net.PacketConn, dscp int) error {
+	if dscp == 0 {
+		return nil
+	}
+	if f == familyIPv4 {
+		return ipv4.NewPacketConn(conn).SetTOS(dscp << 2)
+	}
+	return ipv6.NewPacketConn(conn).SetTrafficClass(dscp << 2)
+}
+
+// setPacketTTL sets the TTL (IPv4) or hop limit (IPv6) of a UDP socket's datagrams
+func (f *ipFamily) setPacketTTL(conn net.PacketConn, ttl int) error {
+	if f == familyIPv4 {
+		return ipv4.NewPacketConn(conn).SetTTL(ttl)
+	}
+	return ipv6.NewPacketConn(conn).SetHopLimit(ttl)
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// minimalQuoteSize is an ICMPv4 error quoting only the probe's IP header and first 8 bytes,
+// the minimum of RFC 792, which RFC 1812 routers exceed
+const minimalQuoteSize = 8 + 20 + 8
+
+// DeviceHint is a best guess at the kind of device behind a hop, inferred from how it answers
+// It is a heuristic: operators can change initial TTLs, and many vendors share behaviours
+type DeviceHint struct {
+	InitialTTL int      // Initial TTL the answer was most likely sent with: 64, 128 or 255
+	Guess      string   // Probable operating systems or vendors, e.g. "Cisco IOS/IOS-XR, Huawei VRP or Juniper Junos"
+	Clues      []string // Reply characteristics the guess rests on
+}
+
+// FingerprintHop guesses the device type of a hop from the initial TTL class and ICMP quirks of
+// its latest answer; destination tells whether the answer was an echo reply from the target
+// rather than a router's error. Returns false if the hop hasn't answered with a known TTL
+func FingerprintHop(hop NetworkHop, destination bool) (DeviceHint, bool) {
+	back, ok := ReturnHops(hop.ReplyTTL)
+	if !ok {
+		return DeviceHint{}, false
+	}
+	hint := DeviceHint{InitialTTL: hop.ReplyTTL + back - 1}
+	hint.Clues = append(hint.Clues, fmt.Sprintf("answers start with TTL %d", hint.InitialTTL))
+
+	// Initial TTL classes of routers' Time Exceeded and hosts' echo replies
+	// (Vanaubel et al., "Network Fingerprinting: TTL-Based Router Signatures", IMC 2013)
+	switch {
+	case destination && hint.InitialTTL == 128:
+		hint.Guess = "Windows host"
+	case destination && hint.InitialTTL == 64:
+		hint.Guess = "Linux, BSD or macOS host, or a Linux-based appliance"
+	case destination:
+		hint.Guess = "Network device (e.g. Cisco IOS) or Solaris host"
+	case hint.InitialTTL == 255:
+		hint.Guess = "Cisco IOS/IOS-XR, Juniper Junos or Huawei VRP router"
+	case hint.InitialTTL == 128:
+		hint.Guess = "Juniper JunOSe (E-series) router or Windows-based gateway"
+	default:
+		hint.Guess = "Linux-based router, e.g. MikroTik RouterOS, Nokia SR OS, Arista EOS or Brocade"
+	}
+
+	ip := net.ParseIP(hop.IP)
+	if !destination && ip != nil && ip.To4() != nil && hop.ReplySize == minimalQuoteSize {
+		hint.Clues = append(hint.Clues, "quotes only 28 bytes of the probe, the RFC 792 minimum, as classic Cisco IOS does")
+	}
+	if len(MPLSLabels(hop.Extensions)) > 0 {
+		hint.Clues = append(hint.Clues, "reports MPLS labels (RFC 4950), so it is a label-switching carrier router")
+	}
+	if len(Interfaces(hop.Extensions)) > 0 {
+		hint.Clues = append(hint.Clues, "names its interface (RFC 5837), which few implementations do")
+	}
+	return hint, true
+}
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FlapStabilityThreshold is the stability percentage below which a hop is flagged as flapping
+const FlapStabilityThreshold = 80.0
+
+// appendResponder adds the responder to a rolling window of the last MaxLatencyHistory answers
+// Timeouts (empty responder) are skipped since they say nothing about which router answered
+func appendResponder(history []string, responder string) []string {
+	if responder == "" {
+		return history
+	}
+	newHistory := make([]string, 0, MaxLatencyHistory)
+	if len(history) >= MaxLatencyHistory {
+		newHistory = append(newHistory, history[1:]...)
+	} else {
+		newHistory = append(newHistory, history...)
+	}
+	return append(newHistory, responder)
+}
+
+// responderStability returns the percentage of consecutive answers that came from the same IP
+// A hop that never changes responder is 100% stable
+func responderStability(history []string) float64 {
+	if len(history) < 2 {
+		return 100
+	}
+	changes := 0
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1] {
+			changes++
+		}
+	}
+	return (1 - float64(changes)/float64(len(history)-1)) * 100
+}
+
+// responderShares returns each distinct responder with its share of the history, most frequent first
+// Ties keep the responder that answered most recently first so the row identity doesn't jump around
+func responderShares(history []string) []Responder {
+	if len(history) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	lastSeen := make(map[string]int)
+	for i, ip := range history {
+		counts[ip]++
+		lastSeen[ip] = i
+	}
+
+	shares := make([]Responder, 0, len(counts))
+	for ip, count := range counts {
+		shares = append(shares, Responder{IP: ip, Share: float64(count) / float64(len(history)) * 100})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share != shares[j].Share {
+			return shares[i].Share > shares[j].Share
+		}
+		return lastSeen[shares[i].IP] > lastSeen[shares[j].IP]
+	})
+	return shares
+}
+
+// countResponder returns counts with one more answer from responder, most answers first
+// Ties keep the router counted first. The slice is copied, since hops sent out earlier share it
+func countResponder(counts []ResponderCount, responder string) []ResponderCount {
+	if responder == "" {
+		return counts
+	}
+	out := make([]ResponderCount, len(counts), len(counts)+1)
+	copy(out, counts)
+	i := slices.IndexFunc(out, func(c ResponderCount) bool { return c.IP == responder })
+	if i < 0 {
+		out = append(out, ResponderCount{IP: responder})
+		i = len(out) - 1
+	}
+	out[i].Count++
+	for ; i > 0 && out[i].Count > out[i-1].Count; i-- {
+		out[i], out[i-1] = out[i-1], out[i]
+	}
+	return out
+}
+
+// FormatResponders lists responders with their answer counts, e.g. "192.0.2.1 (57), 192.0.2.9 (3)"
+func FormatResponders(counts []ResponderCount) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s (%d)", c.IP, c.Count)
+	}
+	return strings.Join(parts, ", ")
+}
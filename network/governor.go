@@ -0,0 +1,160 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// governorSampleInterval is how often the governor samples the process's goroutines and memory
+const governorSampleInterval = time.Second
+
+// GovernorLimits caps the load all sessions of the process put on the host together, so
+// monitoring many targets at once slows down instead of overloading it; 0 leaves a limit off
+type GovernorLimits struct {
+	PacketsPerSecond int    // Probes per second of every monitoring session together
+	Sockets          int    // Sockets held by every session's prober together; more sessions fail to start
+	Goroutines       int    // Goroutines of the process past which monitoring slows down
+	MemoryBytes      uint64 // Heap in use past which monitoring slows down
+}
+
+// DefaultGovernorLimits apply until SetGovernorLimits is called
+var DefaultGovernorLimits = GovernorLimits{
+	PacketsPerSecond: 500,
+	Sockets:          256,
+	Goroutines:       10000,
+	MemoryBytes:      1 << 30,
+}
+
+// ErrGovernorLimit is returned by Start when the sessions already running hold every socket the
+// governor allows
+var ErrGovernorLimit = errors.New("too many sessions running")
+
+// governor shares the limits between the scanners of the process
+type governor struct {
+	mu       sync.Mutex
+	limits   GovernorLimits
+	demand   map[*Scanner]float64 // Packets per second each monitoring session asks for
+	sockets  int                  // Sockets held by the sessions' probers
+	pressure float64              // Goroutines or memory in use relative to their limit, as last sampled
+	sampled  time.Time            // When pressure was sampled
+}
+
+// sessionGovernor is the governor every scanner reports to
+var sessionGovernor = &governor{
+	limits: DefaultGovernorLimits,
+	demand: make(map[*Scanner]float64),
+}
+
+// SetGovernorLimits changes the limits shared by every session; running sessions follow from
+// their next monitoring round
+func SetGovernorLimits(limits GovernorLimits) {
+	sessionGovernor.mu.Lock()
+	defer sessionGovernor.mu.Unlock()
+	sessionGovernor.limits = limits
+	sessionGovernor.sampled = time.Time{}
+}
+
+// acquireSockets reserves sockets for a prober, failing if that would exceed the limit
+func (g *governor) acquireSockets(n int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.limits.Sockets > 0 && g.sockets+n > g.limits.Sockets {
+		return fmt.Errorf("%w: sessions hold %d of %d sockets", ErrGovernorLimit, g.sockets, g.limits.Sockets)
+	}
+	g.sockets += n
+	return nil
+}
+
+// releaseSockets returns the sockets of a closed prober
+func (g *governor) releaseSockets(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sockets -= n
+}
+
+// setDemand records the packets per second a monitoring session sends at its own interval;
+// 0 removes it, e.g. while paused
+func (g *governor) setDemand(s *Scanner, pps float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if pps <= 0 {
+		delete(g.demand, s)
+		return
+	}
+	g.demand[s] = pps
+}
+
+// slowdown returns how many times longer than asked monitoring intervals must be, 1 while the
+// sessions stay within every limit
+// Packet rates scale with the interval, so stretching it by the overshoot meets the limit;
+// goroutines and memory are relieved by the same means, as fewer rounds run at once
+func (g *governor) slowdown() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	factor := 1.0
+	if g.limits.PacketsPerSecond > 0 {
+		total := 0.0
+		for _, pps := range g.demand {
+			total += pps
+		}
+		factor = max(factor, total/float64(g.limits.PacketsPerSecond))
+	}
+
+	if time.Since(g.sampled) >= governorSampleInterval {
+		g.sampled = time.Now()
+		g.pressure = 0
+		if g.limits.Goroutines > 0 {
+			g.pressure = float64(runtime.NumGoroutine()) / float64(g.limits.Goroutines)
+		}
+		if g.limits.MemoryBytes > 0 {
+			var mem runtime.MemStats
+			runtime.ReadMemStats(&mem)
+			g.pressure = max(g.pressure, float64(mem.HeapInuse)/float64(g.limits.MemoryBytes))
+		}
+	}
+	return max(factor, g.pressure)
+}
+
+// socketCost is how many sockets a prober for the method holds: ICMP probes and answers share
+// one, UDP and TCP probes need one more besides the ICMP listener
+func socketCost(method ProbeMethod) int {
+	if method == ProbeICMP {
+		return 1
+	}
+	return 2
+}
+
+// Slowdown reports how many times longer than its interval the session's rounds currently are
+// because the governor is holding back the sessions' combined load, 1 if it isn't
+func (s *Scanner) Slowdown() float64 {
+	if s.paused.Load() {
+		return 1
+	}
+	return sessionGovernor.slowdown()
+}
+
+// reportDemand tells the governor the packet rate the session's monitoring asks for
+func (s *Scanner) reportDemand() {
+	if s.paused.Load() || len(s.hops) == 0 {
+		sessionGovernor.setDemand(s, 0)
+		return
+	}
+	sessionGovernor.setDemand(s, float64(len(s.hops)*s.probesPerRound)/s.interval.Seconds())
+}
+
+// releaseProber closes the scanner's prober and returns its sockets to the governor
+// Callers hold s.mu
+func (s *Scanner) releaseProber() {
+	if s.prober != nil {
+		s.prober.Close()
+		s.prober = nil
+	}
+	if s.sockets > 0 {
+		sessionGovernor.releaseSockets(s.sockets)
+		s.sockets = 0
+	}
+}
@@ -1,18 +1,100 @@
 package network
 
+import "time"
+
+// ProductName names the application in generated reports; white-label builds replace it
+var ProductName = "Visual MTR"
+
 // MaxLatencyHistory is the maximum number of latency samples to keep per hop
 const MaxLatencyHistory = 60
 
+// MaxRTTSamples is the number of recent answered probes per hop that percentiles are computed over
+const MaxRTTSamples = 300
+
+// Probes sent to each hop per monitoring round
+const (
+	DefaultProbesPerRound = 1
+	MaxProbesPerRound     = 10
+)
+
 // NetworkHop represents a single hop in the network path
 type NetworkHop struct {
-	IP             string    // IP address of the hop
-	AvgLatency     float64   // Average latency in milliseconds
-	LossPercent    float64   // Packet loss percentage (0-100)
-	LatencyHistory []float64 // Rolling history of latency samples (last 60)
+	TTL                 int              // TTL at which this hop answers
+	IP                  string           // IP address of the hop
+	ASN                 int              // Origin AS of the IP, 0 until looked up or if unknown
+	ASName              string           // Registered name of the origin AS
+	AvgLatency          float64          // Average latency in milliseconds over every answered probe
+	LossPercent         float64          // Packet loss percentage (0-100) over the latest probes (the scanner's loss window)
+	LifetimeLossPercent float64          // Packet loss percentage (0-100) over every probe sent
+	Sent                int              // Probes sent to this hop while monitoring
+	Received            int              // Probes this hop answered while monitoring
+	Duplicates          int              // Extra answers to probes this hop had already answered, not counted in Received
+	StdDev              float64          // Standard deviation of the RTT of every answered probe, in milliseconds
+	Jitter              float64          // Mean RTT difference between consecutive answered probes, in milliseconds
+	LastLatency         float64          // RTT of the latest answered probe, in milliseconds
+	BestLatency         float64          // Lowest RTT of any answered probe, in milliseconds
+	WorstLatency        float64          // Highest RTT of any answered probe, in milliseconds
+	P50                 float64          // Median RTT of the recent answered probes, in milliseconds
+	P95                 float64          // 95th percentile RTT of the recent answered probes, in milliseconds
+	P99                 float64          // 99th percentile RTT of the recent answered probes, in milliseconds
+	RTTSamples          []float64        // RTTs of the latest answered probes the percentiles come from (last 300)
+	RecentProbes        []bool           // Whether each of the latest probes was answered, oldest first (loss window)
+	ReplySize           int              // Bytes in the hop's latest ICMP answer, 0 if unknown
+	ReplyTTL            int              // TTL (IPv4) or hop limit (IPv6) the latest answer arrived with, 0 if unknown
+	Extensions          []ICMPExtension  // ICMP extension objects of the latest answer, such as MPLS labels (RFC 4884)
+	LatencyHistory      []float64        // Rolling history of each round's mean latency, -1 if all timed out (last 60)
+	ProbeHistory        []float64        // Rolling history of each probe's RTT, -1 for timeouts (last 60)
+	ResponderHistory    []string         // Rolling history of which IP answered for this TTL (last 60)
+	Stability           float64          // Percentage of consecutive samples answered by the same IP (0-100)
+	Flapping            bool             // Responder changes too often (ECMP or route instability)
+	RateLimited         bool             // Loss well above the destination's, likely the router rate-limiting its ICMP answers
+	Alternates          []Responder      // Other IPs that answered for this TTL, most frequent first
+	Responders          []ResponderCount // Every IP that answered for this TTL while monitoring, most answers first
+	Status              HopStatus        // How the latest answered probe was answered, or HopTimeout if the latest round got none
+}
+
+// ReturnHops estimates how many hops an answer crossed on its way back from the TTL it
+// arrived with, assuming the sender started from the nearest common initial TTL (64, 128 or 255)
+// Returns false if the TTL is unknown
+func ReturnHops(receivedTTL int) (int, bool) {
+	for _, initial := range []int{64, 128, 255} {
+		if receivedTTL > 0 && receivedTTL <= initial {
+			return initial - receivedTTL + 1, true
+		}
+	}
+	return 0, false
+}
+
+// Responder is a router that answered probes for a TTL
+type Responder struct {
+	IP    string  // Address of the router
+	Share float64 // Percentage of recent answers that came from this router (0-100)
+}
+
+// ResponderCount is a router that answered probes for a TTL and how many it answered,
+// like a line of mtr's multipath view
+type ResponderCount struct {
+	IP    string // Address of the router
+	Count int    // Probes it answered this session
 }
 
 // HopUpdate is used to send hop updates from the scanner to the UI
 type HopUpdate struct {
-	Index int        // Index of the hop (0-based)
-	Hop   NetworkHop // Updated hop data
+	Index   int        // Index of the hop (0-based)
+	Hop     NetworkHop // Updated hop data
+	Pending bool       // Discovery is probing Hop.TTL for this row and has no answer yet; TTL 0 while locating the destination
+	Cycle   int        // Monitoring round the update comes from, counting from 1; 0 during discovery
+	Time    time.Time  // When the round started, shared by every hop of it; when the hop was found during discovery
+	Target  string     // Hostname the update belongs to, which SetTarget changes
+}
+
+// ProbeResult is the outcome of a single monitoring probe, sent on the scanner's Probes channel
+type ProbeResult struct {
+	Index     int       // Index of the hop probed (0-based)
+	TTL       int       // TTL the probe was sent with
+	Seq       int       // Number of the probe within the session, counting from 1
+	Cycle     int       // Monitoring round the probe belongs to, as in HopUpdate
+	Latency   float64   // RTT in milliseconds, -1 if the probe timed out
+	Responder string    // Address that answered, empty on timeout
+	Time      time.Time // When the probe was sent
 }
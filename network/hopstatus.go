@@ -0,0 +1,123 @@
+package network
+
+import "golang.org/x/net/icmp"
+
+// HopStatus tells how a hop answered its latest probes, and for a Destination Unreachable why
+// the path ends there
+type HopStatus string
+
+const (
+	HopOK                  HopStatus = "OK"                          // Answered with TimeExceeded, or as the destination
+	HopTimeout             HopStatus = "Timeout"                     // No probe of the latest round was answered
+	HopNetUnreachable      HopStatus = "Network unreachable"         // No route to the destination's network (!N)
+	HopHostUnreachable     HopStatus = "Host unreachable"            // The last router couldn't deliver to the host (!H)
+	HopProtocolUnreachable HopStatus = "Protocol unreachable"        // The destination doesn't speak the probe's protocol (!P)
+	HopPortUnreachable     HopStatus = "Port unreachable"            // A router, not the destination, rejected the port
+	HopFragmentationNeeded HopStatus = "Fragmentation needed"        // The probe exceeds the link MTU and may not be fragmented (!F)
+	HopAdminProhibited     HopStatus = "Administratively prohibited" // A firewall or ACL rejected the probe (!X)
+	HopBeyondScope         HopStatus = "Beyond scope"                // The source address can't reach the destination's scope (IPv6)
+	HopUnreachable         HopStatus = "Unreachable"                 // Destination Unreachable with another code
+)
+
+// Dead reports whether the hop rejected the probe, so the path ends at it
+func (s HopStatus) Dead() bool {
+	return s != "" && s != HopOK && s != HopTimeout
+}
+
+// Annotation returns the classic traceroute marker for the status, e.g. "!N", empty if it has none
+func (s HopStatus) Annotation() string {
+	switch s {
+	case HopNetUnreachable:
+		return "!N"
+	case HopHostUnreachable:
+		return "!H"
+	case HopProtocolUnreachable:
+		return "!P"
+	case HopFragmentationNeeded:
+		return "!F"
+	case HopAdminProhibited:
+		return "!X"
+	case HopPortUnreachable, HopBeyondScope, HopUnreachable:
+		return "!"
+	}
+	return ""
+}
+
+// Explain says in a sentence why the path dies at a hop with this status, empty if it doesn't
+func (s HopStatus) Explain() string {
+	switch s {
+	case HopNetUnreachable:
+		return "This router has no route to the destination's network."
+	case HopHostUnreachable:
+		return "This router reaches the destination's network but not the host; it may be down or its address unused."
+	case HopProtocolUnreachable:
+		return "The destination doesn't handle the probe's protocol; try another probe method."
+	case HopPortUnreachable:
+		return "A router rejected the probe's port before the destination; try another probe method or port."
+	case HopFragmentationNeeded:
+		return "The probe is larger than the next link's MTU and may not be fragmented; try a smaller probe size."
+	case HopAdminProhibited:
+		return "A firewall or access list on this router rejects the probes."
+	case HopBeyondScope:
+		return "The source address's scope doesn't reach the destination; pick a global source address."
+	case HopUnreachable:
+		return "This router reported the destination unreachable."
+	}
+	return ""
+}
+
+// unreachableStatus maps a Destination Unreachable code (RFC 792, RFC 1812, RFC 4443) to a status
+func unreachableStatus(family *ipFamily, code int) HopStatus {
+	if family == familyIPv6 {
+		switch code {
+		case 0:
+			return HopNetUnreachable // No route to destination
+		case 1, 5, 6:
+			return HopAdminProhibited // Prohibited, source address failed policy, reject route
+		case 2:
+			return HopBeyondScope
+		case 3:
+			return HopHostUnreachable // Address unreachable
+		case 4:
+			return HopPortUnreachable
+		}
+		return HopUnreachable
+	}
+
+	switch code {
+	case 0, 6, 11:
+		return HopNetUnreachable // Net unreachable, net unknown, net unreachable for TOS
+	case 1, 7, 12:
+		return HopHostUnreachable // Host unreachable, host unknown, host unreachable for TOS
+	case 2:
+		return HopProtocolUnreachable
+	case 3:
+		return HopPortUnreachable
+	case 4:
+		return HopFragmentationNeeded
+	case 9, 10, 13:
+		return HopAdminProhibited // Net or host prohibited, communication prohibited
+	}
+	return HopUnreachable
+}
+
+// replyStatusOf returns a reply's status, treating custom probers that leave it unset as OK
+func replyStatusOf(reply ProbeReply) HopStatus {
+	if reply.Status == "" {
+		return HopOK
+	}
+	return reply.Status
+}
+
+// replyStatus returns the status of an ICMP answer to a probe
+// The destination rejecting a UDP or TCP probe's port is how it answers, so it counts as OK
+func replyStatus(family *ipFamily, msg *icmp.Message, reached bool) HopStatus {
+	if msg.Type != family.destUnreachable {
+		return HopOK
+	}
+	status := unreachableStatus(family, msg.Code)
+	if reached && status == HopPortUnreachable {
+		return HopOK
+	}
+	return status
+}
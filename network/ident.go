@@ -0,0 +1,78 @@
+package network
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+// probeSignature prefixes every probe payload so our replies can be told apart from
+// other ping tools that happen to use the same echo ID
+const probeSignature = "VISUAL-MTR"
+
+// echoIDs tracks the ICMP echo IDs in use by sessions in this process
+var echoIDs = struct {
+	mu    sync.Mutex
+	inUse map[int]bool
+}{inUse: make(map[int]bool)}
+
+// allocateEchoID reserves a random echo ID not used by any other session in this process
+// PID-based IDs collide between sessions and with other instances, so IDs are random
+func allocateEchoID() int {
+	echoIDs.mu.Lock()
+	defer echoIDs.mu.Unlock()
+
+	for {
+		id := rand.IntN(0xFFFF) + 1 // 0 is avoided as some stacks treat it specially
+		if !echoIDs.inUse[id] {
+			echoIDs.inUse[id] = true
+			return id
+		}
+	}
+}
+
+// releaseEchoID returns an echo ID to the pool
+func releaseEchoID(id int) {
+	echoIDs.mu.Lock()
+	defer echoIDs.mu.Unlock()
+	delete(echoIDs.inUse, id)
+}
+
+// probeTokenLen is the length of the random per-session token carried in each probe
+const probeTokenLen = 8
+
+// newProbeToken returns a random token identifying one session's probes
+func newProbeToken() []byte {
+	token := make([]byte, probeTokenLen)
+	crand.Read(token)
+	return token
+}
+
+// probePayload returns the payload for an echo request
+// Layout: signature | session token | probe number (4 bytes) | TTL (1 byte)
+func probePayload(token []byte, probe uint32, ttl int) []byte {
+	data := make([]byte, 0, len(probeSignature)+probeTokenLen+5)
+	data = append(data, probeSignature...)
+	data = append(data, token...)
+	data = binary.BigEndian.AppendUint32(data, probe)
+	return append(data, byte(ttl))
+}
+
+// hasProbeSignature reports whether an echoed payload came from visual-mtr
+func hasProbeSignature(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(probeSignature))
+}
+
+// validProbePayload reports whether an echoed payload matches the given session, probe and TTL
+// Replies to earlier probes, other sessions' probes and forged payloads all fail this check
+func validProbePayload(data, token []byte, probe uint32, ttl int) bool {
+	if len(data) < len(probeSignature)+probeTokenLen+5 || !hasProbeSignature(data) {
+		return false
+	}
+	meta := data[len(probeSignature):]
+	return bytes.Equal(meta[:probeTokenLen], token) &&
+		binary.BigEndian.Uint32(meta[probeTokenLen:]) == probe &&
+		int(meta[probeTokenLen+4]) == ttl
+}
@@ -0,0 +1,179 @@
+package network
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Incident summary tuning
+const (
+	incidentMinLost  = 2                // Probes an event must lose to count as an incident
+	incidentGap      = 30 * time.Second // Loss events closer than this belong to the same incident
+	maxIncidentLines = 5                // Incidents or path changes described before the rest are only counted
+)
+
+// incident is a group of overlapping loss events, described by the first hop that lost probes
+type incident struct {
+	start, end time.Time
+	origin     int                // Index of the lowest hop that lost probes
+	hops       map[int]*LossEvent // Events of each hop, merged
+}
+
+// originEvent returns the merged events of the incident's lowest hop
+func (inc incident) originEvent() LossEvent {
+	return *inc.hops[inc.origin]
+}
+
+// SummarizeIncidents describes a session's loss, path changes and throughput tests in a few plain
+// sentences, e.g. "Between 02:13 and 02:41, hop 7 (192.0.2.1) showed 18% loss, propagating to the
+// destination." hops is the final path, used to tell which events reached the destination; the
+// sentences are written in lang
+func SummarizeIncidents(lang ReportLanguage, events []LossEvent, changes []PathChange, tests []ThroughputResult, hops []NetworkHop) string {
+	dest := len(hops) - 1
+	incidents, blips := groupIncidents(events)
+
+	var lines []string
+	if len(incidents) == 0 {
+		lines = append(lines, lang.Phrase("No sustained loss was seen."))
+	}
+	for i, inc := range incidents {
+		if i == maxIncidentLines {
+			lines = append(lines, lang.Sprintf("%d further loss incidents followed.", len(incidents)-i))
+			break
+		}
+		lines = append(lines, describeIncident(lang, inc, dest))
+	}
+	if blips > 0 {
+		lines = append(lines, lang.Sprintf(pluralize(blips, "%d isolated one-off loss was not counted.", "%d isolated one-off losses were not counted."), blips))
+	}
+
+	if len(changes) == 0 {
+		lines = append(lines, lang.Phrase("The path did not change."))
+	} else {
+		where := make([]string, 0, len(changes))
+		for i, change := range changes {
+			if i == maxIncidentLines {
+				where = append(where, lang.Sprintf("%d more", len(changes)-i))
+				break
+			}
+			where = append(where, lang.Sprintf("hop %d at %s", change.Hop+1, change.At.Format("15:04")))
+		}
+		lines = append(lines, lang.Sprintf("The path changed %s (%s).", countTimes(lang, len(changes)), strings.Join(where, ", ")))
+	}
+
+	for i, test := range tests {
+		if i == maxIncidentLines {
+			lines = append(lines, lang.Sprintf("%d further download tests followed.", len(tests)-i))
+			break
+		}
+		lines = append(lines, describeThroughput(lang, test))
+	}
+	return strings.Join(lines, " ")
+}
+
+// describeThroughput renders a throughput test and the hops that lost probes during it as a
+// sentence in lang
+func describeThroughput(lang ReportLanguage, test ThroughputResult) string {
+	when := lang.Sprintf("At %s", test.Started.Format("15:04"))
+	degraded := test.DegradedHops()
+	if len(degraded) == 0 {
+		return lang.Sprintf("%s, %s.", when, lang.Sprintf("a download test ran at %.1f Mbps with no loss on the path", test.Mbps()))
+	}
+	numbers := make([]string, len(degraded))
+	for i, hop := range degraded {
+		numbers[i] = strconv.Itoa(hop)
+	}
+	format := pluralize(len(degraded), "a download test ran at %.1f Mbps while hop %s lost probes", "a download test ran at %.1f Mbps while hops %s lost probes")
+	return lang.Sprintf("%s, %s.", when, lang.Sprintf(format, test.Mbps(), strings.Join(numbers, ", ")))
+}
+
+// groupIncidents merges loss events closer than incidentGap into incidents, ordered by start
+// Incidents whose first hop lost fewer than incidentMinLost probes are only counted
+func groupIncidents(events []LossEvent) ([]incident, int) {
+	sorted := append([]LossEvent(nil), events...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var all []incident
+	for _, event := range sorted {
+		end := event.End
+		if event.Ongoing() {
+			end = time.Now()
+		}
+
+		n := len(all)
+		if n == 0 || event.Start.After(all[n-1].end.Add(incidentGap)) {
+			all = append(all, incident{start: event.Start, end: end, origin: event.Hop, hops: make(map[int]*LossEvent)})
+			n++
+		}
+		inc := &all[n-1]
+		if end.After(inc.end) {
+			inc.end = end
+		}
+		inc.origin = min(inc.origin, event.Hop)
+		if merged, ok := inc.hops[event.Hop]; ok {
+			// Answers between the hop's events count towards its depth
+			merged.Samples = event.StartSample + event.Samples - merged.StartSample
+			merged.Lost += event.Lost
+		} else {
+			inc.hops[event.Hop] = &event
+		}
+	}
+
+	var incidents []incident
+	blips := 0
+	for _, inc := range all {
+		if inc.originEvent().Lost < incidentMinLost {
+			blips++
+			continue
+		}
+		incidents = append(incidents, inc)
+	}
+	return incidents, blips
+}
+
+// describeIncident renders one incident as a sentence in lang
+func describeIncident(lang ReportLanguage, inc incident, dest int) string {
+	layout := "15:04"
+	if inc.start.YearDay() != inc.end.YearDay() {
+		layout = lang.Phrase("Jan 2 15:04")
+	}
+	when := lang.Sprintf("At %s", inc.start.Format(layout))
+	if inc.end.Sub(inc.start) >= time.Minute {
+		when = lang.Sprintf("Between %s and %s", inc.start.Format(layout), inc.end.Format(layout))
+	}
+
+	origin := inc.originEvent()
+	_, reachedDst := inc.hops[dest]
+	what := lang.Sprintf("hop %d (%s) showed %.0f%% loss", origin.Hop+1, origin.IP, origin.Depth())
+	switch {
+	case origin.Hop == dest:
+		what = lang.Sprintf("the destination (%s) showed %.0f%% loss", origin.IP, origin.Depth())
+	case reachedDst:
+		what += lang.Phrase(", propagating to the destination")
+	default:
+		what += lang.Phrase(" that did not reach the destination, likely ICMP rate limiting")
+	}
+	return lang.Sprintf("%s, %s.", when, what)
+}
+
+// countTimes spells out in lang how many times something happened
+func countTimes(lang ReportLanguage, n int) string {
+	switch n {
+	case 1:
+		return lang.Phrase("once")
+	case 2:
+		return lang.Phrase("twice")
+	default:
+		return lang.Sprintf("%d times", n)
+	}
+}
+
+// pluralize picks the singular or plural wording for a count
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// asnRange is a block of addresses announced by one AS
+type asnRange struct {
+	start, end netip.Addr
+	info       ASNInfo
+}
+
+// ASNTable maps addresses to their origin AS offline, from a downloaded iptoasn.com table
+type ASNTable struct {
+	ranges []asnRange // Sorted by start; IPv4 sorts before IPv6
+}
+
+// LoadASNTable reads an ip2asn TSV file, gzipped or not, with lines of
+// "range_start	range_end	AS_number	country_code	AS_description"
+func LoadASNTable(path string) (*ASNTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ASN table: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ASN table: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return parseASNTable(r)
+}
+
+// parseASNTable parses ip2asn lines, skipping unannounced ranges (AS 0)
+// AS names repeat across ranges, so each is stored once
+func parseASNTable(r io.Reader) (*ASNTable, error) {
+	t := &ASNTable{}
+	names := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		asn, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid AS number on line %d: %q", line, fields[2])
+		}
+		if asn == 0 {
+			continue
+		}
+		start, err1 := netip.ParseAddr(fields[0])
+		end, err2 := netip.ParseAddr(fields[1])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid address range on line %d", line)
+		}
+		name, ok := names[fields[4]]
+		if !ok {
+			name = fields[4]
+			names[name] = name
+		}
+		t.ranges = append(t.ranges, asnRange{
+			start: start,
+			end:   end,
+			info:  ASNInfo{ASN: asn, Name: name, Country: fields[3]},
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ASN table: %v", err)
+	}
+	if len(t.ranges) == 0 {
+		return nil, fmt.Errorf("ASN table is empty")
+	}
+
+	sort.Slice(t.ranges, func(i, j int) bool {
+		return t.ranges[i].start.Less(t.ranges[j].start)
+	})
+	return t, nil
+}
+
+// Len returns the number of announced ranges in the table
+func (t *ASNTable) Len() int {
+	return len(t.ranges)
+}
+
+// Lookup returns the origin AS of ip, or false if no range of the table contains it
+func (t *ASNTable) Lookup(ip string) (ASNInfo, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ASNInfo{}, false
+	}
+	addr = addr.Unmap()
+
+	// The last range starting at or before addr is the only one that can contain it
+	i := sort.Search(len(t.ranges), func(i int) bool {
+		return addr.Less(t.ranges[i].start)
+	}) - 1
+	if i < 0 || t.ranges[i].end.Less(addr) {
+		return ASNInfo{}, false
+	}
+	return t.ranges[i].info, true
+}
@@ -0,0 +1,206 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// PeeringDBURL is the base URL of the PeeringDB API used to refresh IXP prefixes
+const PeeringDBURL = "https://www.peeringdb.com/api"
+
+// IXP is an internet exchange peering LAN
+type IXP struct {
+	Name   string `json:"name"`   // Exchange name as listed in PeeringDB
+	Prefix string `json:"prefix"` // Peering LAN prefix in CIDR notation
+}
+
+// defaultIXPs seeds the database with a few large exchanges until PeeringDB data is loaded
+var defaultIXPs = []IXP{
+	{Name: "AMS-IX", Prefix: "80.249.208.0/21"},
+	{Name: "DE-CIX Frankfurt", Prefix: "80.81.192.0/21"},
+	{Name: "LINX LON1", Prefix: "195.66.224.0/22"},
+}
+
+// ixpNet is an IXP with its prefix parsed for matching
+type ixpNet struct {
+	ixp     IXP
+	network *net.IPNet
+}
+
+// IXPDatabase matches hop IPs against known IXP peering LAN prefixes
+// It is safe for concurrent use
+type IXPDatabase struct {
+	mu   sync.RWMutex
+	nets []ixpNet
+}
+
+// NewIXPDatabase creates a database seeded with well-known exchanges
+func NewIXPDatabase() *IXPDatabase {
+	db := &IXPDatabase{}
+	db.set(defaultIXPs)
+	return db
+}
+
+// Lookup returns the exchange whose peering LAN contains the IP
+func (db *IXPDatabase) Lookup(ip string) (IXP, bool) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return IXP{}, false
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, n := range db.nets {
+		if n.network.Contains(parsed) {
+			return n.ixp, true
+		}
+	}
+	return IXP{}, false
+}
+
+// Len returns the number of known IXP prefixes
+func (db *IXPDatabase) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.nets)
+}
+
+// Load replaces the database contents with prefixes saved by Save
+func (db *IXPDatabase) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read IXP data: %v", err)
+	}
+
+	var ixps []IXP
+	if err := json.Unmarshal(data, &ixps); err != nil {
+		return fmt.Errorf("failed to parse IXP data: %v", err)
+	}
+
+	db.set(ixps)
+	return nil
+}
+
+// Save writes the current prefixes to path so they survive restarts
+func (db *IXPDatabase) Save(path string) error {
+	db.mu.RLock()
+	ixps := make([]IXP, len(db.nets))
+	for i, n := range db.nets {
+		ixps[i] = n.ixp
+	}
+	db.mu.RUnlock()
+
+	data, err := json.Marshal(ixps)
+	if err != nil {
+		return fmt.Errorf("failed to encode IXP data: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write IXP data: %v", err)
+	}
+	return nil
+}
+
+// Refresh downloads the current IXP prefix list from PeeringDB
+// PeeringDB links prefixes to exchanges through the ixlan objects, so three lists are joined
+func (db *IXPDatabase) Refresh(ctx context.Context) error {
+	var ixs struct {
+		Data []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	var ixlans struct {
+		Data []struct {
+			ID   int `json:"id"`
+			IXID int `json:"ix_id"`
+		} `json:"data"`
+	}
+	var ixpfxs struct {
+		Data []struct {
+			IXLanID int    `json:"ixlan_id"`
+			Prefix  string `json:"prefix"`
+		} `json:"data"`
+	}
+
+	if err := fetchJSON(ctx, LookupPeeringDB, PeeringDBURL+"/ix", &ixs); err != nil {
+		return err
+	}
+	if err := fetchJSON(ctx, LookupPeeringDB, PeeringDBURL+"/ixlan", &ixlans); err != nil {
+		return err
+	}
+	if err := fetchJSON(ctx, LookupPeeringDB, PeeringDBURL+"/ixpfx", &ixpfxs); err != nil {
+		return err
+	}
+
+	names := make(map[int]string, len(ixs.Data))
+	for _, ix := range ixs.Data {
+		names[ix.ID] = ix.Name
+	}
+	lanToIX := make(map[int]int, len(ixlans.Data))
+	for _, lan := range ixlans.Data {
+		lanToIX[lan.ID] = lan.IXID
+	}
+
+	ixps := make([]IXP, 0, len(ixpfxs.Data))
+	for _, pfx := range ixpfxs.Data {
+		name, ok := names[lanToIX[pfx.IXLanID]]
+		if !ok {
+			continue
+		}
+		ixps = append(ixps, IXP{Name: name, Prefix: pfx.Prefix})
+	}
+	if len(ixps) == 0 {
+		return fmt.Errorf("PeeringDB returned no IXP prefixes")
+	}
+
+	db.set(ixps)
+	return nil
+}
+
+// set replaces the prefix list, skipping entries that don't parse
+func (db *IXPDatabase) set(ixps []IXP) {
+	nets := make([]ixpNet, 0, len(ixps))
+	for _, ixp := range ixps {
+		_, network, err := net.ParseCIDR(ixp.Prefix)
+		if err != nil {
+			continue
+		}
+		nets = append(nets, ixpNet{ixp: ixp, network: network})
+	}
+
+	db.mu.Lock()
+	db.nets = nets
+	db.mu.Unlock()
+}
+
+// fetchJSON performs a GET request for a lookup feature and decodes the JSON response into out
+// Fails with ErrLookupDisabled without sending anything if the feature is turned off
+func fetchJSON(ctx context.Context, feature LookupFeature, url string, out any) error {
+	if err := checkLookup(feature); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", url, err)
+	}
+	return nil
+}
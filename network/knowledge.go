@@ -0,0 +1,187 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rdnsLookupTimeout bounds one reverse DNS lookup for the knowledge base
+const rdnsLookupTimeout = 5 * time.Second
+
+// knowledgeNameMaxAge is how long a reverse DNS name, or the lack of one, is trusted before the
+// address is looked up again
+const knowledgeNameMaxAge = 30 * 24 * time.Hour
+
+// typicalLatencyWeight is how much the latest session moves an address's typical latency
+const typicalLatencyWeight = 0.2
+
+// IPFacts is what sessions have learned about an address
+type IPFacts struct {
+	RDNS           string    `json:"rdns,omitempty"`           // Reverse DNS name, empty if it has none or it isn't known
+	NameChecked    time.Time `json:"nameChecked,omitempty"`    // When RDNS was looked up, zero if never
+	ASN            int       `json:"asn,omitempty"`            // Origin AS, 0 if unknown
+	ASName         string    `json:"asName,omitempty"`         // Registered name of the origin AS
+	Label          string    `json:"label,omitempty"`          // Name the user gave the address
+	TypicalLatency float64   `json:"typicalLatency,omitempty"` // Weighted average of its sessions' average RTT, in milliseconds
+	Sessions       int       `json:"sessions,omitempty"`       // Sessions whose latency TypicalLatency covers
+	LastSeen       time.Time `json:"lastSeen,omitempty"`       // End of the latest session it was a hop of
+}
+
+// KnowledgeBase keeps facts about hop addresses across sessions in a JSON file, so a new session
+// shows names, origin ASes and typical latency before anything is looked up again
+// It is safe for concurrent use
+type KnowledgeBase struct {
+	path string
+
+	mu       sync.Mutex
+	facts    map[string]IPFacts
+	inFlight map[string]bool
+}
+
+// OpenKnowledgeBase loads the knowledge base stored at path; a missing file is an empty one
+// An unreadable file also yields an empty knowledge base, with the error
+func OpenKnowledgeBase(path string) (*KnowledgeBase, error) {
+	kb := &KnowledgeBase{
+		path:     path,
+		facts:    make(map[string]IPFacts),
+		inFlight: make(map[string]bool),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return kb, nil
+	}
+	if err != nil {
+		return kb, fmt.Errorf("failed to read knowledge base: %v", err)
+	}
+	if err := json.Unmarshal(data, &kb.facts); err != nil {
+		kb.facts = make(map[string]IPFacts)
+		return kb, fmt.Errorf("failed to parse knowledge base: %v", err)
+	}
+	return kb, nil
+}
+
+// Facts returns what is known about ip
+func (kb *KnowledgeBase) Facts(ip string) (IPFacts, bool) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	facts, ok := kb.facts[ip]
+	return facts, ok
+}
+
+// SetLabel names ip, e.g. "office firewall"; an empty label removes the name
+func (kb *KnowledgeBase) SetLabel(ip, label string) {
+	if ip == "" {
+		return
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	facts := kb.facts[ip]
+	facts.Label = strings.TrimSpace(label)
+	kb.facts[ip] = facts
+}
+
+// Learn records the origin AS and average latency of a finished session's hops; each session
+// moves an address's typical latency a fifth of the way towards its own average
+func (kb *KnowledgeBase) Learn(hops []NetworkHop) {
+	now := time.Now()
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	for _, hop := range hops {
+		if hop.IP == "" || hop.Sent == 0 {
+			continue
+		}
+		facts := kb.facts[hop.IP]
+		if hop.ASN != 0 {
+			facts.ASN, facts.ASName = hop.ASN, hop.ASName
+		}
+		if hop.Received > 0 && hop.AvgLatency > 0 {
+			if facts.Sessions == 0 {
+				facts.TypicalLatency = hop.AvgLatency
+			} else {
+				facts.TypicalLatency += typicalLatencyWeight * (hop.AvgLatency - facts.TypicalLatency)
+			}
+			facts.Sessions++
+		}
+		facts.LastSeen = now
+		kb.facts[hop.IP] = facts
+	}
+}
+
+// SeedASNs hands the origin ASes learned in earlier sessions to r, so hops show them at once
+func (kb *KnowledgeBase) SeedASNs(r *ASNResolver) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	for ip, facts := range kb.facts {
+		if facts.ASN != 0 {
+			r.Remember(ip, ASNInfo{ASN: facts.ASN, Name: facts.ASName})
+		}
+	}
+}
+
+// PrefetchName looks up the reverse DNS name of ip in the background unless it is known and
+// recent enough, already being looked up, or reverse DNS lookups are off
+func (kb *KnowledgeBase) PrefetchName(ip string) {
+	if net.ParseIP(ip) == nil || !LookupAllowed(LookupRDNS) {
+		return
+	}
+	kb.mu.Lock()
+	if time.Since(kb.facts[ip].NameChecked) < knowledgeNameMaxAge || kb.inFlight[ip] {
+		kb.mu.Unlock()
+		return
+	}
+	kb.inFlight[ip] = true
+	kb.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rdnsLookupTimeout)
+		defer cancel()
+		names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+
+		kb.mu.Lock()
+		defer kb.mu.Unlock()
+		delete(kb.inFlight, ip)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			// Try again next time rather than remember a resolver failure as no name
+			log.Printf("[DEBUG] Reverse DNS lookup for %s failed: %v\n", ip, err)
+			return
+		}
+		facts := kb.facts[ip]
+		facts.RDNS = ""
+		if len(names) > 0 {
+			facts.RDNS = strings.TrimSuffix(names[0], ".")
+		}
+		facts.NameChecked = time.Now()
+		kb.facts[ip] = facts
+	}()
+}
+
+// Save writes the knowledge base to its file, replacing it only once the new one is complete
+func (kb *KnowledgeBase) Save() error {
+	kb.mu.Lock()
+	data, err := json.MarshalIndent(kb.facts, "", "  ")
+	kb.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode knowledge base: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(kb.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	tmp := kb.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write knowledge base: %v", err)
+	}
+	if err := os.Rename(tmp, kb.path); err != nil {
+		return fmt.Errorf("failed to write knowledge base: %v", err)
+	}
+	return nil
+}
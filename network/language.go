@@ -0,0 +1,233 @@
+package network
+
+import "fmt"
+
+// ReportLanguage is a language reports, tickets and evidence packs are written in, independent
+// of the UI, since evidence often goes to an ISP in another country
+type ReportLanguage string
+
+// Report languages, named in their own language
+const (
+	ReportEnglish ReportLanguage = "English"
+	ReportGerman  ReportLanguage = "Deutsch"
+	ReportFrench  ReportLanguage = "Français"
+	ReportSpanish ReportLanguage = "Español"
+)
+
+// ReportLanguages lists the languages reports can be written in
+var ReportLanguages = []ReportLanguage{ReportEnglish, ReportGerman, ReportFrench, ReportSpanish}
+
+// ParseReportLanguage returns the report language named name, English if it is unknown
+func ParseReportLanguage(name string) ReportLanguage {
+	for _, l := range ReportLanguages {
+		if string(l) == name {
+			return l
+		}
+	}
+	return ReportEnglish
+}
+
+// reportPhrases translates report phrases, keyed by their English text, into each other language
+// Time layouts are phrases too, so dates follow the language's customs
+var reportPhrases = map[string]map[ReportLanguage]string{
+	// Incident summary
+	"No sustained loss was seen.": {
+		ReportGerman:  "Es wurde kein anhaltender Paketverlust beobachtet.",
+		ReportFrench:  "Aucune perte durable n'a été observée.",
+		ReportSpanish: "No se observó pérdida sostenida.",
+	},
+	"%d further loss incidents followed.": {
+		ReportGerman:  "Es folgten %d weitere Verlustvorfälle.",
+		ReportFrench:  "%d autres incidents de perte ont suivi.",
+		ReportSpanish: "Siguieron %d incidentes de pérdida más.",
+	},
+	"%d isolated one-off loss was not counted.": {
+		ReportGerman:  "%d einzelner, isolierter Verlust wurde nicht gezählt.",
+		ReportFrench:  "%d perte isolée et ponctuelle n'a pas été comptée.",
+		ReportSpanish: "%d pérdida aislada y puntual no se contabilizó.",
+	},
+	"%d isolated one-off losses were not counted.": {
+		ReportGerman:  "%d einzelne, isolierte Verluste wurden nicht gezählt.",
+		ReportFrench:  "%d pertes isolées et ponctuelles n'ont pas été comptées.",
+		ReportSpanish: "%d pérdidas aisladas y puntuales no se contabilizaron.",
+	},
+	"The path did not change.": {
+		ReportGerman:  "Der Pfad hat sich nicht geändert.",
+		ReportFrench:  "Le chemin n'a pas changé.",
+		ReportSpanish: "La ruta no cambió.",
+	},
+	"%d more": {
+		ReportGerman:  "%d weitere",
+		ReportFrench:  "%d de plus",
+		ReportSpanish: "%d más",
+	},
+	"hop %d at %s": {
+		ReportGerman:  "Hop %d um %s",
+		ReportFrench:  "saut %d à %s",
+		ReportSpanish: "salto %d a las %s",
+	},
+	"The path changed %s (%s).": {
+		ReportGerman:  "Der Pfad hat sich %s geändert (%s).",
+		ReportFrench:  "Le chemin a changé %s (%s).",
+		ReportSpanish: "La ruta cambió %s (%s).",
+	},
+	"once": {
+		ReportGerman:  "einmal",
+		ReportFrench:  "une fois",
+		ReportSpanish: "una vez",
+	},
+	"twice": {
+		ReportGerman:  "zweimal",
+		ReportFrench:  "deux fois",
+		ReportSpanish: "dos veces",
+	},
+	"%d times": {
+		ReportGerman:  "%d-mal",
+		ReportFrench:  "%d fois",
+		ReportSpanish: "%d veces",
+	},
+	"Jan 2 15:04": {
+		ReportGerman:  "2.1. 15:04",
+		ReportFrench:  "2/1 15:04",
+		ReportSpanish: "2/1 15:04",
+	},
+	"At %s": {
+		ReportGerman:  "Um %s",
+		ReportFrench:  "À %s",
+		ReportSpanish: "A las %s",
+	},
+	"Between %s and %s": {
+		ReportGerman:  "Zwischen %s und %s",
+		ReportFrench:  "Entre %s et %s",
+		ReportSpanish: "Entre las %s y las %s",
+	},
+	"hop %d (%s) showed %.0f%% loss": {
+		ReportGerman:  "Hop %d (%s) zeigte %.0f %% Verlust",
+		ReportFrench:  "le saut %d (%s) a présenté %.0f %% de perte",
+		ReportSpanish: "el salto %d (%s) mostró un %.0f %% de pérdida",
+	},
+	"the destination (%s) showed %.0f%% loss": {
+		ReportGerman:  "das Ziel (%s) zeigte %.0f %% Verlust",
+		ReportFrench:  "la destination (%s) a présenté %.0f %% de perte",
+		ReportSpanish: "el destino (%s) mostró un %.0f %% de pérdida",
+	},
+	", propagating to the destination": {
+		ReportGerman:  ", der sich bis zum Ziel fortsetzte",
+		ReportFrench:  ", propagée jusqu'à la destination",
+		ReportSpanish: ", que se propagó hasta el destino",
+	},
+	" that did not reach the destination, likely ICMP rate limiting": {
+		ReportGerman:  ", der das Ziel nicht erreichte, vermutlich ICMP-Ratenbegrenzung",
+		ReportFrench:  " qui n'a pas atteint la destination, probablement une limitation du débit ICMP",
+		ReportSpanish: " que no llegó al destino, probablemente por limitación de tasa ICMP",
+	},
+	"a download test ran at %.1f Mbps while hop %s lost probes": {
+		ReportGerman:  "ein Download-Test lief mit %.1f Mbit/s, während Hop %s Pakete verlor",
+		ReportFrench:  "un test de téléchargement a atteint %.1f Mbit/s pendant que le saut %s perdait des sondes",
+		ReportSpanish: "una prueba de descarga alcanzó %.1f Mbps mientras el salto %s perdía sondas",
+	},
+	"a download test ran at %.1f Mbps while hops %s lost probes": {
+		ReportGerman:  "ein Download-Test lief mit %.1f Mbit/s, während die Hops %s Pakete verloren",
+		ReportFrench:  "un test de téléchargement a atteint %.1f Mbit/s pendant que les sauts %s perdaient des sondes",
+		ReportSpanish: "una prueba de descarga alcanzó %.1f Mbps mientras los saltos %s perdían sondas",
+	},
+	"a download test ran at %.1f Mbps with no loss on the path": {
+		ReportGerman:  "ein Download-Test lief mit %.1f Mbit/s ohne Verlust auf dem Pfad",
+		ReportFrench:  "un test de téléchargement a atteint %.1f Mbit/s sans perte sur le chemin",
+		ReportSpanish: "una prueba de descarga alcanzó %.1f Mbps sin pérdida en la ruta",
+	},
+	"%d further download tests followed.": {
+		ReportGerman:  "Es folgten %d weitere Download-Tests.",
+		ReportFrench:  "%d autres tests de téléchargement ont suivi.",
+		ReportSpanish: "Siguieron %d pruebas de descarga más.",
+	},
+	// German puts the verb second, so the time is set apart rather than leading the clause
+	"%s, %s.": {
+		ReportGerman: "%s: %s.",
+	},
+
+	// Verdict
+	"Good":         {ReportGerman: "Gut", ReportFrench: "Bon", ReportSpanish: "Buena"},
+	"Fair":         {ReportGerman: "Mäßig", ReportFrench: "Moyen", ReportSpanish: "Regular"},
+	"Poor":         {ReportGerman: "Schlecht", ReportFrench: "Mauvais", ReportSpanish: "Mala"},
+	"Unreachable":  {ReportGerman: "Nicht erreichbar", ReportFrench: "Injoignable", ReportSpanish: "Inalcanzable"},
+	"Inconclusive": {ReportGerman: "Nicht aussagekräftig", ReportFrench: "Non concluant", ReportSpanish: "No concluyente"},
+	"No hops were discovered.": {
+		ReportGerman:  "Es wurden keine Hops gefunden.",
+		ReportFrench:  "Aucun saut n'a été découvert.",
+		ReportSpanish: "No se descubrieron saltos.",
+	},
+	"The destination never replied.": {
+		ReportGerman:  "Das Ziel hat nie geantwortet.",
+		ReportFrench:  "La destination n'a jamais répondu.",
+		ReportSpanish: "El destino nunca respondió.",
+	},
+	"Destination %s: %s average, %.1f%% loss.": {
+		ReportGerman:  "Ziel %s: %s im Mittel, %.1f %% Verlust.",
+		ReportFrench:  "Destination %s : %s en moyenne, %.1f %% de perte.",
+		ReportSpanish: "Destino %s: %s de media, %.1f %% de pérdida.",
+	},
+	"%s score: %d/100.": {
+		ReportGerman:  "Punktzahl (%s): %d/100.",
+		ReportFrench:  "Score (%s) : %d/100.",
+		ReportSpanish: "Puntuación (%s): %d/100.",
+	},
+	// Probing profiles
+	"General":       {ReportGerman: "Allgemein", ReportFrench: "Général", ReportSpanish: "General"},
+	"Bulk transfer": {ReportGerman: "Massenübertragung", ReportFrench: "Transfert en masse", ReportSpanish: "Transferencia masiva"},
+	"Verdict: %s": {
+		ReportGerman:  "Bewertung: %s",
+		ReportFrench:  "Verdict : %s",
+		ReportSpanish: "Veredicto: %s",
+	},
+
+	// Ticket; the title names the verdict after the target where an adjective can't lead
+	"%s path to %s (%s)": {
+		ReportGerman:  "Pfad zu %[2]s: %[1]s (%[3]s)",
+		ReportFrench:  "Chemin vers %[2]s : %[1]s (%[3]s)",
+		ReportSpanish: "Ruta a %[2]s: %[1]s (%[3]s)",
+	},
+	"Summary":           {ReportGerman: "Zusammenfassung", ReportFrench: "Résumé", ReportSpanish: "Resumen"},
+	"Target":            {ReportGerman: "Ziel", ReportFrench: "Cible", ReportSpanish: "Destino"},
+	"Reported":          {ReportGerman: "Gemeldet", ReportFrench: "Signalé", ReportSpanish: "Notificado"},
+	"Verdict":           {ReportGerman: "Bewertung", ReportFrench: "Verdict", ReportSpanish: "Veredicto"},
+	"Hops":              {ReportGerman: "Hops", ReportFrench: "Sauts", ReportSpanish: "Saltos"},
+	"Hop":               {ReportGerman: "Hop", ReportFrench: "Saut", ReportSpanish: "Salto"},
+	"Address":           {ReportGerman: "Adresse", ReportFrench: "Adresse", ReportSpanish: "Dirección"},
+	"Loss":              {ReportGerman: "Verlust", ReportFrench: "Perte", ReportSpanish: "Pérdida"},
+	"Avg":               {ReportGerman: "Mittel", ReportFrench: "Moy.", ReportSpanish: "Media"},
+	"Best":              {ReportGerman: "Min.", ReportFrench: "Min.", ReportSpanish: "Mín."},
+	"Worst":             {ReportGerman: "Max.", ReportFrench: "Max.", ReportSpanish: "Máx."},
+	"Jitter":            {ReportGerman: "Jitter", ReportFrench: "Gigue", ReportSpanish: "Jitter"},
+	"Environment":       {ReportGerman: "Umgebung", ReportFrench: "Environnement", ReportSpanish: "Entorno"},
+	"Measured with %s.": {ReportGerman: "Gemessen mit %s.", ReportFrench: "Mesuré avec %s.", ReportSpanish: "Medido con %s."},
+	"Attachments":       {ReportGerman: "Anhänge", ReportFrench: "Pièces jointes", ReportSpanish: "Adjuntos"},
+
+	// Evidence pack README
+	"%s evidence pack": {
+		ReportGerman:  "%s-Nachweispaket",
+		ReportFrench:  "Dossier de preuves %s",
+		ReportSpanish: "Paquete de evidencias de %s",
+	},
+	"Started":  {ReportGerman: "Beginn", ReportFrench: "Début", ReportSpanish: "Inicio"},
+	"Protocol": {ReportGerman: "Protokoll", ReportFrench: "Protocole", ReportSpanish: "Protocolo"},
+	"ICMP echo to every hop at 1s intervals for %s, hop table snapshot every %s": {
+		ReportGerman:  "ICMP-Echo an jeden Hop im Sekundentakt für %s, Momentaufnahme der Hop-Tabelle alle %s",
+		ReportFrench:  "Écho ICMP vers chaque saut toutes les secondes pendant %s, instantané de la table des sauts toutes les %s",
+		ReportSpanish: "Eco ICMP a cada salto cada segundo durante %s, instantánea de la tabla de saltos cada %s",
+	},
+}
+
+// Phrase returns the report phrase given by its English text in the language, English if it
+// has no translation
+func (l ReportLanguage) Phrase(english string) string {
+	if translated, ok := reportPhrases[english][l]; ok {
+		return translated
+	}
+	return english
+}
+
+// Sprintf formats the report phrase given by its English format in the language
+func (l ReportLanguage) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(l.Phrase(format), args...)
+}
@@ -0,0 +1,102 @@
+package network
+
+import "sort"
+
+// maxLinkRounds is how many recent monitoring rounds PathLinks pairs responders from
+const maxLinkRounds = MaxLatencyHistory
+
+// PathLink is a router answering at one TTL followed by a router answering at the next within
+// the same round. Probes of a round share one flow, which per-flow load balancers send down a
+// single branch, so the pair is a link that flow took
+type PathLink struct {
+	Hop     int     // Index of the nearer hop (0-based)
+	From    string  // Router answering at the nearer hop
+	To      string  // Router answering at the next hop
+	Share   float64 // Percentage of the rounds with answers at both hops that took this link (0-100)
+	Latency float64 // Average RTT of To's answers on this link, in milliseconds
+}
+
+// linkRound holds the first answer each hop gave in one monitoring round
+type linkRound struct {
+	cycle   int
+	answers map[int]ProbeResult // By hop index
+}
+
+// PathLinks pairs the responders of consecutive hops within each monitoring round from the
+// scanner's probe results (see Scanner.Probes), keeping the latest maxLinkRounds rounds
+// It is not safe for concurrent use
+type PathLinks struct {
+	rounds []*linkRound // Oldest first
+}
+
+// NewPathLinks creates a tracker with no rounds
+func NewPathLinks() *PathLinks {
+	return &PathLinks{rounds: make([]*linkRound, 0)}
+}
+
+// Observe records a probe result; timeouts are ignored
+func (l *PathLinks) Observe(result ProbeResult) {
+	if result.Responder == "" {
+		return
+	}
+	var round *linkRound
+	for i := len(l.rounds) - 1; i >= 0 && round == nil; i-- {
+		if l.rounds[i].cycle == result.Cycle {
+			round = l.rounds[i]
+		}
+	}
+	if round == nil {
+		round = &linkRound{cycle: result.Cycle, answers: make(map[int]ProbeResult)}
+		l.rounds = append(l.rounds, round)
+		if len(l.rounds) > maxLinkRounds {
+			l.rounds = l.rounds[1:]
+		}
+	}
+	if _, ok := round.answers[result.Index]; !ok {
+		round.answers[result.Index] = result
+	}
+}
+
+// Links returns the links seen over the recent rounds, ordered by hop and then by share
+func (l *PathLinks) Links() []PathLink {
+	type linkKey struct {
+		hop      int
+		from, to string
+	}
+	counts := make(map[linkKey]int)
+	rtts := make(map[linkKey]float64)
+	paired := make(map[int]int) // Rounds with answers at both a hop and the next
+	for _, round := range l.rounds {
+		for index, from := range round.answers {
+			to, ok := round.answers[index+1]
+			if !ok {
+				continue
+			}
+			key := linkKey{hop: index, from: from.Responder, to: to.Responder}
+			counts[key]++
+			rtts[key] += to.Latency
+			paired[index]++
+		}
+	}
+
+	links := make([]PathLink, 0, len(counts))
+	for key, count := range counts {
+		links = append(links, PathLink{
+			Hop:     key.hop,
+			From:    key.from,
+			To:      key.to,
+			Share:   float64(count) / float64(paired[key.hop]) * 100,
+			Latency: rtts[key] / float64(count),
+		})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Hop != links[j].Hop {
+			return links[i].Hop < links[j].Hop
+		}
+		if links[i].Share != links[j].Share {
+			return links[i].Share > links[j].Share
+		}
+		return links[i].From+links[i].To < links[j].From+links[j].To
+	})
+	return links
+}
@@ -0,0 +1,114 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// LookupFeature is a kind of enrichment traffic sent to services outside the measured path
+type LookupFeature string
+
+const (
+	LookupASN            LookupFeature = "ASN (Team Cymru DNS)"
+	LookupRDNS           LookupFeature = "Reverse DNS of hops"
+	LookupPeeringDB      LookupFeature = "PeeringDB (IXPs and contacts)"
+	LookupRDAP           LookupFeature = "RDAP (registry contacts)"
+	LookupAtlas          LookupFeature = "RIPE Atlas"
+	LookupProviderStatus LookupFeature = "Provider status pages"
+)
+
+// LookupFeatures lists the lookup features in display order
+var LookupFeatures = []LookupFeature{LookupASN, LookupRDNS, LookupPeeringDB, LookupRDAP, LookupAtlas, LookupProviderStatus}
+
+// ErrLookupDisabled is returned by lookups that offline mode or their own toggle turned off
+var ErrLookupDisabled = errors.New("external lookups are turned off")
+
+// lookups holds the process-wide network access settings
+var lookups = struct {
+	mu       sync.RWMutex
+	offline  bool
+	disabled map[LookupFeature]bool
+	client   *http.Client
+}{
+	disabled: make(map[LookupFeature]bool),
+	client:   newHTTPClient(http.ProxyFromEnvironment),
+}
+
+// SetOffline turns every external lookup off, for air-gapped networks, or back on as their toggles allow
+func SetOffline(offline bool) {
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	lookups.offline = offline
+}
+
+// SetLookupEnabled turns one lookup feature on or off
+func SetLookupEnabled(feature LookupFeature, enabled bool) {
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	lookups.disabled[feature] = !enabled
+}
+
+// LookupAllowed reports whether a lookup feature may send traffic
+func LookupAllowed(feature LookupFeature) bool {
+	lookups.mu.RLock()
+	defer lookups.mu.RUnlock()
+	return !lookups.offline && !lookups.disabled[feature]
+}
+
+// checkLookup fails with ErrLookupDisabled, naming the feature, if it may not send traffic
+func checkLookup(feature LookupFeature) error {
+	if !LookupAllowed(feature) {
+		return fmt.Errorf("%w: %s", ErrLookupDisabled, feature)
+	}
+	return nil
+}
+
+// SetProxy sends HTTP requests through the proxy at proxyURL, e.g. "http://proxy.example:3128";
+// empty uses the system proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// DNS-based lookups such as ASN go through the system resolver either way
+func SetProxy(proxyURL string) error {
+	proxy, err := parseProxy(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	lookups.client = newHTTPClient(proxy)
+	return nil
+}
+
+// ValidateProxyURL checks a proxy URL before it is saved; empty is valid and means the system proxy
+func ValidateProxyURL(proxyURL string) error {
+	_, err := parseProxy(proxyURL)
+	return err
+}
+
+// parseProxy returns the proxy function for proxyURL, from the environment if it is empty
+func parseProxy(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// HTTPClient returns the client every HTTP request of the app goes through, honouring the proxy
+func HTTPClient() *http.Client {
+	lookups.mu.RLock()
+	defer lookups.mu.RUnlock()
+	return lookups.client
+}
+
+// newHTTPClient creates a client whose requests go through proxy
+func newHTTPClient(proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,193 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// lossEventRecovery is the number of consecutive answers that ends a loss event
+const lossEventRecovery = 3
+
+// maxLossEvents caps how many loss events and path changes a tracker keeps; the oldest are dropped first
+const maxLossEvents = 1000
+
+// LossEvent is a period during which a hop lost probes
+type LossEvent struct {
+	Hop         int       // Index of the hop (0-based)
+	IP          string    // Address of the hop when the event started
+	Start       time.Time // When the first probe was lost
+	End         time.Time // When the last probe was lost; zero while the event is ongoing
+	Samples     int       // Samples taken from the first to the last loss
+	Lost        int       // Samples lost during the event; with several probes per round, every probe was lost
+	StartSample int       // Number of the hop's sample at which the event started
+}
+
+// Ongoing reports whether the hop is still losing probes
+func (e LossEvent) Ongoing() bool {
+	return e.End.IsZero()
+}
+
+// Duration returns how long the hop was losing probes, up to now for ongoing events
+func (e LossEvent) Duration() time.Duration {
+	if e.Ongoing() {
+		return time.Since(e.Start)
+	}
+	return e.End.Sub(e.Start)
+}
+
+// Depth returns the percentage of samples lost during the event
+func (e LossEvent) Depth() float64 {
+	if e.Samples == 0 {
+		return 0
+	}
+	return float64(e.Lost) / float64(e.Samples) * 100
+}
+
+// PathChange is a hop whose main responder changed during a session
+type PathChange struct {
+	Hop  int       // Index of the hop (0-based)
+	From string    // Previous main responder, empty if a re-trace found a new hop
+	To   string    // New main responder, empty if a re-trace found the path ends before the hop
+	At   time.Time // When the change was seen
+}
+
+// Describe tells what changed, e.g. "Hop 7 changed from 192.0.2.1 to 192.0.2.9"
+func (c PathChange) Describe() string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("Hop %d is new (%s)", c.Hop+1, c.To)
+	case c.To == "":
+		return fmt.Sprintf("Hop %d (%s) is no longer on the path", c.Hop+1, c.From)
+	}
+	return fmt.Sprintf("Hop %d changed from %s to %s", c.Hop+1, c.From, c.To)
+}
+
+// lossState follows the samples of one hop
+type lossState struct {
+	ip       string     // Main responder at the last sample
+	samples  int        // Samples seen for the hop
+	answered int        // Consecutive answers since the last loss
+	lastLoss time.Time  // When the event in progress last lost a probe
+	event    *LossEvent // Event in progress, if any
+}
+
+// LossTracker turns the hops' samples into chronological lists of loss events and path changes,
+// alongside the throughput tests run meanwhile
+// It is not safe for concurrent use
+type LossTracker struct {
+	events     []*LossEvent
+	changes    []PathChange
+	throughput []ThroughputResult
+	hops       map[int]*lossState
+}
+
+// NewLossTracker creates a tracker with no events
+func NewLossTracker() *LossTracker {
+	return &LossTracker{hops: make(map[int]*lossState)}
+}
+
+// Observe records the newest sample of a monitored hop, taken at t, and returns the path change
+// it shows, if the hop's main responder changed
+// Hops without history (discovery updates) are ignored
+func (t *LossTracker) Observe(index int, hop NetworkHop, at time.Time) (PathChange, bool) {
+	if len(hop.LatencyHistory) == 0 {
+		return PathChange{}, false
+	}
+	state, ok := t.hops[index]
+	if !ok {
+		state = &lossState{}
+		t.hops[index] = state
+	}
+	state.samples++
+	var change PathChange
+	changed := false
+	if hop.IP != "" {
+		if state.ip != "" && hop.IP != state.ip {
+			change, changed = PathChange{Hop: index, From: state.ip, To: hop.IP, At: at}, true
+			t.changes = append(t.changes, change)
+			if len(t.changes) > maxLossEvents {
+				t.changes = t.changes[1:]
+			}
+		}
+		state.ip = hop.IP
+	}
+	lost := hop.LatencyHistory[len(hop.LatencyHistory)-1] < 0
+
+	if !lost {
+		if state.event == nil {
+			return change, changed
+		}
+		state.answered++
+		if state.answered >= lossEventRecovery {
+			state.event.End = state.lastLoss
+			state.event = nil
+			state.answered = 0
+		}
+		return change, changed
+	}
+
+	if state.event == nil {
+		state.event = &LossEvent{Hop: index, IP: hop.IP, Start: at, StartSample: state.samples}
+		t.events = append(t.events, state.event)
+		if len(t.events) > maxLossEvents {
+			t.events = t.events[1:]
+		}
+	}
+	// Answers between losses belong to the event; trailing ones that end it don't
+	state.event.Samples += state.answered + 1
+	state.event.Lost++
+	state.lastLoss = at
+	state.answered = 0
+	return change, changed
+}
+
+// RecordPathChanges records the changes a re-trace of the path found
+// Hops may have moved to other rows, so each row's responder is learned afresh rather than
+// reported as another change
+func (t *LossTracker) RecordPathChanges(changes []PathChange) {
+	for _, change := range changes {
+		t.changes = append(t.changes, change)
+		if len(t.changes) > maxLossEvents {
+			t.changes = t.changes[1:]
+		}
+	}
+	for _, state := range t.hops {
+		state.ip = ""
+	}
+}
+
+// RecordThroughput records a throughput test run during the session
+func (t *LossTracker) RecordThroughput(result ThroughputResult) {
+	t.throughput = append(t.throughput, result)
+	if len(t.throughput) > maxLossEvents {
+		t.throughput = t.throughput[1:]
+	}
+}
+
+// Throughput returns the throughput tests, oldest first
+func (t *LossTracker) Throughput() []ThroughputResult {
+	return append([]ThroughputResult(nil), t.throughput...)
+}
+
+// Events returns copies of the loss events, oldest first
+func (t *LossTracker) Events() []LossEvent {
+	events := make([]LossEvent, len(t.events))
+	for i, event := range t.events {
+		events[i] = *event
+	}
+	return events
+}
+
+// PathChanges returns the path changes, oldest first
+func (t *LossTracker) PathChanges() []PathChange {
+	return append([]PathChange(nil), t.changes...)
+}
+
+// SamplesSince returns how many samples the event's hop has taken since the event started
+func (t *LossTracker) SamplesSince(event LossEvent) int {
+	state, ok := t.hops[event.Hop]
+	if !ok {
+		return 0
+	}
+	return state.samples - event.StartSample
+}
@@ -0,0 +1,30 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MPLSLabel is one entry of the MPLS label stack a router reports it received a probe with
+// Routers inside an MPLS core append the stack to their TimeExceeded answers as an ICMP
+// extension (RFC 4950)
+type MPLSLabel struct {
+	Label  int  // Label value
+	Exp    int  // Traffic class, formerly the experimental bits
+	Bottom bool // Bottom of the stack
+	TTL    int  // TTL of the label stack entry
+}
+
+// String formats the entry as label, EXP and TTL
+func (l MPLSLabel) String() string {
+	return fmt.Sprintf("L=%d E=%d TTL=%d", l.Label, l.Exp, l.TTL)
+}
+
+// FormatMPLS formats a label stack top entry first, or returns "" for an empty one
+func FormatMPLS(labels []MPLSLabel) string {
+	entries := make([]string, len(labels))
+	for i, label := range labels {
+		entries[i] = label.String()
+	}
+	return strings.Join(entries, " / ")
+}
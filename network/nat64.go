@@ -0,0 +1,146 @@
+package network
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// nat64LookupTimeout bounds the DNS64 query used to discover the network's NAT64 prefix
+const nat64LookupTimeout = 2 * time.Second
+
+// WellKnownNAT64Prefix is the prefix NAT64 translators use unless the network picks its own (RFC 6052)
+var WellKnownNAT64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// nat64ProbeName only has A records; DNS64 synthesizes AAAA records for it from the
+// network's NAT64 prefix, which reveals the prefix (RFC 7050)
+const nat64ProbeName = "ipv4only.arpa"
+
+var (
+	nat64ProbeIPv4s      = []net.IP{net.IPv4(192, 0, 0, 170), net.IPv4(192, 0, 0, 171)}
+	nat64PrefixLengths   = []int{96, 64, 56, 48, 40, 32}
+	ipv4RouteCheckTarget = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 53} // TEST-NET-1, only reachable by a default route
+)
+
+// NAT64 describes a session whose IPv6 path reaches an IPv4 destination through a NAT64
+// translator, as on IPv6-only networks. The path beyond the translator is IPv4 and hidden
+type NAT64 struct {
+	Prefix netip.Prefix // Prefix the translator maps IPv4 addresses into
+	IPv4   string       // IPv4 address of the destination behind the translator
+}
+
+// Embedded returns the IPv4 address a synthesized address of the prefix stands for
+func (n NAT64) Embedded(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !n.Prefix.Contains(addr) {
+		return "", false
+	}
+	return extractIPv4(net.IP(addr.AsSlice()), n.Prefix.Bits()).String(), true
+}
+
+// detectNAT64 spots an IPv6-only network reaching an IPv4 destination through NAT64
+// An IPv4 destination the host has no route to is mapped into the network's NAT64 prefix,
+// and an IPv6 destination inside that prefix (synthesized by DNS64) is recognised as one
+func (s *Scanner) detectNAT64(dst *net.IPAddr, protocol Protocol) *net.IPAddr {
+	if protocol == ProtocolIPv4 || hasIPv4Route() {
+		return dst
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, nat64LookupTimeout)
+	defer cancel()
+
+	if ip4 := dst.IP.To4(); ip4 != nil {
+		prefix, ok := discoverNAT64Prefix(ctx)
+		if !ok {
+			return dst
+		}
+		s.nat64.Store(NAT64{Prefix: prefix, IPv4: ip4.String()})
+		mapped := &net.IPAddr{IP: embedIPv4(prefix, ip4)}
+		log.Printf("[DEBUG] No IPv4 route; reaching %s through NAT64 as %s\n", ip4, mapped.IP)
+		return mapped
+	}
+
+	addr, ok := netip.AddrFromSlice(dst.IP)
+	if !ok {
+		return dst
+	}
+	prefix := WellKnownNAT64Prefix
+	if !prefix.Contains(addr) {
+		if prefix, ok = discoverNAT64Prefix(ctx); !ok || !prefix.Contains(addr) {
+			return dst
+		}
+	}
+	nat := NAT64{Prefix: prefix, IPv4: extractIPv4(dst.IP, prefix.Bits()).String()}
+	s.nat64.Store(nat)
+	log.Printf("[DEBUG] %s is synthesized by DNS64; the destination is %s behind NAT64\n", dst.IP, nat.IPv4)
+	return dst
+}
+
+// hasIPv4Route reports whether the host can send IPv4 beyond its own networks
+// Connecting a UDP socket only looks up the route; nothing is sent
+func hasIPv4Route() bool {
+	conn, err := net.DialUDP("udp4", nil, ipv4RouteCheckTarget)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// discoverNAT64Prefix finds the network's NAT64 prefix from the addresses DNS64 synthesizes
+// for ipv4only.arpa, reporting false when the network has no DNS64
+func discoverNAT64Prefix(ctx context.Context) (netip.Prefix, bool) {
+	addrs, err := lookupIPAddr(ctx, DNSNAT64, nat64ProbeName)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			continue
+		}
+		for _, bits := range nat64PrefixLengths {
+			embedded := extractIPv4(addr.IP, bits)
+			for _, known := range nat64ProbeIPv4s {
+				if !embedded.Equal(known) {
+					continue
+				}
+				a, _ := netip.AddrFromSlice(addr.IP.To16())
+				return netip.PrefixFrom(a, bits).Masked(), true
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// nat64Offsets returns where the four IPv4 bytes sit in an address of a NAT64 prefix of the
+// given length; bits 64 to 71 are reserved and skipped (RFC 6052)
+func nat64Offsets(bits int) []int {
+	offsets := make([]int, 0, net.IPv4len)
+	for i := bits / 8; len(offsets) < net.IPv4len; i++ {
+		if i == 8 {
+			continue
+		}
+		offsets = append(offsets, i)
+	}
+	return offsets
+}
+
+// embedIPv4 synthesizes the IPv6 address standing for ip4 within a NAT64 prefix
+func embedIPv4(prefix netip.Prefix, ip4 net.IP) net.IP {
+	addr := prefix.Masked().Addr().As16()
+	for i, offset := range nat64Offsets(prefix.Bits()) {
+		addr[offset] = ip4.To4()[i]
+	}
+	return net.IP(addr[:])
+}
+
+// extractIPv4 recovers the IPv4 address embedded in a NAT64 address with a prefix of the given length
+func extractIPv4(ip6 net.IP, bits int) net.IP {
+	ip6 = ip6.To16()
+	ip4 := make(net.IP, net.IPv4len)
+	for i, offset := range nat64Offsets(bits) {
+		ip4[i] = ip6[offset]
+	}
+	return ip4
+}
@@ -0,0 +1,188 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// Probing defaults, used unless overridden with a ScannerOption
+const (
+	DefaultInterval    = 1 * time.Second // Time between monitoring rounds
+	DefaultTimeout     = 3 * time.Second // How long to wait for the answer to a probe
+	DefaultMaxTTL      = 30              // Highest TTL the traceroute tries
+	DefaultLossWindow  = 100             // Latest probes per hop that LossPercent covers
+	DefaultConcurrency = 8               // Hops probed at once while monitoring
+)
+
+// MaxLossWindow is the largest loss window accepted by WithLossWindow
+const MaxLossWindow = 10000
+
+// MaxDSCP is the largest DSCP value accepted by WithDSCP
+const MaxDSCP = 63
+
+// MaxPacketSize is the largest probe packet size accepted by WithPacketSize
+const MaxPacketSize = 65000
+
+// transportHeaderLen is the size of the ICMP or UDP header ahead of a probe's payload
+const transportHeaderLen = 8
+
+// ScannerOption tunes how a Scanner probes; pass options to NewScanner or NewScannerFrom
+type ScannerOption func(*Scanner)
+
+// WithInterval sets the time between monitoring rounds
+func WithInterval(interval time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.interval = interval
+	}
+}
+
+// WithTimeout sets how long to wait for the answer to each probe before counting it as lost
+func WithTimeout(timeout time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.timeout = timeout
+	}
+}
+
+// WithMaxTTL sets the highest TTL the traceroute tries before giving up on reaching the target
+func WithMaxTTL(ttl int) ScannerOption {
+	return func(s *Scanner) {
+		s.maxTTL = ttl
+	}
+}
+
+// WithLossWindow sets how many of each hop's latest probes LossPercent covers
+// Recent loss shows up quickly in a short window and old incidents age out of it; the
+// loss over the whole session stays available as LifetimeLossPercent
+func WithLossWindow(probes int) ScannerOption {
+	return func(s *Scanner) {
+		s.lossWindow = probes
+	}
+}
+
+// WithSource sends probes from a local IP address or from an interface, given by name, so a
+// multi-homed host (VPN and LAN, Wi-Fi and Ethernet) measures the uplink it is asked about;
+// LocalInterfaces lists the choices. An interface sends from its address of the target's IP version
+func WithSource(source string) ScannerOption {
+	return func(s *Scanner) {
+		s.source = source
+	}
+}
+
+// WithPacketSize pads ICMP and UDP probes to the given IP packet size in bytes, headers included
+// Probes are never smaller than their own identifying data; 0 keeps them as small as possible.
+// TCP SYN probes carry no payload and ignore the size
+func WithPacketSize(bytes int) ScannerOption {
+	return func(s *Scanner) {
+		s.packetSize = bytes
+	}
+}
+
+// WithRetraceInterval re-runs discovery every interval while monitoring, so a route that changes
+// mid-session is followed instead of stale hops being probed; 0 keeps the first path
+// Monitoring rounds pause while the path is re-traced
+func WithRetraceInterval(interval time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.retrace = interval
+	}
+}
+
+// WithDSCP marks every probe with a DSCP value, e.g. 46 (EF) as VoIP traffic is marked, to see
+// whether QoS-marked traffic takes another path or gets other treatment than best effort (0)
+func WithDSCP(dscp int) ScannerOption {
+	return func(s *Scanner) {
+		s.dscp = dscp
+	}
+}
+
+// WithIdentityRotation gives probes a fresh ICMP echo ID or UDP source port every monitoring
+// round, so long-running monitoring doesn't look like one endless flow to middleboxes that
+// rate-limit those. Load balancers hashing on the ID or port may then send rounds down different
+// paths, which shows up as responder changes. TCP probes get a new source port for every probe anyway
+func WithIdentityRotation(rotate bool) ScannerOption {
+	return func(s *Scanner) {
+		s.rotate = rotate
+	}
+}
+
+// WithScheduleJitter shifts each monitoring round by a random amount of up to jitter either way,
+// so rounds don't arrive at a fixed period; jitter must be shorter than the interval
+func WithScheduleJitter(jitter time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.jitter = jitter
+	}
+}
+
+// MaxConcurrency is the most hops WithConcurrency lets a session probe at once
+const MaxConcurrency = 64
+
+// WithConcurrency sets how many hops are probed at once while monitoring, so rounds on long
+// paths fit within the interval; 1 probes hops one after another. Only ICMP probes run
+// concurrently, UDP and TCP probes always go one at a time
+func WithConcurrency(workers int) ScannerOption {
+	return func(s *Scanner) {
+		s.concurrency = workers
+	}
+}
+
+// WithCycles ends monitoring after the given number of rounds with an EventCompleted carrying the
+// final hop statistics, for reports and scripts; 0 monitors until Stop. Paused rounds don't count
+// Channels stay open until Stop, which the caller still calls once it has the results
+func WithCycles(cycles int) ScannerOption {
+	return func(s *Scanner) {
+		s.cycles = cycles
+	}
+}
+
+// WithProbeSpacing sends a hop's probes within a round at least spacing apart, so routers that
+// rate-limit the ICMP answers they generate answer each instead of showing loss; 0 sends them
+// back to back. Hops flagged RateLimited get their probes spread further apart regardless
+func WithProbeSpacing(spacing time.Duration) ScannerOption {
+	return func(s *Scanner) {
+		s.spacing = spacing
+	}
+}
+
+// validateOptions checks the probing options before a session starts
+func (s *Scanner) validateOptions() error {
+	switch {
+	case s.interval <= 0:
+		return fmt.Errorf("invalid probe interval: %v", s.interval)
+	case s.timeout <= 0:
+		return fmt.Errorf("invalid probe timeout: %v", s.timeout)
+	case s.retrace < 0:
+		return fmt.Errorf("invalid retrace interval: %v", s.retrace)
+	case s.jitter < 0 || s.jitter >= s.interval:
+		return fmt.Errorf("schedule jitter must be at least 0 and shorter than the interval (%v), got %v", s.interval, s.jitter)
+	case s.maxTTL < 1 || s.maxTTL > 255:
+		return fmt.Errorf("max TTL must be between 1 and 255, got %d", s.maxTTL)
+	case s.packetSize < 0 || s.packetSize > MaxPacketSize:
+		return fmt.Errorf("packet size must be between 0 and %d bytes, got %d", MaxPacketSize, s.packetSize)
+	case s.dscp < 0 || s.dscp > MaxDSCP:
+		return fmt.Errorf("DSCP must be between 0 and %d, got %d", MaxDSCP, s.dscp)
+	case s.concurrency < 1 || s.concurrency > MaxConcurrency:
+		return fmt.Errorf("concurrency must be between 1 and %d hops, got %d", MaxConcurrency, s.concurrency)
+	case s.spacing < 0 || s.spacing >= s.interval:
+		return fmt.Errorf("probe spacing must be at least 0 and shorter than the interval (%v), got %v", s.interval, s.spacing)
+	case s.cycles < 0:
+		return fmt.Errorf("invalid number of cycles: %d", s.cycles)
+	case s.lossWindow < 1 || s.lossWindow > MaxLossWindow:
+		return fmt.Errorf("loss window must be between 1 and %d probes, got %d", MaxLossWindow, s.lossWindow)
+	}
+	return nil
+}
+
+// payloadSize returns the probe payload length that makes packets packetSize bytes long
+func (s *Scanner) payloadSize() int {
+	if s.packetSize == 0 {
+		return 0
+	}
+	return max(s.packetSize-s.family.headerLen-transportHeaderLen, 0)
+}
+
+// padPayload extends data with zeros to size bytes; longer data is returned unchanged
+func padPayload(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	return append(data, make([]byte, size-len(data))...)
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Per-flow load balancers hash header fields to pick an ECMP branch: the addresses, the
+// protocol and, for UDP and TCP, the ports; for ICMP the first four bytes of the header,
+// which include the checksum. Probes keep all of those constant, as Paris traceroute does,
+// and tell themselves apart only through fields the hash ignores, so every probe of a
+// session follows the same branch
+
+// parisChecksumSum is the ones' complement sum every ICMP probe is balanced to, which keeps
+// the ICMP checksum the same for every sequence number and payload
+const parisChecksumSum = 0xa5a5
+
+// onesSum returns the folded 16-bit ones' complement sum of b, padding an odd length with zero
+func onesSum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return uint16(sum)
+}
+
+// balanceWord returns the 16-bit word that brings a ones' complement sum of sum to target
+func balanceWord(sum, target uint16) uint16 {
+	total := uint32(target) + uint32(^sum)
+	for total > 0xffff {
+		total = total&0xffff + total>>16
+	}
+	return uint16(total)
+}
+
+// withBalanceWord appends a zeroed 16-bit word at an even offset of data and returns the
+// data and the word's offset. The ICMP and UDP headers are 8 bytes, so the word stays
+// aligned within the whole message
+func withBalanceWord(data []byte) ([]byte, int) {
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	return append(data, 0, 0), len(data)
+}
+
+// udpChecksum computes the checksum the kernel gives a UDP datagram, pseudo-header included
+func udpChecksum(src, dst net.IP, srcPort, dstPort int, payload []byte) uint16 {
+	length := 8 + len(payload)
+	var pseudo []byte
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		pseudo = append(append(pseudo, src4...), dst4...)
+		pseudo = append(pseudo, 0, 17)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(length))
+	} else {
+		pseudo = append(append(pseudo, src.To16()...), dst.To16()...)
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(length))
+		pseudo = append(pseudo, 0, 0, 0, 17)
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint16(header[0:], uint16(srcPort))
+	binary.BigEndian.PutUint16(header[2:], uint16(dstPort))
+	binary.BigEndian.PutUint16(header[4:], uint16(length))
+
+	sum := uint32(onesSum(pseudo)) + uint32(onesSum(header)) + uint32(onesSum(payload))
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	checksum := ^uint16(sum)
+	if checksum == 0 {
+		return 0xffff // Zero means no checksum, so it is sent as its ones' complement twin
+	}
+	return checksum
+}
+
+// routeSource returns the local address the host sends to dst from, without sending anything
+func routeSource(family *ipFamily, dst *net.IPAddr) (net.IP, error) {
+	conn, err := net.DialUDP(family.udpNet, nil, &net.UDPAddr{IP: dst.IP, Port: udpBasePort, Zone: dst.Zone})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/net/icmp"
+)
+
+func TestICMPProbeChecksumConstant(t *testing.T) {
+	for _, payloadLen := range []int{0, 33, 64, 1400} {
+		p := &icmpProber{family: familyIPv4, token: newProbeToken(), payloadLen: payloadLen}
+		var first uint16
+		for i, seq := range []int{1, 2, 255, 256, 4097, 65535} {
+			probe := &pendingProbe{probeNum: uint32(seq * 7919), seq: seq, ttl: i*5 + 1}
+			b, err := p.marshalProbe(0x1234, probe)
+			if err != nil {
+				t.Fatalf("marshalProbe: %v", err)
+			}
+
+			// The checksum the load balancers hash stays the same...
+			checksum := binary.BigEndian.Uint16(b[2:4])
+			if i == 0 {
+				first = checksum
+			} else if checksum != first {
+				t.Errorf("payload %d, seq %d: checksum %#04x, want %#04x as for the first probe", payloadLen, seq, checksum, first)
+			}
+
+			// ...and is still right for the message, which still carries the probe
+			if sum := internetChecksum(b); sum != 0 {
+				t.Errorf("payload %d, seq %d: message doesn't validate (checksum over it %#04x)", payloadLen, seq, sum)
+			}
+			msg, err := icmp.ParseMessage(1, b)
+			if err != nil {
+				t.Fatalf("ParseMessage: %v", err)
+			}
+			echo, ok := msg.Body.(*icmp.Echo)
+			if !ok || echo.ID != 0x1234 || echo.Seq != seq || !validProbePayload(echo.Data, p.token, probe.probeNum, probe.ttl) {
+				t.Errorf("payload %d, seq %d: probe not recognized in %+v", payloadLen, seq, msg.Body)
+			}
+			if payloadLen > 0 && len(echo.Data) < payloadLen {
+				t.Errorf("payload %d, seq %d: payload is %d bytes", payloadLen, seq, len(echo.Data))
+			}
+		}
+	}
+}
+
+func TestICMPv6ProbeSumConstant(t *testing.T) {
+	// The kernel fills in ICMPv6 checksums over a pseudo-header that is the same for every
+	// probe, so the sum of the message alone must not change
+	p := &icmpProber{family: familyIPv6, token: newProbeToken(), payloadLen: 56}
+	var first uint16
+	for i, seq := range []int{1, 2, 300, 65535} {
+		b, err := p.marshalProbe(0x4321, &pendingProbe{probeNum: uint32(seq + 10), seq: seq, ttl: i + 1})
+		if err != nil {
+			t.Fatalf("marshalProbe: %v", err)
+		}
+		sum := internetChecksum(b)
+		if i == 0 {
+			first = sum
+		} else if sum != first {
+			t.Errorf("seq %d: message sum %#04x, want %#04x as for the first probe", seq, sum, first)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package network
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// historyRow is one row of live CSV history as written to Parquet, with typed columns
+// named like the CSV header so queries work on either
+type historyRow struct {
+	Time                int64   `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Target              string  `parquet:"name=target, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Cycle               int64   `parquet:"name=cycle, type=INT64"`
+	Hop                 int32   `parquet:"name=hop, type=INT32"`
+	IP                  string  `parquet:"name=ip, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	ASN                 int64   `parquet:"name=asn, type=INT64"`
+	ASName              string  `parquet:"name=as_name, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	AvgMs               float64 `parquet:"name=avg_ms, type=DOUBLE"`
+	LossPercent         float64 `parquet:"name=loss_percent, type=DOUBLE"`
+	WindowLossPercent   float64 `parquet:"name=window_loss_percent, type=DOUBLE"`
+	LifetimeLossPercent float64 `parquet:"name=lifetime_loss_percent, type=DOUBLE"`
+	Sent                int64   `parquet:"name=sent, type=INT64"`
+	Received            int64   `parquet:"name=received, type=INT64"`
+	StdDevMs            float64 `parquet:"name=stddev_ms, type=DOUBLE"`
+	JitterMs            float64 `parquet:"name=jitter_ms, type=DOUBLE"`
+	LastMs              float64 `parquet:"name=last_ms, type=DOUBLE"`
+	BestMs              float64 `parquet:"name=best_ms, type=DOUBLE"`
+	WorstMs             float64 `parquet:"name=worst_ms, type=DOUBLE"`
+	P50Ms               float64 `parquet:"name=p50_ms, type=DOUBLE"`
+	P95Ms               float64 `parquet:"name=p95_ms, type=DOUBLE"`
+	P99Ms               float64 `parquet:"name=p99_ms, type=DOUBLE"`
+	MPLSLabels          string  `parquet:"name=mpls_labels, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Responders          string  `parquet:"name=responders, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Status              string  `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// TailFiles returns the daily live CSV files a CSVTail with this prefix wrote into dir, oldest first
+func TailFiles(dir, prefix string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, prefix+"-????-??-??.csv"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths) // Dates sort chronologically
+	return paths, nil
+}
+
+// WriteHistoryParquet converts live CSV files into one ZSTD-compressed Parquet file, which
+// loads far faster than CSV into pandas or DuckDB once history runs to millions of rows
+// Rows are streamed, so the files need not fit in memory. Returns the number of rows written
+func WriteHistoryParquet(w io.Writer, paths []string) (int, error) {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(historyRow), 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create Parquet writer: %v", err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_ZSTD
+
+	rows := 0
+	for _, path := range paths {
+		n, err := appendTailFile(pw, path)
+		rows += n
+		if err != nil {
+			pw.WriteStop()
+			return rows, err
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return rows, fmt.Errorf("failed to finish Parquet file: %v", err)
+	}
+	return rows, nil
+}
+
+// appendTailFile writes the rows of one live CSV file to pw
+// Columns are looked up by header name, so files written before a column existed still load
+func appendTailFile(pw *writer.ParquetWriter, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %v", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	cr := csv.NewReader(file)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	rows := 0
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+		}
+		row, ok := parseHistoryRow(columns, record)
+		if !ok {
+			continue // A row cut short by a crash mid-write
+		}
+		if err := pw.Write(row); err != nil {
+			return rows, fmt.Errorf("failed to write Parquet row: %v", err)
+		}
+		rows++
+	}
+}
+
+// parseHistoryRow converts a live CSV record, reporting false if its time is unreadable
+func parseHistoryRow(columns map[string]int, record []string) (historyRow, bool) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	integer := func(name string) int64 {
+		n, _ := strconv.ParseInt(field(name), 10, 64)
+		return n
+	}
+	float := func(name string) float64 {
+		f, _ := strconv.ParseFloat(field(name), 64)
+		return f
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, field("time"))
+	if err != nil {
+		return historyRow{}, false
+	}
+	return historyRow{
+		Time:                at.UnixMicro(),
+		Target:              field("target"),
+		Cycle:               integer("cycle"),
+		Hop:                 int32(integer("hop")),
+		IP:                  field("ip"),
+		ASN:                 integer("asn"),
+		ASName:              field("as_name"),
+		AvgMs:               float("avg_ms"),
+		LossPercent:         float("loss_percent"),
+		WindowLossPercent:   float("window_loss_percent"),
+		LifetimeLossPercent: float("lifetime_loss_percent"),
+		Sent:                integer("sent"),
+		Received:            integer("received"),
+		StdDevMs:            float("stddev_ms"),
+		JitterMs:            float("jitter_ms"),
+		LastMs:              float("last_ms"),
+		BestMs:              float("best_ms"),
+		WorstMs:             float("worst_ms"),
+		P50Ms:               float("p50_ms"),
+		P95Ms:               float("p95_ms"),
+		P99Ms:               float("p99_ms"),
+		MPLSLabels:          strings.TrimSpace(field("mpls_labels")),
+		Responders:          strings.TrimSpace(field("responders")),
+		Status:              field("status"),
+	}, true
+}
@@ -0,0 +1,209 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// minPathMTU is the smallest datagram every IPv4 host must accept (RFC 791)
+	minPathMTU = 576
+	// pathMTUOverhead is the IPv4 and ICMP echo header bytes in front of an echo's payload
+	pathMTUOverhead = 28
+	// pathMTUAttempts is how many echoes of one size may go unanswered before it counts as too big
+	pathMTUAttempts = 3
+	// pathMTUWait bounds the wait for the answer to one echo
+	pathMTUWait = time.Second
+)
+
+// PathMTUResult reports the largest packet that reached a target without being fragmented
+type PathMTUResult struct {
+	Target   string
+	MTU      int        // Path MTU in bytes, capped at 1500
+	Reported bool       // A router announced the MTU in a fragmentation-needed message
+	Hint     TunnelHint // Encapsulation the MTU is characteristic of, if Tunnel
+	Tunnel   bool
+}
+
+// String renders the result as a short report
+func (r PathMTUResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Path MTU to %s: %d bytes\n", r.Target, r.MTU)
+	if r.Reported {
+		fmt.Fprintf(&b, "Announced by a router on the path (fragmentation needed)\n")
+	}
+	switch {
+	case r.Tunnel:
+		fmt.Fprintf(&b, "\nMTU %d: %s\n", r.MTU, r.Hint.Advice())
+	case r.MTU >= ethernetMTU:
+		fmt.Fprintf(&b, "\nFull-size Ethernet frames pass; no tunnel overhead on the path\n")
+	default:
+		fmt.Fprintf(&b, "\n%d bytes below Ethernet, not an MTU typical of a known tunnel; clamp TCP MSS to %d for IPv4 if large transfers stall\n",
+			ethernetMTU-r.MTU, r.MTU-40)
+	}
+	return b.String()
+}
+
+// ProbePathMTU finds the path MTU to an IPv4 target by sending echo requests of different sizes
+// with the Don't Fragment bit set: the largest answered is the MTU, unless a router announces a
+// smaller one first. The result is checked against the MTUs common tunnels leave (see LikelyTunnel)
+func ProbePathMTU(ctx context.Context, target string) (PathMTUResult, error) {
+	result := PathMTUResult{Target: target}
+
+	addrs, err := dnsResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+		}
+		return result, fmt.Errorf("failed to resolve hostname: %v", err)
+	}
+	var dst *net.IPAddr
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			dst = &net.IPAddr{IP: addr.IP.To4()}
+			break
+		}
+	}
+	if dst == nil {
+		return result, fmt.Errorf("%s has no IPv4 address; IPv6 routers never fragment, so there is no DF bit to probe with", target)
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return result, listenError(err)
+	}
+	defer conn.Close()
+	if err := setDontFragment(conn); err != nil {
+		return result, fmt.Errorf("failed to set the Don't Fragment bit: %v", err)
+	}
+	// Cancellation ends the wait in progress
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	sweep := &mtuSweep{conn: conn, dst: dst, id: allocateEchoID(), token: newProbeToken()}
+	defer releaseEchoID(sweep.id)
+
+	// Binary search between a size that passed and one that didn't
+	low, high := 0, ethernetMTU+1
+	for size := minPathMTU; low+1 < high; size = (low + high) / 2 {
+		passed, mtu, err := sweep.try(ctx, size)
+		if err != nil {
+			return result, err
+		}
+		switch {
+		case passed:
+			low = size
+		case mtu > low && mtu < size:
+			// The router's own MTU is the next size to try
+			result.Reported = true
+			high = size
+			if passed, _, err = sweep.try(ctx, mtu); err != nil {
+				return result, err
+			}
+			if passed {
+				low = mtu
+			} else {
+				high = mtu
+			}
+		default:
+			high = size
+		}
+		if low == 0 && high == minPathMTU {
+			return result, fmt.Errorf("no answers from %s even to %d-byte echo requests", dst.IP, minPathMTU)
+		}
+	}
+	result.MTU = low
+	result.Hint, result.Tunnel = LikelyTunnel(result.MTU)
+	return result, nil
+}
+
+// mtuSweep sends the echo requests of a path MTU probe
+type mtuSweep struct {
+	conn  *icmp.PacketConn
+	dst   *net.IPAddr
+	id    int
+	token []byte
+	seq   int
+}
+
+// try sends echoes of size bytes until one is answered or pathMTUAttempts go unanswered
+// It also returns the next-hop MTU of a router that reported the echo too big, 0 if none did
+func (m *mtuSweep) try(ctx context.Context, size int) (bool, int, error) {
+	for range pathMTUAttempts {
+		if ctx.Err() != nil {
+			return false, 0, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+		}
+		m.seq++
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Body: &icmp.Echo{ID: m.id, Seq: m.seq, Data: padPayload(probePayload(m.token, uint32(m.seq), 0), size-pathMTUOverhead)},
+		}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to marshal message: %v", err)
+		}
+		if _, err := m.conn.WriteTo(b, m.dst); err != nil {
+			// The packet doesn't fit the outgoing interface
+			if errors.Is(err, syscall.EMSGSIZE) {
+				return false, 0, nil
+			}
+			return false, 0, fmt.Errorf("failed to send probe: %v", err)
+		}
+		passed, mtu, answered := m.await()
+		if answered {
+			return passed, mtu, nil
+		}
+	}
+	if ctx.Err() != nil {
+		return false, 0, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+	}
+	return false, 0, nil
+}
+
+// await reads the answer to the last echo: a reply, or a fragmentation-needed message quoting it
+func (m *mtuSweep) await() (passed bool, mtu int, answered bool) {
+	buf := make([]byte, ethernetMTU+1)
+	m.conn.SetReadDeadline(time.Now().Add(pathMTUWait))
+	for {
+		n, peer, err := m.conn.ReadFrom(buf)
+		if err != nil {
+			return false, 0, false
+		}
+		msg, err := icmp.ParseMessage(1, buf[:n]) // 1 for ICMPv4
+		if err != nil {
+			continue
+		}
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if msg.Type == ipv4.ICMPTypeEchoReply && body.ID == m.id && body.Seq == m.seq &&
+				extractIPFromAddr(peer) == m.dst.IP.String() && validProbePayload(body.Data, m.token, uint32(m.seq), 0) {
+				return true, 0, true
+			}
+		case *icmp.DstUnreach:
+			// Code 4 is fragmentation needed; the next-hop MTU is in the second half of the unused word (RFC 1191)
+			if msg.Code != 4 || !m.quotes(body.Data) {
+				continue
+			}
+			return false, int(binary.BigEndian.Uint16(buf[6:8])), true
+		}
+	}
+}
+
+// quotes reports whether the packet an ICMP error quotes is the last echo sent
+func (m *mtuSweep) quotes(data []byte) bool {
+	quoted, ok := parseQuotedPacket(familyIPv4, data)
+	if !ok || quoted.proto != 1 || !quoted.dst.Equal(m.dst.IP) {
+		return false
+	}
+	return binary.BigEndian.Uint16(quoted.transport[4:6]) == uint16(m.id) &&
+		binary.BigEndian.Uint16(quoted.transport[6:8]) == uint16(m.seq)
+}
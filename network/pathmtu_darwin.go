@@ -0,0 +1,32 @@
+package network
+
+import (
+	"syscall"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/sys/unix"
+)
+
+// setDontFragment sets the DF bit on the socket's packets
+func setDontFragment(conn *icmp.PacketConn) error {
+	p4 := conn.IPv4PacketConn()
+	if p4 == nil {
+		return unix.EINVAL
+	}
+	sc, ok := p4.PacketConn.(syscall.Conn)
+	if !ok {
+		return unix.EINVAL
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_DONTFRAG, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
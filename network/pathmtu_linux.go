@@ -0,0 +1,23 @@
+package network
+
+import (
+	"golang.org/x/net/icmp"
+	"golang.org/x/sys/unix"
+)
+
+// setDontFragment sets the DF bit on the socket's packets and makes the kernel send them
+// whatever path MTU it has cached, so the probe measures the path afresh
+func setDontFragment(conn *icmp.PacketConn) error {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package network
+
+import (
+	"errors"
+
+	"golang.org/x/net/icmp"
+)
+
+// setDontFragment is unsupported: raw ICMP sockets here can't set the DF bit
+func setDontFragment(conn *icmp.PacketConn) error {
+	return errors.New("not supported on this platform")
+}
@@ -0,0 +1,172 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// ProbeMethod selects how TTL-limited probes are sent
+type ProbeMethod string
+
+const (
+	ProbeICMP ProbeMethod = "ICMP"    // ICMP echo requests
+	ProbeUDP  ProbeMethod = "UDP"     // UDP datagrams to high ports, as classic traceroute sends
+	ProbeTCP  ProbeMethod = "TCP SYN" // TCP connection attempts, for paths that filter ICMP
+)
+
+// ProbeMethods lists the probe methods in display order
+var ProbeMethods = []ProbeMethod{ProbeICMP, ProbeUDP, ProbeTCP}
+
+// DefaultTCPPort is the port TCP probes target unless another is configured
+const DefaultTCPPort = 443
+
+// SocketAccess describes which sockets a session could open, and so what it can measure
+type SocketAccess string
+
+const (
+	AccessUnknown      SocketAccess = "Unknown"
+	AccessRaw          SocketAccess = "Raw sockets"
+	AccessUnprivileged SocketAccess = "Unprivileged ICMP sockets (ICMP probes only; run as root, or on Linux grant CAP_NET_RAW, for UDP and TCP probes and kernel send timestamps)"
+	AccessIPHelper     SocketAccess = "Windows ICMP helper API (ICMP probes only; run as administrator for UDP and TCP probes)"
+)
+
+// ProbeReply is the answer to a single TTL-limited probe
+type ProbeReply struct {
+	Latency    float64         // RTT in milliseconds
+	Responder  string          // Address of the router or destination that answered
+	Reached    bool            // The destination itself answered
+	Clock      ClockSource     // How the RTT was timed
+	Size       int             // Bytes in the ICMP answer, 0 for TCP handshake answers
+	TTL        int             // TTL (IPv4) or hop limit (IPv6) the answer arrived with, 0 if unknown
+	Extensions []ICMPExtension // ICMP extension objects of the answer, such as MPLS labels (RFC 4884)
+	Status     HopStatus       // HopOK, or why a Destination Unreachable rejected the probe
+}
+
+// Prober sends TTL-limited probes toward a target and waits for their answers
+// The scanner keeps one probe in flight at a time, so implementations need not be safe
+// for concurrent use, apart from Close which may be called while AwaitReply is waiting
+type Prober interface {
+	// SendProbe sends a probe that expires after ttl hops
+	SendProbe(ttl int) error
+	// AwaitReply waits until deadline for the answer to the last probe sent
+	// Returns false if nothing answered it in time
+	AwaitReply(deadline time.Time) (ProbeReply, bool)
+	// Close releases the prober's sockets
+	Close() error
+}
+
+// newProber creates the prober for the session's probe method
+func (s *Scanner) newProber() (Prober, error) {
+	switch s.method {
+	case ProbeUDP:
+		if err := checkRawSockets(); err != nil {
+			return nil, err
+		}
+		return newUDPProber(s.family, s.dstAddr, s.source, s.payloadSize(), s.dscp)
+	case ProbeTCP:
+		if err := checkRawSockets(); err != nil {
+			return nil, err
+		}
+		return newTCPProber(s.family, s.dstAddr, s.source, s.tcpPort, s.dscp)
+	default:
+		if prober, ok, err := newSystemICMPProber(s.family, s.dstAddr, s.source, s.payloadSize(), s.dscp); ok {
+			return prober, err
+		}
+		return newICMPProber(s.family, s.dstAddr, s.source, s.payloadSize(), s.dscp, func() {
+			s.sendStatus(StatusIDClash)
+		})
+	}
+}
+
+// concurrentProber is implemented by probers that can have many probes in flight at once
+type concurrentProber interface {
+	// probeContext sends a probe that expires after ttl hops and waits for its answer until ctx is done
+	// Returns false if nothing answered in time, and an error if the probe couldn't be sent
+	probeContext(ctx context.Context, ttl int) (ProbeReply, bool, error)
+}
+
+// interrupter is implemented by serial probers whose AwaitReply can be ended early
+type interrupter interface {
+	// interrupt ends the wait in progress, and any that follows, with no answer
+	// It may be called from another goroutine at any time, also before the wait begins
+	interrupt()
+}
+
+// duplicateCounter is implemented by probers that recognise duplicate answers to their probes
+type duplicateCounter interface {
+	// takeDuplicates returns how many duplicate answers probes with ttl got since the last call
+	takeDuplicates(ttl int) int
+}
+
+// accessReporter is implemented by the built-in probers to report which sockets they use
+type accessReporter interface {
+	socketAccess() SocketAccess
+}
+
+// listenError explains a failure to open a raw ICMP socket
+func listenError(err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("raw sockets need root or CAP_NET_RAW; only ICMP probes can run without them: %w", err)
+	}
+	return fmt.Errorf("failed to create ICMP connection: %w", err)
+}
+
+// awaitQuotedReply reads conn until its deadline for an ICMP error quoting our UDP or TCP probe
+// The probe is recognised by its destination, transport protocol and ports, and isStale, if
+// set, rejects quotes of earlier probes. Routers answer with TimeExceeded; an unreachable
+// from the destination itself means it was reached, and one from a router why the path ends there
+func awaitQuotedReply(conn *icmp.PacketConn, family *ipFamily, dst net.IP, proto, srcPort, dstPort int, sentAt time.Time, isStale func(quotedPacket) bool) (ProbeReply, bool) {
+	buf := make([]byte, 1500) // MTU size
+	for {
+		pkt, err := recvProbe(conn, buf)
+		if err != nil {
+			return ProbeReply{}, false // Deadline reached or reader stopped
+		}
+
+		recvMsg, err := icmp.ParseMessage(family.proto, buf[:pkt.n])
+		if err != nil {
+			continue
+		}
+		var data []byte
+		switch body := recvMsg.Body.(type) {
+		case *icmp.TimeExceeded:
+			data = body.Data
+		case *icmp.DstUnreach:
+			data = body.Data
+		default:
+			continue
+		}
+		quoted, ok := parseQuotedPacket(family, data)
+		if !ok || quoted.proto != proto || !quoted.dst.Equal(dst) {
+			continue
+		}
+		if src, dport := quoted.ports(); src != srcPort || dport != dstPort {
+			continue
+		}
+		if isStale != nil && isStale(quoted) {
+			continue
+		}
+
+		elapsed := pkt.at.Sub(sentAt)
+		responder := extractIPFromAddr(pkt.peer)
+		reached := recvMsg.Type == family.destUnreachable && net.ParseIP(responder).Equal(dst)
+		log.Printf("[DEBUG] Probe from port %d answered with %v by %s (%.2fms)\n", srcPort, recvMsg.Type, responder, elapsed.Seconds()*1000)
+		return ProbeReply{
+			Latency:    elapsed.Seconds() * 1000,
+			Responder:  responder,
+			Reached:    reached,
+			Clock:      clockSource(false, pkt.kernelTime),
+			Size:       pkt.n,
+			TTL:        pkt.ttl,
+			Extensions: parseExtensions(family, buf[:pkt.n]),
+			Status:     replyStatus(family, recvMsg, reached),
+		}, true
+	}
+}
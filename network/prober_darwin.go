@@ -0,0 +1,22 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// newSystemICMPProber returns false, as macOS ICMP probes use sockets; without root they use
+// an ICMP datagram socket, see rawICMPDenied
+func newSystemICMPProber(family *ipFamily, dst *net.IPAddr, source string, payloadLen, dscp int) (Prober, bool, error) {
+	return nil, false, nil
+}
+
+// checkRawSockets reports why raw sockets, which UDP and TCP probes need, can't be opened
+// macOS only opens them for root, and has no datagram equivalent for UDP and TCP probes
+func checkRawSockets() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	return fmt.Errorf("UDP and TCP probes need raw sockets, which macOS only opens for root; ICMP probes run without them: %w", os.ErrPermission)
+}
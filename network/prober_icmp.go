@@ -0,0 +1,428 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// recentProbes is how many finished probes the ICMP prober remembers, to tell duplicate answers
+// from late answers to probes that already timed out; it divides the 16-bit sequence space
+const recentProbes = 1024
+
+// icmpProber probes with ICMP echo requests
+// A single receiver goroutine reads the socket and hands each answer to the pending probe
+// it belongs to, found by the echo sequence number
+type icmpProber struct {
+	family      *ipFamily
+	dst         *net.IPAddr
+	conn        *icmp.PacketConn
+	dgram       bool                        // conn is an unprivileged ICMP datagram socket
+	echoID      int                         // ICMP echo ID reserved for this prober
+	replyID     int                         // Echo ID our replies carry, which the kernel may rewrite
+	token       []byte                      // Random token embedded in this prober's payloads
+	payloadLen  int                         // Length probe payloads are padded to
+	probeNum    uint32                      // Number of the most recent probe sent
+	last        *pendingProbe               // Probe sent by SendProbe, awaited by AwaitReply
+	onForeignID func()                      // Called once when foreign echo traffic uses our ID
+	foreignIDs  bool                        // Foreign echo traffic was reported; only the receiver uses it
+	mu          sync.Mutex                  // Guards probeNum, echoID, replyID and pending, shared with the receiver
+	sendMu      sync.Mutex                  // Keeps setting a probe's TTL and sending it together
+	pending     map[int]*pendingProbe       // Probes awaiting an answer, by echo sequence number
+	finished    [recentProbes]finishedProbe // Probes no longer awaited, by probe number modulo recentProbes
+	duplicates  map[int]int                 // Duplicate answers by TTL, not yet collected by takeDuplicates
+	done        chan struct{}               // Closed when the receiver stops, as the socket was closed
+}
+
+// pendingProbe is a probe sent and not yet answered
+type pendingProbe struct {
+	probeNum   uint32          // Number of the probe, carried in its payload
+	seq        int             // Echo sequence number; numbering probes rather than TTLs tells late answers apart
+	ttl        int             // TTL the probe was sent with, carried in its payload
+	sentAt     time.Time       // When the probe left
+	kernelSend bool            // sentAt is a kernel transmit timestamp
+	answer     chan icmpAnswer // Receives the probe's answer; buffered so the receiver never blocks
+}
+
+// finishedProbe is a probe no longer awaited, remembered to classify answers still arriving for it
+type finishedProbe struct {
+	probeNum uint32 // Number of the probe, 0 for an empty slot
+	ttl      int    // TTL the probe was sent with
+	answered bool   // The probe got its answer; false if it timed out or was never sent
+}
+
+// icmpAnswer is an ICMP message the receiver matched to a pending probe
+type icmpAnswer struct {
+	pkt  receivedPacket
+	msg  *icmp.Message
+	data []byte // The message as received, for its extensions
+}
+
+// newICMPProber opens a raw ICMP socket and reserves an echo ID for probing dst
+// Without raw socket access it falls back to an unprivileged ICMP datagram socket, which
+// macOS users other than root start with. Probes carry the DSCP value dscp
+func newICMPProber(family *ipFamily, dst *net.IPAddr, source string, payloadLen, dscp int, onForeignID func()) (*icmpProber, error) {
+	p := &icmpProber{
+		family:      family,
+		dst:         dst,
+		payloadLen:  payloadLen,
+		echoID:      allocateEchoID(),
+		token:       newProbeToken(),
+		onForeignID: onForeignID,
+		pending:     make(map[int]*pendingProbe),
+		duplicates:  make(map[int]int),
+		done:        make(chan struct{}),
+	}
+	p.replyID = p.echoID
+
+	var conn *icmp.PacketConn
+	err := rawICMPDenied()
+	if err == nil {
+		conn, err = family.listen(source)
+	}
+	if errors.Is(err, os.ErrPermission) && unprivilegedICMPSupported {
+		log.Printf("[DEBUG] Raw ICMP socket denied, falling back to an unprivileged ICMP socket: %v\n", err)
+		conn, err = family.listenUnprivileged(source)
+		if err != nil {
+			releaseEchoID(p.echoID)
+			return nil, fmt.Errorf("%s: %w", dgramDeniedHint, err)
+		}
+		p.dgram = true
+	}
+	if err != nil {
+		releaseEchoID(p.echoID)
+		return nil, listenError(err)
+	}
+	p.conn = conn
+	if err := family.setDSCP(conn, dscp); err != nil {
+		conn.Close()
+		releaseEchoID(p.echoID)
+		return nil, fmt.Errorf("failed to set DSCP %d: %v", dscp, err)
+	}
+
+	if p.dgram {
+		p.replyID = dgramEchoID(conn, p.echoID)
+		if err := enableDgramErrors(conn); err != nil {
+			conn.Close()
+			releaseEchoID(p.echoID)
+			return nil, fmt.Errorf("failed to enable ICMP errors on unprivileged socket: %v", err)
+		}
+	} else if err := enableTimestamps(conn); err != nil {
+		log.Printf("[DEBUG] Kernel transmit timestamps unavailable: %v\n", err)
+	}
+	go p.receive()
+	return p, nil
+}
+
+// socketAccess reports whether the prober runs on a raw or an unprivileged socket
+func (p *icmpProber) socketAccess() SocketAccess {
+	if p.dgram {
+		return AccessUnprivileged
+	}
+	return AccessRaw
+}
+
+// SendProbe sends an echo request that expires after ttl hops
+func (p *icmpProber) SendProbe(ttl int) error {
+	probe, err := p.send(ttl)
+	p.last = probe
+	return err
+}
+
+// AwaitReply waits until the receiver hands over the TimeExceeded, Destination Unreachable or
+// echo reply answering the last probe
+func (p *icmpProber) AwaitReply(deadline time.Time) (ProbeReply, bool) {
+	if p.last == nil {
+		return ProbeReply{}, false
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	return p.await(ctx, p.last)
+}
+
+// probeContext sends an echo request that expires after ttl hops and waits for its answer
+// until ctx is done; any number of calls may run at once
+func (p *icmpProber) probeContext(ctx context.Context, ttl int) (ProbeReply, bool, error) {
+	probe, err := p.send(ttl)
+	if err != nil {
+		return ProbeReply{}, false, err
+	}
+	reply, ok := p.await(ctx, probe)
+	return reply, ok, nil
+}
+
+// send registers a probe with the receiver and sends it
+func (p *icmpProber) send(ttl int) (*pendingProbe, error) {
+	log.Printf("[DEBUG] Sending PING packet to %s with TTL=%d\n", p.dst.IP.String(), ttl)
+
+	// Register the probe before sending, as the answer may arrive before WriteTo returns
+	p.mu.Lock()
+	p.probeNum++
+	probe := &pendingProbe{probeNum: p.probeNum, seq: int(p.probeNum & 0xffff), ttl: ttl, answer: make(chan icmpAnswer, 1)}
+	p.pending[probe.seq] = probe
+	echoID := p.echoID
+	p.mu.Unlock()
+
+	// Create ICMP Message. Type will be Echo Request
+	msgBytes, err := p.marshalProbe(echoID, probe)
+	if err != nil {
+		p.forget(probe, false)
+		return nil, fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	// The TTL is a socket option, so setting it and sending must not interleave with other probes
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	if err := p.family.setTTL(p.conn, ttl); err != nil {
+		p.forget(probe, false)
+		return nil, fmt.Errorf("failed to set TTL: %v", err)
+	}
+
+	// Send the message; datagram sockets are addressed like UDP and share the error
+	// queue with ICMP errors, so they skip kernel transmit timestamps
+	if p.dgram {
+		probe.sentAt = time.Now()
+		_, err = p.conn.WriteTo(msgBytes, &net.UDPAddr{IP: p.dst.IP, Zone: p.dst.Zone})
+	} else {
+		probe.sentAt, probe.kernelSend, err = sendProbe(p.conn, msgBytes, p.dst)
+	}
+	if err != nil {
+		p.forget(probe, false)
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	log.Printf("[DEBUG] Sent PING packet with TTL=%d (ID=%d, Seq=%d)\n", ttl, echoID, probe.seq)
+	return probe, nil
+}
+
+// await waits until the receiver hands over the TimeExceeded, Destination Unreachable or
+// echo reply answering a probe, or until ctx is done
+func (p *icmpProber) await(ctx context.Context, probe *pendingProbe) (ProbeReply, bool) {
+	var answer icmpAnswer
+	select {
+	case answer = <-probe.answer:
+		p.forget(probe, true)
+	case <-ctx.Done():
+		p.forget(probe, false)
+		log.Printf("[DEBUG] PING TTL=%d: Timeout (no response before the deadline)\n", probe.ttl)
+		return ProbeReply{}, false
+	case <-p.done:
+		p.forget(probe, false)
+		return ProbeReply{}, false // Closed
+	}
+
+	// Intermediate hops answer with TimeExceeded, the destination with EchoReply; a router
+	// that can't forward the probe answers with Destination Unreachable
+	pkt, msg := answer.pkt, answer.msg
+	reply := ProbeReply{
+		Latency:   pkt.at.Sub(probe.sentAt).Seconds() * 1000,
+		Responder: extractIPFromAddr(pkt.peer),
+		Clock:     clockSource(probe.kernelSend, pkt.kernelTime),
+		Size:      pkt.n,
+		TTL:       pkt.ttl,
+		Status:    HopOK,
+	}
+	switch msg.Type {
+	case p.family.echoReply:
+		reply.Reached = true
+	case p.family.timeExceeded:
+		reply.Extensions = parseExtensions(p.family, answer.data)
+	case p.family.destUnreachable:
+		reply.Reached = net.ParseIP(reply.Responder).Equal(p.dst.IP)
+		reply.Status = replyStatus(p.family, msg, reply.Reached)
+		reply.Extensions = parseExtensions(p.family, answer.data)
+		log.Printf("[DEBUG] PING TTL=%d: %s from %s\n", probe.ttl, reply.Status, reply.Responder)
+	}
+	log.Printf("[DEBUG] PING TTL=%d: %v from %s (%.2fms)\n", probe.ttl, msg.Type, reply.Responder, reply.Latency)
+	return reply, true
+}
+
+// forget drops a probe from the demultiplexer, remembering whether it was answered; answers
+// arriving later are discarded, duplicates of its answer counted
+func (p *icmpProber) forget(probe *pendingProbe, answered bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, probe.seq)
+	p.finished[probe.probeNum%recentProbes] = finishedProbe{probeNum: probe.probeNum, ttl: probe.ttl, answered: answered}
+}
+
+// takeDuplicates returns how many duplicate answers probes with ttl got since the last call
+func (p *icmpProber) takeDuplicates(ttl int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := p.duplicates[ttl]
+	delete(p.duplicates, ttl)
+	return n
+}
+
+// unawaited sorts out an answer to a probe no longer awaited: a second answer to an answered
+// probe is counted as a duplicate, an answer to a probe that timed out is dropped, since
+// counting it would credit the round it arrived in
+func (p *icmpProber) unawaited(msg *icmp.Message, echo *icmp.Echo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f := p.finished[echo.Seq%recentProbes]
+	signed := msg.Type == p.family.echoReply || hasProbeSignature(echo.Data)
+	switch {
+	case f.probeNum == 0 || int(f.probeNum&0xffff) != echo.Seq,
+		signed && !validProbePayload(echo.Data, p.token, f.probeNum, f.ttl):
+		log.Printf("[DEBUG] Ignoring %v for probe seq %d, no longer awaited\n", msg.Type, echo.Seq)
+	case f.answered:
+		log.Printf("[DEBUG] Duplicate %v for probe %d (TTL=%d)\n", msg.Type, f.probeNum, f.ttl)
+		p.duplicates[f.ttl]++
+	default:
+		log.Printf("[DEBUG] Ignoring late %v for probe %d (TTL=%d), which timed out\n", msg.Type, f.probeNum, f.ttl)
+	}
+}
+
+// receive reads every ICMP message arriving on the socket until it is closed, handing the
+// answers to pending probes over to their waiters
+// One reader per socket replaces read deadlines per probe, so the socket never sits unread
+// between probes and answers are matched however many probes are in flight
+func (p *icmpProber) receive() {
+	defer close(p.done)
+	for {
+		buf := make([]byte, 1500) // MTU size
+		pkt, err := p.recv(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("[DEBUG] ICMP receive failed: %v\n", err)
+			continue
+		}
+		p.dispatch(pkt, buf[:pkt.n])
+	}
+}
+
+// dispatch matches an ICMP message to the pending probe it answers, by echo ID and sequence
+// number, and hands it to the probe's waiter; messages for anyone else are dropped
+func (p *icmpProber) dispatch(pkt receivedPacket, data []byte) {
+	msg, err := icmp.ParseMessage(p.family.proto, data)
+	if err != nil {
+		log.Printf("[DEBUG] Failed to parse ICMP message from %v: %v\n", pkt.peer, err)
+		return
+	}
+
+	var echo *icmp.Echo
+	switch msg.Type {
+	case p.family.echoReply:
+		echo, _ = msg.Body.(*icmp.Echo)
+	case p.family.timeExceeded, p.family.destUnreachable:
+		// Other traceroutes on the same host receive the same errors on their raw sockets
+		echo = p.quotedEcho(msg.Body)
+	}
+	if echo == nil {
+		return
+	}
+
+	p.mu.Lock()
+	replyID := p.replyID
+	probe := p.pending[echo.Seq]
+	p.mu.Unlock()
+	if echo.ID != replyID {
+		return
+	}
+
+	// Replies carrying our ID without our payload signature mean another tool is using the
+	// same ID, which is reported once since it would otherwise corrupt statistics
+	isReply := msg.Type == p.family.echoReply
+	if isReply && !hasProbeSignature(echo.Data) {
+		log.Printf("[DEBUG] Ignoring echo reply with our ID (%d) but a foreign payload\n", replyID)
+		if !p.foreignIDs {
+			p.foreignIDs = true
+			p.onForeignID()
+		}
+		return
+	}
+	if probe == nil {
+		p.unawaited(msg, echo)
+		return
+	}
+	// Routers quoting more than RFC 792 requires let the payload of errors be checked too
+	if (isReply || hasProbeSignature(echo.Data)) && !validProbePayload(echo.Data, p.token, probe.probeNum, probe.ttl) {
+		log.Printf("[DEBUG] Ignoring %v that doesn't match probe %d (stale, other session or forged)\n", msg.Type, probe.probeNum)
+		return
+	}
+
+	select {
+	case probe.answer <- icmpAnswer{pkt: pkt, msg: msg, data: data}:
+	default: // Already answered, its waiter not yet done
+		p.mu.Lock()
+		p.duplicates[probe.ttl]++
+		p.mu.Unlock()
+	}
+}
+
+// marshalProbe builds the echo request for a probe
+// A balance word in the payload offsets the changing sequence number and probe number,
+// so the checksum, which load balancers hash, is the same for every probe
+func (p *icmpProber) marshalProbe(echoID int, probe *pendingProbe) ([]byte, error) {
+	data, balance := withBalanceWord(probePayload(p.token, probe.probeNum, probe.ttl))
+	data = padPayload(data, p.payloadLen)
+	msg := icmp.Message{
+		Type: p.family.echoRequest,
+		Code: 0,
+		Body: &icmp.Echo{ID: echoID, Seq: probe.seq, Data: data},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	// ICMPv6 checksums are filled in by the kernel over a pseudo-header that is constant for the session
+	b[2], b[3] = 0, 0
+	binary.BigEndian.PutUint16(data[balance:], balanceWord(onesSum(b), parisChecksumSum))
+	return msg.Marshal(nil)
+}
+
+// recv reads the next ICMP message for the prober's socket
+func (p *icmpProber) recv(buf []byte) (receivedPacket, error) {
+	if p.dgram {
+		return recvDgram(p.conn, p.family, buf)
+	}
+	return recvProbe(p.conn, buf)
+}
+
+// Close releases the echo ID and closes the socket
+func (p *icmpProber) Close() error {
+	releaseEchoID(p.echoID)
+	return p.conn.Close()
+}
+
+// quotedEcho returns the echo request quoted in a TimeExceeded or Destination Unreachable,
+// nil if it quotes something else
+func (p *icmpProber) quotedEcho(body icmp.MessageBody) *icmp.Echo {
+	var data []byte
+	switch body := body.(type) {
+	case *icmp.TimeExceeded:
+		data = body.Data
+	case *icmp.DstUnreach:
+		data = body.Data
+	default:
+		return nil
+	}
+
+	// Linux datagram sockets hand back the echo request without its IP header
+	quotedEcho := data
+	if !p.dgram || dgramQuotesIPHeader {
+		quoted, ok := parseQuotedPacket(p.family, data)
+		if !ok || quoted.proto != p.family.proto || !quoted.dst.Equal(p.dst.IP) {
+			return nil
+		}
+		quotedEcho = quoted.transport
+	}
+
+	request, err := icmp.ParseMessage(p.family.proto, quotedEcho)
+	if err != nil || request.Type != p.family.echoRequest {
+		return nil
+	}
+	echo, _ := request.Body.(*icmp.Echo)
+	return echo
+}
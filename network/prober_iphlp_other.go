@@ -0,0 +1,15 @@
+//go:build !windows && !darwin
+
+package network
+
+import "net"
+
+// newSystemICMPProber returns the platform's own ICMP prober; false where ICMP probes use sockets
+func newSystemICMPProber(family *ipFamily, dst *net.IPAddr, source string, payloadLen, dscp int) (Prober, bool, error) {
+	return nil, false, nil
+}
+
+// checkRawSockets reports why raw sockets can't be opened, nil to try them and see
+func checkRawSockets() error {
+	return nil
+}
@@ -0,0 +1,345 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The ICMP helper API of iphlpapi.dll sends echo requests with any TTL and hands back the
+// TimeExceeded answers, without the administrator rights raw sockets need on Windows, whose
+// firewall also keeps ICMP errors from raw sockets unless a rule lets them in
+var (
+	iphlpapi            = windows.NewLazySystemDLL("iphlpapi.dll")
+	procIcmpCreateFile  = iphlpapi.NewProc("IcmpCreateFile")
+	procIcmp6CreateFile = iphlpapi.NewProc("Icmp6CreateFile")
+	procIcmpCloseHandle = iphlpapi.NewProc("IcmpCloseHandle")
+	procIcmpSendEcho2Ex = iphlpapi.NewProc("IcmpSendEcho2Ex")
+	procIcmp6SendEcho2  = iphlpapi.NewProc("Icmp6SendEcho2")
+)
+
+// IP status codes of the ICMP helper API (ipexport.h); IPv6 reuses some with other meanings
+const (
+	ipSuccess             = 0
+	ipDestNetUnreachable  = 11002 // IP_DEST_NO_ROUTE for IPv6
+	ipDestHostUnreachable = 11003 // IP_DEST_ADDR_UNREACHABLE for IPv6
+	ipDestProtUnreachable = 11004 // IP_DEST_PROHIBITED for IPv6
+	ipDestPortUnreachable = 11005
+	ipPacketTooBig        = 11009
+	ipReqTimedOut         = 11010
+	ipTTLExpiredTransit   = 11013 // IP_HOP_LIMIT_EXCEEDED for IPv6
+	ipTTLExpiredReassem   = 11014
+	ipDestScopeMismatch   = 11045
+	ipStatusBase          = 11000
+	ipStatusMax           = 11999
+)
+
+// Offsets into ICMPV6_ECHO_REPLY, whose packed IPV6_ADDRESS_EX is followed by aligned fields
+const (
+	icmp6ReplyAddress = 6  // sin6_addr, after sin6_port and sin6_flowinfo
+	icmp6ReplyStatus  = 28 // Status, after the 26-byte address padded to 4 bytes
+	icmp6ReplySize    = 36
+)
+
+// ioStatusBlockSize is the room the API wants in the reply buffer besides the replies
+const ioStatusBlockSize = 16
+
+// ipOptionInformation is IP_OPTION_INFORMATION: the TTL and TOS of a request or of its answer
+type ipOptionInformation struct {
+	TTL         uint8
+	TOS         uint8
+	Flags       uint8
+	OptionsSize uint8
+	OptionsData uintptr
+}
+
+// icmpEchoReply is ICMP_ECHO_REPLY, the answer to an IPv4 echo request or the ICMP error it caused
+type icmpEchoReply struct {
+	Address       [4]byte
+	Status        uint32
+	RoundTripTime uint32
+	DataSize      uint16
+	Reserved      uint16
+	Data          uintptr
+	Options       ipOptionInformation
+}
+
+// iphlpProber sends ICMP echo requests through the ICMP helper API
+// Each call waits for its own answer, so any number of probes may be in flight at once
+type iphlpProber struct {
+	family     *ipFamily
+	dst        *net.IPAddr
+	source     net.IP
+	payloadLen int
+	tos        uint8
+	handle     uintptr
+
+	mu       sync.Mutex // Guards inflight and closed
+	inflight int        // Calls in progress, which keep the handle open
+	closed   bool       // Close was called; the last call in progress closes the handle
+	last     int        // TTL of the last probe sent with SendProbe
+}
+
+// newSystemICMPProber opens the ICMP helper API, which Windows ICMP probes use in place of
+// raw sockets; the second result is always true here
+func newSystemICMPProber(family *ipFamily, dst *net.IPAddr, source string, payloadLen, dscp int) (Prober, bool, error) {
+	p := &iphlpProber{
+		family:     family,
+		dst:        dst,
+		payloadLen: payloadLen,
+		tos:        uint8(dscp << 2),
+	}
+	if source != "" {
+		p.source, _ = parseScopedIP(source)
+	}
+	create := procIcmpCreateFile
+	if family == familyIPv6 {
+		create = procIcmp6CreateFile
+	}
+	handle, _, err := create.Call()
+	if windows.Handle(handle) == windows.InvalidHandle {
+		return nil, true, fmt.Errorf("failed to open the ICMP helper API: %w", err)
+	}
+	p.handle = handle
+	log.Printf("[DEBUG] Probing %s through the ICMP helper API\n", dst)
+	return p, true, nil
+}
+
+// checkRawSockets reports why raw sockets, which UDP and TCP probes need, can't be opened
+// Windows only opens them for elevated processes
+func checkRawSockets() error {
+	if windows.GetCurrentProcessToken().IsElevated() {
+		return nil
+	}
+	return fmt.Errorf("UDP and TCP probes need raw sockets, which Windows only opens for administrators: %w", os.ErrPermission)
+}
+
+// socketAccess reports that probes go through the ICMP helper API
+func (p *iphlpProber) socketAccess() SocketAccess {
+	return AccessIPHelper
+}
+
+// SendProbe remembers the TTL for AwaitReply, which sends the echo request and waits for its answer
+func (p *iphlpProber) SendProbe(ttl int) error {
+	p.last = ttl
+	return nil
+}
+
+// AwaitReply sends an echo request with the last probe's TTL and waits until deadline for its answer
+func (p *iphlpProber) AwaitReply(deadline time.Time) (ProbeReply, bool) {
+	reply, ok, err := p.echo(p.last, time.Until(deadline))
+	if err != nil {
+		log.Printf("[DEBUG] ICMP helper probe with TTL=%d failed: %v\n", p.last, err)
+	}
+	return reply, ok
+}
+
+// probeContext sends an echo request that expires after ttl hops and waits for its answer until
+// ctx is done, or for DefaultTimeout without a deadline
+// The API can't be interrupted, so a canceled call is left to finish in the background
+func (p *iphlpProber) probeContext(ctx context.Context, ttl int) (ProbeReply, bool, error) {
+	timeout := DefaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if ctx.Err() != nil {
+		return ProbeReply{}, false, nil
+	}
+	type result struct {
+		reply ProbeReply
+		ok    bool
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, ok, err := p.echo(ttl, timeout)
+		done <- result{reply, ok, err}
+	}()
+	select {
+	case r := <-done:
+		return r.reply, r.ok, r.err
+	case <-ctx.Done():
+		return ProbeReply{}, false, nil
+	}
+}
+
+// acquire registers a call, which keeps the handle open until it calls release
+// Returns false once the prober is closed
+func (p *iphlpProber) acquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.inflight++
+	return true
+}
+
+// release ends a call, closing the handle if the prober was closed meanwhile
+func (p *iphlpProber) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inflight--
+	if p.closed && p.inflight == 0 {
+		if err := p.closeHandle(); err != nil {
+			log.Printf("[DEBUG] %v\n", err)
+		}
+	}
+}
+
+// echo sends one echo request and waits up to timeout for the answer
+func (p *iphlpProber) echo(ttl int, timeout time.Duration) (ProbeReply, bool, error) {
+	if !p.acquire() {
+		return ProbeReply{}, false, errors.New("ICMP helper API closed")
+	}
+	defer p.release()
+	waitMs := uint32(max(timeout.Milliseconds(), 1))
+	options := ipOptionInformation{TTL: uint8(ttl), TOS: p.tos}
+	// One spare byte, as RequestData may not be nil even when the payload is empty
+	request := make([]byte, p.payloadLen+1)
+
+	log.Printf("[DEBUG] Sending PING packet to %s with TTL=%d\n", p.dst.IP.String(), ttl)
+	if p.family == familyIPv6 {
+		return p.echo6(request, &options, waitMs)
+	}
+
+	var src, dst [4]byte
+	copy(dst[:], p.dst.IP.To4())
+	if ip4 := p.source.To4(); ip4 != nil {
+		copy(src[:], ip4)
+	}
+	buf := make([]byte, int(unsafe.Sizeof(icmpEchoReply{}))+len(request)+8+ioStatusBlockSize)
+	start := time.Now()
+	n, _, err := procIcmpSendEcho2Ex.Call(p.handle, 0, 0, 0,
+		uintptr(*(*uint32)(unsafe.Pointer(&src[0]))), uintptr(*(*uint32)(unsafe.Pointer(&dst[0]))),
+		uintptr(unsafe.Pointer(&request[0])), uintptr(len(request)-1), uintptr(unsafe.Pointer(&options)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(waitMs))
+	rtt := time.Since(start)
+	// TimeExceeded and unreachables may come back as a failed call, with the answer in the buffer
+	if n == 0 && !answerInBuffer(err) {
+		return ProbeReply{}, false, sendError(err)
+	}
+
+	answer := (*icmpEchoReply)(unsafe.Pointer(&buf[0]))
+	status, ok := p.hopStatus(answer.Status)
+	if !ok {
+		return ProbeReply{}, false, nil
+	}
+	responder := net.IP(answer.Address[:])
+	return ProbeReply{
+		Latency:   float64(rtt.Microseconds()) / 1000,
+		Responder: responder.String(),
+		Reached:   responder.Equal(p.dst.IP),
+		Clock:     ClockUserspace,
+		TTL:       int(answer.Options.TTL),
+		Status:    status,
+	}, true, nil
+}
+
+// echo6 sends one IPv6 echo request and waits up to waitMs milliseconds for the answer
+// Callers have acquired the handle
+func (p *iphlpProber) echo6(request []byte, options *ipOptionInformation, waitMs uint32) (ProbeReply, bool, error) {
+	src := windows.RawSockaddrInet6{Family: windows.AF_INET6}
+	dst := windows.RawSockaddrInet6{Family: windows.AF_INET6}
+	if ip16 := p.source.To16(); ip16 != nil {
+		copy(src.Addr[:], ip16)
+	}
+	copy(dst.Addr[:], p.dst.IP.To16())
+	buf := make([]byte, icmp6ReplySize+len(request)+8+ioStatusBlockSize)
+	start := time.Now()
+	n, _, err := procIcmp6SendEcho2.Call(p.handle, 0, 0, 0,
+		uintptr(unsafe.Pointer(&src)), uintptr(unsafe.Pointer(&dst)),
+		uintptr(unsafe.Pointer(&request[0])), uintptr(len(request)-1), uintptr(unsafe.Pointer(options)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(waitMs))
+	rtt := time.Since(start)
+	if n == 0 && !answerInBuffer(err) {
+		return ProbeReply{}, false, sendError(err)
+	}
+
+	status, ok := p.hopStatus(binary.LittleEndian.Uint32(buf[icmp6ReplyStatus:]))
+	if !ok {
+		return ProbeReply{}, false, nil
+	}
+	responder := net.IP(append([]byte(nil), buf[icmp6ReplyAddress:icmp6ReplyAddress+net.IPv6len]...))
+	return ProbeReply{
+		Latency:   float64(rtt.Microseconds()) / 1000,
+		Responder: responder.String(),
+		Reached:   responder.Equal(p.dst.IP),
+		Clock:     ClockUserspace,
+		Status:    status,
+	}, true, nil
+}
+
+// answerInBuffer reports whether a failed call still left an answer in the reply buffer:
+// it failed with an IP status other than a timeout
+func answerInBuffer(err error) bool {
+	var errno windows.Errno
+	return errors.As(err, &errno) && errno >= ipStatusBase && errno <= ipStatusMax && errno != ipReqTimedOut
+}
+
+// sendError turns the error of a call that got no answer into the prober's error, nil for a timeout
+func sendError(err error) error {
+	var errno windows.Errno
+	if errors.As(err, &errno) && (errno == ipReqTimedOut || errno == 0) {
+		return nil
+	}
+	return fmt.Errorf("failed to send ICMP probe: %w", err)
+}
+
+// hopStatus maps an IP status of the API to the hop's status; false if it is no answer
+func (p *iphlpProber) hopStatus(status uint32) (HopStatus, bool) {
+	switch status {
+	case ipSuccess, ipTTLExpiredTransit, ipTTLExpiredReassem:
+		return HopOK, true
+	case ipDestNetUnreachable:
+		return HopNetUnreachable, true
+	case ipDestHostUnreachable:
+		return HopHostUnreachable, true
+	case ipDestProtUnreachable:
+		if p.family == familyIPv6 {
+			return HopAdminProhibited, true
+		}
+		return HopProtocolUnreachable, true
+	case ipDestPortUnreachable:
+		return HopPortUnreachable, true
+	case ipPacketTooBig:
+		return HopFragmentationNeeded, true
+	case ipDestScopeMismatch:
+		return HopBeyondScope, true
+	case ipReqTimedOut:
+		return "", false
+	default:
+		return HopUnreachable, true
+	}
+}
+
+// Close releases the API handle, at once or as the last call in progress returns
+func (p *iphlpProber) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	if p.inflight > 0 {
+		return nil
+	}
+	return p.closeHandle()
+}
+
+// closeHandle closes the API handle; callers hold p.mu
+func (p *iphlpProber) closeHandle() error {
+	if ok, _, err := procIcmpCloseHandle.Call(p.handle); ok == 0 {
+		return fmt.Errorf("failed to close the ICMP helper API: %w", err)
+	}
+	return nil
+}
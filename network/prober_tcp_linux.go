@@ -0,0 +1,301 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/sys/unix"
+)
+
+// tcpProber probes with TCP connection attempts; routers answer the SYN with TimeExceeded,
+// while the destination completes the handshake (SYN-ACK) or refuses it (RST)
+type tcpProber struct {
+	family    *ipFamily
+	dst       *net.IPAddr
+	source    string
+	port      int              // Destination port
+	dscp      int              // DSCP value probes are marked with
+	icmp      *icmp.PacketConn // Socket router answers arrive on
+	fd        int              // Socket of the probe in flight, -1 if none
+	localPort int
+	sentAt    time.Time
+	refused   bool // The connect was refused before it returned (local destination)
+
+	interrupted   chan struct{} // Closed by interrupt, ending waits for answers
+	interruptOnce sync.Once
+}
+
+// newTCPProber opens a raw ICMP socket for router answers to TCP probes, which carry the DSCP value dscp
+func newTCPProber(family *ipFamily, dst *net.IPAddr, source string, port, dscp int) (*tcpProber, error) {
+	conn, err := family.listen(source)
+	if err != nil {
+		return nil, listenError(err)
+	}
+	if err := enableTimestamps(conn); err != nil {
+		log.Printf("[DEBUG] Kernel receive timestamps unavailable: %v\n", err)
+	}
+	return &tcpProber{family: family, dst: dst, source: source, port: port, dscp: dscp, icmp: conn, fd: -1, interrupted: make(chan struct{})}, nil
+}
+
+// SendProbe sends a SYN that expires after ttl hops by starting a non-blocking connect
+func (p *tcpProber) SendProbe(ttl int) error {
+	log.Printf("[DEBUG] Sending TCP SYN to %s port %d with TTL=%d\n", p.dst.IP.String(), p.port, ttl)
+	p.closeProbe()
+
+	fd, err := p.openProbe(ttl)
+	if err != nil {
+		return err
+	}
+	p.fd = fd
+	if p.localPort, err = tcpLocalPort(fd); err != nil {
+		return err
+	}
+
+	dst, err := tcpSockaddr(p.dst.IP, p.dst.Zone, p.port)
+	if err != nil {
+		return err
+	}
+	p.sentAt = time.Now()
+	p.refused = false
+	if err := unix.Connect(fd, dst); err != nil && err != unix.EINPROGRESS {
+		if err != unix.ECONNREFUSED {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		p.refused = true
+	}
+	return nil
+}
+
+// AwaitReply waits for a router's TimeExceeded or the destination's handshake answer
+func (p *tcpProber) AwaitReply(deadline time.Time) (ProbeReply, bool) {
+	defer p.closeProbe()
+	if p.fd < 0 {
+		return ProbeReply{}, false
+	}
+	if p.refused {
+		return p.reached(time.Since(p.sentAt)), true
+	}
+
+	// Routers answer on the ICMP socket and the destination on the TCP one, so wait for both
+	p.icmp.SetReadDeadline(deadline)
+	answers := make(chan ProbeReply, 2)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if reply, ok := awaitQuotedReply(p.icmp, p.family, p.dst.IP, 6, p.localPort, p.port, p.sentAt, nil); ok {
+			answers <- reply
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if reply, ok := p.awaitHandshake(stop); ok {
+			answers <- reply
+		}
+	}()
+	defer func() {
+		close(stop)
+		p.icmp.SetReadDeadline(time.Now()) // Unblock the ICMP reader
+		wg.Wait()
+	}()
+
+	select {
+	case reply := <-answers:
+		return reply, true
+	case <-time.After(time.Until(deadline)):
+		log.Printf("[DEBUG] TCP probe: Timeout (no response before the deadline)\n")
+		return ProbeReply{}, false
+	case <-p.interrupted:
+		return ProbeReply{}, false
+	}
+}
+
+// interrupt ends the wait for an answer in progress, and any that follows
+func (p *tcpProber) interrupt() {
+	p.interruptOnce.Do(func() { close(p.interrupted) })
+}
+
+// socketAccess reports that the prober needs raw sockets
+func (p *tcpProber) socketAccess() SocketAccess {
+	return AccessRaw
+}
+
+// Close closes the ICMP socket; the probe in flight is closed when AwaitReply returns
+func (p *tcpProber) Close() error {
+	return p.icmp.Close()
+}
+
+// awaitHandshake waits for the probe's connect to finish and reports whether the destination answered
+func (p *tcpProber) awaitHandshake(stop <-chan struct{}) (ProbeReply, bool) {
+	for {
+		select {
+		case <-stop:
+			return ProbeReply{}, false
+		default:
+		}
+
+		// Short polls so the wait ends promptly once the probe is answered elsewhere
+		fds := []unix.PollFd{{Fd: int32(p.fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(fds, 50)
+		if err == unix.EINTR || (err == nil && n == 0) {
+			continue
+		}
+		if err != nil {
+			return ProbeReply{}, false
+		}
+
+		elapsed := time.Since(p.sentAt)
+		soErr, err := unix.GetsockoptInt(p.fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return ProbeReply{}, false
+		}
+		switch syscall.Errno(soErr) {
+		case 0:
+			log.Printf("[DEBUG] TCP probe answered with SYN-ACK (%.2fms)\n", elapsed.Seconds()*1000)
+		case unix.ECONNREFUSED:
+			log.Printf("[DEBUG] TCP probe answered with RST (%.2fms)\n", elapsed.Seconds()*1000)
+		default:
+			// TTL expiry and unreachables also fail the connect; the ICMP reader reports the router
+			log.Printf("[DEBUG] TCP probe failed: %v\n", syscall.Errno(soErr))
+			return ProbeReply{}, false
+		}
+		return p.reached(elapsed), true
+	}
+}
+
+// reached is the reply for a probe the destination answered after elapsed
+func (p *tcpProber) reached(elapsed time.Duration) ProbeReply {
+	return ProbeReply{
+		Latency:   elapsed.Seconds() * 1000,
+		Responder: p.dst.IP.String(),
+		Reached:   true,
+		Clock:     ClockUserspace,
+		Status:    HopOK,
+	}
+}
+
+// openProbe creates a non-blocking TCP socket whose packets expire after ttl hops
+func (p *tcpProber) openProbe(ttl int) (int, error) {
+	domain, level, opt, tosOpt := unix.AF_INET, unix.IPPROTO_IP, unix.IP_TTL, unix.IP_TOS
+	if p.family == familyIPv6 {
+		domain, level, opt, tosOpt = unix.AF_INET6, unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, unix.IPV6_TCLASS
+	}
+
+	fd, err := unix.Socket(domain, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create TCP socket: %v", err)
+	}
+	// Every probe reuses the first probe's local port, so load balancers hash them all onto one path
+	ip, zone := parseScopedIP(p.source)
+	if ip == nil && p.localPort != 0 {
+		ip = net.ParseIP(p.family.wildcard)
+	}
+	if ip != nil {
+		if err := p.bindProbe(fd, ip, zone); err != nil {
+			unix.Close(fd)
+			return -1, err
+		}
+	}
+	if err := unix.SetsockoptInt(fd, level, opt, ttl); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to set TTL: %v", err)
+	}
+	if p.dscp != 0 {
+		if err := unix.SetsockoptInt(fd, level, tosOpt, p.dscp<<2); err != nil {
+			unix.Close(fd)
+			return -1, fmt.Errorf("failed to set DSCP %d: %v", p.dscp, err)
+		}
+	}
+	return fd, nil
+}
+
+// bindProbe binds a probe socket to ip and the session's local port, or to an ephemeral
+// port before the first probe or when another socket has taken it
+func (p *tcpProber) bindProbe(fd int, ip net.IP, zone string) error {
+	if err := unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		log.Printf("[DEBUG] SO_REUSEADDR unavailable: %v\n", err)
+	}
+	sa, err := tcpSockaddr(ip, zone, p.localPort)
+	if err != nil {
+		return err
+	}
+	err = unix.Bind(fd, sa)
+	if err != nil && p.localPort != 0 {
+		log.Printf("[DEBUG] Local port %d unavailable, probes may take another path: %v\n", p.localPort, err)
+		sa, _ = tcpSockaddr(ip, zone, 0)
+		err = unix.Bind(fd, sa)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to bind TCP socket to %s: %v", ip, err)
+	}
+	return nil
+}
+
+// closeProbe closes the probe in flight, resetting any connection the destination accepted
+func (p *tcpProber) closeProbe() {
+	if p.fd < 0 {
+		return
+	}
+	unix.SetsockoptLinger(p.fd, unix.SOL_SOCKET, unix.SO_LINGER, &unix.Linger{Onoff: 1, Linger: 0})
+	unix.Close(p.fd)
+	p.fd = -1
+}
+
+// tcpLocalPort binds the socket to an ephemeral port if needed and returns it
+// The port identifies our SYN in the packets routers quote back
+func tcpLocalPort(fd int) (int, error) {
+	sa, err := unix.Getsockname(fd)
+	if err == nil && sockaddrPort(sa) == 0 {
+		// Not bound yet; bind to the wildcard address so the port is known before connecting
+		switch sa.(type) {
+		case *unix.SockaddrInet4:
+			err = unix.Bind(fd, &unix.SockaddrInet4{})
+		case *unix.SockaddrInet6:
+			err = unix.Bind(fd, &unix.SockaddrInet6{})
+		}
+		if err == nil {
+			sa, err = unix.Getsockname(fd)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read local port: %v", err)
+	}
+	return sockaddrPort(sa), nil
+}
+
+// sockaddrPort returns the port of an IPv4 or IPv6 socket address
+func sockaddrPort(sa unix.Sockaddr) int {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return sa.Port
+	case *unix.SockaddrInet6:
+		return sa.Port
+	}
+	return 0
+}
+
+// tcpSockaddr converts an IP, zone and port to a socket address of the matching family
+// The zone picks the interface a link-local IPv6 address is reached through
+func tcpSockaddr(ip net.IP, zone string, port int) (unix.Sockaddr, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		sa := &unix.SockaddrInet4{Port: port}
+		copy(sa.Addr[:], ip4)
+		return sa, nil
+	}
+	sa := &unix.SockaddrInet6{Port: port}
+	copy(sa.Addr[:], ip.To16())
+	if zone != "" {
+		index, err := zoneIndex(zone)
+		if err != nil {
+			return nil, err
+		}
+		sa.ZoneId = uint32(index)
+	}
+	return sa, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// newTCPProber is unavailable where raw TCP socket options aren't wired up
+func newTCPProber(family *ipFamily, dst *net.IPAddr, source string, port, dscp int) (Prober, error) {
+	return nil, fmt.Errorf("TCP probes are not supported on this platform")
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// UDP probes keep their ports, so load balancers send them all down one path, and differ
+// only in their checksum, which routers quote back. Checksums of recent probes are kept so
+// late answers to earlier probes can't be mistaken for the current one
+const (
+	udpBasePort     = 33434 // Destination port, as used by classic traceroute
+	udpRecentProbes = 256   // Number of earlier probes whose late answers are recognised
+)
+
+// udpProber probes with UDP datagrams, which routers answer with TimeExceeded and the
+// destination with port unreachable
+type udpProber struct {
+	family    *ipFamily
+	dst       *net.IPAddr
+	udp       net.PacketConn   // Socket probes are sent from
+	icmp      *icmp.PacketConn // Socket the ICMP answers arrive on
+	source    string           // Local address probes are sent from, empty for any
+	dscp      int              // DSCP value probes are marked with
+	localIP   net.IP           // Address probes leave from, part of the checksum
+	localPort int
+	payload   []byte                  // Data every probe carries, with the probe number in its balance word
+	balance   int                     // Offset of the word in payload that varies the checksum
+	seq       int                     // Number of probes sent
+	checksums [udpRecentProbes]uint16 // Checksums of recent probes, by sequence number
+	sentAt    time.Time
+
+	interrupted   chan struct{} // Closed by interrupt, ending waits for answers
+	interruptOnce sync.Once
+}
+
+// newUDPProber opens the UDP socket probes are sent from and a raw ICMP socket for the answers
+// Probes carry the DSCP value dscp
+func newUDPProber(family *ipFamily, dst *net.IPAddr, source string, payloadLen, dscp int) (*udpProber, error) {
+	conn, err := family.listen(source)
+	if err != nil {
+		return nil, listenError(err)
+	}
+	if err := enableTimestamps(conn); err != nil {
+		log.Printf("[DEBUG] Kernel receive timestamps unavailable: %v\n", err)
+	}
+
+	udp, err := net.ListenPacket(family.udpNet, net.JoinHostPort(source, "0"))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create UDP socket: %v", err)
+	}
+	if err := family.setPacketDSCP(udp, dscp); err != nil {
+		udp.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to set DSCP %d: %v", dscp, err)
+	}
+	localIP, _ := parseScopedIP(source)
+	if localIP == nil {
+		if localIP, err = routeSource(family, dst); err != nil {
+			udp.Close()
+			conn.Close()
+			return nil, fmt.Errorf("failed to find the source address for %s: %v", dst, err)
+		}
+	}
+	payload, balance := withBalanceWord([]byte(probeSignature))
+	return &udpProber{
+		family:      family,
+		dst:         dst,
+		udp:         udp,
+		icmp:        conn,
+		source:      source,
+		dscp:        dscp,
+		localIP:     localIP,
+		payload:     padPayload(payload, payloadLen),
+		balance:     balance,
+		localPort:   udp.LocalAddr().(*net.UDPAddr).Port,
+		interrupted: make(chan struct{}),
+	}, nil
+}
+
+// SendProbe sends a datagram that expires after ttl hops
+func (p *udpProber) SendProbe(ttl int) error {
+	if err := p.family.setPacketTTL(p.udp, ttl); err != nil {
+		return fmt.Errorf("failed to set TTL: %v", err)
+	}
+
+	p.seq++
+	binary.BigEndian.PutUint16(p.payload[p.balance:], uint16(p.seq))
+	checksum := udpChecksum(p.localIP, p.dst.IP, p.localPort, udpBasePort, p.payload)
+	p.checksums[p.seq%udpRecentProbes] = checksum
+	log.Printf("[DEBUG] Sending UDP probe to %s port %d with TTL=%d (checksum %#04x)\n", p.dst.IP.String(), udpBasePort, ttl, checksum)
+
+	p.sentAt = time.Now()
+	dst := &net.UDPAddr{IP: p.dst.IP, Port: udpBasePort, Zone: p.dst.Zone}
+	if _, err := p.udp.WriteTo(p.payload, dst); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// AwaitReply waits for the ICMP error quoting the last probe
+func (p *udpProber) AwaitReply(deadline time.Time) (ProbeReply, bool) {
+	p.icmp.SetReadDeadline(deadline)
+	// An interrupt before the deadline was set would otherwise go unnoticed
+	select {
+	case <-p.interrupted:
+		return ProbeReply{}, false
+	default:
+	}
+	return awaitQuotedReply(p.icmp, p.family, p.dst.IP, 17, p.localPort, udpBasePort, p.sentAt, p.isStale)
+}
+
+// interrupt ends the wait for an answer in progress, and any that follows, by expiring the read
+func (p *udpProber) interrupt() {
+	p.interruptOnce.Do(func() { close(p.interrupted) })
+	p.icmp.SetReadDeadline(time.Now())
+}
+
+// isStale reports whether a quoted datagram is an earlier probe rather than the last one
+// Quotes whose checksum matches no recent probe are accepted, as NATs may rewrite it
+func (p *udpProber) isStale(quoted quotedPacket) bool {
+	checksum := binary.BigEndian.Uint16(quoted.transport[6:8])
+	if checksum == p.checksums[p.seq%udpRecentProbes] {
+		return false
+	}
+	for _, earlier := range p.checksums {
+		if earlier != 0 && checksum == earlier {
+			return true
+		}
+	}
+	return false
+}
+
+// socketAccess reports that the prober needs raw sockets
+func (p *udpProber) socketAccess() SocketAccess {
+	return AccessRaw
+}
+
+// Close closes both sockets
+func (p *udpProber) Close() error {
+	p.udp.Close()
+	return p.icmp.Close()
+}
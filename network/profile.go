@@ -0,0 +1,133 @@
+package network
+
+import (
+	"math"
+	"time"
+)
+
+// Profile tunes probing, grading and scoring for one use of a path: a VoIP call suffers from
+// jitter and loss long before latency matters, a bulk transfer only from sustained loss
+type Profile struct {
+	Name        string
+	Interval    time.Duration // Time between monitoring rounds, 0 to keep the configured interval
+	LossWindow  int           // Latest probes per hop the loss column covers, 0 to keep the configured window
+	GoodLatency float64       // Round trip in milliseconds below which latency is good
+	FairLatency float64       // Round trip in milliseconds from which latency is poor
+	GoodJitter  float64       // Jitter in milliseconds below which jitter is good, 0 if jitter doesn't grade the path
+	FairJitter  float64       // Jitter in milliseconds from which jitter is poor
+	PoorLoss    float64       // Loss in percent from which the path is poor; any loss makes it fair
+	Weights     ScoreWeights
+}
+
+// ScoreWeights is how much each metric counts towards a profile's score
+type ScoreWeights struct {
+	Latency float64
+	Jitter  float64
+	Loss    float64
+}
+
+// Probing profiles
+var (
+	// ProfileGeneral grades latency and loss alike, with the usual colour thresholds
+	ProfileGeneral = Profile{
+		Name:        "General",
+		GoodLatency: 50,
+		FairLatency: 150,
+		PoorLoss:    5,
+		Weights:     ScoreWeights{Latency: 0.5, Loss: 0.5},
+	}
+	// ProfileGaming probes twice a second and holds latency and jitter to what fast games notice
+	ProfileGaming = Profile{
+		Name:        "Gaming",
+		Interval:    500 * time.Millisecond,
+		GoodLatency: 40,
+		FairLatency: 80,
+		GoodJitter:  10,
+		FairJitter:  25,
+		PoorLoss:    2,
+		Weights:     ScoreWeights{Latency: 0.5, Jitter: 0.3, Loss: 0.2},
+	}
+	// ProfileVoIP probes at the pace of voice packets and weighs jitter and loss over latency;
+	// ITU-T G.114 allows 150 ms one way, so round trips up to 300 ms are still usable
+	ProfileVoIP = Profile{
+		Name:        "VoIP",
+		Interval:    500 * time.Millisecond,
+		GoodLatency: 150,
+		FairLatency: 300,
+		GoodJitter:  20,
+		FairJitter:  40,
+		PoorLoss:    1,
+		Weights:     ScoreWeights{Latency: 0.2, Jitter: 0.4, Loss: 0.4},
+	}
+	// ProfileBulk probes gently over a long loss window, as only sustained loss slows TCP down
+	ProfileBulk = Profile{
+		Name:        "Bulk transfer",
+		Interval:    2 * time.Second,
+		LossWindow:  300,
+		GoodLatency: 200,
+		FairLatency: 400,
+		PoorLoss:    2,
+		Weights:     ScoreWeights{Latency: 0.2, Loss: 0.8},
+	}
+)
+
+// Profiles lists the probing profiles, the default first
+var Profiles = []Profile{ProfileGeneral, ProfileGaming, ProfileVoIP, ProfileBulk}
+
+// ProfileNamed returns the profile called name, ProfileGeneral if there is none
+func ProfileNamed(name string) Profile {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return ProfileGeneral
+}
+
+// Options returns the scanner options the profile sets; they go after the configured ones
+func (p Profile) Options() []ScannerOption {
+	var opts []ScannerOption
+	if p.Interval > 0 {
+		opts = append(opts, WithInterval(p.Interval))
+	}
+	if p.LossWindow > 0 {
+		opts = append(opts, WithLossWindow(p.LossWindow))
+	}
+	return opts
+}
+
+// Grade rates a destination's average latency and jitter in milliseconds and its loss in
+// percent as "Good", "Fair" or "Poor"
+func (p Profile) Grade(latency, jitter, loss float64) string {
+	gradeJitter := p.GoodJitter > 0
+	switch {
+	case loss >= p.PoorLoss || latency >= p.FairLatency || (gradeJitter && jitter >= p.FairJitter):
+		return "Poor"
+	case loss > 0 || latency >= p.GoodLatency || (gradeJitter && jitter >= p.GoodJitter):
+		return "Fair"
+	default:
+		return "Good"
+	}
+}
+
+// Score rates a destination from 0 to 100: each metric loses its weight in proportion to how
+// close it is to the poor threshold, and all of it from there on
+func (p Profile) Score(latency, jitter, loss float64) int {
+	penalty := func(value, poor float64) float64 {
+		if poor <= 0 {
+			return 0
+		}
+		return math.Min(math.Max(value/poor, 0), 1)
+	}
+	w := p.Weights
+	total := w.Latency + w.Loss
+	lost := w.Latency*penalty(latency, p.FairLatency) + w.Loss*penalty(loss, p.PoorLoss)
+	if p.FairJitter > 0 {
+		total += w.Jitter
+		lost += w.Jitter * penalty(jitter, p.FairJitter)
+	}
+	if total <= 0 {
+		return 100
+	}
+	return int(math.Round(100 * (1 - lost/total)))
+}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Provider is a well-known service that publishes a Statuspage-compatible status feed
+type Provider struct {
+	Name      string   // Display name
+	Domains   []string // Hostname suffixes served by the provider
+	StatusURL string   // Statuspage v2 status.json endpoint
+}
+
+// knownProviders lists popular destinations whose status feeds can be cross-checked
+var knownProviders = []Provider{
+	{Name: "GitHub", Domains: []string{"github.com", "githubusercontent.com"}, StatusURL: "https://www.githubstatus.com/api/v2/status.json"},
+	{Name: "Cloudflare", Domains: []string{"cloudflare.com", "one.one.one.one"}, StatusURL: "https://www.cloudflarestatus.com/api/v2/status.json"},
+	{Name: "Discord", Domains: []string{"discord.com", "discord.gg"}, StatusURL: "https://discordstatus.com/api/v2/status.json"},
+	{Name: "Reddit", Domains: []string{"reddit.com"}, StatusURL: "https://www.redditstatus.com/api/v2/status.json"},
+	{Name: "Atlassian", Domains: []string{"atlassian.com", "atlassian.net", "bitbucket.org"}, StatusURL: "https://status.atlassian.com/api/v2/status.json"},
+}
+
+// ProviderStatus is the provider's self-reported health
+type ProviderStatus struct {
+	Provider    Provider
+	Indicator   string // none, minor, major or critical
+	Description string // Human-readable summary, e.g. "Partial System Outage"
+}
+
+// Incident reports whether the provider says something is wrong
+func (s ProviderStatus) Incident() bool {
+	return s.Indicator != "" && s.Indicator != "none"
+}
+
+// LookupProvider finds the well-known provider serving hostname
+func LookupProvider(hostname string) (Provider, bool) {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for _, p := range knownProviders {
+		for _, domain := range p.Domains {
+			if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+				return p, true
+			}
+		}
+	}
+	return Provider{}, false
+}
+
+// CheckProviderStatus fetches the provider's current status feed
+func CheckProviderStatus(ctx context.Context, p Provider) (ProviderStatus, error) {
+	var feed struct {
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+	}
+	if err := fetchJSON(ctx, LookupProviderStatus, p.StatusURL, &feed); err != nil {
+		return ProviderStatus{Provider: p}, fmt.Errorf("failed to check %s status: %v", p.Name, err)
+	}
+	return ProviderStatus{
+		Provider:    p,
+		Indicator:   feed.Status.Indicator,
+		Description: feed.Status.Description,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// quotedPacket is the start of the original packet quoted in an ICMP error
+type quotedPacket struct {
+	proto     int    // Transport protocol of the original packet (1 ICMP, 6 TCP, 58 ICMPv6)
+	dst       net.IP // Original destination
+	transport []byte // Start of the original transport header (at least 8 bytes)
+}
+
+// parseQuotedPacket extracts the original IP and transport headers quoted in a TimeExceeded body
+// Routers quote the IP header plus at least the first 8 bytes of the payload (RFC 792, RFC 4443)
+func parseQuotedPacket(family *ipFamily, data []byte) (quotedPacket, bool) {
+	if family == familyIPv4 {
+		if len(data) < 20 || data[0]>>4 != 4 {
+			return quotedPacket{}, false
+		}
+		hdrLen := int(data[0]&0x0f) << 2
+		if hdrLen < 20 || len(data) < hdrLen+8 {
+			return quotedPacket{}, false
+		}
+		return quotedPacket{proto: int(data[9]), dst: net.IP(data[16:20]), transport: data[hdrLen:]}, true
+	}
+
+	// IPv6 extension headers aren't followed; our probes never carry them
+	if len(data) < 40+8 || data[0]>>4 != 6 {
+		return quotedPacket{}, false
+	}
+	return quotedPacket{proto: int(data[6]), dst: net.IP(data[24:40]), transport: data[40:]}, true
+}
+
+// ports returns the source and destination ports of a quoted TCP or UDP header
+func (q quotedPacket) ports() (int, int) {
+	return int(binary.BigEndian.Uint16(q.transport[0:2])), int(binary.BigEndian.Uint16(q.transport[2:4]))
+}
@@ -0,0 +1,51 @@
+package network
+
+import "time"
+
+// RateLimitLossMargin is how many percentage points a hop's loss must exceed the destination's
+// before the hop is flagged as likely rate-limiting its ICMP answers
+const RateLimitLossMargin = 5.0
+
+// destinationLoss returns the destination's loss over the loss window, or -1 if the path doesn't
+// reach the destination or it hasn't been probed yet, leaving nothing to judge other hops against
+func (s *Scanner) destinationLoss() float64 {
+	if len(s.hops) == 0 || s.dstAddr == nil {
+		return -1
+	}
+	last := s.hops[len(s.hops)-1]
+	if last.IP != s.dstAddr.IP.String() || last.Sent == 0 {
+		return -1
+	}
+	return last.LossPercent
+}
+
+// likelyRateLimited reports whether an intermediate hop loses clearly more probes than the
+// destination: traffic through the router evidently gets on, so the router is holding back the
+// ICMP answers it generates itself rather than dropping packets
+func likelyRateLimited(hop NetworkHop, destination bool, destLoss float64) bool {
+	return !destination && destLoss >= 0 && hop.LossPercent > destLoss+RateLimitLossMargin
+}
+
+// hopSpacing returns the least time between the hop's probes within a round: the configured
+// spacing, widened for a hop flagged as rate-limited so its probes spread over half the interval
+func (s *Scanner) hopSpacing(hop NetworkHop) time.Duration {
+	if !hop.RateLimited || s.probesPerRound < 2 {
+		return s.spacing
+	}
+	return max(s.spacing, s.interval/time.Duration(2*(s.probesPerRound-1)))
+}
+
+// pace waits d before a hop's next probe; returns false if the scanner stopped meanwhile
+func (s *Scanner) pace(d time.Duration) bool {
+	if d <= 0 {
+		return s.ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// retracePath re-runs discovery while monitoring and switches to the new path if the route changed
+// Hops that still answer the same keep their history; new hops start with none
+func (s *Scanner) retracePath() {
+	log.Printf("[DEBUG] Re-tracing path to %s\n", s.dstAddr.IP.String())
+	traced, err := s.performTraceroute(false)
+	if s.ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.sendError(fmt.Errorf("re-trace failed: %v", err))
+		return
+	}
+	reached := len(traced) > 0 && traced[len(traced)-1].IP == s.dstAddr.IP.String()
+	hops, changes := mergePath(s.hops, traced, reached, time.Now())
+	if len(changes) == 0 {
+		log.Printf("[DEBUG] Re-trace found the same %d hops\n", len(s.hops))
+		return
+	}
+
+	oldByTTL := make(map[int]int, len(s.hops))
+	for i, hop := range s.hops {
+		oldByTTL[hop.TTL] = i
+	}
+	rounds := make([]*latencyRing, len(hops))
+	probeRTTs := make([]*latencyRing, len(hops))
+	for i, hop := range hops {
+		if j, ok := oldByTTL[hop.TTL]; ok && s.hops[j].IP == hop.IP {
+			rounds[i], probeRTTs[i] = s.rounds[j], s.probeRTTs[j]
+			continue
+		}
+		rounds[i] = newLatencyRing(MaxLatencyHistory, nil)
+		probeRTTs[i] = newLatencyRing(MaxLatencyHistory, nil)
+	}
+	s.hops, s.rounds, s.probeRTTs = hops, rounds, probeRTTs
+
+	log.Printf("[DEBUG] Path changed at %d hops, now monitoring %d hops\n", len(changes), len(hops))
+	s.sendEvent(Event{Kind: EventPathChanged, Hops: len(hops), Path: copyHops(hops), Changes: changes})
+}
+
+// mergePath compares a re-traced path with the monitored one and returns the path to monitor next
+// with what changed, empty if nothing did
+// A single re-trace probe per TTL proves little, so a TTL that didn't answer it keeps its hop,
+// and one answered by a router the hop has already seen (load balancing) is not a change. The
+// path only ends earlier if the re-trace reached the destination. Changed and new hops carry the
+// index they have in the new path; hops past the new destination carry their old index
+func mergePath(old, traced []NetworkHop, reached bool, at time.Time) ([]NetworkHop, []PathChange) {
+	oldByTTL := make(map[int]NetworkHop, len(old))
+	end := 0
+	for _, hop := range old {
+		oldByTTL[hop.TTL] = hop
+		end = max(end, hop.TTL)
+	}
+	tracedByTTL := make(map[int]NetworkHop, len(traced))
+	for _, hop := range traced {
+		tracedByTTL[hop.TTL] = hop
+	}
+	if reached {
+		end = traced[len(traced)-1].TTL
+	} else if len(traced) > 0 {
+		end = max(end, traced[len(traced)-1].TTL)
+	}
+
+	var path []NetworkHop
+	var changes []PathChange
+	for ttl := 1; ttl <= end; ttl++ {
+		was, inOld := oldByTTL[ttl]
+		now, inTraced := tracedByTTL[ttl]
+		switch {
+		case inOld && (!inTraced || now.IP == was.IP || slices.Contains(was.ResponderHistory, now.IP)):
+			path = append(path, was)
+		case inTraced:
+			changes = append(changes, PathChange{Hop: len(path), From: was.IP, To: now.IP, At: at})
+			path = append(path, now)
+		}
+	}
+	for i, hop := range old {
+		if hop.TTL > end {
+			changes = append(changes, PathChange{Hop: i, From: hop.IP, At: at})
+		}
+	}
+	return path, changes
+}
@@ -0,0 +1,40 @@
+package network
+
+// latencyRing is a fixed-size ring buffer of recent latencies in milliseconds, -1 for timeouts
+// The scanner owns one per hop and series; hops only ever carry snapshots of it
+type latencyRing struct {
+	values []float64
+	next   int  // Slot the next value goes into
+	full   bool // Every slot holds a value
+}
+
+// newLatencyRing creates a ring of size slots holding the newest values of seed
+func newLatencyRing(size int, seed []float64) *latencyRing {
+	r := &latencyRing{values: make([]float64, size)}
+	if len(seed) > size {
+		seed = seed[len(seed)-size:]
+	}
+	for _, v := range seed {
+		r.push(v)
+	}
+	return r
+}
+
+// push adds a value, overwriting the oldest once the ring is full
+func (r *latencyRing) push(v float64) {
+	r.values[r.next] = v
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns a copy of the values, oldest first
+func (r *latencyRing) snapshot() []float64 {
+	if !r.full {
+		return append([]float64(nil), r.values[:r.next]...)
+	}
+	out := make([]float64, 0, len(r.values))
+	out = append(out, r.values[r.next:]...)
+	return append(out, r.values[:r.next]...)
+}
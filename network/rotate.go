@@ -0,0 +1,75 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// identityRotator is implemented by probers whose probe identity (echo ID or source port) can change
+type identityRotator interface {
+	// rotateIdentity switches the probes that follow to a fresh identity
+	rotateIdentity() error
+}
+
+// rotateIdentity gives the prober a fresh identity for the round about to start
+// Answers still on their way to the old identity are dropped as late
+func (s *Scanner) rotateIdentity() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopCalled {
+		return
+	}
+	rotator, ok := s.prober.(identityRotator)
+	if !ok {
+		return
+	}
+	if err := rotator.rotateIdentity(); err != nil {
+		log.Printf("[DEBUG] Keeping the probe identity: %v\n", err)
+	}
+}
+
+// nextInterval returns the time until the next monitoring round: the interval, moved by up to
+// the schedule jitter either way, and stretched while the governor holds back the sessions' load
+func (s *Scanner) nextInterval() time.Duration {
+	interval := time.Duration(float64(s.interval) * sessionGovernor.slowdown())
+	if s.jitter <= 0 {
+		return interval
+	}
+	return interval - s.jitter + rand.N(2*s.jitter+1)
+}
+
+// rotateIdentity switches to a newly reserved echo ID, releasing the old one
+// Unprivileged sockets leave the ID to the kernel, so they keep theirs
+func (p *icmpProber) rotateIdentity() error {
+	if p.dgram {
+		return nil
+	}
+	id := allocateEchoID()
+	releaseEchoID(p.echoID)
+	p.mu.Lock()
+	p.echoID, p.replyID = id, id
+	p.mu.Unlock()
+	log.Printf("[DEBUG] Rotated ICMP echo ID to %d\n", id)
+	return nil
+}
+
+// rotateIdentity moves probes to a new UDP socket, and so a new source port
+func (p *udpProber) rotateIdentity() error {
+	udp, err := net.ListenPacket(p.family.udpNet, net.JoinHostPort(p.source, "0"))
+	if err != nil {
+		return fmt.Errorf("failed to create UDP socket: %v", err)
+	}
+	if err := p.family.setPacketDSCP(udp, p.dscp); err != nil {
+		udp.Close()
+		return fmt.Errorf("failed to set DSCP %d: %v", p.dscp, err)
+	}
+	p.udp.Close()
+	p.udp = udp
+	p.localPort = udp.LocalAddr().(*net.UDPAddr).Port
+	p.checksums = [udpRecentProbes]uint16{}
+	log.Printf("[DEBUG] Rotated UDP source port to %d\n", p.localPort)
+	return nil
+}
@@ -2,15 +2,14 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
-	"time"
-
 	"os"
-
-	"golang.org/x/net/icmp"
-	"golang.org/x/net/ipv4"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ScannerStatus represents the current state of the scanner
@@ -21,33 +20,152 @@ const (
 	StatusResolving ScannerStatus = "Resolving hostname..."
 	StatusTracing   ScannerStatus = "Tracing route..."
 	StatusPinging   ScannerStatus = "Monitoring hops..."
+	StatusPaused    ScannerStatus = "Paused"
+	StatusCompleted ScannerStatus = "Completed"
 	StatusStopped   ScannerStatus = "Stopped"
 	StatusError     ScannerStatus = "Error"
+	StatusIDClash   ScannerStatus = "Foreign echo traffic is using our ICMP ID"
 )
 
 // Scanner manages the network path scanning operations
 type Scanner struct {
-	hostname   string
-	hops       []NetworkHop
-	updates    chan HopUpdate
-	status     chan ScannerStatus
-	ctx        context.Context
-	cancel     context.CancelFunc
-	conn       *icmp.PacketConn // Connection for ICMP operations
-	stopCalled bool             // Flag to prevent double-close of channel
+	hostname       string
+	address        string        // Address of hostname to probe, empty to take the resolver's choice
+	source         string        // Local address or interface probes are sent from (empty for any)
+	iface          string        // Interface given as the source, resolved to an address for each target
+	protocol       Protocol      // IP version requested for the target
+	method         ProbeMethod   // How TTL-limited probes are sent
+	tcpPort        int           // Destination port of TCP probes
+	probesPerRound int           // Probes sent to each hop per monitoring round
+	interval       time.Duration // Time between monitoring rounds
+	timeout        time.Duration // How long to wait for the answer to a probe
+	maxTTL         int           // Highest TTL the traceroute tries
+	packetSize     int           // IP packet size probes are padded to, 0 for the smallest
+	dscp           int           // DSCP value probes are marked with, 0 for best effort
+	lossWindow     int           // Latest probes per hop that LossPercent covers
+	retrace        time.Duration // Time between re-traces of the path while monitoring, 0 for none
+	rotate         bool          // Give probes a fresh echo ID or source port every monitoring round
+	jitter         time.Duration // Largest random shift of each monitoring round, 0 for a fixed schedule
+	concurrency    int           // Hops probed at once while monitoring
+	cycles         int           // Monitoring rounds before the session completes, 0 to run until Stop
+	spacing        time.Duration // Least time between a hop's probes within a round, 0 to send them back to back
+	family         *ipFamily     // ICMP family of the resolved destination
+	dstAddr        *net.IPAddr   // Resolved destination address
+	prober         Prober        // Sends the probes; created by Start unless set with SetProber
+	ownProber      bool          // The prober was created for the current target, not set with SetProber
+	sockets        int           // Sockets reserved with the governor for the prober
+	asn            *ASNResolver  // Looks up the origin AS of hops, nil to skip
+	clock          atomic.Value  // ClockSource used to time the most recent probe
+	access         atomic.Value  // SocketAccess of the prober
+	nat64          atomic.Value  // NAT64 translation the path crosses, if any
+	hops           []NetworkHop
+	rounds         []*latencyRing // Per-hop round means behind LatencyHistory
+	probeRTTs      []*latencyRing // Per-hop probe RTTs behind ProbeHistory
+	history        []NetworkHop   // Hops of a resumed session whose history carries over
+	updates        chan HopUpdate
+	events         chan Event       // Lifecycle events alongside every hop update
+	probes         chan ProbeResult // Outcome of every monitoring probe
+	probeSeq       atomic.Int64     // Number of the latest monitoring probe
+	cycle          int              // Number of the latest monitoring round
+	paused         atomic.Bool      // Monitoring rounds and re-traces are skipped while set
+	status         chan ScannerStatus
+	errs           chan error         // Probe failures the session carries on through
+	life           context.Context    // Lasts until Stop
+	end            context.CancelFunc // Cancels life
+	ctx            context.Context    // Lasts while the scanner follows the current target
+	cancel         context.CancelFunc // Cancels ctx, e.g. to retarget
+	loop           sync.WaitGroup     // Tracks the monitoring loop of the current target
+	producers      sync.WaitGroup     // Tracks everything that may still send on the channels
+	stopOnce       sync.Once          // Runs shutdown for the first Stop
+	targetMu       sync.Mutex         // Serializes Start and SetTarget
+	mu             sync.Mutex         // Guards prober, ctx, hostname and stopCalled
+	stopCalled     bool               // Set once Stop begins; no producer registers after that
 }
 
 // NewScanner creates a new scanner instance
-func NewScanner(hostname string) *Scanner {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Scanner{
-		hostname: hostname,
-		hops:     make([]NetworkHop, 0),
-		updates:  make(chan HopUpdate, 100),
-		status:   make(chan ScannerStatus, 10),
-		ctx:      ctx,
-		cancel:   cancel,
+func NewScanner(hostname string, opts ...ScannerOption) *Scanner {
+	return NewScannerFrom(hostname, "", opts...)
+}
+
+// NewScannerFrom creates a scanner that sends probes from a specific local address or interface
+// On multi-uplink hosts this selects which uplink the session measures
+func NewScannerFrom(hostname, source string, opts ...ScannerOption) *Scanner {
+	life, end := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(life)
+	s := &Scanner{
+		hostname:       hostname,
+		source:         source,
+		protocol:       ProtocolAuto,
+		method:         ProbeICMP,
+		tcpPort:        DefaultTCPPort,
+		probesPerRound: DefaultProbesPerRound,
+		interval:       DefaultInterval,
+		timeout:        DefaultTimeout,
+		maxTTL:         DefaultMaxTTL,
+		lossWindow:     DefaultLossWindow,
+		concurrency:    DefaultConcurrency,
+		hops:           make([]NetworkHop, 0),
+		updates:        make(chan HopUpdate, 100),
+		events:         make(chan Event, 1000),
+		probes:         make(chan ProbeResult, 1000),
+		status:         make(chan ScannerStatus, 10),
+		errs:           make(chan error, 10),
+		life:           life,
+		end:            end,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+// SetProtocol selects IPv4 or IPv6 for dual-stacked targets; call it before Start
+func (s *Scanner) SetProtocol(protocol Protocol) {
+	s.protocol = protocol
+}
+
+// SetAddress pins the session to one of the hostname's addresses instead of the one the
+// resolver prefers; call it before Start
+func (s *Scanner) SetAddress(address string) {
+	s.address = address
+}
+
+// SetProbeMethod selects how probes are sent; call it before Start
+func (s *Scanner) SetProbeMethod(method ProbeMethod) {
+	s.method = method
+}
+
+// SetProber sends probes with a custom prober instead of one for the probe method; call it before Start
+// The scanner closes it when stopped
+func (s *Scanner) SetProber(prober Prober) {
+	s.prober = prober
+}
+
+// SetASNResolver looks up each hop's origin AS with resolver; call it before Start
+// Lookups run in the background and show up in the hop updates once answered. Sharing
+// one resolver between sessions keeps its cache
+func (s *Scanner) SetASNResolver(resolver *ASNResolver) {
+	s.asn = resolver
+}
+
+// SetTCPProbe probes with TCP SYNs to the given port instead of ICMP echo requests; call it before Start
+// Paths that filter ICMP usually still pass connections to web ports such as 80 and 443
+func (s *Scanner) SetTCPProbe(port int) {
+	s.method = ProbeTCP
+	s.tcpPort = port
+}
+
+// SetProbesPerRound sets how many probes each hop gets per monitoring round; call it before Start
+// Loss and average latency are computed over every probe, the graphs show each round's mean
+func (s *Scanner) SetProbesPerRound(probes int) {
+	s.probesPerRound = probes
+}
+
+// RestoreHistory continues the history of a saved session's hops once the path is traced; call it before Start
+func (s *Scanner) RestoreHistory(hops []NetworkHop) {
+	s.history = copyHops(hops)
 }
 
 // Start begins the scanning process
@@ -55,42 +173,177 @@ func NewScanner(hostname string) *Scanner {
 // 1. Perform traceroute to identify all hops
 // 2. Start continuous pinging of all hops in parallel
 // 3. Send updates via the Updates() channel
-func (s *Scanner) Start() error {
+// ctx bounds resolution and discovery only: once Start returns, monitoring runs until Stop.
+// If ctx is done or Stop is called meanwhile, Start returns ErrCanceled as soon as the current
+// resolution or probe gives up, and releases the sockets it opened
+func (s *Scanner) Start(ctx context.Context) error {
+	if !s.enter() {
+		return s.canceled(ctx)
+	}
+	defer s.producers.Done()
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+	return s.start(ctx)
+}
+
+// start resolves the current target, traces its path and starts monitoring it, giving up
+// when ctx is done
+// Callers hold targetMu and are registered producers
+func (s *Scanner) start(ctx context.Context) error {
+	watch := context.AfterFunc(ctx, func() { s.cancelTarget() })
+	defer watch()
+
+	if s.method == ProbeTCP && (s.tcpPort < 1 || s.tcpPort > 65535) {
+		return s.fail(fmt.Errorf("%w: invalid TCP port: %d", ErrInvalidOption, s.tcpPort))
+	}
+	if err := s.validateOptions(); err != nil {
+		return s.fail(fmt.Errorf("%w: %w", ErrInvalidOption, err))
+	}
+	if s.probesPerRound < 1 || s.probesPerRound > MaxProbesPerRound {
+		return s.fail(fmt.Errorf("%w: probes per round must be between 1 and %d, got %d", ErrInvalidOption, MaxProbesPerRound, s.probesPerRound))
+	}
+
+	// Resolve the hostname to an IP address
+	s.sendStatus(StatusResolving)
+	protocol := s.protocol
+	if s.iface == "" && isInterfaceSource(s.source) {
+		s.iface = s.source
+	}
+	if ip, _ := parseScopedIP(s.source); ip != nil && s.iface == "" && protocol == ProtocolAuto {
+		// A source address pins the session to its IP version
+		protocol = ProtocolIPv6
+		if ip.To4() != nil {
+			protocol = ProtocolIPv4
+		}
+	}
+	name := s.hostname
+	if s.address != "" {
+		name = s.address
+	}
+	dstAddr, err := resolveTarget(s.ctx, name, protocol)
+	if s.ctx.Err() != nil {
+		return s.canceled(ctx)
+	}
+	if err != nil {
+		return s.fail(fmt.Errorf("%w: %v", ErrResolve, err))
+	}
+	dstAddr = s.detectNAT64(dstAddr, protocol)
+	if s.ctx.Err() != nil {
+		return s.canceled(ctx)
+	}
+	// An interface stands for its address of the destination's IP version
+	if s.iface != "" {
+		source, err := interfaceSource(s.iface, dstAddr.IP)
+		if err != nil {
+			return s.fail(fmt.Errorf("%w: %w", ErrSocket, err))
+		}
+		s.source = source
+	}
+	s.dstAddr = dstAddr
+	s.family = familyOf(dstAddr.IP)
+
+	// Open the sockets used for both tracing and monitoring
+	if s.prober == nil {
+		cost := socketCost(s.method)
+		if err := sessionGovernor.acquireSockets(cost); err != nil {
+			return s.fail(err)
+		}
+		prober, err := s.newProber()
+		if err != nil {
+			sessionGovernor.releaseSockets(cost)
+			if errors.Is(err, os.ErrPermission) {
+				return s.fail(fmt.Errorf("%w: %w", ErrPermission, err))
+			}
+			return s.fail(fmt.Errorf("%w: %w", ErrSocket, err))
+		}
+		// Stop closes the prober once start has returned
+		s.mu.Lock()
+		s.prober = prober
+		s.ownProber = true
+		s.sockets = cost
+		s.mu.Unlock()
+	}
+	if reporter, ok := s.prober.(accessReporter); ok {
+		s.access.Store(reporter.socketAccess())
+		log.Printf("[DEBUG] Probing with %s\n", reporter.socketAccess())
+	}
+
 	// Send tracing status
 	s.sendStatus(StatusTracing)
+	s.sendEvent(Event{Kind: EventDiscoveryStarted, Address: dstAddr.String()})
 
 	// Perform traceroute to discover all hops, sending them in real-time via updates channel
-	hops, err := performTraceroute(s.hostname, s.updates, s.ctx)
+	hops, err := s.performTraceroute(true)
+	if s.ctx.Err() != nil {
+		return s.canceled(ctx)
+	}
 	if err != nil {
-		s.sendStatus(StatusError)
-		return err
+		return s.fail(err)
 	}
 
 	// Store the discovered hops
-	s.hops = hops
+	s.hops = restoreHistory(hops, s.history, s.lossWindow)
+	s.rounds = make([]*latencyRing, len(s.hops))
+	s.probeRTTs = make([]*latencyRing, len(s.hops))
+	for i, hop := range s.hops {
+		s.rounds[i] = newLatencyRing(MaxLatencyHistory, hop.LatencyHistory)
+		s.probeRTTs[i] = newLatencyRing(MaxLatencyHistory, hop.ProbeHistory)
+	}
 
-	// Create ICMP connection for continuous monitoring
-	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
-	if err != nil {
-		s.sendStatus(StatusError)
-		return fmt.Errorf("failed to create ICMP connection for monitoring: %v", err)
+	// From here on only Stop ends the session; ctx may have expired as discovery finished
+	if !watch() {
+		return s.canceled(ctx)
 	}
-	s.conn = conn
 
 	log.Printf("[DEBUG] Traceroute discovered %d hops, starting PING loop\n", len(s.hops))
+	s.sendEvent(Event{Kind: EventDiscoveryComplete, Hops: len(s.hops)})
 
 	// Start the monitoring loop if we have hops
 	if len(s.hops) > 0 {
 		log.Printf("[DEBUG] Starting PING loop for continuous ping updates\n")
 		s.sendStatus(StatusPinging)
-		go s.monitorLoop()
+		s.sendEvent(Event{Kind: EventMonitoringStarted, Hops: len(s.hops)})
+		// The caller's registration keeps shutdown from waiting yet, so the loop may join it
+		s.loop.Add(1)
+		s.producers.Add(1)
+		go func() {
+			defer s.producers.Done()
+			defer s.loop.Done()
+			s.monitorLoop()
+		}()
 	}
 
 	return nil
 }
 
+// canceled returns ErrCanceled wrapping why start gave up: ctx's error if it is done, otherwise
+// context.Canceled as Stop or SetTarget canceled the target
+func (s *Scanner) canceled(ctx context.Context) error {
+	cause := ctx.Err()
+	if cause == nil {
+		cause = context.Canceled
+	}
+	return fmt.Errorf("%w: %w", ErrCanceled, cause)
+}
+
+// enter registers a producer, which may send on the channels until it calls s.producers.Done
+// Returns false once Stop has begun, when nothing may be sent any more
+func (s *Scanner) enter() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopCalled {
+		return false
+	}
+	s.producers.Add(1)
+	return true
+}
+
 // sendStatus sends a status update to the status channel (non-blocking)
 func (s *Scanner) sendStatus(status ScannerStatus) {
+	if !s.enter() {
+		return
+	}
+	defer s.producers.Done()
 	select {
 	case s.status <- status:
 	default:
@@ -98,6 +351,52 @@ func (s *Scanner) sendStatus(status ScannerStatus) {
 	}
 }
 
+// sendError reports a failure the session carries on through (non-blocking)
+func (s *Scanner) sendError(err error) {
+	s.sendEvent(Event{Kind: EventError, Err: err})
+	if !s.enter() {
+		return
+	}
+	defer s.producers.Done()
+	select {
+	case s.errs <- err:
+	default:
+		// Channel full, the UI already has errors to show
+	}
+}
+
+// sendProbeResult reports a monitoring probe's outcome (non-blocking)
+func (s *Scanner) sendProbeResult(result ProbeResult) {
+	if !s.enter() {
+		return
+	}
+	defer s.producers.Done()
+	select {
+	case s.probes <- result:
+	default:
+		// Nobody is keeping up with the probe stream
+	}
+}
+
+// sendUpdate delivers a hop update, waiting for room in the channel
+// Returns false if the target was canceled or the scanner stopped meanwhile
+func (s *Scanner) sendUpdate(update HopUpdate) bool {
+	if !s.enter() {
+		return false
+	}
+	defer s.producers.Done()
+	s.mu.Lock()
+	ctx := s.ctx
+	update.Target = s.hostname
+	s.mu.Unlock()
+	select {
+	case s.updates <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // extractIPFromAddr extracts the IP address from a net.Addr
 // Handles both "ip:port" format and plain IP addresses
 func extractIPFromAddr(addr net.Addr) string {
@@ -105,31 +404,80 @@ func extractIPFromAddr(addr net.Addr) string {
 	host, _, err := net.SplitHostPort(addrStr)
 	if err != nil {
 		// If SplitHostPort fails, assume it's just an IP address
-		return addrStr
+		host = addrStr
 	}
+	// Link-local answers carry the interface they arrived on, which the session already names
+	host, _ = splitZone(host)
 	return host
 }
 
 // extractDNSfromIP extracts the DNS name /cname for a host found in trace.
 func extractDNSfromIP(addr net.Addr) string {
-	cname, _ := net.LookupAddr(addr.String())
+	cname, err := net.LookupAddr(addr.String())
+	if err != nil || len(cname) == 0 {
+		return ""
+	}
 	return cname[0]
 }
 
-// Stop halts the scanning process
+// Stop halts the scanning process and returns once it has fully stopped: Start or SetTarget
+// in progress and the monitoring loop have returned, the prober is closed and every channel
+// is closed. It is safe to call more than once and from several goroutines; senders blocked on
+// a full channel give up as the session is canceled, so nobody needs to read meanwhile
 func (s *Scanner) Stop() {
-	s.cancel()
-	if s.conn != nil {
-		s.conn.Close()
-		s.conn = nil
+	s.stopOnce.Do(s.shutdown)
+}
+
+// shutdown cancels the session, waits for every producer to give up and then, as the only
+// goroutine left that touches them, closes the prober and the channels
+func (s *Scanner) shutdown() {
+	s.mu.Lock()
+	s.stopCalled = true
+	s.mu.Unlock()
+	// Producers see the cancellation and return; none can register any more
+	s.end()
+	s.producers.Wait()
+
+	s.mu.Lock()
+	s.releaseProber()
+	s.mu.Unlock()
+	select {
+	case s.status <- StatusStopped:
+	default:
+	}
+	select {
+	case s.events <- Event{Kind: EventStopped, Time: time.Now()}:
+	default:
+	}
+	close(s.updates)
+	close(s.events)
+	close(s.probes)
+	close(s.status)
+	close(s.errs)
+}
+
+// Pause stops sending monitoring probes, keeping every hop's statistics and history as they are
+// A round in progress ends after the hops already being probed. Discovery isn't paused
+func (s *Scanner) Pause() {
+	if s.paused.Swap(true) {
+		return
 	}
-	// Safely close the channels (only once)
-	if !s.stopCalled {
-		s.stopCalled = true
-		s.sendStatus(StatusStopped)
-		close(s.updates)
-		close(s.status)
+	s.sendStatus(StatusPaused)
+	s.sendEvent(Event{Kind: EventPaused})
+}
+
+// Resume continues monitoring where Pause left off, with the next round on the usual schedule
+func (s *Scanner) Resume() {
+	if !s.paused.Swap(false) {
+		return
 	}
+	s.sendStatus(StatusPinging)
+	s.sendEvent(Event{Kind: EventResumed})
+}
+
+// Paused reports whether monitoring is paused
+func (s *Scanner) Paused() bool {
+	return s.paused.Load()
 }
 
 // Updates returns the channel that emits hop updates
@@ -137,75 +485,256 @@ func (s *Scanner) Updates() <-chan HopUpdate {
 	return s.updates
 }
 
+// Events returns the unified event stream: lifecycle events and every hop update
+// Events are dropped while the channel is full, so reading it is optional; the stream
+// closes after EventStopped
+func (s *Scanner) Events() <-chan Event {
+	return s.events
+}
+
+// Probes returns the channel that emits the outcome of every monitoring probe
+// Results are dropped while the channel is full, so reading it is optional
+func (s *Scanner) Probes() <-chan ProbeResult {
+	return s.probes
+}
+
 // Status returns the channel that emits status updates
 func (s *Scanner) Status() <-chan ScannerStatus {
 	return s.status
 }
 
+// Errors returns the channel that emits probe failures
+// The session keeps running after them, counting the affected probes as lost
+func (s *Scanner) Errors() <-chan error {
+	return s.errs
+}
+
+// ClockSource reports how the most recent probe was timed
+func (s *Scanner) ClockSource() ClockSource {
+	if clock, ok := s.clock.Load().(ClockSource); ok {
+		return clock
+	}
+	return ClockUnknown
+}
+
+// SocketAccess reports whether the session probes over raw or unprivileged sockets
+func (s *Scanner) SocketAccess() SocketAccess {
+	if access, ok := s.access.Load().(SocketAccess); ok {
+		return access
+	}
+	return AccessUnknown
+}
+
+// NAT64 reports the NAT64 translation the session's path crosses, if any
+func (s *Scanner) NAT64() (NAT64, bool) {
+	nat, ok := s.nat64.Load().(NAT64)
+	return nat, ok && nat.IPv4 != ""
+}
+
 // GetHops returns the current list of hops
 func (s *Scanner) GetHops() []NetworkHop {
 	return s.hops
 }
 
-// monitorLoop continuously pings all hops and sends updates
+// monitorLoop continuously pings all hops and sends updates, re-tracing the path every
+// retrace interval if one is set
 // This runs in a background goroutine
 func (s *Scanner) monitorLoop() {
-	ticker := time.NewTicker(1 * time.Second) // Update every second
+	// Only this loop reports the session's packet rate, so the governor forgets it when the loop ends
+	s.reportDemand()
+	defer sessionGovernor.setDemand(s, 0)
+
+	ticker := time.NewTicker(s.nextInterval())
 	defer ticker.Stop()
+	var retrace <-chan time.Time
+	if s.retrace > 0 {
+		retraceTicker := time.NewTicker(s.retrace)
+		defer retraceTicker.Stop()
+		retrace = retraceTicker.C
+	}
 
 	for {
 		select {
 		case <-s.ctx.Done():
 			return
+		case <-retrace:
+			if !s.paused.Load() {
+				s.retracePath()
+			}
 		case <-ticker.C:
-			s.pingAllHops()
+			if !s.paused.Load() {
+				s.pingAllHops()
+			}
+			if s.cycles > 0 && s.cycle >= s.cycles && s.ctx.Err() == nil {
+				log.Printf("[DEBUG] Completed %d monitoring cycles\n", s.cycle)
+				s.sendStatus(StatusCompleted)
+				s.sendEvent(Event{Kind: EventCompleted, Hops: len(s.hops), Path: copyHops(s.hops)})
+				return
+			}
+			s.reportDemand()
+			ticker.Reset(s.nextInterval())
 		}
 	}
 }
 
-// pingAllHops pings all hops in parallel and sends updates
+// pingAllHops probes every hop probesPerRound times and sends the recomputed statistics to the UI
+// Hops are probed concurrently by up to concurrency workers, so a long path still fits in one
+// interval; probers that keep one probe in flight at a time get a single worker
 func (s *Scanner) pingAllHops() {
 	// Check if the hops are empty
 	if len(s.hops) == 0 {
 		return
 	}
 
-	for i, hop := range s.hops {
-		latency, loss := s.pingHop(hop.IP)
-
-		// Build updated latency history (rolling window of last MaxLatencyHistory samples)
-		newHistory := make([]float64, 0, MaxLatencyHistory)
-		if len(hop.LatencyHistory) > 0 {
-			// Copy existing history
-			if len(hop.LatencyHistory) >= MaxLatencyHistory {
-				// Take last (MaxLatencyHistory-1) elements to make room for new one
-				newHistory = append(newHistory, hop.LatencyHistory[1:]...)
-			} else {
-				newHistory = append(newHistory, hop.LatencyHistory...)
+	// Every hop's update from this round carries the same cycle and timestamp
+	s.cycle++
+	cycleStart := time.Now()
+	if s.rotate {
+		s.rotateIdentity()
+	}
+
+	// Loss of hops is judged against the destination's as of the previous round
+	destLoss := s.destinationLoss()
+
+	// The governor holding back the sessions' load also thins out the workers
+	workers := 1
+	if _, ok := s.prober.(concurrentProber); ok {
+		workers = max(1, int(float64(min(s.concurrency, len(s.hops)))/sessionGovernor.slowdown()))
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				s.pingHop(i, cycleStart, destLoss)
 			}
+		}()
+	}
+	for i := range s.hops {
+		if s.ctx.Err() != nil || s.paused.Load() {
+			break
 		}
-		// Append new latency (use -1 to indicate timeout/no response)
-		if latency > 0 {
-			newHistory = append(newHistory, latency)
-		} else {
-			newHistory = append(newHistory, -1) // -1 indicates timeout
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// pingHop sends the hop at index i its round of probes and sends the recomputed statistics to the UI
+// Workers of a round each take different hops, so only the hop's own state is touched; destLoss
+// is the destination's loss the hop is flagged as rate-limited against, -1 if it isn't known
+func (s *Scanner) pingHop(i int, cycleStart time.Time, destLoss float64) {
+	hop := s.hops[i]
+	spacing := s.hopSpacing(hop)
+
+	// Send the round's probes, folding each answer into the session counters
+	stats := statsOf(hop, s.lossWindow)
+	replySize, replyTTL, extensions := hop.ReplySize, hop.ReplyTTL, hop.Extensions
+	responders, counts := hop.ResponderHistory, hop.Responders
+	status := HopTimeout
+	var roundSum float64
+	var roundReplies int
+	for n := 0; n < s.probesPerRound; n++ {
+		if n > 0 && !s.pace(spacing) {
+			break
+		}
+		result := ProbeResult{Index: i, TTL: hop.TTL, Seq: int(s.probeSeq.Add(1)), Cycle: s.cycle, Latency: -1, Time: time.Now()}
+		reply, ok := s.probe(hop.TTL)
+		if !ok || reply.Latency <= 0 {
+			stats.miss()
+			s.probeRTTs[i].push(-1)
+			s.sendProbeResult(result)
+			continue
 		}
+		result.Latency, result.Responder = reply.Latency, reply.Responder
+		s.probeRTTs[i].push(reply.Latency)
+		s.sendProbeResult(result)
+		responders = appendResponder(responders, reply.Responder)
+		counts = countResponder(counts, reply.Responder)
+		stats.add(reply.Latency)
+		roundSum += reply.Latency
+		roundReplies++
+		replySize, replyTTL, extensions = reply.Size, reply.TTL, reply.Extensions
+		status = replyStatusOf(reply)
+	}
 
-		// Calculate average from valid latencies in history
-		avgLatency := calculateAverageLatency(newHistory)
+	// Record the round's mean latency (use -1 to indicate every probe timed out)
+	if roundReplies > 0 {
+		s.rounds[i].push(roundSum / float64(roundReplies))
+	} else {
+		s.rounds[i].push(-1) // -1 indicates timeout
+	}
 
-		updatedHop := NetworkHop{
-			IP:             hop.IP,
-			AvgLatency:     avgLatency,
-			LossPercent:    loss,
-			LatencyHistory: newHistory,
-		}
+	// Track which router answered for this TTL to detect flapping
+	stability := responderStability(responders)
 
-		// Update local hop data
-		s.hops[i] = updatedHop
+	// The dominant responder is the row identity; the others are listed as alternates
+	ip := hop.IP
+	var alternates []Responder
+	if shares := responderShares(responders); len(shares) > 0 {
+		ip = shares[0].IP
+		alternates = shares[1:]
+	}
+
+	updatedHop := NetworkHop{
+		TTL:              hop.TTL,
+		IP:               ip,
+		ReplySize:        replySize,
+		ReplyTTL:         replyTTL,
+		Extensions:       extensions,
+		LatencyHistory:   s.rounds[i].snapshot(),
+		ProbeHistory:     s.probeRTTs[i].snapshot(),
+		ResponderHistory: responders,
+		Stability:        stability,
+		Flapping:         stability < FlapStabilityThreshold,
+		Alternates:       alternates,
+		Responders:       counts,
+		Status:           status,
+	}
+	stats.apply(&updatedHop)
+	updatedHop.Duplicates = hop.Duplicates
+	if counter, ok := s.prober.(duplicateCounter); ok {
+		updatedHop.Duplicates += counter.takeDuplicates(hop.TTL)
+	}
+	updatedHop.RateLimited = likelyRateLimited(updatedHop, i == len(s.hops)-1, destLoss)
+	s.applyASN(&updatedHop)
+
+	// Update local hop data
+	s.hops[i] = updatedHop
+
+	update := HopUpdate{Index: i, Hop: updatedHop, Cycle: s.cycle, Time: cycleStart}
+	if !s.sendUpdate(update) {
+		return
+	}
+	s.sendEvent(Event{Kind: EventHopUpdated, Update: &update})
+}
+
+// sendPending tells the UI which TTL discovery is probing for the next row
+// Returns false if the scanner was stopped meanwhile
+func (s *Scanner) sendPending(index, ttl int) bool {
+	return s.sendUpdate(HopUpdate{Index: index, Hop: NetworkHop{TTL: ttl}, Pending: true})
+}
+
+// applyASN fills in the hop's origin AS if it is known, and otherwise starts looking it up
+func (s *Scanner) applyASN(hop *NetworkHop) {
+	if s.asn == nil {
+		return
+	}
+	if info, ok := s.asn.Cached(hop.IP); ok {
+		hop.ASN, hop.ASName = info.ASN, info.Name
+		return
+	}
+	s.asn.Prefetch(hop.IP)
+}
 
-		s.updates <- HopUpdate{Index: i, Hop: updatedHop}
+// counterLossPercent returns the share of sent probes that got no answer
+func counterLossPercent(sent, received int) float64 {
+	if sent == 0 {
+		return 0
 	}
+	return float64(sent-received) / float64(sent) * 100
 }
 
 // calculateAverageLatency calculates average from valid latency values (ignoring -1 timeouts)
@@ -227,204 +756,139 @@ func calculateAverageLatency(history []float64) float64 {
 	return sum / float64(count)
 }
 
-func (s *Scanner) pingHop(ip string) (float64, float64) {
-	// TODO: Implement actual ICMP ping logic here
-	startTime := time.Now()
-
-	log.Printf("[DEBUG] Sending PING packet to %s\n", ip)
-
-	// Create ICMP Message. Type will be Echo Request
-	msg := icmp.Message{
-		Type: ipv4.ICMPTypeEcho,
-		Code: 0,
-		Body: &icmp.Echo{
-			ID:   os.Getpid() % 0xFFFF,
-			Seq:  1,
-			Data: []byte("HELLO-PING"),
-		},
+// calculateLossPercent returns the share of timeouts (-1) in a latency history
+func calculateLossPercent(history []float64) float64 {
+	if len(history) == 0 {
+		return 0
 	}
-
-	// Marshal the message
-	msgBytes, err := msg.Marshal(nil)
-	if err != nil {
-		log.Fatalf("Failed to marshal message: %v", err)
+	timeouts := 0
+	for _, lat := range history {
+		if lat < 0 {
+			timeouts++
+		}
 	}
+	return float64(timeouts) / float64(len(history)) * 100
+}
 
-	// Send the message
-	if _, err := s.conn.WriteTo(msgBytes, &net.IPAddr{IP: net.ParseIP(ip)}); err != nil {
-		log.Fatalf("Failed to send message: %v", err)
+// probe sends one TTL-limited probe and waits for its answer until the timeout or until the
+// session is canceled, so Stop and SetTarget never wait out a probe in flight: probers that
+// allow it get a probe of their own, others fall back to probeSerially
+func (s *Scanner) probe(ttl int) (ProbeReply, bool) {
+	prober, ok := s.prober.(concurrentProber)
+	if !ok {
+		return s.probeSerially(ttl)
 	}
-	log.Printf("[DEBUG] Sent PING packet to %s (ID=%d, Seq=%d)\n", ip, msg.Body.(*icmp.Echo).ID, msg.Body.(*icmp.Echo).Seq)
-
-	// Receive the response
-	buf := make([]byte, 1500) // MTU size
-	s.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
-
-	n, peerAddr, err := s.conn.ReadFrom(buf)
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+	reply, ok, err := prober.probeContext(ctx, ttl)
 	if err != nil {
-		log.Printf("[DEBUG] PING to %s: Timeout (no response within 3 seconds)\n", ip)
-		return 0, 0
+		if s.ctx.Err() != nil {
+			return ProbeReply{}, false
+		}
+		log.Printf("[DEBUG] TTL=%d: Failed to send probe: %v\n", ttl, err)
+		s.sendError(probeError(ttl, err))
+		return ProbeReply{}, false
 	}
+	if ok {
+		s.clock.Store(reply.Clock)
+	}
+	return reply, ok
+}
 
-	elapsed := time.Since(startTime)
-	log.Printf("[DEBUG] Received response from %s (%.2fms)\n", peerAddr.String(), elapsed.Seconds()*1000)
-
-	// Unmarshal the response
-	recvMsg, err := icmp.ParseMessage(1, buf[:n]) // 1 for ICMPv4
-	if err != nil {
-		log.Printf("[DEBUG] PING to %s: Failed to parse response: %v\n", ip, err)
-		return 0, 0
-	}
-	log.Printf("[DEBUG] PING to %s: Parsed ICMP message type: %v\n", ip, recvMsg.Type)
-
-	// Handle the response and return the latency and loss percentage
-	switch recvMsg.Type {
-	case ipv4.ICMPTypeEchoReply:
-		return elapsed.Seconds() * 1000, 0
-	case ipv4.ICMPTypeTimeExceeded:
-		return 0, 100
-	case ipv4.ICMPTypeDestinationUnreachable:
-		return 0, 100
-	default:
-		return 0, 0
+// probeSerially sends one probe with SendProbe and waits for its answer with AwaitReply
+// Once the session is canceled it sends nothing, and probers that can be interrupted end the wait
+func (s *Scanner) probeSerially(ttl int) (ProbeReply, bool) {
+	if s.ctx.Err() != nil {
+		return ProbeReply{}, false
+	}
+	if err := s.prober.SendProbe(ttl); err != nil {
+		if s.ctx.Err() != nil {
+			return ProbeReply{}, false
+		}
+		log.Printf("[DEBUG] TTL=%d: Failed to send probe: %v\n", ttl, err)
+		s.sendError(probeError(ttl, err))
+		return ProbeReply{}, false
+	}
+	if i, ok := s.prober.(interrupter); ok {
+		stop := context.AfterFunc(s.ctx, i.interrupt)
+		defer stop()
+	}
+	reply, ok := s.prober.AwaitReply(time.Now().Add(s.timeout))
+	if ok {
+		s.clock.Store(reply.Clock)
 	}
+	return reply, ok
 }
 
 // performTraceroute performs a traceroute to the target hostname
-// Sends hops to the updates channel as they're discovered (for real-time UI updates)
+// With announce set, sends hops to the updates channel as they're discovered (for real-time UI updates);
+// re-traces during monitoring run quietly and report only what changed
 // Returns a slice of NetworkHop with IP addresses populated
-func performTraceroute(hostname string, updates chan<- HopUpdate, ctx context.Context) ([]NetworkHop, error) {
-	// Resolve the hostname to an IP address
-	dstAddr, err := net.ResolveIPAddr("ip", hostname)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve hostname: %v", err)
-	}
-
-	// Create a new ICMP connection
-	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
-	if err != nil {
-
-		return nil, fmt.Errorf("failed to create ICMP connection: %v", err)
-	}
-	defer conn.Close()
+func (s *Scanner) performTraceroute(announce bool) ([]NetworkHop, error) {
+	dstAddr := s.dstAddr
 
-	fmt.Printf("Starting traceroute to: %s on IP: %s\n", hostname, dstAddr.IP.String())
+	log.Printf("[DEBUG] Starting traceroute to: %s on IP: %s\n", s.hostname, dstAddr.IP.String())
 
 	// Local slice to collect hops
 	hops := make([]NetworkHop, 0)
 
-	// Perform traceroute
-	destinationReached := false
-	for ttl := 1; ttl <= 30; ttl++ {
-		startTime := time.Now()
-
-		log.Printf("[DEBUG] TTL=%d: Sending probe to %s\n", ttl, dstAddr.IP.String())
-
-		// Set TTL
-		if err := conn.IPv4PacketConn().SetTTL(ttl); err != nil {
-			log.Fatalf("Failed to set TTL: %v", err)
-		}
-
-		// Create ICMP Message. Type will be Echo Request
-		msg := icmp.Message{
-			Type: ipv4.ICMPTypeEcho,
-			Code: 0,
-			Body: &icmp.Echo{
-				ID:   os.Getpid() % 0xFFFF,
-				Seq:  ttl,
-				Data: []byte("HELLO-TRACEROUTE"),
-			},
-		}
-
-		// Marshal the message
-		msgBytes, err := msg.Marshal(nil)
-		if err != nil {
-			log.Fatalf("Failed to marshal message: %v", err)
-		}
+	// Find how far away the destination is first, so the trace stops there
+	if announce && !s.sendPending(0, 0) {
+		return hops, nil
+	}
+	limit := s.maxTTL
+	targetTTL, probed := s.searchTargetTTL()
+	if targetTTL > 0 {
+		limit = targetTTL
+	}
 
-		// Send the message
-		if _, err := conn.WriteTo(msgBytes, dstAddr); err != nil {
-			log.Fatalf("Failed to send message: %v", err)
+	// Perform traceroute
+	for ttl := 1; ttl <= limit; ttl++ {
+		if s.ctx.Err() != nil {
+			return hops, nil
 		}
-		log.Printf("[DEBUG] TTL=%d: Sent ICMP Echo Request (ID=%d, Seq=%d)\n", ttl, msg.Body.(*icmp.Echo).ID, msg.Body.(*icmp.Echo).Seq)
-
-		// Receive the response
-		buf := make([]byte, 1500) // MTU size
-		conn.SetReadDeadline(time.Now().Add(3 * time.Second))
-
-		n, peerAddr, err := conn.ReadFrom(buf)
-		if err != nil {
-			fmt.Printf("%d\t*\t*\t*\n", ttl) // Timeout
-			log.Printf("[DEBUG] TTL=%d: Timeout (no response within 3 seconds)\n", ttl)
-			continue
+		outcome, seen := probed[ttl]
+		if !seen {
+			if announce && !s.sendPending(len(hops), ttl) {
+				return hops, nil
+			}
+			log.Printf("[DEBUG] TTL=%d: Sending probe to %s\n", ttl, dstAddr.IP.String())
+			outcome.reply, outcome.ok = s.probe(ttl)
 		}
 
-		elapsed := time.Since(startTime)
-		log.Printf("[DEBUG] TTL=%d: Received response from %s (%.2fms)\n", ttl, peerAddr.String(), elapsed.Seconds()*1000)
-
-		// Unmarshal the response
-		recvMsg, err := icmp.ParseMessage(1, buf[:n]) // 1 for ICMPv4
-		if err != nil {
-			log.Printf("[DEBUG] TTL=%d: Failed to parse response: %v\n", ttl, err)
+		reply, ok := outcome.reply, outcome.ok
+		if !ok {
+			log.Printf("[DEBUG] TTL=%d: Timeout (no response within %v)\n", ttl, s.timeout)
 			continue
 		}
-		log.Printf("[DEBUG] TTL=%d: Parsed ICMP message type: %v\n", ttl, recvMsg.Type)
 
 		// Handle the response and add to hops
-		switch recvMsg.Type {
-		case ipv4.ICMPTypeEchoReply:
-			if recvMsg.Body.(*icmp.Echo).ID != os.Getpid()%0xFFFF {
-				log.Printf("[DEBUG] TTL=%d: Received EchoReply with wrong ID, ignoring\n", ttl)
-				continue // Not our message
-			}
-			reply := recvMsg.Body.(*icmp.Echo)
-			// Extract IP from peerAddr (format: "ip:port" or just "ip")
-			hopIP := extractIPFromAddr(peerAddr)
-			fmt.Printf("%d\t%s\t%d\t%.2fms\n", ttl, hopIP, reply.Seq, elapsed.Seconds()*1000)
-			hop := NetworkHop{IP: hopIP, AvgLatency: elapsed.Seconds() * 1000, LossPercent: 0}
-			hops = append(hops, hop)
-			log.Printf("[DEBUG] Added final hop: IP=%s, Latency=%.2fms\n", hopIP, elapsed.Seconds()*1000)
-
-			// Send hop to UI in real-time
+		hopIP := reply.Responder
+		status := replyStatusOf(reply)
+		log.Printf("[DEBUG] TTL=%d: %s replied in %.2fms %s\n", ttl, hopIP, reply.Latency, status.Annotation())
+		hop := NetworkHop{TTL: ttl, IP: hopIP, AvgLatency: reply.Latency, LossPercent: 0, ReplySize: reply.Size, ReplyTTL: reply.TTL, Extensions: reply.Extensions, Status: status}
+		s.applyASN(&hop)
+		hops = append(hops, hop)
+		log.Printf("[DEBUG] Added hop: IP=%s, Latency=%.2fms\n", hopIP, reply.Latency)
+
+		// Send hop to UI in real-time
+		if announce {
 			hopIndex := len(hops) - 1
-			select {
-			case updates <- HopUpdate{Index: hopIndex, Hop: hop}:
-				log.Printf("[DEBUG] Sent hop %d to UI: IP=%s\n", hopIndex+1, hopIP)
-			case <-ctx.Done():
+			update := HopUpdate{Index: hopIndex, Hop: hop, Time: time.Now()}
+			if !s.sendUpdate(update) {
 				return hops, nil
 			}
-
-			// Destination reached, traceroute complete
-			destinationReached = true
-
-		case ipv4.ICMPTypeTimeExceeded:
-			// Extract IP from peerAddr (format: "ip:port" or just "ip")
-			hopIP := extractIPFromAddr(peerAddr)
-			fmt.Printf("%d\t%s\t%d\t%.2fms\n", ttl, hopIP, ttl, elapsed.Seconds()*1000)
-			hop := NetworkHop{IP: hopIP, AvgLatency: elapsed.Seconds() * 1000, LossPercent: 0}
-			hops = append(hops, hop)
-			log.Printf("[DEBUG] TTL=%d: Received TimeExceeded from %s (%.2fms)\n", ttl, hopIP, elapsed.Seconds()*1000)
-			log.Printf("[DEBUG] Added hop: IP=%s, Latency=%.2fms\n", hopIP, elapsed.Seconds()*1000)
-
-			// Send hop to UI in real-time
-			hopIndex := len(hops) - 1
-			select {
-			case updates <- HopUpdate{Index: hopIndex, Hop: hop}:
-				log.Printf("[DEBUG] Sent hop %d to UI: IP=%s\n", hopIndex+1, hopIP)
-			case <-ctx.Done():
-				return hops, nil
-			}
-
-			// Continue to next TTL
-			continue
-		default:
-			fmt.Printf("%d\t*\t*\t*\n", ttl) // Unknown type
-			log.Printf("[DEBUG] TTL=%d: Unknown ICMP type: %v\n", ttl, recvMsg.Type)
-			continue
+			s.sendEvent(Event{Kind: EventHopDiscovered, Update: &update})
+			log.Printf("[DEBUG] Sent hop %d to UI: IP=%s\n", hopIndex+1, hopIP)
 		}
 
-		// Break out of loop if destination reached
-		if destinationReached {
+		// Destination reached, traceroute complete
+		if reply.Reached {
+			break
+		}
+		// A router rejecting the probe ends the path; probes with higher TTLs get the same answer
+		if status.Dead() {
+			log.Printf("[DEBUG] TTL=%d: %s, path ends here\n", ttl, status)
 			break
 		}
 	}
@@ -0,0 +1,228 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cancelWithin is how soon Start and Stop must give up once canceled; the probe timeout of the
+// scanners under test is far longer, so waiting out a probe fails the test
+const cancelWithin = time.Second
+
+// fakeProber answers like a path of hops routers, the last being the destination
+// Probes it holds get no answer until their deadline or until the scanner interrupts them
+type fakeProber struct {
+	hops int
+	hold func(ttl int) bool // Probes to hold; nil holds none
+	held chan int           // Receives the TTL of every probe held
+
+	mu     sync.Mutex
+	last   int
+	closed bool
+
+	interrupted   chan struct{}
+	interruptOnce sync.Once
+}
+
+// newFakeProber creates a prober for a path of hops routers holding the probes hold selects
+func newFakeProber(hops int, hold func(ttl int) bool) *fakeProber {
+	return &fakeProber{
+		hops:        hops,
+		hold:        hold,
+		held:        make(chan int, 64),
+		interrupted: make(chan struct{}),
+	}
+}
+
+func (p *fakeProber) SendProbe(ttl int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("prober closed")
+	}
+	p.last = ttl
+	return nil
+}
+
+func (p *fakeProber) AwaitReply(deadline time.Time) (ProbeReply, bool) {
+	p.mu.Lock()
+	ttl := p.last
+	p.mu.Unlock()
+	if p.hold != nil && p.hold(ttl) {
+		select {
+		case p.held <- ttl:
+		default:
+		}
+		select {
+		case <-p.interrupted:
+		case <-time.After(time.Until(deadline)):
+		}
+		return ProbeReply{}, false
+	}
+	hop := min(ttl, p.hops)
+	return ProbeReply{Latency: 1, Responder: fmt.Sprintf("192.0.2.%d", hop), Reached: hop == p.hops}, true
+}
+
+func (p *fakeProber) interrupt() {
+	p.interruptOnce.Do(func() { close(p.interrupted) })
+}
+
+func (p *fakeProber) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *fakeProber) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// newTestScanner creates a scanner probing through prober with a probe timeout Start must not wait out
+func newTestScanner(hostname string, prober Prober) *Scanner {
+	s := NewScanner(hostname, WithTimeout(30*time.Second), WithInterval(100*time.Millisecond), WithMaxTTL(16))
+	s.SetProber(prober)
+	return s
+}
+
+// waitHeld waits for the prober to hold a probe, failing the test if none comes
+func waitHeld(t *testing.T, prober *fakeProber) int {
+	t.Helper()
+	select {
+	case ttl := <-prober.held:
+		return ttl
+	case <-time.After(5 * time.Second):
+		t.Fatal("no probe was held")
+		return 0
+	}
+}
+
+// cancelStart starts s, cancels its context once ready returns and checks that Start gives up
+// promptly with ErrCanceled, and that Stop then returns promptly and closes the prober
+func cancelStart(t *testing.T, s *Scanner, prober *fakeProber, ready func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- s.Start(ctx) }()
+
+	ready()
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("Start returned %v, want ErrCanceled", err)
+		}
+	case <-time.After(cancelWithin):
+		t.Fatal("Start didn't return after cancellation")
+	}
+	stopWithin(t, s)
+	if !prober.isClosed() {
+		t.Error("prober not closed after Stop")
+	}
+}
+
+// stopWithin stops s, failing the test if Stop doesn't return promptly
+func stopWithin(t *testing.T, s *Scanner) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(cancelWithin):
+		t.Fatal("Stop didn't return promptly")
+	}
+}
+
+func TestStartCanceledDuringResolve(t *testing.T) {
+	// Name servers never answer, so the lookup lasts until it is canceled
+	dialing := make(chan struct{}, 1)
+	resolver := dnsResolver
+	dnsResolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			select {
+			case dialing <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	defer func() { dnsResolver = resolver }()
+
+	prober := newFakeProber(3, nil)
+	s := newTestScanner("target.visual-mtr.test", prober)
+	cancelStart(t, s, prober, func() {
+		select {
+		case <-dialing:
+		case <-time.After(5 * time.Second):
+			t.Fatal("target was never looked up")
+		}
+	})
+}
+
+func TestStartCanceledDuringTTLSearch(t *testing.T) {
+	// The search starts at the max TTL
+	prober := newFakeProber(3, func(ttl int) bool { return ttl == 16 })
+	s := newTestScanner("127.0.0.1", prober)
+	cancelStart(t, s, prober, func() { waitHeld(t, prober) })
+}
+
+func TestStartCanceledDuringTrace(t *testing.T) {
+	// The search finds the destination at TTL 3 without probing TTL 1, which the trace then probes
+	prober := newFakeProber(3, func(ttl int) bool { return ttl == 1 })
+	s := newTestScanner("127.0.0.1", prober)
+	cancelStart(t, s, prober, func() { waitHeld(t, prober) })
+}
+
+func TestStartStopDuringTrace(t *testing.T) {
+	prober := newFakeProber(3, func(ttl int) bool { return ttl == 1 })
+	s := newTestScanner("127.0.0.1", prober)
+	errc := make(chan error, 1)
+	go func() { errc <- s.Start(context.Background()) }()
+
+	waitHeld(t, prober)
+	stopWithin(t, s)
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("Start returned %v, want ErrCanceled", err)
+		}
+	default:
+		t.Error("Stop returned before Start")
+	}
+	if !prober.isClosed() {
+		t.Error("prober not closed after Stop")
+	}
+}
+
+func TestStopDuringMonitoring(t *testing.T) {
+	var monitoring atomic.Bool
+	prober := newFakeProber(3, func(int) bool { return monitoring.Load() })
+	s := newTestScanner("127.0.0.1", prober)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	monitoring.Store(true)
+
+	waitHeld(t, prober)
+	stopWithin(t, s)
+	if !prober.isClosed() {
+		t.Error("prober not closed after Stop")
+	}
+	for range s.Updates() {
+		// Stop closes the channel, ending the loop
+	}
+}
@@ -0,0 +1,106 @@
+package network
+
+import "net"
+
+// Segment identifies which part of the network path a hop belongs to
+type Segment string
+
+const (
+	SegmentAccess      Segment = "Access"      // Local network up to the home/office gateway
+	SegmentISP         Segment = "ISP"         // The access provider's network
+	SegmentTransit     Segment = "Transit"     // Upstream/backbone providers
+	SegmentDestination Segment = "Destination" // The target itself
+)
+
+// RTTStepThreshold is the minimum latency jump (ms) treated as leaving the ISP network
+const RTTStepThreshold = 10.0
+
+// ClassifySegments splits the path into access/ISP/transit/destination sections
+// Leading private hops are the access segment and the final hop is the destination.
+// The remaining hops are split where the origin AS first changes when ASNs are known,
+// and otherwise at the largest RTT step, which usually marks where traffic leaves the
+// access provider for a long-haul or transit link.
+func ClassifySegments(hops []NetworkHop) []Segment {
+	segments := make([]Segment, len(hops))
+	if len(hops) == 0 {
+		return segments
+	}
+
+	last := len(hops) - 1
+	segments[last] = SegmentDestination
+
+	// Leading private hops belong to the local network
+	first := 0
+	for first < last && isPrivateIP(hops[first].IP) {
+		segments[first] = SegmentAccess
+		first++
+	}
+
+	boundary, ok := asnBoundary(hops, first, last)
+	if !ok {
+		boundary = transitBoundary(hops, first, last)
+	}
+	for i := first; i < last; i++ {
+		if i < boundary {
+			segments[i] = SegmentISP
+		} else {
+			segments[i] = SegmentTransit
+		}
+	}
+
+	return segments
+}
+
+// asnBoundary returns the index of the first hop in hops[first:last] announced by a
+// different AS than the first ISP hop, or last if every known AS is the ISP's
+// Returns false when the ASNs needed to decide haven't been looked up
+func asnBoundary(hops []NetworkHop, first, last int) (int, bool) {
+	if first >= last || hops[first].ASN == 0 {
+		return 0, false
+	}
+	known := false
+	for i := first + 1; i < last; i++ {
+		if hops[i].ASN == 0 {
+			continue
+		}
+		if hops[i].ASN != hops[first].ASN {
+			return i, true
+		}
+		known = true
+	}
+	return last, known
+}
+
+// transitBoundary returns the index of the first transit hop in hops[first:last]
+// Returns last when no RTT step exceeds RTTStepThreshold
+func transitBoundary(hops []NetworkHop, first, last int) int {
+	boundary := last
+	largestStep := RTTStepThreshold
+	prevLatency := 0.0
+
+	for i := first; i < last; i++ {
+		latency := hops[i].AvgLatency
+		if latency <= 0 {
+			continue // Timeouts carry no RTT information
+		}
+		// The first ISP hop always stays with the ISP
+		if prevLatency > 0 && i > first {
+			if step := latency - prevLatency; step >= largestStep {
+				largestStep = step
+				boundary = i
+			}
+		}
+		prevLatency = latency
+	}
+
+	return boundary
+}
+
+// isPrivateIP reports whether the address is on a local/private network
+func isPrivateIP(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	return parsed.IsPrivate() || parsed.IsLoopback() || parsed.IsLinkLocalUnicast()
+}
@@ -0,0 +1,199 @@
+package network
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// SelfCheckProbes is the number of echo requests sent per self-check stage
+const SelfCheckProbes = 5
+
+// FirewallVerdict is the outcome of the firewall self-check
+type FirewallVerdict string
+
+const (
+	VerdictOK              FirewallVerdict = "ICMP probes and replies pass the local host"
+	VerdictNoRawSocket     FirewallVerdict = "Raw ICMP sockets are not permitted (run as root or grant CAP_NET_RAW)"
+	VerdictOutboundBlocked FirewallVerdict = "Outgoing ICMP is blocked by the local firewall"
+	VerdictInboundBlocked  FirewallVerdict = "ICMP replies reach this host but are dropped by the local firewall"
+	VerdictSocketFiltered  FirewallVerdict = "The kernel received replies but they never reached the probe socket"
+	VerdictNoReplies       FirewallVerdict = "No replies arrived at this host; the loss is beyond the local machine"
+	VerdictInconclusive    FirewallVerdict = "No replies arrived; kernel counters are unavailable to tell local from remote drops"
+)
+
+// SelfCheckResult reports what the firewall self-check observed
+type SelfCheckResult struct {
+	Target          string
+	Verdict         FirewallVerdict
+	LoopbackReplies int // Replies to echo requests sent to 127.0.0.1
+	Sent            int // Echo requests successfully sent to the target
+	Replies         int // Replies read from our socket
+	KernelEchoReps  int // Echo replies counted by the kernel during the check (-1 if unknown)
+	KernelDropped   int // Packets received by IP but not delivered locally (-1 if unknown)
+}
+
+// String renders the result as a short report
+func (r SelfCheckResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Verdict: %s\n\n", r.Verdict)
+	fmt.Fprintf(&b, "Loopback echo replies: %d/%d\n", r.LoopbackReplies, SelfCheckProbes)
+	fmt.Fprintf(&b, "Probes sent to %s: %d/%d\n", r.Target, r.Sent, SelfCheckProbes)
+	fmt.Fprintf(&b, "Replies received by the app: %d\n", r.Replies)
+	if r.KernelEchoReps >= 0 {
+		fmt.Fprintf(&b, "Echo replies counted by the kernel: %d\n", r.KernelEchoReps)
+		fmt.Fprintf(&b, "Packets dropped before local delivery: %d\n", r.KernelDropped)
+	} else {
+		fmt.Fprintf(&b, "Kernel ICMP counters: unavailable on this platform\n")
+	}
+	return b.String()
+}
+
+// FirewallSelfCheck determines whether the local host is blocking probes or replies
+// It compares what our raw socket sent and received with the kernel's own IP/ICMP counters:
+// a reply the kernel saw but never delivered was dropped locally, not by the network.
+func FirewallSelfCheck(target string) (SelfCheckResult, error) {
+	result := SelfCheckResult{Target: target, KernelEchoReps: -1, KernelDropped: -1}
+
+	dstAddr, err := net.ResolveIPAddr("ip4", target)
+	if err != nil {
+		return result, fmt.Errorf("failed to resolve hostname: %v", err)
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		result.Verdict = VerdictNoRawSocket
+		return result, nil
+	}
+	defer conn.Close()
+
+	// Loopback first: a failure here can only be local policy
+	loopback := &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	_, result.LoopbackReplies, err = echoBurst(conn, loopback)
+	if errors.Is(err, syscall.EPERM) {
+		result.Verdict = VerdictOutboundBlocked
+		return result, nil
+	}
+
+	before, countersErr := readSNMPCounters()
+	result.Sent, result.Replies, err = echoBurst(conn, dstAddr)
+	if errors.Is(err, syscall.EPERM) {
+		result.Verdict = VerdictOutboundBlocked
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	after, _ := readSNMPCounters()
+
+	if countersErr == nil && after != nil {
+		result.KernelEchoReps = after["Icmp.InEchoReps"] - before["Icmp.InEchoReps"]
+		received := after["Ip.InReceives"] - before["Ip.InReceives"]
+		delivered := after["Ip.InDelivers"] - before["Ip.InDelivers"]
+		result.KernelDropped = max(received-delivered, 0)
+	}
+
+	switch {
+	case result.Replies > 0:
+		result.Verdict = VerdictOK
+	case result.LoopbackReplies == 0:
+		result.Verdict = VerdictInboundBlocked
+	case result.KernelEchoReps >= result.Sent && result.Sent > 0:
+		result.Verdict = VerdictSocketFiltered
+	case result.KernelDropped >= result.Sent && result.Sent > 0:
+		result.Verdict = VerdictInboundBlocked
+	case result.KernelEchoReps >= 0:
+		result.Verdict = VerdictNoReplies
+	default:
+		result.Verdict = VerdictInconclusive
+	}
+	return result, nil
+}
+
+// echoBurst sends SelfCheckProbes echo requests and counts matching replies
+func echoBurst(conn *icmp.PacketConn, dst *net.IPAddr) (int, int, error) {
+	id := allocateEchoID()
+	defer releaseEchoID(id)
+	token := newProbeToken()
+
+	sent := 0
+	for seq := 1; seq <= SelfCheckProbes; seq++ {
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho,
+			Code: 0,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: probePayload(token, uint32(seq), 0)},
+		}
+		msgBytes, err := msg.Marshal(nil)
+		if err != nil {
+			return sent, 0, fmt.Errorf("failed to marshal message: %v", err)
+		}
+		if _, err := conn.WriteTo(msgBytes, dst); err != nil {
+			return sent, 0, err
+		}
+		sent++
+	}
+
+	// Collect replies until the deadline passes
+	replies := 0
+	buf := make([]byte, 1500) // MTU size
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for replies < sent {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			break // Deadline reached
+		}
+		if extractIPFromAddr(peer) != dst.IP.String() {
+			continue
+		}
+		recvMsg, err := icmp.ParseMessage(1, buf[:n]) // 1 for ICMPv4
+		if err != nil || recvMsg.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		if echo, ok := recvMsg.Body.(*icmp.Echo); ok && echo.ID == id && validProbePayload(echo.Data, token, uint32(echo.Seq), 0) {
+			replies++
+		}
+	}
+	return sent, replies, nil
+}
+
+// readSNMPCounters reads the Linux kernel IP and ICMP counters, keyed as "Ip.InReceives" etc.
+func readSNMPCounters() (map[string]int, error) {
+	f, err := os.Open("/proc/net/snmp")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	var header []string
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// Each protocol has a header line followed by a value line with the same prefix
+		if header == nil || header[0] != fields[0] {
+			header = fields
+			continue
+		}
+		proto := strings.TrimSuffix(fields[0], ":")
+		for i := 1; i < len(fields) && i < len(header); i++ {
+			value, err := strconv.Atoi(fields[i])
+			if err == nil {
+				counters[proto+"."+header[i]] = value
+			}
+		}
+		header = nil
+	}
+	return counters, scanner.Err()
+}
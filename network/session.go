@@ -0,0 +1,74 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SavedSession is a monitoring session saved on quit so it can be resumed on the next launch
+type SavedSession struct {
+	Target   string       `json:"target"`
+	Protocol Protocol     `json:"protocol"`
+	Saved    time.Time    `json:"saved"`
+	Hops     []NetworkHop `json:"hops"` // Path and history at the time of saving
+}
+
+// SaveSession writes a session to path
+func SaveSession(path string, session SavedSession) error {
+	session.Hops = copyHops(session.Hops)
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %v", err)
+	}
+	return nil
+}
+
+// LoadSession reads a session saved by SaveSession
+func LoadSession(path string) (SavedSession, error) {
+	var session SavedSession
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return session, fmt.Errorf("failed to read session: %v", err)
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, fmt.Errorf("failed to parse session: %v", err)
+	}
+	return session, nil
+}
+
+// restoreHistory carries the latency and responder history of earlier hops over to a
+// freshly traced path, matching hops by TTL, so a resumed session continues its graphs
+// window is the session's loss window, which the carried-over probe outcomes are cut to
+func restoreHistory(hops, earlier []NetworkHop, window int) []NetworkHop {
+	byTTL := make(map[int]NetworkHop, len(earlier))
+	for _, hop := range earlier {
+		byTTL[hop.TTL] = hop
+	}
+
+	for i, hop := range hops {
+		old, ok := byTTL[hop.TTL]
+		if !ok {
+			continue
+		}
+		hop.LatencyHistory = append([]float64(nil), old.LatencyHistory...)
+		hop.ProbeHistory = append([]float64(nil), old.ProbeHistory...)
+		hop.ResponderHistory = append([]string(nil), old.ResponderHistory...)
+		hop.Responders = append([]ResponderCount(nil), old.Responders...)
+		if old.Sent > 0 {
+			stats := statsOf(old, window)
+			stats.apply(&hop)
+		} else {
+			// Sessions saved before probe counters only have their history to go on
+			hop.AvgLatency = calculateAverageLatency(hop.LatencyHistory)
+			hop.LossPercent = calculateLossPercent(hop.LatencyHistory)
+			hop.LifetimeLossPercent = hop.LossPercent
+		}
+		hops[i] = hop
+	}
+	return hops
+}
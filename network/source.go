@@ -0,0 +1,95 @@
+package network
+
+import (
+	"fmt"
+	"net"
+)
+
+// LocalInterface is a network interface probes can be sent from
+type LocalInterface struct {
+	Name  string   // Interface name, e.g. "eth0" or "wg0"
+	Addrs []string // Unicast addresses of the interface, global ones first; link-local IPv6 ones carry the zone
+}
+
+// LocalInterfaces lists the interfaces that are up and have an address, loopback excluded,
+// for choosing which uplink of a multi-homed host (VPN and LAN, Wi-Fi and Ethernet) probes leave on
+func LocalInterfaces() ([]LocalInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %v", err)
+	}
+	var local []LocalInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ips := interfaceIPs(iface)
+		if len(ips) == 0 {
+			continue
+		}
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+			if needsZone(ip) {
+				addrs[i] += "%" + iface.Name
+			}
+		}
+		local = append(local, LocalInterface{Name: iface.Name, Addrs: addrs})
+	}
+	return local, nil
+}
+
+// interfaceIPs returns the unicast addresses of an interface, global ones first
+func interfaceIPs(iface net.Interface) []net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	var global, linkLocal []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsMulticast() {
+			continue
+		}
+		if ipNet.IP.IsLinkLocalUnicast() {
+			linkLocal = append(linkLocal, ipNet.IP)
+		} else {
+			global = append(global, ipNet.IP)
+		}
+	}
+	return append(global, linkLocal...)
+}
+
+// interfaceSource returns the address of the named interface probes to dst are sent from:
+// its first address of dst's IP version, preferring global ones, with the zone for link-local IPv6
+func interfaceSource(name string, dst net.IP) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown source interface %q: %v", name, err)
+	}
+	wantIPv4 := dst.To4() != nil
+	for _, ip := range interfaceIPs(*iface) {
+		if (ip.To4() != nil) != wantIPv4 {
+			continue
+		}
+		// A link-local source only reaches link-local destinations
+		if ip.IsLinkLocalUnicast() && !dst.IsLinkLocalUnicast() {
+			continue
+		}
+		if needsZone(ip) {
+			return ip.String() + "%" + iface.Name, nil
+		}
+		return ip.String(), nil
+	}
+	version := "IPv6"
+	if wantIPv4 {
+		version = "IPv4"
+	}
+	return "", fmt.Errorf("interface %s has no %s address to reach %s from", name, version, dst)
+}
+
+// isInterfaceSource reports whether a source names an interface rather than an address
+func isInterfaceSource(source string) bool {
+	ip, _ := parseScopedIP(source)
+	return source != "" && ip == nil
+}
@@ -0,0 +1,121 @@
+package network
+
+import (
+	"math"
+	"sort"
+)
+
+// rttStats accumulates a hop's per-probe statistics across monitoring rounds
+// Everything but the percentiles and windowed loss is kept as running values; those need
+// a window of recent probes
+type rttStats struct {
+	sent     int
+	received int
+	mean     float64   // Mean RTT of answered probes
+	m2       float64   // Sum of squared deviations from the mean (Welford's algorithm)
+	jitter   float64   // Mean absolute difference between consecutive answered probes
+	last     float64   // RTT of the latest answered probe
+	best     float64   // Lowest RTT, 0 before the first answer
+	worst    float64   // Highest RTT
+	samples  []float64 // Latest answered RTTs, at most MaxRTTSamples
+	recent   []bool    // Whether each of the latest probes was answered, at most window
+	window   int       // Probes the windowed loss covers
+}
+
+// statsOf resumes the statistics carried by a hop, with loss over the latest window probes
+func statsOf(hop NetworkHop, window int) rttStats {
+	recent := hop.RecentProbes
+	if len(recent) > window {
+		recent = recent[len(recent)-window:]
+	}
+	return rttStats{
+		sent:     hop.Sent,
+		received: hop.Received,
+		mean:     hop.AvgLatency,
+		m2:       hop.StdDev * hop.StdDev * float64(max(hop.Received-1, 0)),
+		jitter:   hop.Jitter,
+		last:     hop.LastLatency,
+		best:     hop.BestLatency,
+		worst:    hop.WorstLatency,
+		samples:  append([]float64(nil), hop.RTTSamples...),
+		recent:   append([]bool(nil), recent...),
+		window:   window,
+	}
+}
+
+// miss records a probe that got no answer
+func (r *rttStats) miss() {
+	r.sent++
+	r.observe(false)
+}
+
+// add records an answered probe's RTT in milliseconds
+func (r *rttStats) add(rtt float64) {
+	r.sent++
+	r.received++
+	r.observe(true)
+	delta := rtt - r.mean
+	r.mean += delta / float64(r.received)
+	r.m2 += delta * (rtt - r.mean)
+	if r.received > 1 {
+		r.jitter += (math.Abs(rtt-r.last) - r.jitter) / float64(r.received-1)
+	}
+	r.last = rtt
+	if r.best == 0 || rtt < r.best {
+		r.best = rtt
+	}
+	r.worst = max(r.worst, rtt)
+	if len(r.samples) >= MaxRTTSamples {
+		r.samples = r.samples[len(r.samples)-MaxRTTSamples+1:]
+	}
+	r.samples = append(r.samples, rtt)
+}
+
+// observe pushes a probe's outcome into the loss window
+func (r *rttStats) observe(answered bool) {
+	if len(r.recent) >= r.window {
+		r.recent = r.recent[len(r.recent)-r.window+1:]
+	}
+	r.recent = append(r.recent, answered)
+}
+
+// windowLossPercent returns the share of unanswered probes in the loss window
+// Without a window (sessions saved before it existed) it falls back to the lifetime loss
+func (r *rttStats) windowLossPercent() float64 {
+	if len(r.recent) == 0 {
+		return counterLossPercent(r.sent, r.received)
+	}
+	answered := 0
+	for _, ok := range r.recent {
+		if ok {
+			answered++
+		}
+	}
+	return counterLossPercent(len(r.recent), answered)
+}
+
+// apply stores the statistics on a hop
+func (r *rttStats) apply(hop *NetworkHop) {
+	hop.Sent = r.sent
+	hop.Received = r.received
+	hop.AvgLatency = r.mean
+	hop.LossPercent = r.windowLossPercent()
+	hop.LifetimeLossPercent = counterLossPercent(r.sent, r.received)
+	hop.RecentProbes = r.recent
+	hop.Jitter = r.jitter
+	hop.LastLatency = r.last
+	hop.BestLatency = r.best
+	hop.WorstLatency = r.worst
+	hop.StdDev = 0
+	if r.received > 1 {
+		hop.StdDev = math.Sqrt(r.m2 / float64(r.received-1))
+	}
+
+	// Percentiles sort a copy of the window, which is cheap at a few hundred samples
+	hop.RTTSamples = r.samples
+	sorted := append([]float64(nil), r.samples...)
+	sort.Float64s(sorted)
+	hop.P50 = percentile(sorted, 50)
+	hop.P95 = percentile(sorted, 95)
+	hop.P99 = percentile(sorted, 99)
+}
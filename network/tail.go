@@ -0,0 +1,102 @@
+package network
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CSVTail appends one row per hop and monitoring cycle to a CSV file while a session runs,
+// so other tools can follow the data live. It starts a new file each day, named
+// <prefix>-YYYY-MM-DD.csv, and writes the header only into new files
+type CSVTail struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string
+	day    string // Date of the open file
+	file   *os.File
+	w      *csv.Writer
+	closed bool
+}
+
+// tailCSVHeader names the columns of a live CSV file: when and for which target and cycle
+// each row was measured, then the columns of an exported hop table
+var tailCSVHeader = append([]string{"time", "target", "cycle"}, hopCSVHeader...)
+
+// NewCSVTail creates a tail writing files into dir; nothing is opened until the first row
+func NewCSVTail(dir, prefix string) *CSVTail {
+	return &CSVTail{dir: dir, prefix: prefix}
+}
+
+// Append writes the hop of a monitoring update as a row and flushes it to disk
+// Discovery and pending updates are skipped, as they aren't part of a cycle
+func (t *CSVTail) Append(target string, update HopUpdate) error {
+	if update.Pending || update.Cycle == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	if err := t.rotate(update.Time); err != nil {
+		return err
+	}
+
+	row := append([]string{update.Time.Format(time.RFC3339Nano), target, fmt.Sprintf("%d", update.Cycle)}, hopCSVRow(update.Index, update.Hop)...)
+	if err := t.w.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %v", err)
+	}
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// rotate opens the file for the day of at, closing the previous day's file
+func (t *CSVTail) rotate(at time.Time) error {
+	day := at.Format("2006-01-02")
+	if t.file != nil && day == t.day {
+		return nil
+	}
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create live CSV directory: %v", err)
+	}
+	path := filepath.Join(t.dir, fmt.Sprintf("%s-%s.csv", t.prefix, day))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open live CSV file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open live CSV file: %v", err)
+	}
+
+	t.file, t.day, t.w = file, day, csv.NewWriter(file)
+	if info.Size() == 0 {
+		if err := t.w.Write(tailCSVHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the open file; later rows are dropped
+func (t *CSVTail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if t.file == nil {
+		return nil
+	}
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
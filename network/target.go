@@ -0,0 +1,66 @@
+package network
+
+import (
+	"context"
+	"log"
+)
+
+// SetTarget switches the scanner to hostname without tearing it down: probes in flight are
+// canceled, discovery runs again for the new target and monitoring follows it, while the
+// Updates, Events and other channels stay open. Like Start it returns once discovery is done,
+// and ctx bounds only that
+// Hop state and statistics of the previous target are dropped, and a paused scanner resumes.
+// A prober set with SetProber is kept; the scanner's own probers are reopened for the new target
+func (s *Scanner) SetTarget(ctx context.Context, hostname string) error {
+	if !s.enter() {
+		return s.canceled(ctx)
+	}
+	defer s.producers.Done()
+	// Cancel first so a discovery in progress, which holds targetMu, gives up promptly
+	if !s.cancelTarget() {
+		return s.canceled(ctx)
+	}
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+	// Another SetTarget may have started monitoring meanwhile
+	if !s.cancelTarget() {
+		return s.canceled(ctx)
+	}
+	s.loop.Wait()
+
+	s.mu.Lock()
+	if s.stopCalled {
+		s.mu.Unlock()
+		return s.canceled(ctx)
+	}
+	if s.ownProber {
+		s.releaseProber()
+		s.ownProber = false
+	}
+	s.ctx, s.cancel = context.WithCancel(s.life)
+	s.hostname = hostname
+	s.mu.Unlock()
+
+	log.Printf("[DEBUG] Retargeting scanner to %s\n", hostname)
+	s.address = ""
+	s.history = nil
+	s.hops = make([]NetworkHop, 0)
+	s.rounds, s.probeRTTs = nil, nil
+	s.cycle = 0
+	s.paused.Store(false)
+	s.nat64.Store(NAT64{})
+	s.sendEvent(Event{Kind: EventTargetChanged, Target: hostname})
+
+	return s.start(ctx)
+}
+
+// cancelTarget cancels the probes of the current target; returns false if the scanner is stopped
+func (s *Scanner) cancelTarget() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopCalled {
+		return false
+	}
+	s.cancel()
+	return true
+}
@@ -0,0 +1,95 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Limits for HTTP download throughput tests
+const (
+	ThroughputMaxDuration = 10 * time.Second // Stop reading after this long
+	ThroughputMaxBytes    = 100 << 20        // Stop reading after 100 MiB
+)
+
+// ThroughputResult is the outcome of a download test and the path it ran over
+type ThroughputResult struct {
+	URL      string
+	Started  time.Time
+	Duration time.Duration
+	Bytes    int64
+	Before   []NetworkHop // Hop table as the download started
+	Path     []NetworkHop // Hop table as the download ended
+}
+
+// Mbps returns the measured throughput in megabits per second
+func (r ThroughputResult) Mbps() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) * 8 / r.Duration.Seconds() / 1e6
+}
+
+// DegradedHops returns the 1-based hop numbers on the path that lost probes while the download ran
+// Only probes sent during the test count; a hop missing from the table at the start counts all of its
+func (r ThroughputResult) DegradedHops() []int {
+	degraded := make([]int, 0)
+	for i, hop := range r.Path {
+		sent, received := hop.Sent, hop.Received
+		if i < len(r.Before) && r.Before[i].TTL == hop.TTL {
+			sent -= r.Before[i].Sent
+			received -= r.Before[i].Received
+		}
+		if received < sent {
+			degraded = append(degraded, i+1)
+		}
+	}
+	return degraded
+}
+
+// RunDownloadTest downloads url for up to ThroughputMaxDuration and measures throughput
+// path returns the current hop table; it is called as the download starts and as it ends, so
+// throughput dips can be matched to the hops that lost probes meanwhile. If ctx is done first
+// the test returns ErrCanceled
+func RunDownloadTest(ctx context.Context, url string, path func() []NetworkHop) (ThroughputResult, error) {
+	result := ThroughputResult{URL: url, Started: time.Now(), Before: copyHops(path())}
+
+	limited, cancel := context.WithTimeout(ctx, ThroughputMaxDuration)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(limited, http.MethodGet, url, nil)
+	if err != nil {
+		return result, fmt.Errorf("invalid throughput test URL: %v", err)
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+		}
+		return result, fmt.Errorf("throughput test failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf("throughput test failed: %s", resp.Status)
+	}
+
+	// Time only the body transfer so connection setup doesn't skew the result
+	start := time.Now()
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, ThroughputMaxBytes))
+	result.Duration = time.Since(start)
+	result.Bytes = n
+	result.Path = copyHops(path())
+
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+	}
+	// Hitting the time limit is the normal way a large download ends
+	if err != nil && limited.Err() == nil {
+		return result, fmt.Errorf("throughput test failed: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxTicketURLLength is the longest tracker URL opened with the report in it; browsers and
+// trackers reject longer ones, so the report is left out to be pasted by hand instead
+const maxTicketURLLength = 8000
+
+// Ticket is a bug or incident report for an issue tracker such as GitHub or Jira
+type Ticket struct {
+	Target      string
+	Created     time.Time
+	Verdict     string         // Grade of the path, e.g. "Poor"
+	Detail      string         // Destination latency and loss behind the verdict
+	Summary     string         // Loss incidents and path changes in plain sentences
+	Hops        []NetworkHop   // Hop table at the time of the report
+	Attachments []string       // Names of the files saved alongside the report
+	Language    ReportLanguage // Language of the title and body; Verdict, Detail and Summary are expected in it too
+}
+
+// Title returns a one-line subject for the ticket
+func (t Ticket) Title() string {
+	return t.Language.Sprintf("%s path to %s (%s)", t.Verdict, t.Target, t.Created.Format("2006-01-02 15:04 MST"))
+}
+
+// Markdown renders the ticket body: summary, hop table, environment and attachments
+func (t Ticket) Markdown() string {
+	var b strings.Builder
+	l := t.Language
+
+	fmt.Fprintf(&b, "## %s\n\n", l.Phrase("Summary"))
+	fmt.Fprintf(&b, "**%s:** %s  \n", l.Phrase("Target"), t.Target)
+	fmt.Fprintf(&b, "**%s:** %s  \n", l.Phrase("Reported"), t.Created.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "**%s:** %s - %s\n\n", l.Phrase("Verdict"), t.Verdict, t.Detail)
+	fmt.Fprintf(&b, "%s\n\n", t.Summary)
+
+	fmt.Fprintf(&b, "## %s\n\n", l.Phrase("Hops"))
+	fmt.Fprintf(&b, "| %s | %s | AS | %s | %s | %s | %s | %s |\n", l.Phrase("Hop"), l.Phrase("Address"),
+		l.Phrase("Loss"), l.Phrase("Avg"), l.Phrase("Best"), l.Phrase("Worst"), l.Phrase("Jitter"))
+	fmt.Fprintf(&b, "|----:|---------|----|-----:|----:|-----:|------:|-------:|\n")
+	for i, hop := range t.Hops {
+		as := ""
+		if hop.ASN != 0 {
+			as = strings.TrimSpace(fmt.Sprintf("AS%d %s", hop.ASN, hop.ASName))
+		}
+		if hop.AvgLatency <= 0 {
+			fmt.Fprintf(&b, "| %d | %s | %s | %.1f%% | - | - | - | - |\n", i+1, hop.IP, as, HistoryLossPercent(hop))
+			continue
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %.1f%% | %.1f ms | %.1f ms | %.1f ms | %.1f ms |\n",
+			i+1, hop.IP, as, HistoryLossPercent(hop), hop.AvgLatency, hop.BestLatency, hop.WorstLatency, hop.Jitter)
+	}
+
+	fmt.Fprintf(&b, "\n## %s\n\n", l.Phrase("Environment"))
+	fmt.Fprintf(&b, "%s\n\n```\n%s```\n", l.Sprintf("Measured with %s.", ProductName), SystemInfo())
+
+	if len(t.Attachments) > 0 {
+		fmt.Fprintf(&b, "\n## %s\n\n", l.Phrase("Attachments"))
+		for _, name := range t.Attachments {
+			fmt.Fprintf(&b, "- %s\n", name)
+		}
+	}
+	return b.String()
+}
+
+// TicketURL fills a tracker's new-issue URL template with a title and body, e.g.
+// "https://github.com/owner/repo/issues/new?title={title}&body={body}" or, for Jira,
+// "https://example.atlassian.net/secure/CreateIssueDetails!init.jspa?pid=10000&issuetype=1&summary={title}&description={body}"
+// A body too long for a URL is replaced by a request to paste it; the second result reports whether it was
+func TicketURL(template, title, body string) (string, bool, error) {
+	fill := func(body string) string {
+		return strings.NewReplacer("{title}", url.QueryEscape(title), "{body}", url.QueryEscape(body)).Replace(template)
+	}
+
+	filled := fill(body)
+	omitted := false
+	if len(filled) > maxTicketURLLength && strings.Contains(template, "{body}") {
+		filled = fill("(Paste the report copied to the clipboard here.)")
+		omitted = true
+	}
+
+	u, err := url.Parse(filled)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid tracker URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false, fmt.Errorf("invalid tracker URL %q: must start with http:// or https://", template)
+	}
+	return filled, omitted, nil
+}
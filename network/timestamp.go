@@ -0,0 +1,56 @@
+package network
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// ClockSource describes where probe timestamps come from
+type ClockSource string
+
+const (
+	ClockUnknown   ClockSource = "Unknown (no replies yet)"
+	ClockKernel    ClockSource = "Kernel send and receive timestamps"
+	ClockMixed     ClockSource = "Kernel timestamps in one direction, userspace clock in the other"
+	ClockUserspace ClockSource = "Userspace clock after send and on receive"
+)
+
+// receivedPacket describes an ICMP message read from a probe socket
+type receivedPacket struct {
+	n          int       // Length of the ICMP message at the start of the buffer
+	peer       net.Addr  // Sender of the message
+	at         time.Time // When the message arrived
+	kernelTime bool      // at is a kernel receive timestamp
+	ttl        int       // TTL (IPv4) or hop limit (IPv6) the message arrived with, 0 if unknown
+}
+
+// clockSource describes the timing of a probe from which ends used kernel timestamps
+func clockSource(kernelSend, kernelRecv bool) ClockSource {
+	switch {
+	case kernelSend && kernelRecv:
+		return ClockKernel
+	case kernelSend || kernelRecv:
+		return ClockMixed
+	default:
+		return ClockUserspace
+	}
+}
+
+// sendProbe writes a probe and returns when it actually left the socket
+// Timing starts at send completion rather than before marshalling so time spent queueing
+// behind our own work isn't counted as network latency; the kernel's transmit timestamp
+// is used where the platform provides one
+func sendProbe(conn *icmp.PacketConn, b []byte, dst net.Addr) (time.Time, bool, error) {
+	drainTxTimestamps(conn)
+	if _, err := conn.WriteTo(b, dst); err != nil {
+		return time.Time{}, false, err
+	}
+	sent := time.Now()
+
+	if ts, ok := txTimestamp(conn); ok {
+		return ts, true, nil
+	}
+	return sent, false, nil
+}
@@ -0,0 +1,202 @@
+package network
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/sys/unix"
+)
+
+// txTimestampWait bounds how long to wait for the kernel's transmit timestamp
+const txTimestampWait = time.Millisecond
+
+// enableTimestamps asks the kernel to report software transmit and receive timestamps on the socket
+func enableTimestamps(conn *icmp.PacketConn) error {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		flags := unix.SOF_TIMESTAMPING_TX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE | unix.SOF_TIMESTAMPING_OPT_TSONLY
+		if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags); sockErr != nil {
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// txTimestamp reads the transmit timestamp of the last probe from the socket error queue
+func txTimestamp(conn *icmp.PacketConn) (time.Time, bool) {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Software timestamps are queued as the packet reaches the driver, normally
+	// before WriteTo returns, so only a very short wait is needed
+	deadline := time.Now().Add(txTimestampWait)
+	for {
+		var ts time.Time
+		var ok bool
+		rc.Control(func(fd uintptr) {
+			ts, ok = readErrQueueTimestamp(int(fd))
+		})
+		if ok || time.Now().After(deadline) {
+			return ts, ok
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+// drainTxTimestamps discards timestamps left over from earlier probes
+func drainTxTimestamps(conn *icmp.PacketConn) {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return
+	}
+	rc.Control(func(fd uintptr) {
+		for {
+			if _, ok := readErrQueueTimestamp(int(fd)); !ok {
+				return
+			}
+		}
+	})
+}
+
+// readErrQueueTimestamp reads one entry from the error queue without blocking
+func readErrQueueTimestamp(fd int) (time.Time, bool) {
+	buf := make([]byte, 64)
+	oob := make([]byte, 512)
+	_, oobn, _, _, err := unix.Recvmsg(fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+	if err != nil {
+		return time.Time{}, false
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level != unix.SOL_SOCKET || msg.Header.Type != unix.SO_TIMESTAMPING {
+			continue
+		}
+		// scm_timestamping holds three timespecs; the first is the software timestamp
+		if len(msg.Data) < int(unsafe.Sizeof(unix.Timespec{})) {
+			continue
+		}
+		ts := (*unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+		return time.Unix(ts.Unix()), true
+	}
+	return time.Time{}, false
+}
+
+// enableRecvTTL asks the kernel to report the TTL (IPv4) or hop limit (IPv6) of received packets
+func enableRecvTTL(conn *icmp.PacketConn) error {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return err
+	}
+	level, opt := unix.IPPROTO_IP, unix.IP_RECVTTL
+	if conn.IPv6PacketConn() != nil {
+		level, opt = unix.IPPROTO_IPV6, unix.IPV6_RECVHOPLIMIT
+	}
+	var sockErr error
+	err = rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), level, opt, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// recvProbe reads a packet and returns when it arrived
+// The kernel's receive timestamp reflects arrival at the host rather than when this
+// goroutine was scheduled, which matters for sub-millisecond LAN latencies
+func recvProbe(conn *icmp.PacketConn, buf []byte) (receivedPacket, error) {
+	ipConn, ok := packetConn(conn).(*net.IPConn)
+	if !ok {
+		n, peer, err := conn.ReadFrom(buf)
+		return receivedPacket{n: n, peer: peer, at: time.Now()}, err
+	}
+
+	oob := make([]byte, 256)
+	n, oobn, _, peer, err := ipConn.ReadMsgIP(buf, oob)
+	pkt := receivedPacket{n: n, peer: peer, at: time.Now()}
+	if err != nil {
+		return receivedPacket{at: pkt.at}, err
+	}
+	// Unlike ReadFrom, ReadMsgIP leaves the IPv4 header in place (IPv6 raw sockets never include it)
+	if conn.IPv4PacketConn() != nil {
+		if n >= 20 {
+			pkt.ttl = int(buf[8])
+		}
+		pkt.n = stripIPv4Header(buf, n)
+	}
+	parseRecvControl(oob[:oobn], &pkt)
+	return pkt, nil
+}
+
+// parseRecvControl fills in the receive timestamp and TTL from a packet's control messages
+func parseRecvControl(oob []byte, pkt *receivedPacket) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return
+	}
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == unix.SOL_SOCKET && msg.Header.Type == unix.SO_TIMESTAMPNS:
+			if len(msg.Data) < int(unsafe.Sizeof(unix.Timespec{})) {
+				continue
+			}
+			ts := (*unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+			pkt.at, pkt.kernelTime = time.Unix(ts.Unix()), true
+		case msg.Header.Level == unix.IPPROTO_IP && msg.Header.Type == unix.IP_TTL,
+			msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_HOPLIMIT:
+			if len(msg.Data) < 4 {
+				continue
+			}
+			pkt.ttl = int(*(*int32)(unsafe.Pointer(&msg.Data[0])))
+		}
+	}
+}
+
+// stripIPv4Header moves the payload of a raw IPv4 packet to the start of buf
+func stripIPv4Header(buf []byte, n int) int {
+	if n < 20 || buf[0]>>4 != 4 {
+		return n
+	}
+	hdrLen := int(buf[0]&0x0f) << 2
+	if hdrLen < 20 || hdrLen > n {
+		return n
+	}
+	copy(buf, buf[hdrLen:n])
+	return n - hdrLen
+}
+
+// packetConn returns the connection underlying an ICMP connection
+func packetConn(conn *icmp.PacketConn) net.PacketConn {
+	if p4 := conn.IPv4PacketConn(); p4 != nil {
+		return p4.PacketConn
+	}
+	if p6 := conn.IPv6PacketConn(); p6 != nil {
+		return p6.PacketConn
+	}
+	return nil
+}
+
+// rawConn returns the socket underlying an ICMP connection
+func rawConn(conn *icmp.PacketConn) (syscall.RawConn, error) {
+	sc, ok := packetConn(conn).(syscall.Conn)
+	if !ok {
+		return nil, unix.EINVAL
+	}
+	return sc.SyscallConn()
+}
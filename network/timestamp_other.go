@@ -0,0 +1,35 @@
+//go:build !linux
+
+package network
+
+import (
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// enableTimestamps is a no-op where kernel socket timestamps aren't supported
+func enableTimestamps(conn *icmp.PacketConn) error {
+	return nil
+}
+
+// txTimestamp reports that no kernel transmit timestamp is available
+func txTimestamp(conn *icmp.PacketConn) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// drainTxTimestamps is a no-op where kernel transmit timestamps aren't supported
+func drainTxTimestamps(conn *icmp.PacketConn) {}
+
+// enableRecvTTL is a no-op where the received TTL isn't reported
+func enableRecvTTL(conn *icmp.PacketConn) error {
+	return nil
+}
+
+// recvProbe reads a packet, timing its arrival with the userspace clock
+// Windows receive timestamps need WSARecvMsg with SIO_TIMESTAMPING, which the
+// standard library doesn't expose for raw sockets; the received TTL is unknown
+func recvProbe(conn *icmp.PacketConn, buf []byte) (receivedPacket, error) {
+	n, peer, err := conn.ReadFrom(buf)
+	return receivedPacket{n: n, peer: peer, at: time.Now()}, err
+}
@@ -0,0 +1,40 @@
+package network
+
+import "fmt"
+
+// ethernetMTU is the MTU of an untunnelled Ethernet path
+const ethernetMTU = 1500
+
+// TunnelHint is the encapsulation a characteristic MTU points to
+type TunnelHint struct {
+	MTU  int    // MTU the hint applies to
+	Kind string // Likely tunnel or VPN type
+}
+
+// tunnelMTUs maps MTUs left over after common encapsulations of a 1500-byte path
+var tunnelMTUs = []TunnelHint{
+	{MTU: 1492, Kind: "PPPoE"},
+	{MTU: 1480, Kind: "IPv6-in-IPv4 (6in4) tunnel"},
+	{MTU: 1476, Kind: "GRE tunnel"},
+	{MTU: 1440, Kind: "WireGuard over IPv4"},
+	{MTU: 1436, Kind: "GRE over IPsec"},
+	{MTU: 1420, Kind: "WireGuard over IPv6"},
+	{MTU: 1400, Kind: "IPsec or SSL VPN"},
+	{MTU: 1380, Kind: "IPsec with NAT traversal"},
+}
+
+// LikelyTunnel returns the encapsulation an MTU below 1500 is characteristic of
+func LikelyTunnel(mtu int) (TunnelHint, bool) {
+	for _, hint := range tunnelMTUs {
+		if hint.MTU == mtu {
+			return hint, true
+		}
+	}
+	return TunnelHint{}, false
+}
+
+// Advice suggests the TCP MSS clamp that avoids fragmentation over the tunnel
+func (h TunnelHint) Advice() string {
+	return fmt.Sprintf("likely %s (%d bytes below Ethernet); clamp TCP MSS to %d for IPv4 or %d for IPv6 if large transfers stall",
+		h.Kind, ethernetMTU-h.MTU, h.MTU-40, h.MTU-60)
+}
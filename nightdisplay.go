@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// Night display preference keys
+const (
+	prefNightEnabled    = "nightDisplay"    // Switch to the night palette on a schedule
+	prefNightStart      = "nightStart"      // Time of day the night palette starts, "HH:MM"
+	prefNightEnd        = "nightEnd"        // Time of day the night palette ends, "HH:MM"
+	prefNightBrightness = "nightBrightness" // Brightness of the night palette, in percent
+)
+
+// Night display defaults
+const (
+	defaultNightStart      = "22:00"
+	defaultNightEnd        = "07:00"
+	defaultNightBrightness = 40
+)
+
+// nightCheckInterval is how often the schedule is checked; the palette changes within a minute
+const nightCheckInterval = time.Minute
+
+// parseTimeOfDay parses "HH:MM" into the time since midnight
+func parseTimeOfDay(text string) (time.Duration, error) {
+	t, err := time.Parse("15:04", text)
+	if err != nil {
+		return 0, fmt.Errorf("enter a time as HH:MM, e.g. 22:00")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// validTimeOfDay validates a time of day entry
+func validTimeOfDay(text string) error {
+	_, err := parseTimeOfDay(text)
+	return err
+}
+
+// inNightWindow reports whether now falls between start and end, which may span midnight
+func inNightWindow(now time.Time, start, end time.Duration) bool {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := now.Sub(midnight)
+	if start <= end {
+		return since >= start && since < end
+	}
+	return since >= start || since < end
+}
+
+// nightDue reports whether the saved schedule calls for the night palette now
+func (vm *VisualMTR) nightDue(now time.Time) bool {
+	prefs := vm.app.Preferences()
+	if !prefs.Bool(prefNightEnabled) {
+		return false
+	}
+	start, err := parseTimeOfDay(prefs.StringWithFallback(prefNightStart, defaultNightStart))
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(prefs.StringWithFallback(prefNightEnd, defaultNightEnd))
+	if err != nil {
+		return false
+	}
+	return inNightWindow(now, start, end)
+}
+
+// applyNightDisplay switches between the night palette and the normal theme as the schedule says
+func (vm *VisualMTR) applyNightDisplay() {
+	base := ui.NewTheme(theme.DefaultTheme())
+	if !vm.nightDue(time.Now()) {
+		if vm.nightActive {
+			vm.nightActive = false
+			vm.app.Settings().SetTheme(base)
+		}
+		return
+	}
+	brightness := float64(vm.app.Preferences().IntWithFallback(prefNightBrightness, defaultNightBrightness)) / 100
+	vm.nightActive = true
+	vm.app.Settings().SetTheme(ui.NewNightTheme(base, brightness))
+}
+
+// startNightSchedule applies the night display schedule now and then every minute
+func (vm *VisualMTR) startNightSchedule() {
+	vm.applyNightDisplay()
+	go func() {
+		ticker := time.NewTicker(nightCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			fyne.Do(func() {
+				if vm.nightDue(time.Now()) != vm.nightActive {
+					vm.applyNightDisplay()
+				}
+			})
+		}
+	}()
+}
+
+// onNightDisplay edits the hours a wall display dims to the red night palette
+func (vm *VisualMTR) onNightDisplay() {
+	prefs := vm.app.Preferences()
+
+	enabledCheck := widget.NewCheck("Dim to a red palette at night", nil)
+	enabledCheck.SetChecked(prefs.Bool(prefNightEnabled))
+	startEntry := widget.NewEntry()
+	startEntry.SetText(prefs.StringWithFallback(prefNightStart, defaultNightStart))
+	startEntry.Validator = validTimeOfDay
+	endEntry := widget.NewEntry()
+	endEntry.SetText(prefs.StringWithFallback(prefNightEnd, defaultNightEnd))
+	endEntry.Validator = validTimeOfDay
+	brightnessEntry := widget.NewEntry()
+	brightnessEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefNightBrightness, defaultNightBrightness)))
+	brightnessEntry.Validator = intInRange(10, 100)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Night display", enabledCheck),
+		widget.NewFormItem("From", startEntry),
+		widget.NewFormItem("Until", endEntry),
+		widget.NewFormItem("Brightness (%)", brightnessEntry),
+	}
+	items[0].HintText = "For 24/7 wall displays: less light in the NOC and less burn-in"
+	items[1].HintText = "Local time, e.g. 22:00"
+	items[2].HintText = "May be past midnight, e.g. 07:00"
+	items[3].HintText = "Share of the normal brightness kept at night"
+
+	d := dialog.NewForm("Night Display", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		brightness, _ := strconv.Atoi(brightnessEntry.Text)
+		prefs.SetBool(prefNightEnabled, enabledCheck.Checked)
+		prefs.SetString(prefNightStart, startEntry.Text)
+		prefs.SetString(prefNightEnd, endEntry.Text)
+		prefs.SetInt(prefNightBrightness, brightness)
+		vm.applyNightDisplay()
+	}, vm.window)
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// Probe tuning preference keys
+const (
+	prefInterval   = "probeInterval"       // Seconds between monitoring rounds
+	prefTimeout    = "probeTimeout"        // Seconds to wait for each answer
+	prefMaxTTL     = "maxTTL"              // Highest TTL traced
+	prefPacketSize = "packetSize"          // Probe packet size in bytes, 0 for the smallest
+	prefLossWindow = "lossWindow"          // Latest probes per hop the loss column covers
+	prefRetrace    = "retraceMinutes"      // Minutes between re-traces of the path, 0 for none
+	prefDSCP       = "dscp"                // DSCP value probes are marked with, 0 for best effort
+	prefRotate     = "rotateIdentity"      // Give probes a fresh echo ID or source port every round
+	prefJitter     = "jitterPercent"       // Random shift of each round, in percent of the interval
+	prefParallel   = "parallelHops"        // Hops probed at once while monitoring
+	prefMaxPPS     = "maxPacketsPerSecond" // Probes per second of all sessions together, 0 for no limit
+	prefCycles     = "cycles"              // Monitoring rounds before a session ends with a summary, 0 for no end
+	prefSpacing    = "probeSpacingMs"      // Milliseconds between a hop's probes within a round
+)
+
+// maxJitterPercent keeps jittered rounds from running into each other
+const maxJitterPercent = 90
+
+// defaultRetraceMinutes is how often the path is re-traced unless set in Probe Settings
+const defaultRetraceMinutes = 5
+
+// scannerOptions returns the probe tuning saved in Probe Settings, overridden where the picked
+// probing profile sets its own
+func (vm *VisualMTR) scannerOptions() []network.ScannerOption {
+	prefs := vm.app.Preferences()
+	interval := seconds(prefs.FloatWithFallback(prefInterval, network.DefaultInterval.Seconds()))
+	opts := []network.ScannerOption{
+		network.WithInterval(interval),
+		network.WithTimeout(seconds(prefs.FloatWithFallback(prefTimeout, network.DefaultTimeout.Seconds()))),
+		network.WithMaxTTL(prefs.IntWithFallback(prefMaxTTL, network.DefaultMaxTTL)),
+		network.WithPacketSize(prefs.Int(prefPacketSize)),
+		network.WithLossWindow(prefs.IntWithFallback(prefLossWindow, network.DefaultLossWindow)),
+		network.WithRetraceInterval(time.Duration(prefs.IntWithFallback(prefRetrace, defaultRetraceMinutes)) * time.Minute),
+		network.WithDSCP(prefs.Int(prefDSCP)),
+		network.WithIdentityRotation(prefs.Bool(prefRotate)),
+		network.WithScheduleJitter(interval * time.Duration(prefs.Int(prefJitter)) / 100),
+		network.WithConcurrency(prefs.IntWithFallback(prefParallel, network.DefaultConcurrency)),
+		network.WithCycles(prefs.Int(prefCycles)),
+		network.WithProbeSpacing(time.Duration(prefs.Int(prefSpacing)) * time.Millisecond),
+	}
+	return append(opts, vm.selectedProfile().Options()...)
+}
+
+// applyGovernorLimits caps the combined probe rate of all sessions as saved in Probe Settings
+func (vm *VisualMTR) applyGovernorLimits() {
+	limits := network.DefaultGovernorLimits
+	limits.PacketsPerSecond = vm.app.Preferences().IntWithFallback(prefMaxPPS, limits.PacketsPerSecond)
+	network.SetGovernorLimits(limits)
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// onProbeSettings edits the probe interval, timeout, TTL limit, packet size, loss window,
+// re-trace interval, DSCP marking, identity rotation, schedule jitter, parallelism, cycle limit and
+// probe spacing used by new sessions, and the probe rate all sessions share
+func (vm *VisualMTR) onProbeSettings() {
+	prefs := vm.app.Preferences()
+
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText(strconv.FormatFloat(prefs.FloatWithFallback(prefInterval, network.DefaultInterval.Seconds()), 'g', -1, 64))
+	intervalEntry.Validator = positiveFloat
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText(strconv.FormatFloat(prefs.FloatWithFallback(prefTimeout, network.DefaultTimeout.Seconds()), 'g', -1, 64))
+	timeoutEntry.Validator = positiveFloat
+	maxTTLEntry := widget.NewEntry()
+	maxTTLEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefMaxTTL, network.DefaultMaxTTL)))
+	maxTTLEntry.Validator = intInRange(1, 255)
+	sizeEntry := widget.NewEntry()
+	sizeEntry.SetText(strconv.Itoa(prefs.Int(prefPacketSize)))
+	sizeEntry.Validator = intInRange(0, network.MaxPacketSize)
+	windowEntry := widget.NewEntry()
+	windowEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefLossWindow, network.DefaultLossWindow)))
+	windowEntry.Validator = intInRange(1, network.MaxLossWindow)
+	retraceEntry := widget.NewEntry()
+	retraceEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefRetrace, defaultRetraceMinutes)))
+	retraceEntry.Validator = intInRange(0, 1440)
+	dscpEntry := widget.NewEntry()
+	dscpEntry.SetText(strconv.Itoa(prefs.Int(prefDSCP)))
+	dscpEntry.Validator = intInRange(0, network.MaxDSCP)
+	rotateCheck := widget.NewCheck("Fresh echo ID or source port every round", nil)
+	rotateCheck.SetChecked(prefs.Bool(prefRotate))
+	jitterEntry := widget.NewEntry()
+	jitterEntry.SetText(strconv.Itoa(prefs.Int(prefJitter)))
+	jitterEntry.Validator = intInRange(0, maxJitterPercent)
+	parallelEntry := widget.NewEntry()
+	parallelEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefParallel, network.DefaultConcurrency)))
+	parallelEntry.Validator = intInRange(1, network.MaxConcurrency)
+	maxPPSEntry := widget.NewEntry()
+	maxPPSEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefMaxPPS, network.DefaultGovernorLimits.PacketsPerSecond)))
+	maxPPSEntry.Validator = intInRange(0, 100000)
+	cyclesEntry := widget.NewEntry()
+	cyclesEntry.SetText(strconv.Itoa(prefs.Int(prefCycles)))
+	cyclesEntry.Validator = intInRange(0, 1000000)
+	spacingEntry := widget.NewEntry()
+	spacingEntry.SetText(strconv.Itoa(prefs.Int(prefSpacing)))
+	spacingEntry.Validator = intInRange(0, 10000)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Interval (s)", intervalEntry),
+		widget.NewFormItem("Timeout (s)", timeoutEntry),
+		widget.NewFormItem("Max TTL", maxTTLEntry),
+		widget.NewFormItem("Packet size", sizeEntry),
+		widget.NewFormItem("Loss window", windowEntry),
+		widget.NewFormItem("Re-trace (min)", retraceEntry),
+		widget.NewFormItem("DSCP", dscpEntry),
+		widget.NewFormItem("Rotate identity", rotateCheck),
+		widget.NewFormItem("Jitter (%)", jitterEntry),
+		widget.NewFormItem("Parallel hops", parallelEntry),
+		widget.NewFormItem("Max packets/s", maxPPSEntry),
+		widget.NewFormItem("Cycles", cyclesEntry),
+		widget.NewFormItem("Probe spacing (ms)", spacingEntry),
+	}
+	items[0].HintText = "Time between monitoring rounds"
+	items[1].HintText = "Wait for each answer before counting it as lost"
+	items[3].HintText = "Bytes including headers; 0 sends the smallest probes"
+	items[4].HintText = "Latest probes per hop the loss column covers"
+	items[5].HintText = "Re-run discovery to follow route changes; 0 keeps the first path"
+	items[6].HintText = "QoS marking of probes, e.g. 46 (EF) like VoIP; 0 for best effort"
+	items[7].HintText = "Keeps middleboxes from rate-limiting one long-lived flow; load balancers may vary the path"
+	items[8].HintText = "Random shift of each round, in percent of the interval; 0 for a fixed schedule"
+	items[9].HintText = "Hops probed at once with ICMP, so long paths fit in one interval"
+	items[10].HintText = "All sessions together; more stretches their intervals. 0 for no limit"
+	items[11].HintText = "Rounds before the session ends with a summary; 0 runs until Stop"
+	items[12].HintText = "Gap between a hop's probes in a round, so ICMP rate limits don't show as loss"
+
+	d := dialog.NewForm("Probe Settings", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		interval, _ := strconv.ParseFloat(intervalEntry.Text, 64)
+		timeout, _ := strconv.ParseFloat(timeoutEntry.Text, 64)
+		maxTTL, _ := strconv.Atoi(maxTTLEntry.Text)
+		size, _ := strconv.Atoi(sizeEntry.Text)
+		window, _ := strconv.Atoi(windowEntry.Text)
+		retrace, _ := strconv.Atoi(retraceEntry.Text)
+		dscp, _ := strconv.Atoi(dscpEntry.Text)
+		jitter, _ := strconv.Atoi(jitterEntry.Text)
+		parallel, _ := strconv.Atoi(parallelEntry.Text)
+		maxPPS, _ := strconv.Atoi(maxPPSEntry.Text)
+		cycles, _ := strconv.Atoi(cyclesEntry.Text)
+		spacing, _ := strconv.Atoi(spacingEntry.Text)
+		prefs.SetFloat(prefInterval, interval)
+		prefs.SetFloat(prefTimeout, timeout)
+		prefs.SetInt(prefMaxTTL, maxTTL)
+		prefs.SetInt(prefPacketSize, size)
+		prefs.SetInt(prefLossWindow, window)
+		prefs.SetInt(prefRetrace, retrace)
+		prefs.SetInt(prefDSCP, dscp)
+		prefs.SetBool(prefRotate, rotateCheck.Checked)
+		prefs.SetInt(prefJitter, jitter)
+		prefs.SetInt(prefParallel, parallel)
+		prefs.SetInt(prefMaxPPS, maxPPS)
+		prefs.SetInt(prefCycles, cycles)
+		prefs.SetInt(prefSpacing, spacing)
+		vm.applyGovernorLimits()
+		vm.statusLabel.SetText("Probe settings saved - they apply to the next session")
+	}, vm.window)
+	d.Resize(fyne.NewSize(420, 0))
+	d.Show()
+}
+
+// positiveFloat validates a number of seconds
+func positiveFloat(text string) error {
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value <= 0 {
+		return fmt.Errorf("enter a number greater than 0")
+	}
+	return nil
+}
+
+// intInRange returns a validator for whole numbers from lo to hi
+func intInRange(lo, hi int) func(string) error {
+	return func(text string) error {
+		value, err := strconv.Atoi(text)
+		if err != nil || value < lo || value > hi {
+			return fmt.Errorf("enter a whole number from %d to %d", lo, hi)
+		}
+		return nil
+	}
+}
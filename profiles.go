@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// prefProfilePrefix prefixes the preference key holding the probing profile last used for a target
+const prefProfilePrefix = "profile."
+
+// setupProfileSelect creates the probing profile dropdown; picking a profile recolours latency
+// at once, and a session remembers its profile for the target
+func (vm *VisualMTR) setupProfileSelect() {
+	names := make([]string, len(network.Profiles))
+	for i, profile := range network.Profiles {
+		names[i] = profile.Name
+	}
+	vm.profileSelect = widget.NewSelect(names, func(name string) {
+		profile := network.ProfileNamed(name)
+		ui.SetLatencyThresholds(profile.GoodLatency, profile.FairLatency)
+		if vm.hopList != nil {
+			vm.hopList.Refresh()
+		}
+	})
+	vm.profileSelect.SetSelected(vm.targetProfile(vm.hostnameEntry.Text).Name)
+	vm.profile = vm.selectedProfile()
+}
+
+// selectedProfile returns the profile picked in the dropdown
+func (vm *VisualMTR) selectedProfile() network.Profile {
+	return network.ProfileNamed(vm.profileSelect.Selected)
+}
+
+// targetProfile returns the profile last used for target, the default one if none was
+func (vm *VisualMTR) targetProfile(target string) network.Profile {
+	return network.ProfileNamed(vm.app.Preferences().String(prefProfilePrefix + target))
+}
+
+// onTargetEdited shows the profile last used for a target as its name is entered
+// Targets without one keep the current pick, so typing doesn't reset it
+func (vm *VisualMTR) onTargetEdited(target string) {
+	if vm.startButton.Disabled() || vm.app.Preferences().String(prefProfilePrefix+target) == "" {
+		return
+	}
+	vm.profileSelect.SetSelected(vm.targetProfile(target).Name)
+}
+
+// rememberProfile stores the picked profile as the one for target
+func (vm *VisualMTR) rememberProfile(target string) {
+	vm.app.Preferences().SetString(prefProfilePrefix+target, vm.profileSelect.Selected)
+}
+
+// sessionProfile returns the profile of the current or last session
+func (vm *VisualMTR) sessionProfile() network.Profile {
+	vm.hopsMutex.RLock()
+	defer vm.hopsMutex.RUnlock()
+	return vm.profile
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefReportLanguage is the preference key for the language of tickets, evidence packs and
+// incident summaries; the UI stays in English
+const prefReportLanguage = "reportLanguage"
+
+// reportLanguage returns the language reports are written in
+func (vm *VisualMTR) reportLanguage() network.ReportLanguage {
+	return network.ParseReportLanguage(vm.app.Preferences().StringWithFallback(prefReportLanguage, string(network.ReportEnglish)))
+}
+
+// setReportLanguage saves the language reports are written in and ticks it in the menu
+func (vm *VisualMTR) setReportLanguage(lang network.ReportLanguage) {
+	vm.app.Preferences().SetString(prefReportLanguage, string(lang))
+	vm.setupMenu()
+}
+
+// reportLanguageMenu lists the report languages with the current one ticked
+func (vm *VisualMTR) reportLanguageMenu() *fyne.Menu {
+	current := vm.reportLanguage()
+	items := make([]*fyne.MenuItem, 0, len(network.ReportLanguages))
+	for _, lang := range network.ReportLanguages {
+		item := fyne.NewMenuItem(string(lang), func() {
+			vm.setReportLanguage(lang)
+		})
+		item.Checked = lang == current
+		items = append(items, item)
+	}
+	return fyne.NewMenu("Report Language", items...)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// showScannerError tells the user why a session for hostname failed to start or switch target,
+// with the treatment its category calls for
+func (vm *VisualMTR) showScannerError(hostname string, err error) {
+	category := network.CategoryOf(err)
+	log.Printf("[DEBUG] Session for %s failed (%s): %v\n", hostname, category, err)
+
+	switch category {
+	case network.CategoryCanceled:
+		return
+	case network.CategoryDNS:
+		vm.offerLastKnownAddress(hostname, err)
+		return
+	case network.CategoryPermission:
+		vm.statusLabel.SetText("Error: not permitted to open probe sockets")
+		vm.showPermissionWizard(err)
+		return
+	}
+
+	vm.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+	switch category {
+	case network.CategorySettings:
+		dialog.ShowConfirm("Invalid Probe Settings", fmt.Sprintf("%v\n\n%s\nOpen Probe Settings now?", err, category.Hint()), func(ok bool) {
+			if ok {
+				vm.onProbeSettings()
+			}
+		}, vm.window)
+	case network.CategoryFirewall, network.CategorySocket, network.CategoryLimit, network.CategoryLookup:
+		dialog.ShowInformation(fmt.Sprintf("%s Problem", category), fmt.Sprintf("%v\n\n%s", err, category.Hint()), vm.window)
+	}
+}
+
+// showPermissionWizard walks through the ways of giving the app the socket access it lacks on
+// this platform, and offers to retry with ICMP probes, which need no privileges on most systems
+func (vm *VisualMTR) showPermissionWizard(err error) {
+	message := widget.NewLabel(fmt.Sprintf("%v\n\n%s", err, network.CategoryPermission.Hint()))
+	message.Wrapping = fyne.TextWrapWord
+	steps := container.NewVBox(message)
+
+	copyStep := func(text, command string) {
+		label := widget.NewLabel(text)
+		label.Wrapping = fyne.TextWrapWord
+		button := widget.NewButton("Copy Command", func() {
+			vm.app.Clipboard().SetContent(command)
+			vm.statusLabel.SetText(fmt.Sprintf("Copied %s", command))
+		})
+		steps.Add(container.NewBorder(nil, nil, nil, button, label))
+	}
+	switch runtime.GOOS {
+	case "linux":
+		exe, _ := os.Executable()
+		copyStep("1. Grant the app raw socket access once, then restart it", fmt.Sprintf("sudo setcap cap_net_raw+ep %q", exe))
+		copyStep("2. Or let every user send ICMP probes without it", `sudo sysctl -w net.ipv4.ping_group_range="0 2147483647"`)
+	case "darwin":
+		steps.Add(widget.NewLabel("1. ICMP probes work without privileges; UDP and TCP probes need the app run with sudo"))
+	case "windows":
+		steps.Add(widget.NewLabel("1. Right-click the app and choose \"Run as administrator\""))
+	default:
+		steps.Add(widget.NewLabel("1. Run the app as root"))
+	}
+
+	if network.ProbeMethod(vm.methodSelect.Selected) == network.ProbeICMP {
+		dialog.ShowCustom("Permission Needed", "Close", steps, vm.window)
+		return
+	}
+	dialog.ShowCustomConfirm("Permission Needed", "Retry with ICMP", "Close", steps, func(retry bool) {
+		if !retry {
+			return
+		}
+		vm.methodSelect.SetSelected(string(network.ProbeICMP))
+		vm.onStart()
+	}, vm.window)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// sessionPath returns where a session saved on quit is kept until the next launch
+func (vm *VisualMTR) sessionPath() string {
+	return filepath.Join(vm.app.Storage().RootURI().Path(), "session.json")
+}
+
+// confirmSaveSession offers to save the running session, then calls quit
+func (vm *VisualMTR) confirmSaveSession(quit func()) {
+	vm.hopsMutex.RLock()
+	session := network.SavedSession{
+		Target:   vm.target,
+		Protocol: network.Protocol(vm.protocolSelect.Selected),
+		Saved:    time.Now(),
+		Hops:     append([]network.NetworkHop(nil), vm.hops...),
+	}
+	vm.hopsMutex.RUnlock()
+
+	message := fmt.Sprintf("Save the session for %s and offer to resume it next time?", session.Target)
+	d := dialog.NewConfirm("Save Session", message, func(save bool) {
+		if save {
+			path := vm.sessionPath()
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err == nil {
+				err = network.SaveSession(path, session)
+			}
+			if err != nil {
+				log.Printf("[DEBUG] Failed to save session: %v\n", err)
+			}
+		}
+		quit()
+	}, vm.window)
+	d.SetConfirmText("Save and Quit")
+	d.SetDismissText("Quit")
+	d.Show()
+}
+
+// offerResumeSession asks whether to resume a session saved on the last quit
+// The saved file is removed either way so the question is only asked once
+func (vm *VisualMTR) offerResumeSession() {
+	path := vm.sessionPath()
+	session, err := network.LoadSession(path)
+	if err != nil {
+		return
+	}
+	os.Remove(path)
+
+	message := fmt.Sprintf("Resume monitoring %s? The session was saved %s with %d hops of history.",
+		session.Target, session.Saved.Format("Jan 2 15:04"), len(session.Hops))
+	d := dialog.NewConfirm("Resume Session", message, func(resume bool) {
+		if !resume {
+			return
+		}
+		vm.hostnameEntry.SetText(session.Target)
+		vm.protocolSelect.SetSelected(string(session.Protocol))
+		vm.hopsMutex.Lock()
+		vm.restored = session.Hops
+		vm.hopsMutex.Unlock()
+		vm.onStart()
+	}, vm.window)
+	d.SetConfirmText("Resume")
+	d.SetDismissText("Discard")
+	d.Show()
+}
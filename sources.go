@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefSource is the preference key for the local address or interface probes are sent from
+const prefSource = "source"
+
+// anySource is the source choice that leaves picking the uplink to the routing table
+const anySource = "Any source"
+
+// refreshSources offers the host's current interfaces and their addresses as probe sources
+// A saved source that has gone away, such as a disconnected VPN, stays selected and marked unavailable
+func (vm *VisualMTR) refreshSources() {
+	saved := vm.app.Preferences().String(prefSource)
+	options := []string{anySource}
+	sources := map[string]string{anySource: ""}
+	selected := anySource
+
+	ifaces, err := network.LocalInterfaces()
+	if err != nil {
+		log.Printf("[DEBUG] Failed to list interfaces: %v\n", err)
+	}
+	for _, iface := range ifaces {
+		options = append(options, iface.Name)
+		sources[iface.Name] = iface.Name
+		if saved == iface.Name {
+			selected = iface.Name
+		}
+		for _, addr := range iface.Addrs {
+			label := fmt.Sprintf("%s: %s", iface.Name, addr)
+			options = append(options, label)
+			sources[label] = addr
+			if saved == addr {
+				selected = label
+			}
+		}
+	}
+	if saved != "" && selected == anySource {
+		selected = fmt.Sprintf("%s (unavailable)", saved)
+		options = append(options, selected)
+		sources[selected] = saved
+	}
+
+	vm.sources = sources
+	vm.sourceSelect.SetOptions(options)
+	vm.sourceSelect.SetSelected(selected)
+}
+
+// selectedSource returns the local address or interface chosen to send probes from, empty for any
+func (vm *VisualMTR) selectedSource() string {
+	return vm.sources[vm.sourceSelect.Selected]
+}
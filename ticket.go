@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// prefTrackerURL is the preference key for the issue tracker's new-issue URL template
+const prefTrackerURL = "trackerURL"
+
+// defaultTrackerURL shows the template format until a tracker is configured
+const defaultTrackerURL = "https://github.com/owner/repo/issues/new?title={title}&body={body}"
+
+// Files saved next to a ticket for attaching to it
+const (
+	ticketHopsFile   = "hops.csv"
+	ticketGraphsFile = "graphs.png"
+	ticketSystemFile = "system.txt"
+	ticketBodyFile   = "ticket.md"
+)
+
+// onCreateTicket composes a bug or incident ticket from the session: a markdown body with the
+// verdict, incident summary, hop table and environment, plus the graphs and hop table as files
+func (vm *VisualMTR) onCreateTicket() {
+	vm.hopsMutex.RLock()
+	target := vm.target
+	hops := append([]network.NetworkHop(nil), vm.hops...)
+	if len(hops) == 0 {
+		hops = append(hops, vm.cachedHops...)
+	}
+	vm.hopsMutex.RUnlock()
+	if target == "" || len(hops) == 0 {
+		dialog.ShowInformation("Create Ticket", "Run a session first; the ticket reports its hops.", vm.window)
+		return
+	}
+
+	// Capture the graphs before the dialog covers them
+	graphs := vm.window.Canvas().Capture()
+
+	created := time.Now()
+	titleEntry := widget.NewEntry()
+	bodyEntry := widget.NewMultiLineEntry()
+	bodyEntry.SetMinRowsVisible(14)
+	// The ticket is rewritten in another language when one is picked, discarding edits
+	write := func(lang network.ReportLanguage) {
+		verdict, detail := sessionVerdict(hops, vm.sessionProfile(), lang)
+		ticket := network.Ticket{
+			Target:      target,
+			Created:     created,
+			Verdict:     verdict,
+			Detail:      detail,
+			Summary:     vm.incidentSummary(hops, lang),
+			Hops:        hops,
+			Attachments: []string{ticketGraphsFile, ticketHopsFile, ticketSystemFile},
+			Language:    lang,
+		}
+		titleEntry.SetText(ticket.Title())
+		bodyEntry.SetText(ticket.Markdown())
+	}
+	languages := make([]string, 0, len(network.ReportLanguages))
+	for _, lang := range network.ReportLanguages {
+		languages = append(languages, string(lang))
+	}
+	languageSelect := widget.NewSelect(languages, func(name string) {
+		lang := network.ParseReportLanguage(name)
+		if lang != vm.reportLanguage() {
+			vm.setReportLanguage(lang)
+		}
+		write(lang)
+	})
+	languageSelect.SetSelected(string(vm.reportLanguage()))
+	trackerEntry := widget.NewEntry()
+	trackerEntry.SetText(vm.app.Preferences().StringWithFallback(prefTrackerURL, defaultTrackerURL))
+
+	copyButton := widget.NewButton("Copy Body", func() {
+		vm.app.Clipboard().SetContent(bodyEntry.Text)
+		vm.statusLabel.SetText("Ticket body copied")
+	})
+	saveButton := widget.NewButton("Save Attachments...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				return
+			}
+			if err := saveTicketFiles(dir.Path(), bodyEntry.Text, hops, graphs); err != nil {
+				dialog.ShowError(err, vm.window)
+				return
+			}
+			vm.statusLabel.SetText(fmt.Sprintf("Ticket attachments saved to %s", dir.Path()))
+		}, vm.window)
+	})
+	openButton := widget.NewButton("Open in Tracker", func() {
+		vm.app.Preferences().SetString(prefTrackerURL, trackerEntry.Text)
+		link, omitted, err := network.TicketURL(trackerEntry.Text, titleEntry.Text, bodyEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		u, err := url.Parse(link)
+		if err != nil {
+			dialog.ShowError(err, vm.window)
+			return
+		}
+		if omitted {
+			vm.app.Clipboard().SetContent(bodyEntry.Text)
+			dialog.ShowInformation("Create Ticket", "The report is too long to send in a link, so it was copied to the clipboard. Paste it into the ticket's description.", vm.window)
+		}
+		if err := vm.app.OpenURL(u); err != nil {
+			dialog.ShowError(err, vm.window)
+		}
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Title", titleEntry),
+		widget.NewFormItem("Language", languageSelect),
+		widget.NewFormItem("Tracker URL", trackerEntry),
+	)
+	form.Items[1].HintText = "Language of the report, e.g. that of the ISP's support team"
+	form.Items[2].HintText = "New-issue URL of GitHub, Jira or another tracker; {title} and {body} are filled in"
+	content := container.NewBorder(form, container.NewHBox(copyButton, saveButton, openButton), nil, nil, bodyEntry)
+
+	d := dialog.NewCustom("Create Ticket", "Close", content, vm.window)
+	d.Resize(fyne.NewSize(720, 560))
+	d.Show()
+}
+
+// saveTicketFiles writes the ticket body and its attachments into dir
+func saveTicketFiles(dir, body string, hops []network.NetworkHop, graphs image.Image) error {
+	var csv strings.Builder
+	if err := network.WriteHopsCSV(&csv, hops); err != nil {
+		return err
+	}
+	var img bytes.Buffer
+	if err := png.Encode(&img, graphs); err != nil {
+		return fmt.Errorf("failed to encode graphs: %v", err)
+	}
+
+	files := map[string][]byte{
+		ticketBodyFile:   []byte(body),
+		ticketHopsFile:   []byte(csv.String()),
+		ticketGraphsFile: img.Bytes(),
+		ticketSystemFile: []byte(network.SystemInfo()),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to save %s: %v", name, err)
+		}
+	}
+	return nil
+}
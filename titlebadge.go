@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// sessionBadge sums up the path's health in a few words for the window title, e.g. "DEGRADED 6% loss"
+func sessionBadge(hops []network.NetworkHop, profile network.Profile) string {
+	if len(hops) == 0 {
+		return "TRACING"
+	}
+	verdict, _ := sessionVerdict(hops, profile, network.ReportEnglish)
+	dest := hops[len(hops)-1]
+	loss := network.HistoryLossPercent(dest)
+
+	switch verdict {
+	case "Unreachable":
+		return "DOWN"
+	case "Good":
+		return fmt.Sprintf("OK %s", ui.FormatLatency(dest.AvgLatency))
+	}
+	if loss > 0 {
+		return fmt.Sprintf("DEGRADED %.0f%% loss", loss)
+	}
+	return fmt.Sprintf("DEGRADED %s", ui.FormatLatency(dest.AvgLatency))
+}
+
+// updateTitle shows the session's target and health in the window title, which is also what
+// the taskbar, dock and window switcher show, so a minimized session still tells its state
+// Fyne has no badge API for taskbar or dock icons, so the title is the only place for it
+// Without a running session the title goes back to the plain one
+func (vm *VisualMTR) updateTitle() {
+	vm.hopsMutex.RLock()
+	title := vm.branding.WindowTitle
+	if vm.scanner != nil && vm.target != "" {
+		badge := sessionBadge(vm.hops, vm.profile)
+		if vm.scanner.Paused() {
+			badge = "PAUSED"
+		}
+		title = fmt.Sprintf("%s — %s — %s", vm.branding.AppName, vm.target, badge)
+	}
+	vm.hopsMutex.RUnlock()
+
+	fyne.Do(func() {
+		if vm.window.Title() != title {
+			vm.window.SetTitle(title)
+		}
+	})
+}
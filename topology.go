@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+	"github.com/afroash/visual-mtr/ui"
+)
+
+// pathLayers converts hops into per-TTL responder layers for the path graph
+func pathLayers(hops []network.NetworkHop) [][]ui.PathNode {
+	layers := make([][]ui.PathNode, 0, len(hops))
+	for _, hop := range hops {
+		// The dominant responder gets whatever share the alternates don't
+		share := 100.0
+		for _, alt := range hop.Alternates {
+			share -= alt.Share
+		}
+
+		layer := []ui.PathNode{{IP: hop.IP, Share: share, Latency: hop.AvgLatency, Loss: network.HistoryLossPercent(hop)}}
+		for _, alt := range hop.Alternates {
+			layer = append(layer, ui.PathNode{IP: alt.IP, Share: alt.Share, Latency: hop.AvgLatency})
+		}
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// pathEdges places the links the session's probes took between the responders of pathLayers,
+// labelled with the share of rounds that took them and the RTT seen on them
+func pathEdges(layers [][]ui.PathNode, links []network.PathLink) []ui.PathEdge {
+	position := func(layer int, ip string) int {
+		for i, node := range layers[layer] {
+			if node.IP == ip {
+				return i
+			}
+		}
+		return -1
+	}
+
+	edges := make([]ui.PathEdge, 0, len(links))
+	for _, link := range links {
+		if link.Hop+1 >= len(layers) {
+			continue
+		}
+		from, to := position(link.Hop, link.From), position(link.Hop+1, link.To)
+		if from < 0 || to < 0 {
+			continue
+		}
+		edges = append(edges, ui.PathEdge{
+			Layer: link.Hop,
+			From:  from,
+			To:    to,
+			Share: link.Share,
+			Label: fmt.Sprintf("%.0f%% · %s", link.Share, ui.FormatLatency(link.Latency)),
+		})
+	}
+	return edges
+}
+
+// onShowPathGraph opens a window drawing the current path as an ECMP tree, refreshed every second
+// Links appear once monitoring probes have answered at both of their ends
+func (vm *VisualMTR) onShowPathGraph() {
+	w := vm.app.NewWindow("Path Graph")
+	graph := ui.NewPathGraph()
+
+	update := func() {
+		vm.hopsMutex.RLock()
+		layers := pathLayers(vm.hops)
+		var links []network.PathLink
+		if vm.pathLinks != nil {
+			links = vm.pathLinks.Links()
+		}
+		vm.hopsMutex.RUnlock()
+		graph.SetTopology(layers, pathEdges(layers, links))
+	}
+	update()
+
+	refreshWhileOpen(w, update)
+
+	w.SetContent(container.NewScroll(graph))
+	w.Resize(fyne.NewSize(900, 400))
+	w.Show()
+}
+
+// topologyNode identifies a router at a position in the merged topology
+type topologyNode struct {
+	layer int
+	ip    string
+}
+
+// mergedTopology builds one graph from several targets' paths, merging hops they share
+// so loss on common upstream infrastructure shows up as a single red node
+func mergedTopology(paths map[string][]network.NetworkHop) ([][]ui.PathNode, []ui.PathEdge) {
+	targets := make([]string, 0, len(paths))
+	for target := range paths {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets) // Stable layout between refreshes
+
+	layers := make([][]ui.PathNode, 0)
+	index := make(map[topologyNode]int)
+	users := make(map[topologyNode][]string)
+	edgeSet := make(map[ui.PathEdge]bool)
+
+	for _, target := range targets {
+		prev := -1
+		for i, hop := range paths[target] {
+			ip := hop.IP
+			if ip == "" {
+				ip = "*"
+			}
+			for len(layers) <= i {
+				layers = append(layers, make([]ui.PathNode, 0))
+			}
+
+			key := topologyNode{layer: i, ip: ip}
+			idx, ok := index[key]
+			if !ok {
+				idx = len(layers[i])
+				layers[i] = append(layers[i], ui.PathNode{IP: ip, Latency: hop.AvgLatency})
+				index[key] = idx
+			}
+			node := &layers[i][idx]
+			node.Loss = max(node.Loss, network.HistoryLossPercent(hop))
+			users[key] = append(users[key], target)
+
+			if prev >= 0 {
+				edgeSet[ui.PathEdge{Layer: i - 1, From: prev, To: idx}] = true
+			}
+			prev = idx
+		}
+	}
+
+	// Label each node with the targets that cross it
+	for key, idx := range index {
+		node := &layers[key.layer][idx]
+		crossing := users[key]
+		node.Share = float64(len(crossing)) / float64(len(targets)) * 100
+		if len(crossing) > 1 {
+			node.Note = fmt.Sprintf("shared by %d targets", len(crossing))
+		} else {
+			node.Note = crossing[0]
+		}
+	}
+
+	edges := make([]ui.PathEdge, 0, len(edgeSet))
+	for edge := range edgeSet {
+		edges = append(edges, edge)
+	}
+	return layers, edges
+}
+
+// onShowTopology opens a merged graph of the current and recently monitored targets' paths
+func (vm *VisualMTR) onShowTopology() {
+	w := vm.app.NewWindow("Multi-Target Topology")
+	graph := ui.NewPathGraph()
+
+	// Shown while the shared upstream loss alert fires
+	alert := widget.NewLabel("")
+	alert.Importance = widget.DangerImportance
+	alert.Hide()
+
+	update := func() {
+		paths := vm.pathCache.All()
+		vm.hopsMutex.RLock()
+		if vm.target != "" && len(vm.hops) > 0 {
+			paths[vm.target] = append([]network.NetworkHop(nil), vm.hops...)
+		}
+		vm.hopsMutex.RUnlock()
+		graph.SetTopology(mergedTopology(paths))
+
+		if fate, ok := vm.alerts.SharedFate(); ok {
+			alert.SetText(fmt.Sprintf("Upstream hop %s (hop %d) affecting %d targets: %s",
+				fate.IP, fate.Index+1, len(fate.Targets), strings.Join(fate.Targets, ", ")))
+			alert.Show()
+		} else {
+			alert.Hide()
+		}
+	}
+	update()
+
+	refreshWhileOpen(w, update)
+
+	w.SetContent(container.NewBorder(alert, nil, nil, nil, container.NewScroll(graph)))
+	w.Resize(fyne.NewSize(900, 500))
+	w.Show()
+}
+
+// refreshWhileOpen calls update on the UI thread every second until the window closes
+func refreshWhileOpen(w fyne.Window, update func()) {
+	done := make(chan struct{})
+	w.SetOnClosed(func() {
+		close(done)
+	})
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fyne.Do(update)
+			}
+		}
+	}()
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/afroash/visual-mtr/network"
+)
+
+// HopSource provides the live path shown by the embeddable widgets
+// Implementations must be safe to call from the UI thread while probing continues
+type HopSource interface {
+	Target() string             // Host being monitored
+	Hops() []network.NetworkHop // Snapshot of the current path
+}
+
+// ScannerSource is a HopSource fed by a scanner's update channel
+type ScannerSource struct {
+	target string
+	mu     sync.RWMutex
+	hops   []network.NetworkHop
+}
+
+// NewScannerSource collects hop updates for target until the channel closes
+// It consumes the channel, so the scanner's updates should not be read elsewhere
+func NewScannerSource(target string, updates <-chan network.HopUpdate) *ScannerSource {
+	s := &ScannerSource{target: target}
+	go func() {
+		for update := range updates {
+			if update.Pending {
+				continue
+			}
+			s.mu.Lock()
+			for len(s.hops) <= update.Index {
+				s.hops = append(s.hops, network.NetworkHop{})
+			}
+			s.hops[update.Index] = update.Hop
+			s.mu.Unlock()
+		}
+	}()
+	return s
+}
+
+// Target returns the host being monitored
+func (s *ScannerSource) Target() string {
+	return s.target
+}
+
+// Hops returns a copy of the current path
+func (s *ScannerSource) Hops() []network.NetworkHop {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]network.NetworkHop(nil), s.hops...)
+}
+
+// HopTable is a read-only table of the path: hop, address, latency, loss and a latency graph
+type HopTable struct {
+	widget.BaseWidget
+	source HopSource
+	hops   []network.NetworkHop
+	list   *widget.List
+}
+
+// NewHopTable creates a hop table showing source; call Refresh (or AutoRefresh) to update it
+func NewHopTable(source HopSource) *HopTable {
+	t := &HopTable{source: source}
+	t.list = widget.NewList(
+		func() int {
+			return len(t.hops)
+		},
+		func() fyne.CanvasObject {
+			hopNum := widget.NewLabel("")
+			hopNum.TextStyle = fyne.TextStyle{Bold: true}
+			return container.NewHBox(hopNum, widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), NewLatencyGraph())
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(t.hops) {
+				return
+			}
+			hop := t.hops[id]
+			row := obj.(*fyne.Container).Objects
+			row[0].(*widget.Label).SetText(fmt.Sprintf("%d", id+1))
+			row[1].(*widget.Label).SetText(hop.IP)
+			row[2].(*widget.Label).SetText(formatLatency(hop.AvgLatency))
+			row[3].(*widget.Label).SetText(fmt.Sprintf("%.1f%%", network.HistoryLossPercent(hop)))
+			row[4].(*LatencyGraph).SetData(hop.LatencyHistory)
+		},
+	)
+	t.ExtendBaseWidget(t)
+	t.hops = source.Hops()
+	return t
+}
+
+// Refresh reloads the path from the source and redraws the table
+func (t *HopTable) Refresh() {
+	t.hops = t.source.Hops()
+	t.list.Refresh()
+	t.BaseWidget.Refresh()
+}
+
+// CreateRenderer creates the renderer for this widget
+func (t *HopTable) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(t.list)
+}
+
+// SummaryPanel is a read-only one-paragraph summary of the path to the destination
+type SummaryPanel struct {
+	widget.BaseWidget
+	source HopSource
+	label  *widget.Label
+}
+
+// NewSummaryPanel creates a summary panel showing source; call Refresh (or AutoRefresh) to update it
+func NewSummaryPanel(source HopSource) *SummaryPanel {
+	p := &SummaryPanel{source: source, label: widget.NewLabel("")}
+	p.label.Wrapping = fyne.TextWrapWord
+	p.ExtendBaseWidget(p)
+	p.label.SetText(p.summary())
+	return p
+}
+
+// summary describes the destination's latency and loss and the worst hop on the way
+func (p *SummaryPanel) summary() string {
+	hops := p.source.Hops()
+	if len(hops) == 0 {
+		return fmt.Sprintf("%s: discovering path...", p.source.Target())
+	}
+
+	dest := hops[len(hops)-1]
+	text := fmt.Sprintf("%s: %d hops, %s, %.1f%% loss",
+		p.source.Target(), len(hops), formatLatency(dest.AvgLatency), network.HistoryLossPercent(dest))
+
+	worst, worstLoss := -1, 0.0
+	for i, hop := range hops[:len(hops)-1] {
+		if loss := network.HistoryLossPercent(hop); loss > worstLoss {
+			worst, worstLoss = i, loss
+		}
+	}
+	if worst >= 0 {
+		text += fmt.Sprintf("\nMost loss at hop %d (%s): %.1f%%", worst+1, hops[worst].IP, worstLoss)
+	}
+	return text
+}
+
+// Refresh reloads the path from the source and redraws the summary
+func (p *SummaryPanel) Refresh() {
+	p.label.SetText(p.summary())
+	p.BaseWidget.Refresh()
+}
+
+// CreateRenderer creates the renderer for this widget
+func (p *SummaryPanel) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(p.label)
+}
+
+// NewHopLatencyGraph creates a latency graph for one hop of source, updated on Refresh
+func NewHopLatencyGraph(source HopSource, index int) *LatencyGraph {
+	g := NewLatencyGraph()
+	g.source = source
+	g.index = index
+	g.pull()
+	return g
+}
+
+// AutoRefresh refreshes the given widgets on the UI thread every interval until stop is called
+func AutoRefresh(interval time.Duration, objects ...fyne.CanvasObject) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fyne.Do(func() {
+					for _, obj := range objects {
+						obj.Refresh()
+					}
+				})
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// formatLatency renders a latency, or N/A when unknown
+func formatLatency(latency float64) string {
+	if latency <= 0 {
+		return "N/A"
+	}
+	return FormatLatency(latency)
+}
@@ -1,14 +1,17 @@
 package ui
 
 import (
+	"fmt"
 	"image/color"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
-// Latency threshold colors
+// Default latency threshold colors, overridable through the theme (see Theme)
 var (
 	ColorGood    = color.NRGBA{R: 34, G: 197, B: 94, A: 255}   // Green - < 50ms
 	ColorMedium  = color.NRGBA{R: 251, G: 191, B: 36, A: 255}  // Amber - 50-150ms
@@ -18,18 +21,48 @@ var (
 	ColorGrid    = color.NRGBA{R: 55, G: 55, B: 70, A: 255}    // Grid lines
 )
 
-// Latency thresholds in milliseconds
-const (
+// Latency thresholds in milliseconds, set by the session's probing profile
+// They are read while drawing, so they only change on the UI goroutine
+var (
 	ThresholdGood   = 50.0
 	ThresholdMedium = 150.0
 )
 
+// SetLatencyThresholds colours latency below good green, below medium amber and the rest red
+func SetLatencyThresholds(good, medium float64) {
+	ThresholdGood, ThresholdMedium = good, medium
+}
+
+// MicrosecondScale is the latency in milliseconds below which values are shown in microseconds
+// LAN and gateway round trips are a fraction of a millisecond and read as "0.20 ms" otherwise
+const MicrosecondScale = 1.0
+
+// FormatLatency renders a latency given in milliseconds, switching to microseconds below 1 ms
+func FormatLatency(ms float64) string {
+	if ms < MicrosecondScale {
+		return fmt.Sprintf("%.0f µs", ms*1000)
+	}
+	return fmt.Sprintf("%.2f ms", ms)
+}
+
 // LatencyGraph is a custom widget that displays a mini line graph of latency history
 type LatencyGraph struct {
 	widget.BaseWidget
 	data      []float64 // Latency history data
 	maxPoints int       // Maximum number of points to display
 	minSize   fyne.Size // Minimum size of the graph
+	source    HopSource // Optional live source; Refresh pulls the history of hop index from it
+	index     int
+	window    GraphWindow // Part of the history shown; the zero window shows all of it
+	cursor    int         // Hover marker position in samples back from the newest, -1 for none
+	group     *ZoomGroup  // Graphs sharing this graph's window and hover marker, if any
+}
+
+// GraphWindow is the part of the latency history a graph shows, counted back from the newest sample
+// Hops are probed in the same round, so equal windows line up the same seconds across hops
+type GraphWindow struct {
+	Span   int // Number of samples shown (0 for the whole history)
+	Offset int // Number of newest samples scrolled past, to look further back
 }
 
 // NewLatencyGraph creates a new latency graph widget
@@ -38,6 +71,7 @@ func NewLatencyGraph() *LatencyGraph {
 		data:      make([]float64, 0),
 		maxPoints: 60,
 		minSize:   fyne.NewSize(200, 40),
+		cursor:    -1,
 	}
 	g.ExtendBaseWidget(g)
 	return g
@@ -46,7 +80,89 @@ func NewLatencyGraph() *LatencyGraph {
 // SetData updates the latency data displayed in the graph
 func (g *LatencyGraph) SetData(data []float64) {
 	g.data = data
-	g.Refresh()
+	g.BaseWidget.Refresh()
+}
+
+// SetWindow selects the part of the history shown
+func (g *LatencyGraph) SetWindow(window GraphWindow) {
+	g.window = window
+	g.BaseWidget.Refresh()
+}
+
+// Window returns the part of the history shown
+func (g *LatencyGraph) Window() GraphWindow {
+	return g.window
+}
+
+// SetCursor places the hover marker samplesBack samples before the newest, or removes it when negative
+func (g *LatencyGraph) SetCursor(samplesBack int) {
+	g.cursor = samplesBack
+	g.BaseWidget.Refresh()
+}
+
+// MouseIn shows the hover marker under the pointer
+func (g *LatencyGraph) MouseIn(ev *desktop.MouseEvent) {
+	g.hover(g.cursorAt(ev.Position.X))
+}
+
+// MouseMoved moves the hover marker with the pointer
+func (g *LatencyGraph) MouseMoved(ev *desktop.MouseEvent) {
+	g.hover(g.cursorAt(ev.Position.X))
+}
+
+// MouseOut removes the hover marker
+func (g *LatencyGraph) MouseOut() {
+	g.hover(-1)
+}
+
+// hover places the marker on this graph, or on every graph of its group
+func (g *LatencyGraph) hover(samplesBack int) {
+	if g.group != nil {
+		g.group.SetCursor(samplesBack)
+		return
+	}
+	g.SetCursor(samplesBack)
+}
+
+// cursorAt converts a horizontal position to the nearest sample, counted back from the newest
+func (g *LatencyGraph) cursorAt(x float32) int {
+	_, span := g.visible()
+	width := g.Size().Width
+	if width <= 0 {
+		return -1
+	}
+	slot := int(x/(width/float32(span-1)) + 0.5)
+	slot = min(max(slot, 0), span-1)
+	return g.window.Offset + span - 1 - slot
+}
+
+// visible returns the samples inside the window and the number of sample slots across the graph
+func (g *LatencyGraph) visible() ([]float64, int) {
+	span := g.window.Span
+	if span < 2 || span > g.maxPoints {
+		span = g.maxPoints
+	}
+	end := max(len(g.data)-g.window.Offset, 0)
+	start := max(end-span, 0)
+	return g.data[start:end], span
+}
+
+// Refresh redraws the graph, first reloading its hop's history when it has a source
+func (g *LatencyGraph) Refresh() {
+	g.pull()
+	g.BaseWidget.Refresh()
+}
+
+// pull copies the hop's history from the source, if any
+func (g *LatencyGraph) pull() {
+	if g.source == nil {
+		return
+	}
+	if hops := g.source.Hops(); g.index < len(hops) {
+		g.data = hops[g.index].LatencyHistory
+	} else {
+		g.data = nil
+	}
 }
 
 // MinSize returns the minimum size of the widget
@@ -95,7 +211,7 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 	objects := make([]fyne.CanvasObject, 0)
 
 	// Background rectangle
-	bg := canvas.NewRectangle(ColorBg)
+	bg := canvas.NewRectangle(themeColor(r.graph, ColorNameGraphBackground))
 	bg.Resize(size)
 	bg.Move(fyne.NewPos(0, 0))
 	objects = append(objects, bg)
@@ -103,30 +219,39 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 	// Draw grid lines (horizontal)
 	for i := 1; i < 4; i++ {
 		y := size.Height * float32(i) / 4
-		line := canvas.NewLine(ColorGrid)
+		line := canvas.NewLine(themeColor(r.graph, ColorNameGraphGrid))
 		line.Position1 = fyne.NewPos(0, y)
 		line.Position2 = fyne.NewPos(size.Width, y)
 		line.StrokeWidth = 0.5
 		objects = append(objects, line)
 	}
 
-	data := r.graph.data
+	data, span := r.graph.visible()
 	if len(data) < 2 {
 		return objects
 	}
 
 	// Find max latency for scaling (ignore timeouts which are -1)
-	maxLatency := 100.0 // Minimum scale of 100ms
+	maxLatency := 0.0
 	for _, lat := range data {
 		if lat > maxLatency {
 			maxLatency = lat
 		}
 	}
+	// Sub-millisecond paths get a microsecond scale instead of a flat line at the bottom
+	// of the usual minimum scale of 100ms
+	micro := maxLatency > 0 && maxLatency < MicrosecondScale
+	if !micro {
+		maxLatency = max(maxLatency, 100.0)
+	}
 	// Add 20% headroom
 	maxLatency *= 1.2
+	if micro {
+		objects = append(objects, r.createScaleCaption(maxLatency))
+	}
 
 	// Calculate point spacing
-	pointWidth := size.Width / float32(r.graph.maxPoints-1)
+	pointWidth := size.Width / float32(span-1)
 	startX := size.Width - float32(len(data)-1)*pointWidth
 
 	// Draw the line graph
@@ -143,7 +268,7 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 		// Skip if both points are timeouts
 		if lat1 < 0 && lat2 < 0 {
 			// Draw timeout indicator dots
-			dot := canvas.NewCircle(ColorTimeout)
+			dot := canvas.NewCircle(themeColor(r.graph, ColorNameLatencyTimeout))
 			dot.Resize(fyne.NewSize(3, 3))
 			dot.Move(fyne.NewPos(x1-1.5, size.Height/2-1.5))
 			objects = append(objects, dot)
@@ -153,7 +278,7 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 		// Handle timeout on first point
 		if lat1 < 0 {
 			// Draw timeout dot and start from middle
-			dot := canvas.NewCircle(ColorTimeout)
+			dot := canvas.NewCircle(themeColor(r.graph, ColorNameLatencyTimeout))
 			dot.Resize(fyne.NewSize(3, 3))
 			dot.Move(fyne.NewPos(x1-1.5, size.Height/2-1.5))
 			objects = append(objects, dot)
@@ -166,14 +291,14 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 			y1 := padding + graphHeight*(1-float32(lat1/maxLatency))
 			y2 := size.Height / 2
 
-			lineColor := getLatencyColor(lat1)
+			lineColor := getLatencyColor(r.graph, lat1)
 			line := canvas.NewLine(lineColor)
 			line.Position1 = fyne.NewPos(x1, y1)
 			line.Position2 = fyne.NewPos(x2, y2)
 			line.StrokeWidth = 2
 			objects = append(objects, line)
 
-			dot := canvas.NewCircle(ColorTimeout)
+			dot := canvas.NewCircle(themeColor(r.graph, ColorNameLatencyTimeout))
 			dot.Resize(fyne.NewSize(3, 3))
 			dot.Move(fyne.NewPos(x2-1.5, y2-1.5))
 			objects = append(objects, dot)
@@ -185,7 +310,7 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 		y2 := padding + graphHeight*(1-float32(lat2/maxLatency))
 
 		// Use gradient color based on the higher latency of the two points
-		lineColor := getLatencyColor(max(lat1, lat2))
+		lineColor := getLatencyColor(r.graph, max(lat1, lat2))
 
 		line := canvas.NewLine(lineColor)
 		line.Position1 = fyne.NewPos(x1, y1)
@@ -202,26 +327,72 @@ func (r *latencyGraphRenderer) createGraphObjects() []fyne.CanvasObject {
 		x := startX + float32(i)*pointWidth
 		y := padding + graphHeight*(1-float32(lat/maxLatency))
 
-		dotColor := getLatencyColor(lat)
+		dotColor := getLatencyColor(r.graph, lat)
 		dot := canvas.NewCircle(dotColor)
 		dot.Resize(fyne.NewSize(4, 4))
 		dot.Move(fyne.NewPos(x-2, y-2))
 		objects = append(objects, dot)
 	}
 
-	return objects
+	return append(objects, r.createCursorObjects(size, pointWidth)...)
+}
+
+// createScaleCaption labels the top of a microsecond-scale graph with its full-scale value
+func (r *latencyGraphRenderer) createScaleCaption(maxLatency float64) fyne.CanvasObject {
+	caption := canvas.NewText(FormatLatency(maxLatency), themeColor(r.graph, ColorNameGraphGrid))
+	caption.TextSize = themeSize(r.graph, SizeNameGraphCaption)
+	caption.Move(fyne.NewPos(2, 0))
+	caption.Resize(caption.MinSize())
+	return caption
+}
+
+// createCursorObjects draws the hover marker and this hop's value at that instant
+func (r *latencyGraphRenderer) createCursorObjects(size fyne.Size, pointWidth float32) []fyne.CanvasObject {
+	g := r.graph
+	_, span := g.visible()
+	slotsBack := g.cursor - g.window.Offset
+	if g.cursor < 0 || slotsBack < 0 || slotsBack > span-1 {
+		return nil
+	}
+	x := size.Width - float32(slotsBack)*pointWidth
+
+	marker := canvas.NewLine(themeColor(g, theme.ColorNameForeground))
+	marker.Position1 = fyne.NewPos(x, 0)
+	marker.Position2 = fyne.NewPos(x, size.Height)
+	marker.StrokeWidth = 1
+
+	index := len(g.data) - 1 - g.cursor
+	if index < 0 {
+		return []fyne.CanvasObject{marker} // No sample that far back
+	}
+	value := "timeout"
+	if g.data[index] >= 0 {
+		value = FormatLatency(g.data[index])
+	}
+	label := canvas.NewText(value, themeColor(g, theme.ColorNameForeground))
+	label.TextSize = themeSize(g, SizeNameGraphCaption)
+	labelSize := label.MinSize()
+
+	// Keep the value inside the graph, to the left of the marker near the right edge
+	labelX := x + 3
+	if labelX+labelSize.Width > size.Width {
+		labelX = x - 3 - labelSize.Width
+	}
+	label.Move(fyne.NewPos(labelX, 0))
+	label.Resize(labelSize)
+	return []fyne.CanvasObject{marker, label}
 }
 
-// getLatencyColor returns the appropriate color for a given latency value
-func getLatencyColor(latency float64) color.Color {
+// getLatencyColor returns the theme color for a given latency value
+func getLatencyColor(w fyne.Widget, latency float64) color.Color {
 	if latency < 0 {
-		return ColorTimeout
+		return themeColor(w, ColorNameLatencyTimeout)
 	}
 	if latency < ThresholdGood {
-		return ColorGood
+		return themeColor(w, ColorNameLatencyGood)
 	}
 	if latency < ThresholdMedium {
-		return ColorMedium
+		return themeColor(w, ColorNameLatencyMedium)
 	}
-	return ColorHigh
+	return themeColor(w, ColorNameLatencyHigh)
 }
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Night palette channel weights: red keeps the brightest channel, green and blue keep a little
+// of their own so the latency and segment colors stay apart
+const (
+	nightGreen = 0.35
+	nightBlue  = 0.2
+)
+
+// NightTheme dims a base theme and tints it red, for wall displays left on overnight: less light
+// in a dark NOC and less burn-in. The base theme's dark variant is used whatever the system asks for
+type NightTheme struct {
+	fyne.Theme
+	Brightness float64 // Share of the base theme's brightness kept, from 0 to 1
+}
+
+// NewNightTheme creates a night theme over base keeping brightness (0-1) of its light
+func NewNightTheme(base fyne.Theme, brightness float64) *NightTheme {
+	return &NightTheme{Theme: base, Brightness: math.Max(0, math.Min(1, brightness))}
+}
+
+// Color returns the base theme's dark color dimmed and tinted red
+func (t *NightTheme) Color(name fyne.ThemeColorName, _ fyne.ThemeVariant) color.Color {
+	c := t.Theme.Color(name, theme.VariantDark)
+	if c == nil {
+		return nil
+	}
+	return NightColor(c, t.Brightness)
+}
+
+// NightColor dims c to brightness (0-1) and shifts it towards red, keeping its transparency
+func NightColor(c color.Color, brightness float64) color.Color {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return c
+	}
+	scale := func(v uint32, weight float64) uint8 {
+		// RGBA is premultiplied; scale in that space so translucent colors stay consistent
+		return uint8(float64(v>>8) * weight * brightness)
+	}
+	return color.RGBA{
+		R: scale(max(r, g, b), 1),
+		G: scale(g, nightGreen),
+		B: scale(b, nightBlue),
+		A: uint8(a >> 8),
+	}
+}
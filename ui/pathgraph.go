@@ -0,0 +1,193 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Default path graph colors, overridable through the theme (see Theme)
+var (
+	ColorNode     = color.NRGBA{R: 96, G: 165, B: 250, A: 255}  // Blue - router
+	ColorNodeText = color.NRGBA{R: 229, G: 231, B: 235, A: 255} // Light gray - labels
+)
+
+// Path graph geometry
+const (
+	pathGraphColumnWidth = 150 // Horizontal space per TTL
+	pathGraphRowHeight   = 70  // Vertical space per responder
+	pathGraphNodeSize    = 12  // Diameter of a router node
+)
+
+// PathNode is one router answering at a TTL
+type PathNode struct {
+	IP      string  // Router address
+	Share   float64 // Percentage of answers at this TTL (0-100)
+	Latency float64 // Average latency to this TTL in milliseconds (<= 0 if unknown)
+	Loss    float64 // Packet loss percentage; lossy nodes are drawn in red
+	Note    string  // Replaces the share label when set, e.g. "shared by 3 targets"
+}
+
+// PathEdge links node From in a layer to node To in the next layer
+type PathEdge struct {
+	Layer int
+	From  int
+	To    int
+	Share float64 // Percentage of probe flows taking the link (0-100); 0 weights it by To's share
+	Label string  // Drawn at the middle of the link when set, e.g. its share and RTT
+}
+
+// PathGraph is a custom widget that draws the path as a tree of ECMP alternatives
+// Each column is a TTL; only links actually observed are drawn, weighted by their share
+type PathGraph struct {
+	widget.BaseWidget
+	layers [][]PathNode // Responders per TTL, most frequent first
+	edges  []PathEdge   // Links observed between responders of consecutive TTLs
+}
+
+// NewPathGraph creates a new path graph widget
+func NewPathGraph() *PathGraph {
+	g := &PathGraph{layers: make([][]PathNode, 0)}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// SetTopology updates the responders displayed for each TTL and the links observed between them
+// Responders without observed links are drawn unconnected, since which router follows which
+// can't be told from their shares alone
+func (g *PathGraph) SetTopology(layers [][]PathNode, edges []PathEdge) {
+	g.layers = layers
+	g.edges = edges
+	g.Refresh()
+}
+
+// MinSize returns a size large enough to show every layer and responder
+func (g *PathGraph) MinSize() fyne.Size {
+	rows := 1
+	for _, layer := range g.layers {
+		rows = max(rows, len(layer))
+	}
+	return fyne.NewSize(float32(max(len(g.layers), 1))*pathGraphColumnWidth, float32(rows)*pathGraphRowHeight)
+}
+
+// CreateRenderer creates the renderer for this widget
+func (g *PathGraph) CreateRenderer() fyne.WidgetRenderer {
+	return &pathGraphRenderer{graph: g}
+}
+
+// pathGraphRenderer handles the drawing of the path graph
+type pathGraphRenderer struct {
+	graph   *PathGraph
+	objects []fyne.CanvasObject
+}
+
+func (r *pathGraphRenderer) Destroy() {}
+
+func (r *pathGraphRenderer) Layout(size fyne.Size) {
+	r.objects = r.createGraphObjects(size)
+}
+
+func (r *pathGraphRenderer) MinSize() fyne.Size {
+	return r.graph.MinSize()
+}
+
+func (r *pathGraphRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *pathGraphRenderer) Refresh() {
+	r.objects = r.createGraphObjects(r.graph.Size())
+	canvas.Refresh(r.graph)
+}
+
+// nodePosition returns the center of a node within the graph
+func nodePosition(layer, index, count int, height float32) fyne.Position {
+	x := float32(layer)*pathGraphColumnWidth + pathGraphColumnWidth/2
+	// Center the layer's nodes vertically
+	top := (height - float32(count)*pathGraphRowHeight) / 2
+	y := top + float32(index)*pathGraphRowHeight + pathGraphNodeSize
+	return fyne.NewPos(x, y)
+}
+
+func (r *pathGraphRenderer) createGraphObjects(size fyne.Size) []fyne.CanvasObject {
+	minSize := r.graph.MinSize()
+	size = fyne.NewSize(max(size.Width, minSize.Width), max(size.Height, minSize.Height))
+
+	objects := make([]fyne.CanvasObject, 0)
+
+	bg := canvas.NewRectangle(themeColor(r.graph, ColorNameGraphBackground))
+	bg.Resize(size)
+	objects = append(objects, bg)
+
+	layers := r.graph.layers
+
+	// Edges first so nodes are drawn on top
+	for _, edge := range r.graph.edges {
+		if edge.Layer < 0 || edge.Layer+1 >= len(layers) || edge.From >= len(layers[edge.Layer]) || edge.To >= len(layers[edge.Layer+1]) {
+			continue
+		}
+		target := layers[edge.Layer+1][edge.To]
+		share := edge.Share
+		if share <= 0 {
+			share = target.Share
+		}
+		from := nodePosition(edge.Layer, edge.From, len(layers[edge.Layer]), size.Height)
+		to := nodePosition(edge.Layer+1, edge.To, len(layers[edge.Layer+1]), size.Height)
+		line := canvas.NewLine(getLatencyColor(r.graph, target.Latency))
+		line.Position1, line.Position2 = from, to
+		line.StrokeWidth = 1 + float32(share/100)*3
+		objects = append(objects, line)
+
+		if edge.Label != "" {
+			label := canvas.NewText(edge.Label, themeColor(r.graph, ColorNameLatencyTimeout))
+			label.TextSize = themeSize(r.graph, SizeNameGraphCaption)
+			label.Alignment = fyne.TextAlignCenter
+			label.Move(fyne.NewPos((from.X+to.X)/2-pathGraphColumnWidth/2, (from.Y+to.Y)/2-14))
+			label.Resize(fyne.NewSize(pathGraphColumnWidth, 12))
+			objects = append(objects, label)
+		}
+	}
+
+	// Nodes with IP and "share · latency" labels
+	for i, layer := range layers {
+		for j, node := range layer {
+			pos := nodePosition(i, j, len(layer), size.Height)
+
+			nodeColor := themeColor(r.graph, ColorNamePathNode)
+			if node.Loss > 0 {
+				nodeColor = themeColor(r.graph, ColorNameLatencyHigh)
+			}
+			dot := canvas.NewCircle(nodeColor)
+			dot.Resize(fyne.NewSize(pathGraphNodeSize, pathGraphNodeSize))
+			dot.Move(fyne.NewPos(pos.X-pathGraphNodeSize/2, pos.Y-pathGraphNodeSize/2))
+			objects = append(objects, dot)
+
+			ipText := canvas.NewText(node.IP, themeColor(r.graph, ColorNamePathNodeText))
+			ipText.TextSize = themeSize(r.graph, SizeNameGraphLabel)
+			ipText.Alignment = fyne.TextAlignCenter
+			ipText.Move(fyne.NewPos(pos.X-pathGraphColumnWidth/2, pos.Y+pathGraphNodeSize/2))
+			ipText.Resize(fyne.NewSize(pathGraphColumnWidth, 14))
+			objects = append(objects, ipText)
+
+			latency := "N/A"
+			if node.Latency > 0 {
+				latency = FormatLatency(node.Latency)
+			}
+			stat := fmt.Sprintf("%.0f%% · %s", node.Share, latency)
+			if node.Note != "" {
+				stat = fmt.Sprintf("%s · %s", node.Note, latency)
+			}
+			statText := canvas.NewText(stat, themeColor(r.graph, ColorNameLatencyTimeout))
+			statText.TextSize = themeSize(r.graph, SizeNameGraphCaption)
+			statText.Alignment = fyne.TextAlignCenter
+			statText.Move(fyne.NewPos(pos.X-pathGraphColumnWidth/2, pos.Y+pathGraphNodeSize/2+14))
+			statText.Resize(fyne.NewSize(pathGraphColumnWidth, 12))
+			objects = append(objects, statText)
+		}
+	}
+
+	return objects
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ReorderList is a vertical list of items that can be reordered by dragging them
+type ReorderList struct {
+	widget.BaseWidget
+	Items       []string
+	OnReordered func(items []string) // Called after a drag moves an item
+
+	box *fyne.Container
+}
+
+// NewReorderList creates a drag-to-reorder list of items
+func NewReorderList(items []string) *ReorderList {
+	l := &ReorderList{Items: items, box: container.NewVBox()}
+	l.ExtendBaseWidget(l)
+	l.rebuild()
+	return l
+}
+
+// Refresh rebuilds the rows from Items
+func (l *ReorderList) Refresh() {
+	l.rebuild()
+	l.BaseWidget.Refresh()
+}
+
+// CreateRenderer creates the renderer for this widget
+func (l *ReorderList) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(l.box)
+}
+
+// rebuild recreates one draggable row per item
+func (l *ReorderList) rebuild() {
+	rows := make([]fyne.CanvasObject, len(l.Items))
+	for i, item := range l.Items {
+		rows[i] = newReorderRow(l, i, item)
+	}
+	l.box.Objects = rows
+	l.box.Refresh()
+}
+
+// move shifts the item at from to position to
+func (l *ReorderList) move(from, to int) {
+	to = max(0, min(to, len(l.Items)-1))
+	if from == to || from < 0 || from >= len(l.Items) {
+		l.Refresh() // Snap the dragged row back
+		return
+	}
+
+	item := l.Items[from]
+	items := append(append([]string(nil), l.Items[:from]...), l.Items[from+1:]...)
+	items = append(items[:to], append([]string{item}, items[to:]...)...)
+	l.Items = items
+	l.Refresh()
+
+	if l.OnReordered != nil {
+		l.OnReordered(l.Items)
+	}
+}
+
+// reorderRow is one draggable item in a ReorderList
+type reorderRow struct {
+	widget.BaseWidget
+	list    *ReorderList
+	index   int
+	content fyne.CanvasObject
+	start   fyne.Position // Row position when the drag started
+	offset  float32       // Vertical distance dragged so far
+}
+
+func newReorderRow(list *ReorderList, index int, text string) *reorderRow {
+	handle := widget.NewIcon(theme.MenuIcon())
+	r := &reorderRow{
+		list:    list,
+		index:   index,
+		content: container.NewBorder(nil, nil, handle, nil, widget.NewLabel(text)),
+	}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+// CreateRenderer creates the renderer for this widget
+func (r *reorderRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(r.content)
+}
+
+// Dragged follows the pointer vertically while the row is dragged
+func (r *reorderRow) Dragged(e *fyne.DragEvent) {
+	if r.offset == 0 {
+		r.start = r.Position()
+	}
+	r.offset += e.Dragged.DY
+	r.Move(fyne.NewPos(r.start.X, r.start.Y+r.offset))
+}
+
+// DragEnd drops the row at the position nearest to where it was released
+func (r *reorderRow) DragEnd() {
+	rowHeight := r.Size().Height + theme.Padding()
+	shift := 0
+	if rowHeight > 0 {
+		shift = int(math.Round(float64(r.offset / rowHeight)))
+	}
+	r.offset = 0
+	r.list.move(r.index, r.index+shift)
+}
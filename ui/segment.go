@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Default path segment colors, overridable through the theme (see Theme)
+var (
+	ColorSegmentAccess      = color.NRGBA{R: 96, G: 165, B: 250, A: 255}  // Blue - local network
+	ColorSegmentISP         = color.NRGBA{R: 167, G: 139, B: 250, A: 255} // Purple - access provider
+	ColorSegmentTransit     = color.NRGBA{R: 45, G: 212, B: 191, A: 255}  // Teal - backbone/transit
+	ColorSegmentDestination = color.NRGBA{R: 244, G: 114, B: 182, A: 255} // Pink - target
+)
+
+// SegmentMarkerWidth is the width of the colored bar separating path segments
+const SegmentMarkerWidth = 4
+
+// SegmentColor returns the theme color used for a path segment name
+func SegmentColor(segment string) color.Color {
+	var name fyne.ThemeColorName
+	switch segment {
+	case "Access":
+		name = ColorNameSegmentAccess
+	case "ISP":
+		name = ColorNameSegmentISP
+	case "Transit":
+		name = ColorNameSegmentTransit
+	case "Destination":
+		name = ColorNameSegmentDestination
+	default:
+		return color.Transparent
+	}
+	return paletteColor(theme.Current(), name)
+}
+
+// NewSegmentMarker creates the colored bar shown at the start of each hop row
+func NewSegmentMarker() *canvas.Rectangle {
+	marker := canvas.NewRectangle(color.Transparent)
+	marker.SetMinSize(fyne.NewSize(SegmentMarkerWidth, 0))
+	return marker
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Theme color names for the graph palette
+// A theme returning its own values for these names restyles every graph without touching this package
+const (
+	ColorNameLatencyGood        fyne.ThemeColorName = "visualmtr.latencyGood"
+	ColorNameLatencyMedium      fyne.ThemeColorName = "visualmtr.latencyMedium"
+	ColorNameLatencyHigh        fyne.ThemeColorName = "visualmtr.latencyHigh"
+	ColorNameLatencyTimeout     fyne.ThemeColorName = "visualmtr.latencyTimeout"
+	ColorNameGraphBackground    fyne.ThemeColorName = "visualmtr.graphBackground"
+	ColorNameGraphGrid          fyne.ThemeColorName = "visualmtr.graphGrid"
+	ColorNamePathNode           fyne.ThemeColorName = "visualmtr.pathNode"
+	ColorNamePathNodeText       fyne.ThemeColorName = "visualmtr.pathNodeText"
+	ColorNameSegmentAccess      fyne.ThemeColorName = "visualmtr.segmentAccess"
+	ColorNameSegmentISP         fyne.ThemeColorName = "visualmtr.segmentISP"
+	ColorNameSegmentTransit     fyne.ThemeColorName = "visualmtr.segmentTransit"
+	ColorNameSegmentDestination fyne.ThemeColorName = "visualmtr.segmentDestination"
+)
+
+// Theme size names for graph text
+const (
+	SizeNameGraphLabel   fyne.ThemeSizeName = "visualmtr.graphLabel"   // Router addresses
+	SizeNameGraphCaption fyne.ThemeSizeName = "visualmtr.graphCaption" // Share and latency under a router
+)
+
+// defaultPaletteColor returns the built-in value of a graph palette color
+func defaultPaletteColor(name fyne.ThemeColorName) (color.Color, bool) {
+	switch name {
+	case ColorNameLatencyGood:
+		return ColorGood, true
+	case ColorNameLatencyMedium:
+		return ColorMedium, true
+	case ColorNameLatencyHigh:
+		return ColorHigh, true
+	case ColorNameLatencyTimeout:
+		return ColorTimeout, true
+	case ColorNameGraphBackground:
+		return ColorBg, true
+	case ColorNameGraphGrid:
+		return ColorGrid, true
+	case ColorNamePathNode:
+		return ColorNode, true
+	case ColorNamePathNodeText:
+		return ColorNodeText, true
+	case ColorNameSegmentAccess:
+		return ColorSegmentAccess, true
+	case ColorNameSegmentISP:
+		return ColorSegmentISP, true
+	case ColorNameSegmentTransit:
+		return ColorSegmentTransit, true
+	case ColorNameSegmentDestination:
+		return ColorSegmentDestination, true
+	default:
+		return nil, false
+	}
+}
+
+// defaultPaletteSize returns the built-in value of a graph size
+func defaultPaletteSize(name fyne.ThemeSizeName) (float32, bool) {
+	switch name {
+	case SizeNameGraphLabel:
+		return 11, true
+	case SizeNameGraphCaption:
+		return 10, true
+	default:
+		return 0, false
+	}
+}
+
+// Theme adds the graph palette to a base theme, which supplies everything else
+// (widget colors, fonts, icons, paddings). Forks restyle the app by embedding Theme
+// and overriding Color/Size/Font, or by wrapping part of the UI in container.NewThemeOverride.
+type Theme struct {
+	fyne.Theme
+}
+
+// NewTheme creates a theme with the graph palette on top of base
+func NewTheme(base fyne.Theme) *Theme {
+	return &Theme{Theme: base}
+}
+
+// Color returns the graph palette for its own names and defers to the base theme otherwise
+func (t *Theme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if c, ok := defaultPaletteColor(name); ok {
+		return c
+	}
+	return t.Theme.Color(name, variant)
+}
+
+// Size returns the graph text sizes for its own names and defers to the base theme otherwise
+func (t *Theme) Size(name fyne.ThemeSizeName) float32 {
+	if size, ok := defaultPaletteSize(name); ok {
+		return size
+	}
+	return t.Theme.Size(name)
+}
+
+// themeColor looks up a palette color in the theme applied to w
+func themeColor(w fyne.Widget, name fyne.ThemeColorName) color.Color {
+	return paletteColor(theme.CurrentForWidget(w), name)
+}
+
+// paletteColor looks up a palette color in t
+// Themes that don't know the palette return transparent, so the built-in value is used instead
+func paletteColor(t fyne.Theme, name fyne.ThemeColorName) color.Color {
+	variant := fyne.CurrentApp().Settings().ThemeVariant()
+	if c := t.Color(name, variant); c != nil {
+		if _, _, _, a := c.RGBA(); a > 0 {
+			return c
+		}
+	}
+	c, _ := defaultPaletteColor(name)
+	return c
+}
+
+// themeSize looks up a graph size in the theme applied to w, falling back to the built-in value
+func themeSize(w fyne.Widget, name fyne.ThemeSizeName) float32 {
+	if size := theme.CurrentForWidget(w).Size(name); size > 0 {
+		return size
+	}
+	size, _ := defaultPaletteSize(name)
+	return size
+}
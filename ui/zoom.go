@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Zoom limits of a HopChart, in samples
+const (
+	minZoomSpan = 5
+	zoomStep    = 1.25 // Span factor per scroll wheel notch
+)
+
+// ZoomGroup keeps the time window and hover marker of several latency graphs in step
+// It is used from the UI thread only
+type ZoomGroup struct {
+	window GraphWindow
+	cursor int // Hover marker in samples back from the newest, -1 for none
+	graphs []*LatencyGraph
+}
+
+// NewZoomGroup creates a group showing the whole history
+func NewZoomGroup() *ZoomGroup {
+	return &ZoomGroup{cursor: -1}
+}
+
+// Add makes a graph follow the group's window, and hovering it move every graph's marker
+func (z *ZoomGroup) Add(graph *LatencyGraph) {
+	z.graphs = append(z.graphs, graph)
+	graph.group = z
+	graph.cursor = z.cursor
+	graph.SetWindow(z.window)
+}
+
+// SetCursor places the hover marker of every graph samplesBack samples before the newest,
+// or removes it when negative
+func (z *ZoomGroup) SetCursor(samplesBack int) {
+	if samplesBack == z.cursor {
+		return
+	}
+	z.cursor = samplesBack
+	for _, graph := range z.graphs {
+		graph.SetCursor(samplesBack)
+	}
+}
+
+// SetWindow shows window on every graph of the group
+func (z *ZoomGroup) SetWindow(window GraphWindow) {
+	z.window = window
+	for _, graph := range z.graphs {
+		graph.SetWindow(window)
+	}
+}
+
+// Window returns the window the group's graphs show
+func (z *ZoomGroup) Window() GraphWindow {
+	return z.window
+}
+
+// HopChart is a large latency graph that zooms with the scroll wheel and pans by dragging
+// While synchronised with a ZoomGroup, every graph in the group follows its window
+type HopChart struct {
+	widget.BaseWidget
+	Graph           *LatencyGraph
+	OnWindowChanged func(window GraphWindow) // Called after a zoom or pan
+
+	group *ZoomGroup // Graphs following this chart, nil when not synchronised
+	drag  float32    // Drag distance not yet turned into whole samples
+}
+
+// NewHopChart creates a zoomable chart around graph
+func NewHopChart(graph *LatencyGraph) *HopChart {
+	graph.minSize = fyne.NewSize(360, 140)
+	c := &HopChart{Graph: graph}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetSync makes the graphs of group follow this chart, or releases them when group is nil
+// Released graphs go back to showing the whole history
+func (c *HopChart) SetSync(group *ZoomGroup) {
+	if c.group != nil {
+		c.group.SetWindow(GraphWindow{})
+	}
+	c.group = group
+	if group != nil {
+		group.SetWindow(c.Graph.Window())
+	}
+}
+
+// ResetZoom shows the whole history again
+func (c *HopChart) ResetZoom() {
+	c.setWindow(GraphWindow{})
+}
+
+// Scrolled zooms the time axis in (scrolling up) or out (scrolling down)
+func (c *HopChart) Scrolled(ev *fyne.ScrollEvent) {
+	window := c.Graph.Window()
+	span := window.Span
+	if span == 0 {
+		span = c.Graph.maxPoints
+	}
+
+	if ev.Scrolled.DY > 0 {
+		span = max(int(float32(span)/zoomStep), minZoomSpan)
+	} else if ev.Scrolled.DY < 0 {
+		span = min(int(float32(span)*zoomStep)+1, c.Graph.maxPoints)
+	}
+	window.Span = span
+	window.Offset = min(window.Offset, c.Graph.maxPoints-span)
+	c.setWindow(window)
+}
+
+// Dragged pans the time axis; dragging right looks further back
+func (c *HopChart) Dragged(ev *fyne.DragEvent) {
+	window := c.Graph.Window()
+	span := window.Span
+	if span == 0 {
+		span = c.Graph.maxPoints
+	}
+	width := c.Size().Width
+	if width <= 0 || span < 2 {
+		return
+	}
+
+	c.drag += ev.Dragged.DX
+	sampleWidth := width / float32(span-1)
+	samples := int(c.drag / sampleWidth)
+	if samples == 0 {
+		return
+	}
+	c.drag -= float32(samples) * sampleWidth
+
+	window.Span = span
+	window.Offset = min(max(window.Offset+samples, 0), c.Graph.maxPoints-span)
+	c.setWindow(window)
+}
+
+// DragEnd discards the leftover drag distance
+func (c *HopChart) DragEnd() {
+	c.drag = 0
+}
+
+// setWindow shows window on the chart and on the graphs following it
+func (c *HopChart) setWindow(window GraphWindow) {
+	c.Graph.SetWindow(window)
+	if c.group != nil {
+		c.group.SetWindow(window)
+	}
+	if c.OnWindowChanged != nil {
+		c.OnWindowChanged(window)
+	}
+}
+
+// MinSize returns the minimum size of the chart
+func (c *HopChart) MinSize() fyne.Size {
+	return c.Graph.MinSize()
+}
+
+// CreateRenderer creates the renderer for this widget
+func (c *HopChart) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(c.Graph)
+}